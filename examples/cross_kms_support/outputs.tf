@@ -16,3 +16,8 @@ output "kms_instance_guid" {
   value       = module.ocp_base.kms_config.instance_id
   description = "GUID of the KMS instance existing in the cross account."
 }
+
+output "kms_key_id" {
+  value       = module.ocp_base.kms_config.crk_id
+  description = "Id of the KMS root key used to encrypt the cluster."
+}
@@ -87,6 +87,11 @@ variable "size" {
   type        = string
   description = "Defines the cluster size configuration. [Learn more](https://github.com/terraform-ibm-modules/terraform-ibm-base-ocp-vpc/blob/main/solutions/quickstart/DA_docs.md)."
   default     = "mini"
+
+  validation {
+    condition     = contains(["mini", "small", "medium", "large"], var.size)
+    error_message = "Invalid size. Allowed values are 'mini', 'small', 'medium', or 'large'."
+  }
 }
 
 variable "allow_public_access_to_cluster_management" {
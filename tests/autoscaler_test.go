@@ -0,0 +1,85 @@
+//go:build autoscaler
+
+// Tests in this file are only built with the autoscaler build tag, as waiting for worker pools to scale up and down takes
+// well over an hour: go test -tags autoscaler -run TestRunAutoscalerExample -timeout 6h
+package test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/random"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/terraform-ibm-modules/ibmcloud-terratest-wrapper/testhelper"
+
+	"github.com/terraform-ibm-modules/terraform-ibm-base-ocp-vpc/internal/deadline"
+	"github.com/terraform-ibm-modules/terraform-ibm-base-ocp-vpc/internal/ibmcloud"
+	"github.com/terraform-ibm-modules/terraform-ibm-base-ocp-vpc/internal/verify"
+)
+
+// How long scaling the autoscaled pool up and back down may take in total
+const autoscalerTimeout = 2 * time.Hour
+
+// How long deleting the added workers may take after the scale down delay of the autoscaler
+const autoscalerScaleDownGrace = 30 * time.Minute
+
+// autoscaledPool is the worker pool of the advanced example with autoscaling enabled
+const autoscaledPool = "default"
+
+// checkAutoscaling makes the autoscaler add a node to the autoscaled pool of the cluster and remove it again, and logs
+// how long both took
+func checkAutoscaling(options *testhelper.TestOptions) error {
+	t := options.Testing
+	ctx, cancel := context.WithTimeout(deadline.New(t, destroyBudget).Context(), autoscalerTimeout)
+	defer cancel()
+	clusterName, err := terraform.OutputContextE(t, ctx, options.TerraformOptions, "cluster_name")
+	if !assert.NoError(t, err, "error getting the cluster_name output") {
+		return err
+	}
+	authenticator, err := ibmcloud.NewIamAuthenticator(validateEnvVariable(t, "TF_VAR_ibmcloud_api_key"))
+	if !assert.NoError(t, err, "Failed to create IAM authenticator") {
+		return err
+	}
+	_, kubectl, err := clusterKubectl(t, ctx, ibmcloud.NewContainersClient(authenticator), clusterName, options.Region)
+	if !assert.NoError(t, err, "Failed to get the kubeconfig of the cluster") {
+		return err
+	}
+	// the example keeps outbound traffic protection enabled, so the load runs an image of the OpenShift release
+	image, err := verify.ToolsImage(ctx, kubectl)
+	if !assert.NoError(t, err, "Failed to get the image of the autoscaler load") {
+		return err
+	}
+
+	load := verify.AutoscalerLoad{
+		Namespace:      "autoscaler-" + strings.ToLower(random.UniqueID()),
+		Image:          image,
+		Pool:           autoscaledPool,
+		ExtraNodes:     1,
+		ScaleDownGrace: autoscalerScaleDownGrace,
+	}
+	report, err := verify.AutoscalerScaling(ctx, kubectl, load, 30*time.Second)
+	logger.Logf(t, "Autoscaler of cluster %s: %s", clusterName, report)
+	assert.NoError(t, err, "Cluster autoscaler did not scale the pool up and down as configured")
+	return err
+}
+
+// TestRunAutoscalerExample scales the autoscaled pool of the advanced example. The consistency check that follows the
+// hook proves that ignore_worker_pool_size_changes keeps Terraform from resizing the pool.
+func TestRunAutoscalerExample(t *testing.T) {
+	t.Parallel()
+	acquireResources(t)
+
+	options := setupOptions(t, "base-ocp-as", advancedExampleDir, ocpVersion3)
+	options.PostApplyHook = mustGatherOnFailure(checkAutoscaling, "cluster_name")
+
+	options.IgnoreUpdates = testhelper.Exemptions{List: []string{"module.logs_agents.helm_release.logs_agent"}}
+	options.IgnoreDestroys = testhelper.Exemptions{List: []string{"module.logs_agents.terraform_data.install_required_binaries[0]"}}
+	output, err := options.RunTestConsistency()
+
+	assert.Nil(t, err, "This should not have errored")
+	assert.NotNil(t, output, "Expected some output")
+}
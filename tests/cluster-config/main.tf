@@ -0,0 +1,11 @@
+#############################################################################
+# Download an admin kubeconfig for a cluster under test
+#############################################################################
+
+data "ibm_container_cluster_config" "cluster_config" {
+  cluster_name_id   = var.cluster_id
+  resource_group_id = var.cluster_resource_group_id
+  config_dir        = "${path.module}/kubeconfig"
+  admin             = true
+  endpoint_type     = var.cluster_config_endpoint_type != "default" ? var.cluster_config_endpoint_type : null # null value represents default
+}
@@ -0,0 +1,8 @@
+########################################################################################################################
+# Outputs
+########################################################################################################################
+
+output "config_file_path" {
+  description = "Path to the downloaded admin kubeconfig"
+  value       = data.ibm_container_cluster_config.cluster_config.config_file_path
+}
@@ -0,0 +1,32 @@
+##############################################################################
+# Input variables
+##############################################################################
+
+variable "ibmcloud_api_key" {
+  type        = string
+  description = "The IBM Cloud API Key"
+  sensitive   = true
+}
+
+variable "region" {
+  type        = string
+  description = "Region the cluster is deployed in"
+}
+
+variable "cluster_id" {
+  type        = string
+  description = "The name or ID of the cluster to download the kubeconfig for"
+}
+
+variable "cluster_resource_group_id" {
+  type        = string
+  description = "The resource group ID of the cluster. Only required if cluster_id is a cluster name"
+  default     = null
+}
+
+variable "cluster_config_endpoint_type" {
+  type        = string
+  description = "Specify which type of endpoint to use for for cluster config access: 'default', 'private', 'vpe', 'link'. 'default' value will use the default endpoint of the cluster."
+  default     = "default"
+  nullable    = false
+}
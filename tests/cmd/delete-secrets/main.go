@@ -0,0 +1,145 @@
+// Command delete-secrets deletes every secret in a Secrets Manager secret group. It is a Go port of
+// solutions/fully-configurable/scripts/delete_secrets.sh and takes the same positional arguments:
+//
+//	delete-secrets [-dry-run] <secret_group_id> <provider_visibility> <secrets_manager_instance_id> <secrets_manager_region> <secrets_manager_endpoint>
+//
+// The API key is read from the API_KEY environment variable. IBMCLOUD_IAM_API_ENDPOINT is honoured the same way as in
+// the script.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/IBM/go-sdk-core/v5/core"
+	"github.com/terraform-ibm-modules/terraform-ibm-base-ocp-vpc/internal/ibmcloud"
+)
+
+const defaultIamEndpoint = "iam.cloud.ibm.com"
+
+// secretsClient is the subset of the Secrets Manager API used to empty a secret group
+type secretsClient interface {
+	ListSecrets(ctx context.Context, groupID string) ([]ibmcloud.Secret, error)
+	DeleteSecret(ctx context.Context, id string) error
+}
+
+type deleteOptions struct {
+	dryRun bool
+	// attempts is the number of times deleting a single secret is tried
+	attempts int
+	// settle is how long to wait before checking that the group is empty
+	settle time.Duration
+}
+
+func main() {
+	if err := run(os.Args[1:], os.Getenv, os.Stderr); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string, getenv func(string) string, log io.Writer) error {
+	flags := flag.NewFlagSet("delete-secrets", flag.ContinueOnError)
+	flags.SetOutput(log)
+	dryRun := flags.Bool("dry-run", false, "list the secrets that would be deleted without deleting them")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 5 {
+		return errors.New("usage: delete-secrets [-dry-run] <secret_group_id> <provider_visibility> <secrets_manager_instance_id> <secrets_manager_region> <secrets_manager_endpoint>")
+	}
+	groupID, visibility, instanceID, region, endpoint := flags.Arg(0), flags.Arg(1), flags.Arg(2), flags.Arg(3), flags.Arg(4)
+
+	apiKey := getenv("API_KEY")
+	if apiKey == "" {
+		return errors.New("API_KEY environment variable is not set")
+	}
+	authenticator, err := core.NewIamAuthenticatorBuilder().
+		SetApiKey(apiKey).
+		SetURL("https://" + iamEndpoint(getenv("IBMCLOUD_IAM_API_ENDPOINT"), visibility)).
+		Build()
+	if err != nil {
+		return fmt.Errorf("could not create an IAM authenticator: %w", err)
+	}
+
+	client := ibmcloud.NewSecretsManagerClient(instanceID, region, endpoint, authenticator)
+	return deleteSecrets(context.Background(), client, groupID, deleteOptions{dryRun: *dryRun, attempts: 2, settle: 5 * time.Second}, log)
+}
+
+// iamEndpoint returns the IAM host to use. The private endpoint is only picked for the default public IAM host, so
+// that a custom IBMCLOUD_IAM_API_ENDPOINT is always used as is.
+func iamEndpoint(configured, visibility string) string {
+	endpoint := configured
+	if endpoint == "" {
+		endpoint = defaultIamEndpoint
+	}
+	endpoint = strings.TrimPrefix(endpoint, "https://")
+	if endpoint == defaultIamEndpoint && visibility == "private" {
+		endpoint = "private." + endpoint
+	}
+	return endpoint
+}
+
+// deleteSecrets deletes all secrets in the group, then checks that the group is empty
+func deleteSecrets(ctx context.Context, client secretsClient, groupID string, opts deleteOptions, log io.Writer) error {
+	secrets, err := client.ListSecrets(ctx, groupID)
+	if err != nil {
+		return fmt.Errorf("could not list the secrets of group %s: %w", groupID, err)
+	}
+	if len(secrets) == 0 {
+		fmt.Fprintln(log, "Found no secrets to delete")
+		return nil
+	}
+
+	for _, secret := range secrets {
+		if opts.dryRun {
+			fmt.Fprintf(log, "Would delete secret %s with id %s\n", secret.Name, secret.ID)
+			continue
+		}
+		fmt.Fprintf(log, "Deleting secret %s with id %s\n", secret.Name, secret.ID)
+		if err := deleteWithRetry(ctx, client, secret, opts.attempts, log); err != nil {
+			return err
+		}
+	}
+	if opts.dryRun {
+		fmt.Fprintf(log, "Dry run: %d secrets would be deleted from group %s\n", len(secrets), groupID)
+		return nil
+	}
+
+	fmt.Fprintln(log, "Waiting for the secrets to be deleted")
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(opts.settle):
+	}
+
+	remaining, err := client.ListSecrets(ctx, groupID)
+	if err != nil {
+		return fmt.Errorf("could not list the secrets of group %s: %w", groupID, err)
+	}
+	if len(remaining) > 0 {
+		return fmt.Errorf("failed to delete %d secrets in group %s, please delete manually", len(remaining), groupID)
+	}
+	fmt.Fprintln(log, "Successfully deleted all the secrets in the group")
+	return nil
+}
+
+func deleteWithRetry(ctx context.Context, client secretsClient, secret ibmcloud.Secret, attempts int, log io.Writer) error {
+	var err error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		if err = client.DeleteSecret(ctx, secret.ID); err == nil {
+			fmt.Fprintln(log, "Successfully deleted the secret")
+			return nil
+		}
+		if attempt < attempts {
+			fmt.Fprintf(log, "Failed to remove the secret, retrying: %v\n", err)
+		}
+	}
+	return fmt.Errorf("failed to delete secret %s with id %s, please delete manually: %w", secret.Name, secret.ID, err)
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/terraform-ibm-modules/terraform-ibm-base-ocp-vpc/internal/ibmcloud"
+	"github.com/terraform-ibm-modules/terraform-ibm-base-ocp-vpc/internal/ibmcloud/ibmcloudtest"
+)
+
+func newSecretsManager(t *testing.T) *ibmcloudtest.SecretsManager {
+	sm := ibmcloudtest.NewSecretsManager(t)
+	sm.AddSecret(ibmcloud.Secret{ID: "s-1", Name: "ingress-cert-1", SecretGroupID: "cluster-group"})
+	sm.AddSecret(ibmcloud.Secret{ID: "s-2", Name: "ingress-cert-2", SecretGroupID: "cluster-group"})
+	sm.AddSecret(ibmcloud.Secret{ID: "s-3", Name: "unrelated", SecretGroupID: "default"})
+	return sm
+}
+
+func TestDeleteSecrets(t *testing.T) {
+	sm := newSecretsManager(t)
+	var log bytes.Buffer
+
+	err := deleteSecrets(context.Background(), sm.Client(), "cluster-group", deleteOptions{attempts: 2}, &log)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"s-1", "s-2"}, sm.Deleted())
+	assert.Contains(t, log.String(), "Deleting secret ingress-cert-1 with id s-1")
+	assert.Contains(t, log.String(), "Successfully deleted all the secrets in the group")
+}
+
+func TestDeleteSecretsDryRun(t *testing.T) {
+	sm := newSecretsManager(t)
+	var log bytes.Buffer
+
+	err := deleteSecrets(context.Background(), sm.Client(), "cluster-group", deleteOptions{dryRun: true, attempts: 2}, &log)
+	require.NoError(t, err)
+	assert.Empty(t, sm.Deleted())
+	assert.Contains(t, log.String(), "Would delete secret ingress-cert-2 with id s-2")
+	assert.Contains(t, log.String(), "Dry run: 2 secrets would be deleted from group cluster-group")
+}
+
+func TestDeleteSecretsEmptyGroup(t *testing.T) {
+	sm := newSecretsManager(t)
+	var log bytes.Buffer
+
+	err := deleteSecrets(context.Background(), sm.Client(), "empty-group", deleteOptions{attempts: 2}, &log)
+	require.NoError(t, err)
+	assert.Empty(t, sm.Deleted())
+	assert.Contains(t, log.String(), "Found no secrets to delete")
+}
+
+func TestDeleteSecretsRetries(t *testing.T) {
+	sm := newSecretsManager(t)
+	// the client retries three times, so the first attempt fails and the second succeeds
+	sm.FailDeletes("s-1", 4)
+	var log bytes.Buffer
+
+	err := deleteSecrets(context.Background(), sm.Client(), "cluster-group", deleteOptions{attempts: 2}, &log)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"s-1", "s-2"}, sm.Deleted())
+	assert.Contains(t, log.String(), "Failed to remove the secret, retrying")
+}
+
+func TestDeleteSecretsGivesUp(t *testing.T) {
+	sm := newSecretsManager(t)
+	sm.FailDeletes("s-2", 8)
+
+	err := deleteSecrets(context.Background(), sm.Client(), "cluster-group", deleteOptions{attempts: 2}, &bytes.Buffer{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to delete secret ingress-cert-2 with id s-2, please delete manually")
+	assert.Equal(t, []string{"s-1"}, sm.Deleted())
+}
+
+func TestIamEndpoint(t *testing.T) {
+	assert.Equal(t, "iam.cloud.ibm.com", iamEndpoint("", "public"))
+	assert.Equal(t, "private.iam.cloud.ibm.com", iamEndpoint("", "private"))
+	assert.Equal(t, "private.iam.cloud.ibm.com", iamEndpoint("https://iam.cloud.ibm.com", "private"))
+	assert.Equal(t, "iam.test.cloud.ibm.com", iamEndpoint("https://iam.test.cloud.ibm.com", "private"))
+}
+
+func TestRunUsage(t *testing.T) {
+	getenv := func(string) string { return "" }
+
+	err := run([]string{"group"}, getenv, &bytes.Buffer{})
+	assert.ErrorContains(t, err, "usage: delete-secrets")
+
+	err = run([]string{"group", "private", "guid", "us-south", "private"}, getenv, &bytes.Buffer{})
+	assert.ErrorContains(t, err, "API_KEY environment variable is not set")
+}
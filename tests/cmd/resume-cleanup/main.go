@@ -0,0 +1,196 @@
+// Command resume-cleanup destroys what test runs left behind, as recorded in their run journal, e.g. after a CI runner
+// was pre-empted:
+//
+//	go run ./cmd/resume-cleanup -journal run-journal.jsonl
+//
+// Terraform configurations are destroyed from their state directory, with the variables they were applied with and the
+// API key read from TF_VAR_ibmcloud_api_key. Schematics workspaces are found by their tag, and deleted after destroying
+// their resources. Every artifact that is cleaned up is recorded in the journal, so the command can be run again after
+// a failure. The artifacts that could not be cleaned up are written to the -leftovers file.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/terraform-ibm-modules/terraform-ibm-base-ocp-vpc/internal/cleanup"
+	"github.com/terraform-ibm-modules/terraform-ibm-base-ocp-vpc/internal/ibmcloud"
+	"github.com/terraform-ibm-modules/terraform-ibm-base-ocp-vpc/internal/journal"
+)
+
+// varFileName is the variables file written into the Terraform directory for the destroy
+const varFileName = "resume-cleanup.tfvars.json"
+
+type resumeOptions struct {
+	dryRun      bool
+	concurrency int
+	leftovers   string
+}
+
+// cleaner destroys the artifacts of a journal
+type cleaner struct {
+	// terraform is the Terraform binary
+	terraform string
+	// schematics deletes the Schematics workspaces with a tag
+	schematics func(ctx context.Context, tag string) error
+	log        io.Writer
+}
+
+func main() {
+	if err := run(os.Args[1:], os.Getenv, os.Stderr); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string, getenv func(string) string, log io.Writer) error {
+	flags := flag.NewFlagSet("resume-cleanup", flag.ContinueOnError)
+	flags.SetOutput(log)
+	path := flags.String("journal", "run-journal.jsonl", "run journal written by the tests")
+	terraform := flags.String("terraform", "terraform", "Terraform binary")
+	concurrency := flags.Int("concurrency", 4, "how many artifacts are destroyed at the same time")
+	leftovers := flags.String("leftovers", "cleanup-leftovers.json", "file listing the artifacts that could not be cleaned up")
+	dryRun := flags.Bool("dry-run", false, "list the artifacts that would be cleaned up without destroying them")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 0 {
+		return errors.New("usage: resume-cleanup [-journal file] [-terraform binary] [-concurrency n] [-leftovers file] [-dry-run]")
+	}
+
+	c := &cleaner{
+		terraform: *terraform,
+		schematics: func(ctx context.Context, tag string) error {
+			return deleteSchematicsWorkspaces(ctx, getenv("TF_VAR_ibmcloud_api_key"), tag)
+		},
+		log: log,
+	}
+	return resume(context.Background(), *path, c, resumeOptions{dryRun: *dryRun, concurrency: *concurrency, leftovers: *leftovers})
+}
+
+// resume destroys the artifacts of the journal at path that were neither destroyed nor released, each after the
+// artifacts that depend on it
+func resume(ctx context.Context, path string, c *cleaner, opts resumeOptions) error {
+	records, malformed, err := journal.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("could not read the journal: %w", err)
+	}
+	if malformed > 0 {
+		fmt.Fprintf(c.log, "Skipped %d lines of %s that are not records, e.g. truncated by a crash\n", malformed, path)
+	}
+	remaining := journal.Remaining(records)
+	if len(remaining) == 0 {
+		fmt.Fprintf(c.log, "Found nothing to clean up in %s\n", path)
+		return nil
+	}
+	if opts.dryRun {
+		for _, artifact := range remaining {
+			fmt.Fprintf(c.log, "Would clean up %s\n", artifact)
+		}
+		fmt.Fprintf(c.log, "Dry run: %d artifacts would be cleaned up\n", len(remaining))
+		return nil
+	}
+
+	j, err := journal.Open(path, "resume-cleanup-"+time.Now().UTC().Format("20060102T150405"))
+	if err != nil {
+		return err
+	}
+	defer j.Close()
+
+	registry := cleanup.NewRegistry(opts.concurrency)
+	entries := map[string]*cleanup.Entry{}
+	for _, artifact := range remaining {
+		var dependsOn []*cleanup.Entry
+		for _, id := range artifact.DependsOn {
+			if entry, ok := entries[id]; ok {
+				dependsOn = append(dependsOn, entry)
+			}
+		}
+		entries[artifact.ID] = registry.Register(artifact.String(), func(ctx context.Context) error {
+			fmt.Fprintf(c.log, "Cleaning up %s\n", artifact)
+			if err := c.destroy(ctx, artifact); err != nil {
+				return err
+			}
+			return j.Destroy(artifact.ID)
+		}, dependsOn...)
+	}
+
+	leftovers := registry.Cleanup(ctx)
+	if len(leftovers) == 0 {
+		fmt.Fprintf(c.log, "Cleaned up %d artifacts\n", len(remaining))
+		return nil
+	}
+	if err := cleanup.WriteLeftovers(opts.leftovers, leftovers); err != nil {
+		return err
+	}
+	return fmt.Errorf("%d of %d artifacts could not be cleaned up, they are listed in %s", len(leftovers), len(remaining), opts.leftovers)
+}
+
+func (c *cleaner) destroy(ctx context.Context, artifact journal.Artifact) error {
+	switch artifact.Kind {
+	case journal.KindTerraform:
+		return c.destroyTerraform(ctx, artifact)
+	case journal.KindSchematics:
+		return c.schematics(ctx, artifact.Tag)
+	default:
+		return fmt.Errorf("unknown kind of artifact %q", artifact.Kind)
+	}
+}
+
+// destroyTerraform destroys a Terraform configuration from its state directory, then deletes its workspace
+func (c *cleaner) destroyTerraform(ctx context.Context, artifact journal.Artifact) error {
+	if _, err := os.Stat(artifact.Dir); err != nil {
+		return fmt.Errorf("the Terraform state is gone with its directory, delete the resources with prefix %s in %s manually: %w", artifact.Prefix, artifact.Region, err)
+	}
+	vars, err := json.Marshal(artifact.Vars)
+	if artifact.Vars == nil {
+		vars = []byte("{}")
+	}
+	if err != nil {
+		return err
+	}
+	varFile := filepath.Join(artifact.Dir, varFileName)
+	if err := os.WriteFile(varFile, vars, 0o600); err != nil {
+		return err
+	}
+	defer os.Remove(varFile)
+
+	commands := [][]string{{"init", "-input=false"}}
+	if artifact.Workspace != "" {
+		commands = append(commands, []string{"workspace", "select", "-or-create=true", artifact.Workspace})
+	}
+	commands = append(commands, []string{"destroy", "-auto-approve", "-input=false", "-var-file=" + varFileName})
+	if artifact.Workspace != "" {
+		commands = append(commands, []string{"workspace", "select", "default"}, []string{"workspace", "delete", artifact.Workspace})
+	}
+	for _, args := range commands {
+		cmd := exec.CommandContext(ctx, c.terraform, args...)
+		cmd.Dir = artifact.Dir
+		cmd.Stdout, cmd.Stderr = c.log, c.log
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("terraform %s in %s failed: %w", strings.Join(args, " "), artifact.Dir, err)
+		}
+	}
+	return nil
+}
+
+// deleteSchematicsWorkspaces deletes the workspaces with the tag in every Schematics location
+func deleteSchematicsWorkspaces(ctx context.Context, apiKey string, tag string) error {
+	if apiKey == "" {
+		return errors.New("TF_VAR_ibmcloud_api_key environment variable is not set")
+	}
+	authenticator, err := ibmcloud.NewIamAuthenticator(apiKey)
+	if err != nil {
+		return err
+	}
+	return ibmcloud.DeleteTaggedWorkspacesInAllLocations(ctx, authenticator, tag, time.Minute)
+}
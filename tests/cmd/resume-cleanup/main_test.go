@@ -0,0 +1,187 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/terraform-ibm-modules/terraform-ibm-base-ocp-vpc/internal/journal"
+)
+
+// fakeTerraform logs its arguments and, for destroy, the variables file, and fails in directories with a fail file
+const fakeTerraform = `#!/bin/sh
+echo "$(basename "$PWD") $*" >> "$FAKE_TERRAFORM_LOG"
+case "$1" in
+destroy)
+	cat resume-cleanup.tfvars.json >> "$FAKE_TERRAFORM_LOG"
+	echo >> "$FAKE_TERRAFORM_LOG"
+	[ ! -e fail ]
+	;;
+esac
+`
+
+// lockedBuffer is a log written by concurrent destroys
+type lockedBuffer struct {
+	mu sync.Mutex
+	b  bytes.Buffer
+}
+
+func (l *lockedBuffer) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.b.Write(p)
+}
+
+func (l *lockedBuffer) String() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.b.String()
+}
+
+type fixture struct {
+	dir          string
+	journal      string
+	terraformLog string
+	cleaner      *cleaner
+	log          *lockedBuffer
+	mu           sync.Mutex
+	tags         []string
+}
+
+func newFixture(t *testing.T) *fixture {
+	f := &fixture{dir: t.TempDir(), log: &lockedBuffer{}}
+	f.journal = filepath.Join(f.dir, "run-journal.jsonl")
+	f.terraformLog = filepath.Join(f.dir, "terraform.log")
+	t.Setenv("FAKE_TERRAFORM_LOG", f.terraformLog)
+	terraform := filepath.Join(f.dir, "terraform")
+	require.NoError(t, os.WriteFile(terraform, []byte(fakeTerraform), 0o755))
+	f.cleaner = &cleaner{
+		terraform: terraform,
+		schematics: func(ctx context.Context, tag string) error {
+			f.mu.Lock()
+			defer f.mu.Unlock()
+			f.tags = append(f.tags, tag)
+			return nil
+		},
+		log: f.log,
+	}
+	return f
+}
+
+// stateDir creates a Terraform directory of an artifact
+func (f *fixture) stateDir(t *testing.T, name string) string {
+	dir := filepath.Join(f.dir, name)
+	require.NoError(t, os.MkdirAll(dir, 0o755))
+	return dir
+}
+
+func (f *fixture) terraformCommands(t *testing.T) []string {
+	data, err := os.ReadFile(f.terraformLog)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	require.NoError(t, err)
+	return strings.Split(strings.TrimSpace(string(data)), "\n")
+}
+
+func TestResumePartialJournal(t *testing.T) {
+	f := newFixture(t)
+	vpc := f.stateDir(t, "existing")
+	cluster := f.stateDir(t, "upgrade")
+	released := f.stateDir(t, "released")
+	destroyed := f.stateDir(t, "destroyed")
+	lines := []string{
+		fmt.Sprintf(`{"event":"created","id":"run1/1","kind":"terraform","dir":%q,"workspace":"ocp-existing-abc","vars":{"prefix":"ocp-existing-abc","region":"us-south"}}`, vpc),
+		fmt.Sprintf(`{"event":"created","id":"run1/2","kind":"terraform","dir":%q,"depends_on":["run1/1"]}`, cluster),
+		`{"event":"created","id":"run1/3","kind":"schematics","tag":"test-cleanup:xyz","depends_on":["run1/1"]}`,
+		fmt.Sprintf(`{"event":"created","id":"run1/4","kind":"terraform","dir":%q}`, released),
+		`{"event":"released","id":"run1/4"}`,
+		fmt.Sprintf(`{"event":"created","id":"run1/5","kind":"terraform","dir":%q}`, destroyed),
+		`{"event":"destroyed","id":"run1/5"}`,
+		`{"event":"created","id":"run1/6","kind":"terraform","dir":"/gone/with/the/runner","prefix":"ocp-upg-def","region":"eu-de"}`,
+		`{"event":"destroyed","id":"run1/`,
+	}
+	require.NoError(t, os.WriteFile(f.journal, []byte(strings.Join(lines, "\n")), 0o644))
+	leftovers := filepath.Join(f.dir, "leftovers.json")
+
+	err := resume(context.Background(), f.journal, f.cleaner, resumeOptions{concurrency: 2, leftovers: leftovers})
+	assert.EqualError(t, err, "1 of 4 artifacts could not be cleaned up, they are listed in "+leftovers)
+	assert.Contains(t, f.log.String(), "Skipped 1 lines")
+
+	commands := f.terraformCommands(t)
+	assert.Equal(t, []string{
+		"upgrade init -input=false",
+		"upgrade destroy -auto-approve -input=false -var-file=resume-cleanup.tfvars.json",
+		"{}",
+		"existing init -input=false",
+		"existing workspace select -or-create=true ocp-existing-abc",
+		"existing destroy -auto-approve -input=false -var-file=resume-cleanup.tfvars.json",
+		`{"prefix":"ocp-existing-abc","region":"us-south"}`,
+		"existing workspace select default",
+		"existing workspace delete ocp-existing-abc",
+	}, commands, "the VPC is destroyed after the cluster and the Schematics workspace in it")
+	assert.Equal(t, []string{"test-cleanup:xyz"}, f.tags)
+	assert.NoFileExists(t, filepath.Join(vpc, varFileName))
+
+	data, err := os.ReadFile(leftovers)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "terraform run1/6 prefix=ocp-upg-def region=eu-de dir=/gone/with/the/runner")
+	assert.Contains(t, string(data), "delete the resources with prefix ocp-upg-def in eu-de manually")
+
+	// what was cleaned up is recorded, so running again only retries what is left
+	records, _, err := journal.ReadFile(f.journal)
+	require.NoError(t, err)
+	remaining := journal.Remaining(records)
+	require.Len(t, remaining, 1)
+	assert.Equal(t, "run1/6", remaining[0].ID)
+
+	require.NoError(t, os.Remove(f.terraformLog))
+	f.tags = nil
+	err = resume(context.Background(), f.journal, f.cleaner, resumeOptions{concurrency: 2, leftovers: leftovers})
+	assert.EqualError(t, err, "1 of 1 artifacts could not be cleaned up, they are listed in "+leftovers)
+	assert.Empty(t, f.terraformCommands(t))
+	assert.Empty(t, f.tags)
+}
+
+func TestResumeFailedDependent(t *testing.T) {
+	f := newFixture(t)
+	vpc := f.stateDir(t, "existing")
+	cluster := f.stateDir(t, "upgrade")
+	require.NoError(t, os.WriteFile(filepath.Join(cluster, "fail"), nil, 0o644))
+	lines := []string{
+		fmt.Sprintf(`{"event":"created","id":"run1/1","kind":"terraform","dir":%q}`, vpc),
+		fmt.Sprintf(`{"event":"created","id":"run1/2","kind":"terraform","dir":%q,"depends_on":["run1/1"]}`, cluster),
+	}
+	require.NoError(t, os.WriteFile(f.journal, []byte(strings.Join(lines, "\n")+"\n"), 0o644))
+	leftovers := filepath.Join(f.dir, "leftovers.json")
+
+	err := resume(context.Background(), f.journal, f.cleaner, resumeOptions{concurrency: 2, leftovers: leftovers})
+	assert.EqualError(t, err, "2 of 2 artifacts could not be cleaned up, they are listed in "+leftovers)
+	for _, command := range f.terraformCommands(t) {
+		assert.NotContains(t, command, "existing destroy", "the VPC is not destroyed while the cluster may still be in it")
+	}
+}
+
+func TestResumeDryRun(t *testing.T) {
+	f := newFixture(t)
+	require.NoError(t, os.WriteFile(f.journal, []byte(`{"event":"created","id":"run1/1","kind":"schematics","tag":"test-cleanup:xyz","test":"TestRunQuickstartSchematics"}`+"\n"), 0o644))
+
+	require.NoError(t, run([]string{"-journal", f.journal, "-dry-run"}, func(string) string { return "" }, f.log))
+	assert.Contains(t, f.log.String(), "Would clean up schematics run1/1 test=TestRunQuickstartSchematics tag=test-cleanup:xyz")
+	assert.Contains(t, f.log.String(), "Dry run: 1 artifacts would be cleaned up")
+}
+
+func TestResumeNothingToCleanUp(t *testing.T) {
+	f := newFixture(t)
+	require.NoError(t, resume(context.Background(), f.journal, f.cleaner, resumeOptions{concurrency: 1}))
+	assert.Contains(t, f.log.String(), "Found nothing to clean up")
+	assert.NoFileExists(t, f.journal)
+}
@@ -0,0 +1,136 @@
+// Command select-tests prints a go test -run expression that selects the tests of the tests package affected by the
+// changes of a pull request:
+//
+//	go test -run "$(go run ./cmd/select-tests -base origin/main)" ./...
+//
+// A test is affected if a changed file is deployed by the examples or solutions it runs, through their local module
+// sources and path.module references, or matches its TarIncludePatterns. The addon tests deploy the working directory
+// of the catalog flavor that they name. Tests that do not name a Terraform directory are selected by any change, and a
+// change to the Go sources of the tests or to common-dev-assets selects all the tests.
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/terraform-ibm-modules/terraform-ibm-base-ocp-vpc/internal/catalog"
+	"github.com/terraform-ibm-modules/terraform-ibm-base-ocp-vpc/internal/selection"
+)
+
+// testsDir is the directory of the tests package, relative to the repository root
+const testsDir = "tests"
+
+// catalogFile is the catalog of the deployable architectures, relative to the repository root
+const catalogFile = "ibm_catalog.json"
+
+// runAll are the changed files that select every test
+var runAll = []string{
+	testsDir + "/*_test.go",
+	testsDir + "/go.mod",
+	testsDir + "/go.sum",
+	testsDir + "/internal",
+	"common-dev-assets",
+}
+
+func main() {
+	if err := run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string, stdin io.Reader, stdout, log io.Writer) error {
+	flags := flag.NewFlagSet("select-tests", flag.ContinueOnError)
+	flags.SetOutput(log)
+	repo := flags.String("repo", "", "root of the repository, the git top level directory by default")
+	base := flags.String("base", "origin/main", "git revision that the changes are compared with")
+	filesFrom := flags.String("files", "", "read the changed files, one per line, from this file instead of git, - for stdin")
+	explain := flags.Bool("explain", false, "print the changed file that selected each test")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 0 {
+		return errors.New("usage: select-tests [-repo dir] [-base revision | -files path] [-explain]")
+	}
+
+	if *repo == "" {
+		out, err := git(".", "rev-parse", "--show-toplevel")
+		if err != nil {
+			return err
+		}
+		*repo = strings.TrimSpace(out)
+	}
+
+	var changed []string
+	var err error
+	switch *filesFrom {
+	case "":
+		var out string
+		out, err = git(*repo, "diff", "--name-only", *base+"...HEAD")
+		changed = strings.Fields(out)
+	case "-":
+		changed, err = readLines(stdin)
+	default:
+		var file *os.File
+		if file, err = os.Open(*filesFrom); err == nil {
+			changed, err = readLines(file)
+			_ = file.Close()
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("could not list the changed files: %w", err)
+	}
+
+	graph, err := selection.LoadGraph(*repo)
+	if err != nil {
+		return fmt.Errorf("could not load the Terraform modules: %w", err)
+	}
+	var offerings *catalog.Catalog
+	if _, err := os.Stat(filepath.Join(*repo, catalogFile)); err == nil {
+		if offerings, err = catalog.Load(filepath.Join(*repo, catalogFile)); err != nil {
+			return err
+		}
+	}
+	tests, err := graph.Tests(testsDir, offerings)
+	if err != nil {
+		return fmt.Errorf("could not parse the tests: %w", err)
+	}
+	selector := &selection.Selector{Graph: graph, Tests: tests, RunAll: runAll}
+	selected := selector.Select(changed)
+	if *explain {
+		fmt.Fprintf(log, "%d changed files select %d of %d tests\n", len(changed), len(selected), len(tests))
+		for _, s := range selected {
+			fmt.Fprintf(log, "%s: %s\n", s.Test, s.File)
+		}
+	}
+	_, err = fmt.Fprintln(stdout, selection.RunRegex(selected))
+	return err
+}
+
+func git(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if exitErr := (*exec.ExitError)(nil); errors.As(err, &exitErr) {
+		return "", fmt.Errorf("git %s: %w: %s", strings.Join(args, " "), err, strings.TrimSpace(string(exitErr.Stderr)))
+	}
+	return string(out), err
+}
+
+func readLines(r io.Reader) ([]string, error) {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines, scanner.Err()
+}
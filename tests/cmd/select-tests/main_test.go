@@ -0,0 +1,41 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// selectTests runs the command against this repository with the changed files on stdin
+func selectTests(t *testing.T, changed ...string) (string, string) {
+	var stdout, log bytes.Buffer
+	err := run([]string{"-repo", "../../..", "-files", "-", "-explain"}, strings.NewReader(strings.Join(changed, "\n")), &stdout, &log)
+	require.NoError(t, err)
+	return strings.TrimSpace(stdout.String()), log.String()
+}
+
+func TestSelectTests(t *testing.T) {
+	regex, _ := selectTests(t, "README.md")
+	assert.Equal(t, "^$", regex, "documentation does not select any test")
+
+	regex, _ = selectTests(t, "examples/gpu/main.tf")
+	assert.Equal(t, "^(TestRunGpuExample)$", regex)
+
+	regex, log := selectTests(t, "modules/kube-audit/scripts/https_audit.sh")
+	assert.Contains(t, regex, "TestRunFullyConfigurableInSchematics")
+	assert.Contains(t, regex, "TestRunQuickstartSchematics", "selected by TarIncludePatterns")
+	assert.NotContains(t, regex, "TestRunBasicExample")
+	assert.Contains(t, log, "TestRunAdvancedExample: modules/kube-audit/scripts/https_audit.sh")
+
+	regex, _ = selectTests(t, "tests/go.mod")
+	assert.Contains(t, regex, "TestRunBasicExample")
+}
+
+func TestSelectTestsUsage(t *testing.T) {
+	var stdout, log bytes.Buffer
+	err := run([]string{"-files", "-", "extra"}, strings.NewReader(""), &stdout, &log)
+	assert.ErrorContains(t, err, "usage: select-tests")
+}
@@ -0,0 +1,54 @@
+// Command test-durations records the durations of the tests from the output of go test -json, for the sharding of the
+// tests between CI runners:
+//
+//	go test -json -timeout 720m ./... | tee test-output.json | go run ./cmd/test-durations -o test-durations.json
+//
+// The durations of tests that did not run are kept from the existing file, so that the durations of all the tests
+// build up over partial runs.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/terraform-ibm-modules/terraform-ibm-base-ocp-vpc/internal/shard"
+)
+
+func main() {
+	if err := run(os.Args[1:], os.Stdin, os.Stderr); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string, stdin io.Reader, log io.Writer) error {
+	flags := flag.NewFlagSet("test-durations", flag.ContinueOnError)
+	flags.SetOutput(log)
+	output := flags.String("o", "test-durations.json", "durations file to update")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 0 {
+		return errors.New("usage: test-durations [-o file] < go-test-json-output")
+	}
+
+	durations, err := shard.LoadDurations(*output)
+	if err != nil {
+		return err
+	}
+	recorded, err := shard.ReadTestEvents(stdin)
+	if err != nil {
+		return fmt.Errorf("could not read the test events: %w", err)
+	}
+	for test, duration := range recorded {
+		durations[test] = duration
+	}
+	if err := durations.Save(*output); err != nil {
+		return err
+	}
+	fmt.Fprintf(log, "Recorded the durations of %d tests in %s\n", len(recorded), *output)
+	return nil
+}
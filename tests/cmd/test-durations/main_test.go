@@ -0,0 +1,28 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordDurations(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test-durations.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"TestRunBasicExample": 5000, "TestRunGpuExample": 4000}`), 0o644))
+
+	events := `{"Action":"pass","Test":"TestRunBasicExample","Elapsed":5400}
+{"Action":"fail","Test":"TestRunCustomsgExample","Elapsed":4800.4}
+`
+	var log bytes.Buffer
+	require.NoError(t, run([]string{"-o", path}, strings.NewReader(events), &log))
+	assert.Contains(t, log.String(), "Recorded the durations of 2 tests")
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"TestRunBasicExample": 5400, "TestRunCustomsgExample": 4800, "TestRunGpuExample": 4000}`, string(data))
+}
@@ -0,0 +1,161 @@
+// Package catalog loads the ibm_catalog.json of the deployable architectures and checks it against the Terraform
+// variables of the solutions that the catalog flavors deploy.
+package catalog
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Catalog is the subset of ibm_catalog.json used by the tests
+type Catalog struct {
+	Path     string    `json:"-"`
+	Products []Product `json:"products"`
+}
+
+// Product is an offering of the catalog
+type Product struct {
+	Name    string   `json:"name"`
+	Flavors []Flavor `json:"flavors"`
+}
+
+// Flavor is a variation of a product that deploys the Terraform in WorkingDirectory
+type Flavor struct {
+	Name             string       `json:"name"`
+	WorkingDirectory string       `json:"working_directory"`
+	TerraformVersion string       `json:"terraform_version"`
+	Configuration    []Input      `json:"configuration"`
+	Dependencies     []Dependency `json:"dependencies"`
+	Line             int          `json:"-"`
+}
+
+// Input is a configuration entry of a flavor
+type Input struct {
+	Key          string      `json:"key"`
+	Type         string      `json:"type"`
+	TypeMetadata string      `json:"type_metadata"`
+	DefaultValue interface{} `json:"default_value"`
+	Required     bool        `json:"required"`
+	// Virtual inputs are not variables of the flavor, they are passed on to its dependencies
+	Virtual bool `json:"virtual"`
+	Line    int  `json:"-"`
+}
+
+// Dependency is a deployable architecture that can be deployed together with a flavor. Dependencies that are not
+// optional are required addons, they are always deployed.
+type Dependency struct {
+	Name        string   `json:"name"`
+	Flavors     []string `json:"flavors"`
+	Optional    bool     `json:"optional"`
+	OnByDefault bool     `json:"on_by_default"`
+}
+
+// DeclaredType returns the type of the input, falling back to its type metadata
+func (i Input) DeclaredType() string {
+	if i.Type != "" {
+		return i.Type
+	}
+	return i.TypeMetadata
+}
+
+// Load reads a catalog and records the line of every flavor and input, so that mismatches can point at them
+func Load(path string) (*Catalog, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	catalog := &Catalog{Path: path}
+	if err := json.Unmarshal(data, catalog); err != nil {
+		return nil, fmt.Errorf("error decoding %s: %w", path, err)
+	}
+
+	lines, err := objectLines(data)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding %s: %w", path, err)
+	}
+	for p := range catalog.Products {
+		for f := range catalog.Products[p].Flavors {
+			flavor := &catalog.Products[p].Flavors[f]
+			flavorPath := fmt.Sprintf("products.%d.flavors.%d", p, f)
+			flavor.Line = lines[flavorPath]
+			for i := range flavor.Configuration {
+				flavor.Configuration[i].Line = lines[fmt.Sprintf("%s.configuration.%d", flavorPath, i)]
+			}
+		}
+	}
+	return catalog, nil
+}
+
+// Flavor returns the flavor of a product
+func (c *Catalog) Flavor(product string, flavor string) (*Flavor, error) {
+	for p := range c.Products {
+		if c.Products[p].Name != product {
+			continue
+		}
+		for f := range c.Products[p].Flavors {
+			if c.Products[p].Flavors[f].Name == flavor {
+				return &c.Products[p].Flavors[f], nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("flavor %s of product %s not found in %s", flavor, product, c.Path)
+}
+
+// Location returns the catalog path and line of an entry
+func (c *Catalog) Location(line int) string {
+	return fmt.Sprintf("%s:%d", c.Path, line)
+}
+
+// objectLines returns the line on which each JSON object starts, keyed by its dot separated path, e.g. "products.0"
+func objectLines(data []byte) (map[string]int, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	lines := map[string]int{}
+	lineOf := func(offset int64) int {
+		return bytes.Count(data[:offset], []byte("\n")) + 1
+	}
+
+	var walk func(path []string) error
+	walk = func(path []string) error {
+		token, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+		delim, ok := token.(json.Delim)
+		if !ok {
+			return nil
+		}
+		switch delim {
+		case '{':
+			// the offset is just past the opening brace
+			lines[strings.Join(path, ".")] = lineOf(decoder.InputOffset() - 1)
+			for decoder.More() {
+				key, err := decoder.Token()
+				if err != nil {
+					return err
+				}
+				if err := walk(append(path, key.(string))); err != nil {
+					return err
+				}
+			}
+		case '[':
+			for i := 0; decoder.More(); i++ {
+				if err := walk(append(path, strconv.Itoa(i))); err != nil {
+					return err
+				}
+			}
+		}
+		// closing delimiter
+		_, err = decoder.Token()
+		return err
+	}
+
+	if err := walk(nil); err != nil && err != io.EOF {
+		return nil, err
+	}
+	return lines, nil
+}
@@ -0,0 +1,68 @@
+package catalog
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zclconf/go-cty/cty"
+
+	"github.com/terraform-ibm-modules/terraform-ibm-base-ocp-vpc/internal/tfstatic"
+)
+
+func TestLoad(t *testing.T) {
+	catalog, err := Load("testdata/ibm_catalog.json")
+	require.NoError(t, err)
+
+	require.Len(t, catalog.Products, 1)
+	flavor := catalog.Products[0].Flavors[0]
+	assert.Equal(t, "standard", flavor.Name)
+	assert.Equal(t, "1.12.2", flavor.TerraformVersion)
+	assert.Equal(t, 6, flavor.Line)
+	require.Len(t, flavor.Configuration, 8)
+	assert.Equal(t, 11, flavor.Configuration[0].Line)
+	assert.Equal(t, 19, flavor.Configuration[2].Line)
+	assert.True(t, flavor.Configuration[7].Virtual)
+}
+
+func TestCheckFlavor(t *testing.T) {
+	catalog, err := Load("testdata/ibm_catalog.json")
+	require.NoError(t, err)
+	module, err := tfstatic.LoadModule("testdata/solution")
+	require.NoError(t, err)
+
+	var messages []string
+	for _, mismatch := range catalog.CheckFlavor(catalog.Products[0].Flavors[0], module) {
+		messages = append(messages, mismatch.String())
+	}
+	assert.Equal(t, []string{
+		`testdata/ibm_catalog.json:19: input "workers_per_zone" of flavor standard has default 2, but the variable defaults to 1 (variable declared at testdata/solution/variables.tf:14)`,
+		`testdata/ibm_catalog.json:34: input "tags" of flavor standard has type "boolean", which cannot be used for a variable of type list of string (variable declared at testdata/solution/variables.tf:29)`,
+		`testdata/ibm_catalog.json:38: input "removed_input" of flavor standard is not a variable of testdata/solution`,
+		`testdata/ibm_catalog.json:6: required variable "region" is not an input of flavor standard (variable declared at testdata/solution/variables.tf:10)`,
+	}, messages)
+}
+
+func TestTypeCompatible(t *testing.T) {
+	assert.True(t, typeCompatible("string", cty.Number))
+	assert.True(t, typeCompatible("password", cty.String))
+	assert.True(t, typeCompatible("array", cty.Set(cty.String)))
+	assert.True(t, typeCompatible("list(object)", cty.List(cty.Object(map[string]cty.Type{"name": cty.String}))))
+	assert.False(t, typeCompatible("list(object)", cty.List(cty.String)))
+	assert.False(t, typeCompatible("boolean", cty.String))
+	assert.False(t, typeCompatible("string", cty.Map(cty.String)))
+	assert.True(t, typeCompatible("boolean", cty.DynamicPseudoType))
+}
+
+func TestInputValue(t *testing.T) {
+	value, err := inputValue("3", cty.Number)
+	require.NoError(t, err)
+	assert.True(t, value.Equals(cty.NumberIntVal(3)).True())
+
+	value, err = inputValue(`{ zone-1 = [{ name = "a" }] }`, cty.Map(cty.List(cty.Object(map[string]cty.Type{"name": cty.String}))))
+	require.NoError(t, err)
+	assert.Equal(t, "a", value.Index(cty.StringVal("zone-1")).Index(cty.NumberIntVal(0)).GetAttr("name").AsString())
+
+	_, err = inputValue("three", cty.Number)
+	assert.Error(t, err)
+}
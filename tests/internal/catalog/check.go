@@ -0,0 +1,196 @@
+package catalog
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+
+	"github.com/terraform-ibm-modules/terraform-ibm-base-ocp-vpc/internal/tfstatic"
+)
+
+// Mismatch is an inconsistency between a catalog flavor and the variables of the Terraform it deploys
+type Mismatch struct {
+	Catalog string // location of the catalog entry
+	HCL     string // location of the variable block, empty if the variable does not exist
+	Message string
+}
+
+func (m Mismatch) String() string {
+	if m.HCL == "" {
+		return fmt.Sprintf("%s: %s", m.Catalog, m.Message)
+	}
+	return fmt.Sprintf("%s: %s (variable declared at %s)", m.Catalog, m.Message, m.HCL)
+}
+
+// typeKinds maps catalog input types to the kind of Terraform type they can be used for
+var typeKinds = map[string]string{
+	"string":   "string",
+	"password": "string",
+	"boolean":  "bool",
+	"number":   "number",
+	"int":      "number",
+	"integer":  "number",
+	"float":    "number",
+	"array":    "list",
+	"map":      "map",
+	"object":   "map",
+}
+
+// CheckFlavor checks that every input of the flavor, other than virtual inputs, is a variable of module with a
+// compatible type and default, and that every required variable of module is an input of the flavor
+func (c *Catalog) CheckFlavor(flavor Flavor, module *tfstatic.Module) []Mismatch {
+	var mismatches []Mismatch
+	var keys []string
+	for _, input := range flavor.Configuration {
+		keys = append(keys, input.Key)
+		if input.Virtual {
+			continue
+		}
+		variable, ok := module.Variables[input.Key]
+		if !ok {
+			mismatches = append(mismatches, Mismatch{
+				Catalog: c.Location(input.Line),
+				Message: fmt.Sprintf("input %q of flavor %s is not a variable of %s", input.Key, flavor.Name, module.Dir),
+			})
+			continue
+		}
+		for _, problem := range inputProblems(input, variable) {
+			mismatches = append(mismatches, Mismatch{
+				Catalog: c.Location(input.Line),
+				HCL:     location(variable.Range),
+				Message: fmt.Sprintf("input %q of flavor %s %s", input.Key, flavor.Name, problem),
+			})
+		}
+	}
+
+	var names []string
+	for name := range module.Variables {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		variable := module.Variables[name]
+		if variable.Required && !slices.Contains(keys, name) {
+			mismatches = append(mismatches, Mismatch{
+				Catalog: c.Location(flavor.Line),
+				HCL:     location(variable.Range),
+				Message: fmt.Sprintf("required variable %q is not an input of flavor %s", name, flavor.Name),
+			})
+		}
+	}
+	return mismatches
+}
+
+// inputProblems describes how an input is incompatible with the variable it sets
+func inputProblems(input Input, variable *tfstatic.Variable) []string {
+	var problems []string
+	if declared := input.DeclaredType(); declared != "" && !typeCompatible(declared, variable.Type) {
+		problems = append(problems, fmt.Sprintf("has type %q, which cannot be used for a variable of type %s", declared, variable.Type.FriendlyName()))
+	}
+	if input.DefaultValue == nil {
+		return problems
+	}
+
+	value, err := inputValue(input.DefaultValue, variable.Type)
+	switch {
+	case err != nil:
+		problems = append(problems, fmt.Sprintf("has default %v, which is not a valid %s: %v", input.DefaultValue, variable.Type.FriendlyName(), err))
+	case !variable.Required && !variable.Default.IsNull() && variable.Default.IsWhollyKnown():
+		want, err := convert.Convert(variable.Default, variable.Type)
+		if err == nil && !value.Equals(want).True() {
+			problems = append(problems, fmt.Sprintf("has default %v, but the variable defaults to %s", input.DefaultValue, formatValue(want)))
+		}
+	}
+	return problems
+}
+
+// typeCompatible reports whether a catalog type can set a variable of type ty. Catalog types like "list(object)" are
+// compared by their outer kind and element kind. String inputs can set number and bool variables since Terraform
+// converts them, which the catalog relies on to apply regex constraints to numbers.
+func typeCompatible(declared string, ty cty.Type) bool {
+	if ty == cty.DynamicPseudoType {
+		return true
+	}
+	outer, elem, _ := strings.Cut(strings.TrimSuffix(declared, ")"), "(")
+	kind, ok := typeKinds[outer]
+	if outer == "list" || outer == "set" {
+		kind, ok = "list", true
+	}
+	if !ok {
+		return false
+	}
+	if kind == "string" && ty.IsPrimitiveType() {
+		return true
+	}
+	if kind != typeKind(ty) {
+		return false
+	}
+	if elem == "" || !(ty.IsListType() || ty.IsSetType()) {
+		return true
+	}
+	elemKind, ok := typeKinds[elem]
+	return !ok || elemKind == typeKind(ty.ElementType())
+}
+
+func typeKind(ty cty.Type) string {
+	switch {
+	case ty == cty.String:
+		return "string"
+	case ty == cty.Bool:
+		return "bool"
+	case ty == cty.Number:
+		return "number"
+	case ty.IsListType(), ty.IsSetType(), ty.IsTupleType():
+		return "list"
+	case ty.IsMapType(), ty.IsObjectType():
+		return "map"
+	}
+	return ty.FriendlyName()
+}
+
+// inputValue converts a catalog default to the variable type. Defaults of complex variables may be given as a string
+// holding an HCL expression.
+func inputValue(value interface{}, ty cty.Type) (cty.Value, error) {
+	if s, ok := value.(string); ok && !ty.IsPrimitiveType() && ty != cty.DynamicPseudoType {
+		expr, diags := hclsyntax.ParseExpression([]byte(s), "default_value", hcl.InitialPos)
+		if diags.HasErrors() {
+			return cty.NilVal, diags
+		}
+		parsed, diags := expr.Value(nil)
+		if diags.HasErrors() {
+			return cty.NilVal, diags
+		}
+		return convert.Convert(parsed, ty)
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return cty.NilVal, err
+	}
+	if ty == cty.DynamicPseudoType {
+		ty, err = ctyjson.ImpliedType(data)
+		if err != nil {
+			return cty.NilVal, err
+		}
+	}
+	return ctyjson.Unmarshal(data, ty)
+}
+
+func formatValue(value cty.Value) string {
+	data, err := ctyjson.Marshal(value, value.Type())
+	if err != nil {
+		return value.GoString()
+	}
+	return string(data)
+}
+
+func location(r hcl.Range) string {
+	return fmt.Sprintf("%s:%d", r.Filename, r.Start.Line)
+}
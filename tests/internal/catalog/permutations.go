@@ -0,0 +1,185 @@
+package catalog
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Coverage selects how many addon permutations are generated for a flavor
+type Coverage string
+
+const (
+	// CoverageFull generates every combination of the optional dependencies
+	CoverageFull Coverage = "full"
+	// CoveragePairwise generates combinations until every pair of optional dependencies has been seen in all four
+	// enabled/disabled states
+	CoveragePairwise Coverage = "pairwise"
+	// CoverageSmoke only generates the cases without and with all optional dependencies
+	CoverageSmoke Coverage = "smoke"
+)
+
+// maxOptionalDependencies bounds the search over all combinations of the optional dependencies
+const maxOptionalDependencies = 16
+
+// addonNamePrefix is stripped from the dependency names to build the case names
+const addonNamePrefix = "deploy-arch-ibm-"
+
+// ParseCoverage returns the coverage named by s
+func ParseCoverage(s string) (Coverage, error) {
+	switch coverage := Coverage(s); coverage {
+	case CoverageFull, CoveragePairwise, CoverageSmoke:
+		return coverage, nil
+	}
+	return "", fmt.Errorf("unknown addon coverage %q, expected one of %s, %s or %s", s, CoverageFull, CoveragePairwise, CoverageSmoke)
+}
+
+// AddonCase is a permutation of the dependencies of a flavor
+type AddonCase struct {
+	Name   string
+	Prefix string
+	Addons []Addon
+}
+
+// Addon is a dependency of a flavor and whether it is deployed in a case
+type Addon struct {
+	Name     string
+	Flavor   string
+	Enabled  bool
+	Required bool
+}
+
+// AddonPermutations returns the permutations of the optional dependencies of the flavor for the given coverage.
+// Required dependencies are enabled in every case. Cases are named after the optional dependencies they enable, and
+// their prefix encodes the same set as a bit mask over the optional dependencies in catalog order, so both stay stable
+// as long as the dependencies of the flavor do not change.
+func (f Flavor) AddonPermutations(coverage Coverage) ([]AddonCase, error) {
+	var optional []int
+	for i, dependency := range f.Dependencies {
+		if len(dependency.Flavors) == 0 {
+			return nil, fmt.Errorf("dependency %s of flavor %s has no flavors", dependency.Name, f.Name)
+		}
+		if dependency.Optional {
+			optional = append(optional, i)
+		}
+	}
+	if len(optional) > maxOptionalDependencies {
+		return nil, fmt.Errorf("flavor %s has %d optional dependencies, at most %d are supported", f.Name, len(optional), maxOptionalDependencies)
+	}
+
+	all := uint(1)<<len(optional) - 1
+	var masks []uint
+	switch coverage {
+	case CoverageFull:
+		for mask := uint(0); mask <= all; mask++ {
+			masks = append(masks, mask)
+		}
+	case CoveragePairwise:
+		masks = pairwiseMasks(len(optional))
+	case CoverageSmoke:
+		masks = []uint{0, all}
+	default:
+		return nil, fmt.Errorf("unknown addon coverage %q", coverage)
+	}
+
+	cases := make([]AddonCase, 0, len(masks))
+	seen := map[uint]bool{}
+	for _, mask := range masks {
+		// without optional dependencies the smoke and pairwise masks coincide
+		if seen[mask] {
+			continue
+		}
+		seen[mask] = true
+		cases = append(cases, f.addonCase(optional, mask))
+	}
+	return cases, nil
+}
+
+// addonCase builds the case enabling the optional dependencies whose bit is set in mask
+func (f Flavor) addonCase(optional []int, mask uint) AddonCase {
+	enabled := map[int]bool{}
+	var names []string
+	for bit, i := range optional {
+		if mask&(1<<bit) != 0 {
+			enabled[i] = true
+			names = append(names, strings.TrimPrefix(f.Dependencies[i].Name, addonNamePrefix))
+		}
+	}
+
+	addonCase := AddonCase{
+		Name:   "with-" + strings.Join(names, "-"),
+		Prefix: fmt.Sprintf("addons-%x", mask),
+	}
+	switch {
+	case mask == 0:
+		addonCase.Name, addonCase.Prefix = "no-addons", "no-addons"
+	case len(names) == len(optional):
+		addonCase.Name, addonCase.Prefix = "all-addons", "all-addons"
+	}
+
+	for i, dependency := range f.Dependencies {
+		addonCase.Addons = append(addonCase.Addons, Addon{
+			Name:     dependency.Name,
+			Flavor:   dependency.Flavors[0],
+			Enabled:  !dependency.Optional || enabled[i],
+			Required: !dependency.Optional,
+		})
+	}
+	return addonCase
+}
+
+// pairwiseMasks returns bit masks over n dependencies that together cover all four enabled/disabled states of every
+// pair of dependencies. It starts with no and all dependencies enabled, then greedily adds the mask covering the most
+// uncovered states, preferring the lowest mask on ties so that the result is deterministic.
+func pairwiseMasks(n int) []uint {
+	all := uint(1)<<n - 1
+	uncovered := map[pairState]bool{}
+	for i := 0; i < n; i++ {
+		for j := i + 1; j < n; j++ {
+			for _, vi := range []bool{false, true} {
+				for _, vj := range []bool{false, true} {
+					uncovered[pairState{i, j, vi, vj}] = true
+				}
+			}
+		}
+	}
+	covered := func(mask uint) []pairState {
+		var states []pairState
+		for state := range uncovered {
+			if state.in(mask) {
+				states = append(states, state)
+			}
+		}
+		return states
+	}
+	take := func(mask uint) {
+		for _, state := range covered(mask) {
+			delete(uncovered, state)
+		}
+	}
+
+	masks := []uint{0, all}
+	take(0)
+	take(all)
+	for len(uncovered) > 0 {
+		best, bestCount := uint(0), 0
+		for mask := uint(0); mask <= all; mask++ {
+			if count := len(covered(mask)); count > bestCount {
+				best, bestCount = mask, count
+			}
+		}
+		masks = append(masks, best)
+		take(best)
+	}
+	return masks
+}
+
+// pairState is an enabled/disabled state of the dependency pair i, j
+type pairState struct {
+	i, j   int
+	vi, vj bool
+}
+
+// in returns whether the state occurs in mask
+func (s pairState) in(mask uint) bool {
+	return (mask>>s.i&1 == 1) == s.vi && (mask>>s.j&1 == 1) == s.vj
+}
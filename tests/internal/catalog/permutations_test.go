@@ -0,0 +1,129 @@
+package catalog
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func loadFlavor(t *testing.T) *Flavor {
+	catalog, err := Load("testdata/ibm_catalog.json")
+	require.NoError(t, err)
+	flavor, err := catalog.Flavor("example", "standard")
+	require.NoError(t, err)
+	return flavor
+}
+
+func caseNames(cases []AddonCase) []string {
+	var names []string
+	for _, c := range cases {
+		names = append(names, c.Name+"/"+c.Prefix)
+	}
+	return names
+}
+
+func TestFlavor(t *testing.T) {
+	catalog, err := Load("testdata/ibm_catalog.json")
+	require.NoError(t, err)
+
+	flavor, err := catalog.Flavor("example", "standard")
+	require.NoError(t, err)
+	require.Len(t, flavor.Dependencies, 6)
+	assert.Equal(t, Dependency{Name: "deploy-arch-ibm-cos", Flavors: []string{"instance"}, OnByDefault: true}, flavor.Dependencies[2])
+
+	_, err = catalog.Flavor("example", "quickstart")
+	assert.EqualError(t, err, "flavor quickstart of product example not found in testdata/ibm_catalog.json")
+}
+
+func TestAddonPermutationsSmoke(t *testing.T) {
+	cases, err := loadFlavor(t).AddonPermutations(CoverageSmoke)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"no-addons/no-addons", "all-addons/all-addons"}, caseNames(cases))
+
+	assert.Equal(t, []Addon{
+		{Name: "deploy-arch-ibm-slz-vpc", Flavor: "fully-configurable", Enabled: true, Required: true},
+		{Name: "deploy-arch-ibm-kms", Flavor: "fully-configurable"},
+		{Name: "deploy-arch-ibm-cos", Flavor: "instance", Enabled: true, Required: true},
+		{Name: "deploy-arch-ibm-cloud-logs", Flavor: "fully-configurable"},
+		{Name: "deploy-arch-ibm-cloud-monitoring", Flavor: "fully-configurable"},
+		{Name: "deploy-arch-ibm-activity-tracker", Flavor: "fully-configurable"},
+	}, cases[0].Addons)
+	for _, addon := range cases[1].Addons {
+		assert.True(t, addon.Enabled, addon.Name)
+	}
+}
+
+func TestAddonPermutationsFull(t *testing.T) {
+	cases, err := loadFlavor(t).AddonPermutations(CoverageFull)
+	require.NoError(t, err)
+	require.Len(t, cases, 16)
+	assert.Equal(t, "no-addons/no-addons", caseNames(cases)[0])
+	assert.Equal(t, "with-kms-cloud-monitoring/addons-5", caseNames(cases)[5])
+	assert.Equal(t, "all-addons/all-addons", caseNames(cases)[15])
+
+	prefixes := map[string]bool{}
+	for _, c := range cases {
+		assert.False(t, prefixes[c.Prefix], "duplicate prefix %s", c.Prefix)
+		prefixes[c.Prefix] = true
+	}
+}
+
+func TestAddonPermutationsPairwise(t *testing.T) {
+	flavor := loadFlavor(t)
+	cases, err := flavor.AddonPermutations(CoveragePairwise)
+	require.NoError(t, err)
+	assert.Less(t, len(cases), 16)
+	assert.Equal(t, []string{"no-addons/no-addons", "all-addons/all-addons"}, caseNames(cases)[:2])
+
+	// every pair of optional dependencies is seen in all four states, and required dependencies are always enabled
+	seen := map[[2]string]map[[2]bool]bool{}
+	for _, c := range cases {
+		for i, a := range c.Addons {
+			if a.Required {
+				assert.True(t, a.Enabled, "%s in %s", a.Name, c.Name)
+				continue
+			}
+			for _, b := range c.Addons[i+1:] {
+				if b.Required {
+					continue
+				}
+				key := [2]string{a.Name, b.Name}
+				if seen[key] == nil {
+					seen[key] = map[[2]bool]bool{}
+				}
+				seen[key][[2]bool{a.Enabled, b.Enabled}] = true
+			}
+		}
+	}
+	assert.Len(t, seen, 6)
+	for pair, states := range seen {
+		assert.Len(t, states, 4, "%v", pair)
+	}
+
+	again, err := flavor.AddonPermutations(CoveragePairwise)
+	require.NoError(t, err)
+	assert.Equal(t, cases, again)
+}
+
+func TestAddonPermutationsWithoutOptionalDependencies(t *testing.T) {
+	flavor := Flavor{Name: "standard", Dependencies: []Dependency{{Name: "deploy-arch-ibm-slz-vpc", Flavors: []string{"fully-configurable"}}}}
+	for _, coverage := range []Coverage{CoverageFull, CoveragePairwise, CoverageSmoke} {
+		cases, err := flavor.AddonPermutations(coverage)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"no-addons/no-addons"}, caseNames(cases), coverage)
+	}
+
+	flavor.Dependencies[0].Flavors = nil
+	_, err := flavor.AddonPermutations(CoverageSmoke)
+	assert.EqualError(t, err, "dependency deploy-arch-ibm-slz-vpc of flavor standard has no flavors")
+}
+
+func TestParseCoverage(t *testing.T) {
+	coverage, err := ParseCoverage("pairwise")
+	require.NoError(t, err)
+	assert.Equal(t, CoveragePairwise, coverage)
+
+	_, err = ParseCoverage("some")
+	assert.EqualError(t, err, `unknown addon coverage "some", expected one of full, pairwise or smoke`)
+}
@@ -0,0 +1,32 @@
+variable "ibmcloud_api_key" {
+  type      = string
+  sensitive = true
+}
+
+variable "prefix" {
+  type = string
+}
+
+variable "region" {
+  type = string
+}
+
+variable "workers_per_zone" {
+  type    = number
+  default = 1
+}
+
+variable "enable_logs" {
+  type    = bool
+  default = true
+}
+
+variable "zones" {
+  type    = list(string)
+  default = ["1", "2"]
+}
+
+variable "tags" {
+  type    = list(string)
+  default = []
+}
@@ -0,0 +1,244 @@
+// Package cleanup keeps track of the resources that the tests of a run have provisioned and not destroyed yet, so that
+// they can be destroyed when the run is interrupted with SIGINT or SIGTERM, instead of being abandoned with the test
+// process. Anything that cannot be destroyed is written to a file for manual deletion.
+package cleanup
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"slices"
+	"sync"
+	"syscall"
+)
+
+// ErrInterrupted is the cause of the cancellation of the context of a registry when the run is interrupted
+var ErrInterrupted = errors.New("the test run was interrupted")
+
+// DestroyFunc destroys a provisioned resource
+type DestroyFunc func(ctx context.Context) error
+
+// Registry holds the entries of the resources that are provisioned and not destroyed yet
+type Registry struct {
+	// Concurrency is how many entries are destroyed at the same time by Cleanup
+	Concurrency int
+
+	ctx    context.Context
+	cancel context.CancelCauseFunc
+
+	mu      sync.Mutex
+	entries []*Entry
+}
+
+// Entry is a provisioned resource, registered until it is destroyed or released
+type Entry struct {
+	// Name describes the resource well enough to find and delete it manually
+	Name string
+
+	registry  *Registry
+	destroy   DestroyFunc
+	dependsOn []*Entry
+
+	once sync.Once
+	err  error
+}
+
+// Leftover is a resource that could not be destroyed
+type Leftover struct {
+	Name  string `json:"name"`
+	Error string `json:"error"`
+}
+
+// NewRegistry returns a registry that destroys concurrency entries at a time
+func NewRegistry(concurrency int) *Registry {
+	r := &Registry{Concurrency: concurrency}
+	r.ctx, r.cancel = context.WithCancelCause(context.Background())
+	return r
+}
+
+// Context returns a context that is cancelled with ErrInterrupted when the run is interrupted, for work that should stop
+// so that the destroys are not delayed
+func (r *Registry) Context() context.Context {
+	return r.ctx
+}
+
+// Register adds a resource, before it is provisioned so that a partial provisioning is destroyed too. The resource is
+// destroyed after the resources that depend on it, e.g. a VPC after the clusters deployed into it.
+func (r *Registry) Register(name string, destroy DestroyFunc, dependsOn ...*Entry) *Entry {
+	e := &Entry{Name: name, registry: r, destroy: destroy, dependsOn: dependsOn}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries = append(r.entries, e)
+	return e
+}
+
+// Pending returns the registered entries, in the order they were registered
+func (r *Registry) Pending() []*Entry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slices.Clone(r.entries)
+}
+
+func (r *Registry) remove(e *Entry) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries = slices.DeleteFunc(r.entries, func(entry *Entry) bool { return entry == e })
+}
+
+// Destroy destroys the resource and unregisters it if that succeeds. The resource is destroyed once: when it is
+// already being destroyed, e.g. by Cleanup after an interrupt, Destroy waits for that and returns its result.
+func (e *Entry) Destroy(ctx context.Context) error {
+	e.once.Do(func() {
+		if e.err = e.destroy(ctx); e.err == nil {
+			e.registry.remove(e)
+		}
+	})
+	return e.err
+}
+
+// Release unregisters the resource without destroying it, because it was destroyed by other means or is deliberately
+// kept. It waits for a destroy that is in progress.
+func (e *Entry) Release() {
+	e.once.Do(func() {})
+	e.registry.remove(e)
+}
+
+// Cleanup destroys the registered entries, the most recently registered first, running up to Concurrency destroys at
+// the same time. An entry is only destroyed once the entries that depend on it are destroyed, and is left if one of
+// them could not be. It returns the entries that were not destroyed.
+func (r *Registry) Cleanup(ctx context.Context) []Leftover {
+	pending := r.Pending()
+	slices.Reverse(pending)
+	concurrency := max(r.Concurrency, 1)
+
+	// dependents of each entry among the pending ones, which must be destroyed first
+	dependents := map[*Entry][]*Entry{}
+	for _, e := range pending {
+		for _, d := range e.dependsOn {
+			dependents[d] = append(dependents[d], e)
+		}
+	}
+	failed := map[*Entry]error{}
+	finished := map[*Entry]bool{}
+	var leftovers []Leftover
+
+	type result struct {
+		entry *Entry
+		err   error
+	}
+	results := make(chan result)
+	running := 0
+	for len(pending) > 0 || running > 0 {
+		for i := 0; i < len(pending) && running < concurrency; {
+			e := pending[i]
+			ready, blocked := true, error(nil)
+			for _, d := range dependents[e] {
+				if !finished[d] {
+					ready = false
+				} else if failed[d] != nil && blocked == nil {
+					blocked = fmt.Errorf("not destroyed because %s could not be destroyed", d.Name)
+				}
+			}
+			if !ready {
+				i++
+				continue
+			}
+			pending = slices.Delete(pending, i, i+1)
+			if blocked != nil {
+				finished[e], failed[e] = true, blocked
+				leftovers = append(leftovers, Leftover{Name: e.Name, Error: blocked.Error()})
+				// entries skipped so far may depend on this one
+				i = 0
+				continue
+			}
+			running++
+			go func() {
+				results <- result{entry: e, err: e.Destroy(ctx)}
+			}()
+		}
+		if running == 0 {
+			break
+		}
+		res := <-results
+		running--
+		finished[res.entry] = true
+		if res.err != nil {
+			failed[res.entry] = res.err
+			leftovers = append(leftovers, Leftover{Name: res.entry.Name, Error: res.err.Error()})
+		}
+	}
+	return leftovers
+}
+
+// WriteLeftovers writes the leftovers to path as JSON
+func WriteLeftovers(path string, leftovers []Leftover) error {
+	if leftovers == nil {
+		leftovers = []Leftover{}
+	}
+	data, err := json.MarshalIndent(leftovers, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// HandleSignals cleans up when the process receives SIGINT or SIGTERM, writes what could not be destroyed to
+// leftoversPath, and exits. A second signal exits right away, listing everything that was not destroyed yet. The
+// returned function stops the handling.
+func (r *Registry) HandleSignals(leftoversPath string) (stop func()) {
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	done := make(chan struct{})
+	go r.handle(signals, done, leftoversPath, os.Exit)
+	return func() {
+		signal.Stop(signals)
+		close(done)
+	}
+}
+
+func (r *Registry) handle(signals <-chan os.Signal, done <-chan struct{}, leftoversPath string, exit func(int)) {
+	var sig os.Signal
+	select {
+	case sig = <-signals:
+	case <-done:
+		return
+	}
+	code := 1
+	if s, ok := sig.(syscall.Signal); ok {
+		code = 128 + int(s)
+	}
+	r.cancel(ErrInterrupted)
+
+	pending := r.Pending()
+	log.Printf("Received %s, destroying %d provisioned resources, send it again to exit without destroying", sig, len(pending))
+	cleaned := make(chan []Leftover, 1)
+	go func() {
+		cleaned <- r.Cleanup(context.Background())
+	}()
+
+	var leftovers []Leftover
+	select {
+	case leftovers = <-cleaned:
+	case sig = <-signals:
+		log.Printf("Received %s again, exiting without waiting for the destroys", sig)
+		for _, e := range r.Pending() {
+			leftovers = append(leftovers, Leftover{Name: e.Name, Error: "not destroyed, the cleanup was interrupted"})
+		}
+	}
+
+	if len(leftovers) == 0 {
+		log.Printf("Destroyed the %d provisioned resources", len(pending))
+	} else if err := WriteLeftovers(leftoversPath, leftovers); err != nil {
+		log.Printf("Failed to write %s: %v", leftoversPath, err)
+		for _, l := range leftovers {
+			log.Printf("Not destroyed, delete it manually: %s: %s", l.Name, l.Error)
+		}
+	} else {
+		log.Printf("%d resources could not be destroyed, delete them manually, they are listed in %s", len(leftovers), leftoversPath)
+	}
+	exit(code)
+}
@@ -0,0 +1,211 @@
+package cleanup
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recorder records the destroys in the order they happen
+type recorder struct {
+	mu        sync.Mutex
+	events    []string
+	running   int
+	maxActive int
+}
+
+func (r *recorder) destroy(name string, err error) DestroyFunc {
+	return func(ctx context.Context) error {
+		r.mu.Lock()
+		r.events = append(r.events, name)
+		r.running++
+		r.maxActive = max(r.maxActive, r.running)
+		r.mu.Unlock()
+		time.Sleep(10 * time.Millisecond)
+		r.mu.Lock()
+		r.running--
+		r.mu.Unlock()
+		return err
+	}
+}
+
+func (r *recorder) index(name string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, event := range r.events {
+		if event == name {
+			return i
+		}
+	}
+	return -1
+}
+
+func TestCleanupReverseOrder(t *testing.T) {
+	rec := &recorder{}
+	registry := NewRegistry(1)
+	registry.Register("existing resources", rec.destroy("existing resources", nil))
+	registry.Register("cluster", rec.destroy("cluster", nil))
+	registry.Register("schematics workspace", rec.destroy("schematics workspace", nil))
+
+	assert.Empty(t, registry.Cleanup(context.Background()))
+	assert.Equal(t, []string{"schematics workspace", "cluster", "existing resources"}, rec.events)
+	assert.Empty(t, registry.Pending())
+}
+
+func TestCleanupDependencies(t *testing.T) {
+	rec := &recorder{}
+	registry := NewRegistry(4)
+	vpc := registry.Register("vpc", rec.destroy("vpc", nil))
+	registry.Register("cluster a", rec.destroy("cluster a", nil), vpc)
+	registry.Register("cluster b", rec.destroy("cluster b", nil), vpc)
+	registry.Register("unrelated", rec.destroy("unrelated", nil))
+
+	assert.Empty(t, registry.Cleanup(context.Background()))
+	assert.Len(t, rec.events, 4)
+	assert.Equal(t, 3, rec.index("vpc"), "the vpc is destroyed after the clusters in it")
+	assert.Equal(t, 3, rec.maxActive, "the clusters and the unrelated resource are destroyed at the same time")
+}
+
+func TestCleanupConcurrency(t *testing.T) {
+	rec := &recorder{}
+	registry := NewRegistry(2)
+	for _, name := range []string{"a", "b", "c", "d", "e"} {
+		registry.Register(name, rec.destroy(name, nil))
+	}
+
+	assert.Empty(t, registry.Cleanup(context.Background()))
+	assert.ElementsMatch(t, []string{"a", "b", "c", "d", "e"}, rec.events)
+	assert.Equal(t, 2, rec.maxActive)
+	assert.ElementsMatch(t, []string{"e", "d"}, rec.events[:2], "the most recently registered start first")
+}
+
+func TestCleanupLeftovers(t *testing.T) {
+	rec := &recorder{}
+	registry := NewRegistry(2)
+	vpc := registry.Register("vpc", rec.destroy("vpc", nil))
+	subnet := registry.Register("subnet", rec.destroy("subnet", nil), vpc)
+	registry.Register("cluster", rec.destroy("cluster", errors.New("cluster is still deleting")), subnet)
+	registry.Register("workspace", rec.destroy("workspace", nil))
+
+	leftovers := registry.Cleanup(context.Background())
+	assert.Equal(t, []Leftover{
+		{Name: "cluster", Error: "cluster is still deleting"},
+		{Name: "subnet", Error: "not destroyed because cluster could not be destroyed"},
+		{Name: "vpc", Error: "not destroyed because subnet could not be destroyed"},
+	}, leftovers)
+	assert.ElementsMatch(t, []string{"cluster", "workspace"}, rec.events)
+	assert.Len(t, registry.Pending(), 3, "the entries that were not destroyed stay registered")
+}
+
+func TestEntryDestroyedOnce(t *testing.T) {
+	registry := NewRegistry(1)
+	calls := 0
+	started, unblock := make(chan struct{}), make(chan struct{})
+	entry := registry.Register("existing resources", func(ctx context.Context) error {
+		calls++
+		close(started)
+		<-unblock
+		return nil
+	})
+
+	ownerDone := make(chan error)
+	go func() {
+		ownerDone <- entry.Destroy(context.Background())
+	}()
+	<-started
+
+	cleaned := make(chan []Leftover)
+	go func() {
+		cleaned <- registry.Cleanup(context.Background())
+	}()
+	close(unblock)
+
+	assert.NoError(t, <-ownerDone)
+	assert.Empty(t, <-cleaned)
+	assert.Equal(t, 1, calls, "the destroy of the owner is not repeated by the cleanup")
+	assert.Empty(t, registry.Pending())
+}
+
+func TestEntryRelease(t *testing.T) {
+	rec := &recorder{}
+	registry := NewRegistry(1)
+	kept := registry.Register("kept", rec.destroy("kept", nil))
+	registry.Register("destroyed", rec.destroy("destroyed", nil))
+
+	kept.Release()
+	assert.Empty(t, registry.Cleanup(context.Background()))
+	assert.Equal(t, []string{"destroyed"}, rec.events)
+	assert.NoError(t, kept.Destroy(context.Background()), "a released entry is not destroyed")
+	assert.Equal(t, []string{"destroyed"}, rec.events)
+}
+
+func TestEntryDestroyFailure(t *testing.T) {
+	registry := NewRegistry(1)
+	destroyErr := errors.New("destroy failed")
+	entry := registry.Register("cluster", func(ctx context.Context) error { return destroyErr })
+
+	assert.ErrorIs(t, entry.Destroy(context.Background()), destroyErr)
+	assert.Equal(t, []*Entry{entry}, registry.Pending())
+	assert.Equal(t, []Leftover{{Name: "cluster", Error: "destroy failed"}}, registry.Cleanup(context.Background()))
+}
+
+func TestHandle(t *testing.T) {
+	t.Run("destroys and exits", func(t *testing.T) {
+		registry := NewRegistry(2)
+		registry.Register("vpc", func(ctx context.Context) error { return nil })
+		registry.Register("cluster", func(ctx context.Context) error { return errors.New("timed out") })
+		path := filepath.Join(t.TempDir(), "leftovers.json")
+
+		signals := make(chan os.Signal, 1)
+		exitCode := make(chan int, 1)
+		signals <- syscall.SIGTERM
+		registry.handle(signals, nil, path, func(code int) { exitCode <- code })
+
+		assert.Equal(t, 128+int(syscall.SIGTERM), <-exitCode)
+		assert.ErrorIs(t, context.Cause(registry.Context()), ErrInterrupted)
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.JSONEq(t, `[{"name": "cluster", "error": "timed out"}]`, string(data))
+	})
+
+	t.Run("second signal exits without waiting", func(t *testing.T) {
+		registry := NewRegistry(2)
+		unblock := make(chan struct{})
+		defer close(unblock)
+		started := make(chan struct{})
+		registry.Register("cluster", func(ctx context.Context) error {
+			close(started)
+			<-unblock
+			return nil
+		})
+		path := filepath.Join(t.TempDir(), "leftovers.json")
+
+		signals := make(chan os.Signal, 1)
+		exitCode := make(chan int, 1)
+		signals <- os.Interrupt
+		go registry.handle(signals, nil, path, func(code int) { exitCode <- code })
+		<-started
+		signals <- os.Interrupt
+
+		assert.Equal(t, 128+int(syscall.SIGINT), <-exitCode)
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.JSONEq(t, `[{"name": "cluster", "error": "not destroyed, the cleanup was interrupted"}]`, string(data))
+	})
+
+	t.Run("stopped", func(t *testing.T) {
+		registry := NewRegistry(1)
+		done := make(chan struct{})
+		close(done)
+		registry.handle(make(chan os.Signal), done, "", func(code int) { t.Errorf("exited with %d", code) })
+		assert.NoError(t, registry.Context().Err())
+	})
+}
@@ -0,0 +1,131 @@
+// Package deadline splits the time that a test has before go test -timeout ends the run into work and destroy, so
+// that an apply that runs late is cancelled while there is still time to destroy what it created, instead of the
+// process being killed mid-apply and leaving clusters behind.
+package deadline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrBudgetReached is the cause of the cancellation of the work context once only the destroy budget is left
+var ErrBudgetReached = errors.New("only the destroy budget is left before the test deadline")
+
+// Deadliner is implemented by *testing.T
+type Deadliner interface {
+	Deadline() (time.Time, bool)
+}
+
+// TB is the subset of testing.TB used by a budget
+type TB interface {
+	Deadliner
+	Context() context.Context
+	Cleanup(func())
+}
+
+// Terraform runs the apply and destroy of a Terraform configuration. Apply must stop Terraform gracefully when its
+// context is done, as a killed Terraform leaves what it was creating out of the state, and holds the state lock.
+type Terraform interface {
+	Apply(ctx context.Context) error
+	Destroy(ctx context.Context) error
+}
+
+// Budget is the time of a test, split into work, which ends reserve before the deadline of the test, and destroy
+type Budget struct {
+	deadline    time.Time
+	hasDeadline bool
+	work        context.Context
+}
+
+// New returns the budget of a test, keeping reserve at the end of the test for destroy. Without a deadline, e.g. with
+// -timeout 0, the work context is only cancelled at the end of the test.
+func New(t TB, reserve time.Duration) *Budget {
+	b := &Budget{}
+	var cancel context.CancelFunc
+	if b.deadline, b.hasDeadline = t.Deadline(); b.hasDeadline {
+		b.work, cancel = context.WithDeadlineCause(t.Context(), b.deadline.Add(-reserve), ErrBudgetReached)
+	} else {
+		b.work, cancel = context.WithCancel(t.Context())
+	}
+	t.Cleanup(cancel)
+	return b
+}
+
+// NewDetached returns the budget of work that outlives the test starting it, such as a fixture shared with other tests.
+// Its work context is not derived from the context of the test, so it is neither cancelled when that test ends nor when
+// it fails, but it still ends reserve before the deadline of the run. The caller cancels it once the work is done.
+func NewDetached(t Deadliner, reserve time.Duration) (*Budget, context.CancelFunc) {
+	b := &Budget{}
+	var cancel context.CancelFunc
+	if b.deadline, b.hasDeadline = t.Deadline(); b.hasDeadline {
+		b.work, cancel = context.WithDeadlineCause(context.Background(), b.deadline.Add(-reserve), ErrBudgetReached)
+	} else {
+		b.work, cancel = context.WithCancel(context.Background())
+	}
+	return b, cancel
+}
+
+// Context returns the context for the work of the test, such as apply, plan or checks of the deployed resources. It is
+// cancelled with ErrBudgetReached as cause when only the destroy budget is left.
+func (b *Budget) Context() context.Context {
+	return b.work
+}
+
+// Apply applies with the work context. If apply fails, including when it is cancelled because the budget is reached,
+// whatever it created is destroyed right away with a fresh context.
+func (b *Budget) Apply(tf Terraform) error {
+	err := tf.Apply(b.work)
+	if err == nil {
+		return nil
+	}
+	err = b.Wrap(err)
+	if destroyErr := b.Destroy(tf); destroyErr != nil {
+		return errors.Join(err, fmt.Errorf("destroy after the failed apply also failed, delete the resources manually: %w", destroyErr))
+	}
+	return err
+}
+
+// Run applies, runs check with the work context if the apply succeeded, and destroys with a fresh context in any case
+func (b *Budget) Run(tf Terraform, check func(ctx context.Context) error) error {
+	if err := b.Apply(tf); err != nil {
+		return err
+	}
+	var err error
+	if check != nil {
+		err = b.Wrap(check(b.work))
+	}
+	return errors.Join(err, b.Destroy(tf))
+}
+
+// Destroy destroys with a fresh context, which ends at the deadline of the test
+func (b *Budget) Destroy(tf Terraform) error {
+	ctx, cancel := b.destroyContext()
+	defer cancel()
+	return tf.Destroy(ctx)
+}
+
+func (b *Budget) destroyContext() (context.Context, context.CancelFunc) {
+	if b.hasDeadline {
+		return context.WithDeadline(context.Background(), b.deadline)
+	}
+	return context.WithCancel(context.Background())
+}
+
+// Wrap adds ErrBudgetReached to an error of the work of the test if the budget was reached, so that a timeout of the
+// work is not mistaken for a failure of the module
+func (b *Budget) Wrap(err error) error {
+	if err != nil && errors.Is(context.Cause(b.work), ErrBudgetReached) && !errors.Is(err, ErrBudgetReached) {
+		return fmt.Errorf("%w: %w", ErrBudgetReached, err)
+	}
+	return err
+}
+
+// DestroyContext returns a fresh context for a destroy, which ends at the deadline of the test. It is not derived from
+// the context of the test, so that it can be used in a cleanup, after the work of the test was cancelled.
+func DestroyContext(t Deadliner) (context.Context, context.CancelFunc) {
+	b := &Budget{}
+	b.deadline, b.hasDeadline = t.Deadline()
+	return b.destroyContext()
+}
@@ -0,0 +1,212 @@
+package deadline
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeT is a test with a deadline set by the test of this package
+type fakeT struct {
+	deadline    time.Time
+	hasDeadline bool
+	ctx         context.Context
+	cancel      context.CancelFunc
+	cleanups    []func()
+}
+
+func newFakeT(t *testing.T, timeout time.Duration) *fakeT {
+	ctx, cancel := context.WithCancel(context.Background())
+	f := &fakeT{ctx: ctx, cancel: cancel}
+	if timeout > 0 {
+		f.deadline, f.hasDeadline = time.Now().Add(timeout), true
+	}
+	t.Cleanup(f.finish)
+	return f
+}
+
+func (f *fakeT) Deadline() (time.Time, bool) { return f.deadline, f.hasDeadline }
+func (f *fakeT) Context() context.Context    { return f.ctx }
+func (f *fakeT) Cleanup(cleanup func())      { f.cleanups = append(f.cleanups, cleanup) }
+
+// finish ends the test like the testing package: the context is cancelled, then the cleanups run
+func (f *fakeT) finish() {
+	f.cancel()
+	for i := len(f.cleanups) - 1; i >= 0; i-- {
+		f.cleanups[i]()
+	}
+	f.cleanups = nil
+}
+
+// fakeTerraform records the commands that run and the state of their contexts
+type fakeTerraform struct {
+	mu     sync.Mutex
+	events []string
+	// blockApply makes apply run until its context is cancelled, like an apply that takes too long
+	blockApply bool
+	applyErr   error
+	// destroyDeadline is the deadline of the context of the last destroy
+	destroyDeadline time.Time
+	destroyAt       time.Time
+}
+
+func (f *fakeTerraform) record(event string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.events = append(f.events, event)
+}
+
+func (f *fakeTerraform) Apply(ctx context.Context) error {
+	f.record("apply")
+	if ctx.Err() != nil {
+		f.record("apply not started")
+		return ctx.Err()
+	}
+	if f.blockApply {
+		<-ctx.Done()
+		f.record("apply cancelled")
+		return ctx.Err()
+	}
+	return f.applyErr
+}
+
+func (f *fakeTerraform) Destroy(ctx context.Context) error {
+	f.record("destroy")
+	f.destroyAt = time.Now()
+	f.destroyDeadline, _ = ctx.Deadline()
+	return ctx.Err()
+}
+
+func TestApplyCancelledWhenBudgetIsReached(t *testing.T) {
+	ft := newFakeT(t, 300*time.Millisecond)
+	budget := New(ft, 200*time.Millisecond)
+	tf := &fakeTerraform{blockApply: true}
+
+	start := time.Now()
+	err := budget.Apply(tf)
+	assert.ErrorIs(t, err, ErrBudgetReached)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	assert.Equal(t, []string{"apply", "apply cancelled", "destroy"}, tf.events)
+	assert.GreaterOrEqual(t, tf.destroyAt.Sub(start), 100*time.Millisecond, "apply runs until the budget is reached")
+	assert.True(t, tf.destroyAt.Before(ft.deadline), "destroy starts before the test deadline")
+	assert.Equal(t, ft.deadline, tf.destroyDeadline, "destroy has until the test deadline")
+}
+
+func TestApplyAfterBudgetIsReached(t *testing.T) {
+	budget := New(newFakeT(t, 50*time.Millisecond), time.Hour)
+	tf := &fakeTerraform{}
+
+	err := budget.Apply(tf)
+	assert.ErrorIs(t, err, ErrBudgetReached)
+	assert.Equal(t, []string{"apply", "apply not started", "destroy"}, tf.events, "destroy runs even though the work context is done")
+}
+
+func TestApplyFailure(t *testing.T) {
+	budget := New(newFakeT(t, time.Hour), time.Minute)
+	applyErr := errors.New("quota exceeded")
+	tf := &fakeTerraform{applyErr: applyErr}
+
+	err := budget.Apply(tf)
+	assert.ErrorIs(t, err, applyErr)
+	assert.NotErrorIs(t, err, ErrBudgetReached)
+	assert.Equal(t, []string{"apply", "destroy"}, tf.events, "a partial apply is destroyed")
+}
+
+func TestRun(t *testing.T) {
+	t.Run("checks and destroys", func(t *testing.T) {
+		budget := New(newFakeT(t, time.Hour), time.Minute)
+		tf := &fakeTerraform{}
+		err := budget.Run(tf, func(ctx context.Context) error {
+			tf.record("check")
+			assert.Equal(t, budget.Context(), ctx)
+			return nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"apply", "check", "destroy"}, tf.events)
+	})
+
+	t.Run("destroys after a failed check", func(t *testing.T) {
+		budget := New(newFakeT(t, time.Hour), time.Minute)
+		tf := &fakeTerraform{}
+		checkErr := errors.New("ingress is not healthy")
+		err := budget.Run(tf, func(ctx context.Context) error {
+			tf.record("check")
+			return checkErr
+		})
+		assert.ErrorIs(t, err, checkErr)
+		assert.Equal(t, []string{"apply", "check", "destroy"}, tf.events)
+	})
+
+	t.Run("check cancelled when the budget is reached", func(t *testing.T) {
+		ft := newFakeT(t, 100*time.Millisecond)
+		budget := New(ft, 50*time.Millisecond)
+		tf := &fakeTerraform{}
+		err := budget.Run(tf, func(ctx context.Context) error {
+			tf.record("check")
+			<-ctx.Done()
+			tf.record("check cancelled")
+			return ctx.Err()
+		})
+		assert.ErrorIs(t, err, ErrBudgetReached)
+		assert.Equal(t, []string{"apply", "check", "check cancelled", "destroy"}, tf.events)
+		assert.Equal(t, ft.deadline, tf.destroyDeadline)
+	})
+
+	t.Run("no check after a failed apply", func(t *testing.T) {
+		budget := New(newFakeT(t, time.Hour), time.Minute)
+		tf := &fakeTerraform{applyErr: errors.New("apply failed")}
+		err := budget.Run(tf, func(ctx context.Context) error {
+			tf.record("check")
+			return nil
+		})
+		assert.EqualError(t, err, "apply failed")
+		assert.Equal(t, []string{"apply", "destroy"}, tf.events, "destroyed once")
+	})
+}
+
+func TestWithoutDeadline(t *testing.T) {
+	ft := newFakeT(t, 0)
+	budget := New(ft, time.Hour)
+	_, ok := budget.Context().Deadline()
+	assert.False(t, ok)
+
+	tf := &fakeTerraform{}
+	require.NoError(t, budget.Run(tf, nil))
+	assert.Equal(t, []string{"apply", "destroy"}, tf.events)
+	assert.True(t, tf.destroyDeadline.IsZero())
+
+	ft.finish()
+	assert.ErrorIs(t, budget.Context().Err(), context.Canceled, "the work ends with the test")
+}
+
+func TestNewDetached(t *testing.T) {
+	ft := newFakeT(t, time.Hour)
+	budget, cancel := NewDetached(ft, 10*time.Minute)
+	defer cancel()
+	deadline, ok := budget.Context().Deadline()
+	assert.True(t, ok)
+	assert.Equal(t, ft.deadline.Add(-10*time.Minute), deadline)
+
+	ft.finish()
+	assert.NoError(t, budget.Context().Err(), "the work outlives the test starting it")
+	cancel()
+	assert.ErrorIs(t, budget.Context().Err(), context.Canceled)
+}
+
+func TestDestroyContext(t *testing.T) {
+	ft := newFakeT(t, time.Hour)
+	ft.finish()
+
+	ctx, cancel := DestroyContext(ft)
+	defer cancel()
+	assert.NoError(t, ctx.Err(), "usable after the test context is cancelled")
+	deadline, ok := ctx.Deadline()
+	assert.True(t, ok)
+	assert.Equal(t, ft.deadline, deadline)
+}
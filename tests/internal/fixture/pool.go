@@ -0,0 +1,105 @@
+// Package fixture shares expensive test fixtures, such as the existing resources that solutions are deployed into,
+// between the tests of a run.
+package fixture
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// errAborted is returned to the borrowers of a fixture whose provisioning exited without returning, e.g. through
+// t.FailNow
+var errAborted = errors.New("provisioning was aborted")
+
+// Pool provisions one fixture per key, e.g. per region, and keeps it while it is borrowed. The last borrower to return
+// a fixture is responsible for destroying it.
+type Pool[T any] struct {
+	mu      sync.Mutex
+	entries map[string]*entry[T]
+}
+
+type entry[T any] struct {
+	ready chan struct{} // closed once provisioning is done
+	value T
+	err   error
+	refs  int
+}
+
+// NewPool returns an empty pool
+func NewPool[T any]() *Pool[T] {
+	return &Pool[T]{entries: map[string]*entry[T]{}}
+}
+
+// Borrow returns the fixture of key, calling create if it is not provisioned yet. Concurrent borrowers of a key wait for
+// the first one to provision it. If create fails, panics or exits the goroutine, all of them get an error and the next
+// borrower provisions the fixture again; create is expected to clean up after a failed attempt itself.
+//
+// Every successful Borrow must be paired with a Return, typically from t.Cleanup so that it also runs when the test
+// fails or panics.
+func (p *Pool[T]) Borrow(key string, create func() (T, error)) (T, error) {
+	p.mu.Lock()
+	e, ok := p.entries[key]
+	if ok {
+		e.refs++
+		p.mu.Unlock()
+		<-e.ready
+		return e.value, e.err
+	}
+	e = &entry[T]{ready: make(chan struct{}), refs: 1}
+	p.entries[key] = e
+	p.mu.Unlock()
+
+	p.provision(key, e, create)
+	return e.value, e.err
+}
+
+// Return gives back a borrowed fixture. It returns the fixture and true if this was the last borrower, in which case the
+// fixture is removed from the pool and must be destroyed by the caller.
+func (p *Pool[T]) Return(key string) (T, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	e, ok := p.entries[key]
+	if !ok {
+		var zero T
+		return zero, false
+	}
+	e.refs--
+	if e.refs > 0 {
+		return e.value, false
+	}
+	delete(p.entries, key)
+	return e.value, true
+}
+
+// Borrowers returns the number of borrowers of each provisioned or provisioning fixture
+func (p *Pool[T]) Borrowers() map[string]int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	refs := map[string]int{}
+	for key, e := range p.entries {
+		refs[key] = e.refs
+	}
+	return refs
+}
+
+// provision runs create for a new entry, turning panics and goroutine exits into an error for all waiting borrowers
+func (p *Pool[T]) provision(key string, e *entry[T], create func() (T, error)) {
+	done := false
+	defer func() {
+		if r := recover(); r != nil {
+			e.err = fmt.Errorf("provisioning fixture %s panicked: %v", key, r)
+		} else if !done {
+			e.err = fmt.Errorf("fixture %s: %w", key, errAborted)
+		}
+		if e.err != nil {
+			// the borrowers get the error instead of the fixture, so they do not return it
+			p.mu.Lock()
+			delete(p.entries, key)
+			p.mu.Unlock()
+		}
+		close(e.ready)
+	}()
+	e.value, e.err = create()
+	done = true
+}
@@ -0,0 +1,148 @@
+package fixture
+
+import (
+	"errors"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPoolSharesFixture(t *testing.T) {
+	pool := NewPool[string]()
+	var creates atomic.Int32
+	create := func() (string, error) {
+		creates.Add(1)
+		time.Sleep(10 * time.Millisecond)
+		return "vpc-1", nil
+	}
+
+	var wg sync.WaitGroup
+	for range 5 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			value, err := pool.Borrow("us-south", create)
+			assert.NoError(t, err)
+			assert.Equal(t, "vpc-1", value)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), creates.Load())
+	assert.Equal(t, map[string]int{"us-south": 5}, pool.Borrowers())
+
+	last := 0
+	for range 5 {
+		value, isLast := pool.Return("us-south")
+		assert.Equal(t, "vpc-1", value)
+		if isLast {
+			last++
+		}
+	}
+	assert.Equal(t, 1, last, "exactly one borrower destroys the fixture")
+	assert.Empty(t, pool.Borrowers())
+
+	_, isLast := pool.Return("us-south")
+	assert.False(t, isLast, "returning an unknown fixture")
+
+	// the next borrower provisions a new fixture
+	_, err := pool.Borrow("us-south", create)
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), creates.Load())
+}
+
+func TestPoolKeys(t *testing.T) {
+	pool := NewPool[string]()
+
+	usSouth, err := pool.Borrow("us-south", func() (string, error) { return "vpc-1", nil })
+	require.NoError(t, err)
+	euDe, err := pool.Borrow("eu-de", func() (string, error) { return "vpc-2", nil })
+	require.NoError(t, err)
+	assert.Equal(t, "vpc-1", usSouth)
+	assert.Equal(t, "vpc-2", euDe)
+	assert.Equal(t, map[string]int{"us-south": 1, "eu-de": 1}, pool.Borrowers())
+}
+
+func TestPoolProvisioningFailures(t *testing.T) {
+	testCases := []struct {
+		name   string
+		create func() (string, error)
+		error  string
+	}{
+		{
+			name:   "error",
+			create: func() (string, error) { return "", errors.New("apply failed") },
+			error:  "apply failed",
+		},
+		{
+			name:   "panic",
+			create: func() (string, error) { panic("nil map") },
+			error:  "provisioning fixture us-south panicked: nil map",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			pool := NewPool[string]()
+			release := make(chan struct{})
+			waiter := make(chan error)
+			go func() {
+				_, err := pool.Borrow("us-south", func() (string, error) {
+					<-release
+					return tc.create()
+				})
+				waiter <- err
+			}()
+			// wait for the first borrower to start provisioning
+			require.Eventually(t, func() bool { return pool.Borrowers()["us-south"] == 1 }, time.Second, time.Millisecond)
+			go func() {
+				_, err := pool.Borrow("us-south", func() (string, error) { return "unused", nil })
+				waiter <- err
+			}()
+			require.Eventually(t, func() bool { return pool.Borrowers()["us-south"] == 2 }, time.Second, time.Millisecond)
+
+			close(release)
+			assert.ErrorContains(t, <-waiter, tc.error)
+			assert.ErrorContains(t, <-waiter, tc.error)
+			assert.Empty(t, pool.Borrowers())
+
+			value, err := pool.Borrow("us-south", func() (string, error) { return "vpc-1", nil })
+			require.NoError(t, err, "a failed fixture is provisioned again")
+			assert.Equal(t, "vpc-1", value)
+		})
+	}
+}
+
+// TestPoolProvisioningGoexit checks that borrowers waiting for a fixture are not stuck when the provisioning test calls
+// t.FailNow, which exits its goroutine
+func TestPoolProvisioningGoexit(t *testing.T) {
+	pool := NewPool[string]()
+	started := make(chan struct{})
+	release := make(chan struct{})
+	go func() {
+		_, _ = pool.Borrow("us-south", func() (string, error) {
+			close(started)
+			<-release
+			runtime.Goexit()
+			return "", nil
+		})
+	}()
+	<-started
+
+	waiter := make(chan error)
+	go func() {
+		_, err := pool.Borrow("us-south", func() (string, error) { return "unused", nil })
+		waiter <- err
+	}()
+	require.Eventually(t, func() bool { return pool.Borrowers()["us-south"] == 2 }, time.Second, time.Millisecond)
+
+	close(release)
+	err := <-waiter
+	assert.ErrorIs(t, err, errAborted)
+	assert.EqualError(t, err, "fixture us-south: provisioning was aborted")
+	assert.Empty(t, pool.Borrowers())
+}
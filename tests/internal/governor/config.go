@@ -0,0 +1,88 @@
+package governor
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultWeight is the key of Config.Weights used for tests that are not listed
+const DefaultWeight = "default"
+
+// Config is the YAML file of the resource limits of the account and the weight of each test
+type Config struct {
+	// Limits are the amounts of each resource that the tests may hold at the same time
+	Limits Resources `yaml:"limits"`
+	// Weights are the resources that a test creates, by test name
+	Weights map[string]Resources `yaml:"weights"`
+	// Addons are the resources that an addon creates when an addon test case enables it, by offering name
+	Addons map[string]Resources `yaml:"addons"`
+}
+
+// LoadConfig reads and validates a configuration
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	config := &Config{}
+	if err := yaml.Unmarshal(data, config); err != nil {
+		return nil, fmt.Errorf("error decoding %s: %w", path, err)
+	}
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid resource limits in %s: %w", path, err)
+	}
+	return config, nil
+}
+
+// Validate checks that the limits are positive, that there is a default weight, and that every weight is of a limited
+// resource and within its limit
+func (c *Config) Validate() error {
+	var errs []error
+	for _, resource := range slices.Sorted(maps.Keys(c.Limits)) {
+		if c.Limits[resource] <= 0 {
+			errs = append(errs, fmt.Errorf("limit of %s must be positive", resource))
+		}
+	}
+	if _, ok := c.Weights[DefaultWeight]; !ok {
+		errs = append(errs, fmt.Errorf("no %s weight", DefaultWeight))
+	}
+	check := func(kind string, weights map[string]Resources) {
+		for _, name := range slices.Sorted(maps.Keys(weights)) {
+			for _, resource := range slices.Sorted(maps.Keys(weights[name])) {
+				limit, ok := c.Limits[resource]
+				switch amount := weights[name][resource]; {
+				case !ok:
+					errs = append(errs, fmt.Errorf("%s %s: %s has no limit", kind, name, resource))
+				case amount < 0 || amount > limit:
+					errs = append(errs, fmt.Errorf("%s %s: %d %s is not within the limit of %d", kind, name, amount, resource, limit))
+				}
+			}
+		}
+	}
+	check("weight of", c.Weights)
+	check("addon", c.Addons)
+	return errors.Join(errs...)
+}
+
+// Weight returns the weight of a test, or of a test case of an addon test with the enabled addons
+func (c *Config) Weight(test string, addons ...string) Resources {
+	base, ok := c.Weights[test]
+	if !ok {
+		base = c.Weights[DefaultWeight]
+	}
+	weight := maps.Clone(base)
+	if weight == nil {
+		weight = Resources{}
+	}
+	for _, addon := range addons {
+		for resource, amount := range c.Addons[addon] {
+			weight[resource] += amount
+		}
+	}
+	return weight
+}
@@ -0,0 +1,177 @@
+// Package governor limits the resources that the tests of a run create at the same time in the test account, such as
+// clusters, VPCs and Secrets Manager trial instances, so that parallel tests queue instead of failing on account quotas.
+package governor
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Resources are amounts of account resources by name, e.g. clusters or vpcs
+type Resources map[string]int64
+
+// String formats the resources in name order, e.g. clusters=2 vpcs=1
+func (r Resources) String() string {
+	var parts []string
+	for _, name := range slices.Sorted(maps.Keys(r)) {
+		parts = append(parts, fmt.Sprintf("%s=%d", name, r[name]))
+	}
+	return strings.Join(parts, " ")
+}
+
+// Governor is a weighted semaphore over several resources. A weight is granted all at once, when every resource has
+// room for it, and in the order of the requests, so that a heavy test is not starved by lighter ones.
+type Governor struct {
+	limits Resources
+
+	mu    sync.Mutex
+	used  Resources
+	queue []*waiter
+	waits []Wait
+}
+
+type waiter struct {
+	weight  Resources
+	granted chan struct{}
+}
+
+// Permit is a granted weight, held until it is released
+type Permit struct {
+	governor *Governor
+	weight   Resources
+	once     sync.Once
+	// Waited is how long the request was queued
+	Waited time.Duration
+}
+
+// Wait is the queue time of a granted request
+type Wait struct {
+	Name   string
+	Weight Resources
+	Waited time.Duration
+}
+
+// New returns a governor with limits per resource. Resources without a limit are not restricted.
+func New(limits Resources) *Governor {
+	return &Governor{limits: limits, used: Resources{}}
+}
+
+// Acquire waits until the weight can be granted, or until ctx is done. The name identifies the request in Waits.
+func (g *Governor) Acquire(ctx context.Context, name string, weight Resources) (*Permit, error) {
+	for resource, amount := range weight {
+		if limit, ok := g.limits[resource]; ok && amount > limit {
+			return nil, fmt.Errorf("%s needs %d %s, more than the limit of %d", name, amount, resource, limit)
+		}
+	}
+
+	start := time.Now()
+	g.mu.Lock()
+	w := &waiter{weight: weight, granted: make(chan struct{})}
+	g.queue = append(g.queue, w)
+	g.grant()
+	g.mu.Unlock()
+
+	select {
+	case <-w.granted:
+	case <-ctx.Done():
+		g.mu.Lock()
+		defer g.mu.Unlock()
+		select {
+		case <-w.granted:
+			// granted while giving up
+			g.take(w.weight, -1)
+		default:
+			g.queue = slices.DeleteFunc(g.queue, func(q *waiter) bool { return q == w })
+		}
+		// the head of the queue may have been blocking smaller requests
+		g.grant()
+		return nil, fmt.Errorf("%s gave up waiting for %s: %w", name, weight, ctx.Err())
+	}
+
+	permit := &Permit{governor: g, weight: weight, Waited: time.Since(start)}
+	g.mu.Lock()
+	g.waits = append(g.waits, Wait{Name: name, Weight: weight, Waited: permit.Waited})
+	g.mu.Unlock()
+	return permit, nil
+}
+
+// Release gives the weight back. Releasing a permit more than once has no effect.
+func (p *Permit) Release() {
+	p.once.Do(func() {
+		p.governor.mu.Lock()
+		defer p.governor.mu.Unlock()
+		p.governor.take(p.weight, -1)
+		p.governor.grant()
+	})
+}
+
+// InUse returns the resources held by the granted permits
+func (g *Governor) InUse() Resources {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	used := Resources{}
+	for resource, amount := range g.used {
+		if amount != 0 {
+			used[resource] = amount
+		}
+	}
+	return used
+}
+
+// Waits returns the queue time of every granted request, in the order they were granted
+func (g *Governor) Waits() []Wait {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return slices.Clone(g.waits)
+}
+
+// Report summarises the queue times, listing the requests that waited a second or more, longest first
+func (g *Governor) Report() string {
+	waits := g.Waits()
+	slices.SortStableFunc(waits, func(a, b Wait) int { return cmp.Compare(b.Waited, a.Waited) })
+	var total time.Duration
+	for _, w := range waits {
+		total += w.Waited
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d tests waited %s in total for account resources", len(waits), total.Round(time.Second))
+	for _, w := range waits {
+		if w.Waited < time.Second {
+			break
+		}
+		fmt.Fprintf(&b, "\n  %s waited %s for %s", w.Name, w.Waited.Round(time.Second), w.Weight)
+	}
+	return b.String()
+}
+
+// grant grants the waiters at the head of the queue that fit, stopping at the first one that does not. The caller holds
+// g.mu.
+func (g *Governor) grant() {
+	for len(g.queue) > 0 && g.fits(g.queue[0].weight) {
+		w := g.queue[0]
+		g.queue = g.queue[1:]
+		g.take(w.weight, 1)
+		close(w.granted)
+	}
+}
+
+func (g *Governor) fits(weight Resources) bool {
+	for resource, amount := range weight {
+		if limit, ok := g.limits[resource]; ok && g.used[resource]+amount > limit {
+			return false
+		}
+	}
+	return true
+}
+
+func (g *Governor) take(weight Resources, sign int64) {
+	for resource, amount := range weight {
+		g.used[resource] += sign * amount
+	}
+}
@@ -0,0 +1,215 @@
+package governor
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func acquire(t *testing.T, g *Governor, name string, weight Resources) *Permit {
+	permit, err := g.Acquire(context.Background(), name, weight)
+	require.NoError(t, err)
+	return permit
+}
+
+// acquireAsync requests a weight from another goroutine, sending the permit once it is granted
+func acquireAsync(t *testing.T, g *Governor, name string, weight Resources) <-chan *Permit {
+	granted := make(chan *Permit, 1)
+	go func() {
+		permit, err := g.Acquire(context.Background(), name, weight)
+		assert.NoError(t, err)
+		granted <- permit
+	}()
+	// wait until the request is queued
+	require.Eventually(t, func() bool {
+		g.mu.Lock()
+		defer g.mu.Unlock()
+		for _, w := range g.queue {
+			if fmt.Sprint(w.weight) == fmt.Sprint(weight) {
+				return true
+			}
+		}
+		return false
+	}, time.Second, time.Millisecond)
+	return granted
+}
+
+func TestAcquireWithinLimits(t *testing.T) {
+	g := New(Resources{"clusters": 3, "vpcs": 2})
+
+	first := acquire(t, g, "basic", Resources{"clusters": 1, "vpcs": 1})
+	acquire(t, g, "multi-cluster", Resources{"clusters": 2, "vpcs": 1})
+	assert.Equal(t, Resources{"clusters": 3, "vpcs": 2}, g.InUse())
+	assert.Less(t, first.Waited, time.Second)
+
+	acquire(t, g, "plan", Resources{"cos": 5})
+	assert.Equal(t, Resources{"clusters": 3, "cos": 5, "vpcs": 2}, g.InUse(), "resources without a limit are not restricted")
+
+	_, err := g.Acquire(context.Background(), "huge", Resources{"clusters": 4})
+	assert.EqualError(t, err, "huge needs 4 clusters, more than the limit of 3")
+}
+
+func TestAcquireWaitsForRelease(t *testing.T) {
+	g := New(Resources{"clusters": 2, "secrets_manager_trials": 1})
+	withTrial := acquire(t, g, "addon-1", Resources{"clusters": 1, "secrets_manager_trials": 1})
+
+	granted := acquireAsync(t, g, "addon-2", Resources{"clusters": 1, "secrets_manager_trials": 1})
+	select {
+	case <-granted:
+		t.Fatal("granted while the trial instance is in use")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	withTrial.Release()
+	permit := <-granted
+	assert.GreaterOrEqual(t, permit.Waited, 20*time.Millisecond)
+	withTrial.Release()
+	assert.Equal(t, Resources{"clusters": 1, "secrets_manager_trials": 1}, g.InUse(), "releasing twice has no effect")
+
+	waits := g.Waits()
+	require.Len(t, waits, 2)
+	assert.Equal(t, "addon-2", waits[1].Name)
+	assert.GreaterOrEqual(t, waits[1].Waited, 20*time.Millisecond)
+	assert.Equal(t, "2 tests waited 0s in total for account resources", g.Report(), "waits under a second are not listed")
+}
+
+func TestAcquireIsFirstInFirstOut(t *testing.T) {
+	g := New(Resources{"clusters": 2})
+	held := acquire(t, g, "basic", Resources{"clusters": 1})
+
+	// the two cluster request is queued first, so the later one cluster request does not overtake it
+	heavy := acquireAsync(t, g, "multi-cluster", Resources{"clusters": 2})
+	light := acquireAsync(t, g, "gpu", Resources{"clusters": 1})
+	select {
+	case <-light:
+		t.Fatal("a later request overtook a queued one")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	held.Release()
+	(<-heavy).Release()
+	<-light
+}
+
+func TestAcquireCancelled(t *testing.T) {
+	g := New(Resources{"clusters": 2})
+	held := acquire(t, g, "basic", Resources{"clusters": 1})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	light := make(chan *Permit, 1)
+	go func() {
+		// queued behind the heavy request until it gives up
+		time.Sleep(5 * time.Millisecond)
+		light <- acquire(t, g, "gpu", Resources{"clusters": 1})
+	}()
+	_, err := g.Acquire(ctx, "multi-cluster", Resources{"clusters": 2})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.ErrorContains(t, err, "multi-cluster gave up waiting for clusters=2")
+
+	select {
+	case <-light:
+	case <-time.After(time.Second):
+		t.Fatal("the request behind the cancelled one was not granted")
+	}
+	held.Release()
+	assert.Equal(t, Resources{"clusters": 1}, g.InUse())
+}
+
+func TestAcquireConcurrently(t *testing.T) {
+	limits := Resources{"clusters": 4, "vpcs": 3}
+	g := New(limits)
+
+	var mu sync.Mutex
+	peak := Resources{}
+	var wg sync.WaitGroup
+	for i := range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			permit := acquire(t, g, fmt.Sprintf("test-%d", i), Resources{"clusters": int64(1 + i%2), "vpcs": 1})
+			mu.Lock()
+			for resource, amount := range g.InUse() {
+				peak[resource] = max(peak[resource], amount)
+			}
+			mu.Unlock()
+			time.Sleep(time.Millisecond)
+			permit.Release()
+		}()
+	}
+	wg.Wait()
+
+	for resource, amount := range peak {
+		assert.LessOrEqual(t, amount, limits[resource], resource)
+	}
+	assert.Empty(t, g.InUse())
+	assert.Len(t, g.Waits(), 20)
+}
+
+const configYAML = `
+limits:
+  clusters: 6
+  vpcs: 8
+  secrets_manager_trials: 1
+weights:
+  default:
+    clusters: 1
+    vpcs: 1
+  TestRunMultiClusterExample:
+    clusters: 2
+    vpcs: 1
+  TestRunUnitOnly: {}
+addons:
+  deploy-arch-ibm-secrets-manager:
+    secrets_manager_trials: 1
+  deploy-arch-ibm-slz-vpc:
+    vpcs: 1
+`
+
+func writeConfig(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "resource-limits.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	return path
+}
+
+func TestLoadConfig(t *testing.T) {
+	config, err := LoadConfig(writeConfig(t, configYAML))
+	require.NoError(t, err)
+
+	assert.Equal(t, Resources{"clusters": 6, "vpcs": 8, "secrets_manager_trials": 1}, config.Limits)
+	assert.Equal(t, Resources{"clusters": 2, "vpcs": 1}, config.Weight("TestRunMultiClusterExample"))
+	assert.Equal(t, Resources{"clusters": 1, "vpcs": 1}, config.Weight("TestRunBasicExample"))
+	assert.Equal(t, Resources{}, config.Weight("TestRunUnitOnly"))
+	assert.Equal(t, Resources{"clusters": 1, "vpcs": 2, "secrets_manager_trials": 1},
+		config.Weight("TestAddonPermutations", "deploy-arch-ibm-secrets-manager", "deploy-arch-ibm-slz-vpc", "deploy-arch-ibm-cos"))
+	assert.Equal(t, Resources{"clusters": 1, "vpcs": 1}, config.Weights[DefaultWeight], "the configured weight is not modified")
+}
+
+func TestLoadInvalidConfig(t *testing.T) {
+	_, err := LoadConfig(writeConfig(t, `
+limits:
+  clusters: 0
+  vpcs: 2
+weights:
+  TestRunMultiClusterExample:
+    vpcs: 3
+addons:
+  deploy-arch-ibm-secrets-manager:
+    secrets_manager_trials: 1
+`))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "limit of clusters must be positive")
+	assert.Contains(t, err.Error(), "no default weight")
+	assert.Contains(t, err.Error(), "weight of TestRunMultiClusterExample: 3 vpcs is not within the limit of 2")
+	assert.Contains(t, err.Error(), "addon deploy-arch-ibm-secrets-manager: secrets_manager_trials has no limit")
+
+	_, err = LoadConfig(writeConfig(t, "limits: [1]"))
+	assert.ErrorContains(t, err, "error decoding")
+}
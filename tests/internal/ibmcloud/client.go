@@ -0,0 +1,161 @@
+// Package ibmcloud contains small clients for the IBM Cloud APIs that the tests use to verify what was deployed. The
+// clients of APIs that have an IBM Cloud Go SDK wrap it, the others are minimal REST clients. Only the fields that the
+// verifiers need are modelled.
+package ibmcloud
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/IBM/go-sdk-core/v5/core"
+)
+
+// defaultRetryDelay is the delay before the first retry of a request when Client.RetryDelay is not set
+const defaultRetryDelay = time.Second
+
+// Client is a minimal JSON REST client that authenticates every request with an IBM Cloud authenticator
+type Client struct {
+	URL           string
+	Authenticator core.Authenticator
+	HTTPClient    *http.Client
+	// Retries is how many times a request is retried after a transport error or a transient error response, i.e. 408,
+	// 429 or 5xx, like curl --retry does
+	Retries int
+	// RetryDelay is the delay before the first retry, which doubles with every further retry
+	RetryDelay time.Duration
+}
+
+// ErrNotFound is wrapped by the errors of the SDK clients when the requested resource does not exist
+var ErrNotFound = errors.New("not found")
+
+// APIError is returned when an API responds with a non 2xx status code
+type APIError struct {
+	Method     string
+	URL        string
+	StatusCode int
+	Body       string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("%s %s returned %d: %s", e.Method, e.URL, e.StatusCode, strings.TrimSpace(e.Body))
+}
+
+// NewIamAuthenticator returns an IAM authenticator for the given API key
+func NewIamAuthenticator(apiKey string) (core.Authenticator, error) {
+	return core.NewIamAuthenticatorBuilder().SetApiKey(apiKey).Build()
+}
+
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, in interface{}, out interface{}) error {
+	return c.doWithHeader(ctx, method, path, query, nil, in, out)
+}
+
+// doWithHeader is do with extra request headers, for APIs that take parameters as headers
+func (c *Client) doWithHeader(ctx context.Context, method, path string, query url.Values, header http.Header, in interface{}, out interface{}) error {
+	endpoint := strings.TrimSuffix(c.URL, "/") + path
+	if len(query) > 0 {
+		endpoint += "?" + query.Encode()
+	}
+
+	var payload []byte
+	if in != nil {
+		var err error
+		if payload, err = json.Marshal(in); err != nil {
+			return fmt.Errorf("error encoding request body for %s %s: %w", method, endpoint, err)
+		}
+	}
+
+	delay := c.RetryDelay
+	if delay == 0 {
+		delay = defaultRetryDelay
+	}
+	for retry := 0; ; retry++ {
+		respBody, err := c.send(ctx, method, endpoint, header, payload)
+		if retry >= c.Retries || !transient(ctx, err) {
+			if err != nil || out == nil || len(respBody) == 0 {
+				return err
+			}
+			if err := json.Unmarshal(respBody, out); err != nil {
+				return fmt.Errorf("error decoding response of %s %s: %w", method, endpoint, err)
+			}
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay << retry):
+		}
+	}
+}
+
+// send sends a request once and returns the body of a 2xx response
+func (c *Client) send(ctx context.Context, method, endpoint string, header http.Header, payload []byte) ([]byte, error) {
+	var body io.Reader
+	if payload != nil {
+		body = bytes.NewReader(payload)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, body)
+	if err != nil {
+		return nil, err
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	req.Header.Set("Accept", "application/json")
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.Authenticator != nil {
+		if err := c.Authenticator.Authenticate(req); err != nil {
+			return nil, fmt.Errorf("error authenticating %s %s: %w", method, endpoint, err)
+		}
+	}
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading response of %s %s: %w", method, endpoint, err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, &APIError{Method: method, URL: endpoint, StatusCode: resp.StatusCode, Body: string(respBody)}
+	}
+	return respBody, nil
+}
+
+// transient reports whether a request that failed with err may succeed when it is retried: after a transport error,
+// unless ctx is done, and after a 408, 429 or 5xx response
+func transient(ctx context.Context, err error) bool {
+	if err == nil || ctx.Err() != nil {
+		return false
+	}
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		var urlErr *url.Error
+		return errors.As(err, &urlErr)
+	}
+	return apiErr.StatusCode == http.StatusRequestTimeout || apiErr.StatusCode == http.StatusTooManyRequests || apiErr.StatusCode >= 500
+}
+
+func (c *Client) get(ctx context.Context, path string, query url.Values, out interface{}) error {
+	return c.do(ctx, http.MethodGet, path, query, nil, out)
+}
+
+func (c *Client) post(ctx context.Context, path string, in interface{}, out interface{}) error {
+	return c.do(ctx, http.MethodPost, path, nil, in, out)
+}
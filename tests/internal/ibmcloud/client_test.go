@@ -0,0 +1,333 @@
+package ibmcloud
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/IBM/go-sdk-core/v5/core"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestServer(t *testing.T, handler http.HandlerFunc) string {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return server.URL
+}
+
+func newTestClient(t *testing.T, handler http.HandlerFunc) Client {
+	return Client{URL: newTestServer(t, handler)}
+}
+
+func TestContainersClient(t *testing.T) {
+	client := &ContainersClient{newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "my-cluster", r.URL.Query().Get("cluster"))
+		switch r.URL.Path {
+		case "/v2/getCluster":
+			fmt.Fprint(w, `{"id":"abc123","name":"my-cluster","crn":"crn:v1:bluemix:public:containers-kubernetes:us-south:a/acct:abc123::","features":{"keyProtectEnabled":true}}`)
+		case "/v2/vpc/getWorkers":
+			fmt.Fprint(w, `[{"id":"kube-abc123-default-00000101","poolName":"default","location":"us-south-1"}]`)
+		case "/v2/getKMS":
+			fmt.Fprint(w, `{"instance_id":"inst","crk_id":"key","account_id":"other","private_endpoint":true}`)
+		case "/v2/vpc/getWorkerPools":
+			fmt.Fprint(w, `[{"id":"pool-1","poolName":"default","workerVolumeEncryption":{"kmsInstanceID":"inst","workerVolumeCRKID":"key"}},{"id":"pool-2","poolName":"zone-2"}]`)
+		default:
+			http.NotFound(w, r)
+		}
+	})}
+
+	cluster, err := client.GetCluster(context.Background(), "my-cluster")
+	require.NoError(t, err)
+	assert.Equal(t, "abc123", cluster.ID)
+	assert.True(t, cluster.Features.KeyProtectEnabled)
+
+	workers, err := client.ListWorkers(context.Background(), "my-cluster")
+	require.NoError(t, err)
+	assert.Equal(t, []Worker{{ID: "kube-abc123-default-00000101", PoolName: "default", Location: "us-south-1"}}, workers)
+
+	kms, err := client.GetClusterKMS(context.Background(), "my-cluster")
+	require.NoError(t, err)
+	assert.Equal(t, &ClusterKMS{InstanceID: "inst", CRKID: "key", AccountID: "other", PrivateEndpoint: true}, kms)
+
+	pools, err := client.ListWorkerPools(context.Background(), "my-cluster")
+	require.NoError(t, err)
+	assert.Equal(t, []WorkerPool{
+		{ID: "pool-1", PoolName: "default", WorkerVolumeEncryption: &WorkerVolumeEncryption{KmsInstanceID: "inst", WorkerVolumeCRKID: "key"}},
+		{ID: "pool-2", PoolName: "zone-2"},
+	}, pools)
+}
+
+func TestContainersClientReplaceWorker(t *testing.T) {
+	client := &ContainersClient{newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "/v2/vpc/replaceWorker", r.URL.Path)
+		var body map[string]interface{}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.Equal(t, map[string]interface{}{"cluster": "my-cluster", "workerID": "kube-abc123-default-00000101", "update": true}, body)
+		w.WriteHeader(http.StatusNoContent)
+	})}
+
+	require.NoError(t, client.ReplaceWorker(context.Background(), "my-cluster", "kube-abc123-default-00000101", true))
+}
+
+func TestClientRetries(t *testing.T) {
+	statuses := []int{http.StatusServiceUnavailable, http.StatusTooManyRequests, http.StatusOK}
+	requests := 0
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]string
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&body), "the body is sent with every attempt")
+		w.WriteHeader(statuses[min(requests, len(statuses)-1)])
+		requests++
+		fmt.Fprint(w, `{"id":"abc"}`)
+	})
+	client.Retries, client.RetryDelay = 3, time.Millisecond
+
+	var out struct {
+		ID string `json:"id"`
+	}
+	require.NoError(t, client.post(context.Background(), "/things", map[string]string{"name": "a"}, &out))
+	assert.Equal(t, "abc", out.ID)
+	assert.Equal(t, 3, requests)
+
+	statuses, requests = []int{http.StatusInternalServerError}, 0
+	var apiErr *APIError
+	require.ErrorAs(t, client.post(context.Background(), "/things", map[string]string{"name": "a"}, nil), &apiErr)
+	assert.Equal(t, http.StatusInternalServerError, apiErr.StatusCode)
+	assert.Equal(t, 4, requests, "the request is retried three times")
+
+	statuses, requests = []int{http.StatusBadRequest}, 0
+	require.ErrorAs(t, client.post(context.Background(), "/things", map[string]string{"name": "a"}, nil), &apiErr)
+	assert.Equal(t, 1, requests, "client errors are not retried")
+
+	unreachable := Client{URL: "http://127.0.0.1:1", Retries: 2, RetryDelay: time.Millisecond}
+	assert.Error(t, unreachable.get(context.Background(), "/things", nil, nil))
+}
+
+func TestVpcClientListLoadBalancers(t *testing.T) {
+	var url string
+	url = newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/load_balancers", r.URL.Path)
+		assert.Equal(t, vpcAPIVersion, r.URL.Query().Get("version"))
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Query().Get("start") {
+		case "":
+			fmt.Fprintf(w, `{"load_balancers":[{"id":"lb-1","hostname":"a.lb.appdomain.cloud","provisioning_status":"active","operating_status":"online"}],"next":{"href":"%s/load_balancers?limit=100&start=page2"}}`, url)
+		case "page2":
+			fmt.Fprint(w, `{"load_balancers":[{"id":"lb-2","hostname":"b.lb.appdomain.cloud","provisioning_status":"create_pending","operating_status":"offline"}]}`)
+		}
+	})
+	client, err := newVpcClient(url, &core.NoAuthAuthenticator{})
+	require.NoError(t, err)
+
+	loadBalancers, err := client.ListLoadBalancers(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []LoadBalancer{
+		{ID: "lb-1", Hostname: "a.lb.appdomain.cloud", ProvisioningStatus: "active", OperatingStatus: "online"},
+		{ID: "lb-2", Hostname: "b.lb.appdomain.cloud", ProvisioningStatus: "create_pending", OperatingStatus: "offline"},
+	}, loadBalancers)
+}
+
+func TestVpcClientGetInstanceBootVolume(t *testing.T) {
+	client, err := newVpcClient(newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/instances":
+			if r.URL.Query().Get("name") == "kube-abc-default-00000001" {
+				fmt.Fprint(w, `{"instances":[{"id":"i-1","name":"kube-abc-default-00000001","boot_volume_attachment":{"id":"a-1","volume":{"id":"vol-1"}}}]}`)
+				return
+			}
+			fmt.Fprint(w, `{"instances":[]}`)
+		case "/volumes/vol-1":
+			fmt.Fprint(w, `{"id":"vol-1","name":"boot","encryption":"user_managed","encryption_key":{"crn":"crn:v1:bluemix:public:kms:us-south:a/acc:inst:key:k1"}}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}), &core.NoAuthAuthenticator{})
+	require.NoError(t, err)
+
+	volume, err := client.GetInstanceBootVolume(context.Background(), "kube-abc-default-00000001")
+	require.NoError(t, err)
+	assert.Equal(t, &Volume{ID: "vol-1", Name: "boot", EncryptionKeyCRN: "crn:v1:bluemix:public:kms:us-south:a/acc:inst:key:k1"}, volume)
+
+	_, err = client.GetInstanceBootVolume(context.Background(), "missing")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestResourceControllerClientListResourceInstances(t *testing.T) {
+	client, err := newResourceControllerClient(newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v2/resource_instances", r.URL.Path)
+		w.Header().Set("Content-Type", "application/json")
+		assert.Equal(t, COSResourceID, r.URL.Query().Get("resource_id"))
+		assert.Equal(t, "my-cluster_cos", r.URL.Query().Get("name"))
+		switch r.URL.Query().Get("start") {
+		case "":
+			fmt.Fprintf(w, `{"resources":[{"guid":"cos-1","name":"my-cluster_cos","state":"active"}],"next_url":"/v2/resource_instances?limit=100&name=my-cluster_cos&resource_id=%s&start=page2"}`, COSResourceID)
+		case "page2":
+			fmt.Fprint(w, `{"resources":[{"guid":"cos-2","name":"my-cluster_cos","state":"active"}],"next_url":null}`)
+		}
+	}), &core.NoAuthAuthenticator{})
+	require.NoError(t, err)
+
+	instances, err := client.ListResourceInstances(context.Background(), COSResourceID, "my-cluster_cos")
+	require.NoError(t, err)
+	assert.Equal(t, []ResourceInstance{
+		{GUID: "cos-1", Name: "my-cluster_cos", State: "active"},
+		{GUID: "cos-2", Name: "my-cluster_cos", State: "active"},
+	}, instances)
+}
+
+// newTestSchematicsClient returns a Schematics client for the handler, with a fixed refresh token
+func newTestSchematicsClient(t *testing.T, handler http.HandlerFunc) *SchematicsClient {
+	client, err := newSchematicsClient(newTestServer(t, handler), &core.NoAuthAuthenticator{})
+	require.NoError(t, err)
+	client.RefreshToken = func() (string, error) { return "refresh", nil }
+	return client
+}
+
+func TestSchematicsClient(t *testing.T) {
+	client := newTestSchematicsClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/v1/workspaces":
+			offset := r.URL.Query().Get("offset")
+			assert.Equal(t, "100", r.URL.Query().Get("limit"))
+			var workspaces []map[string]interface{}
+			if offset == "0" {
+				for i := range workspacesPageSize {
+					workspaces = append(workspaces, map[string]interface{}{"id": fmt.Sprintf("ws-%d", i)})
+				}
+			} else {
+				assert.Equal(t, "100", offset)
+				workspaces = []map[string]interface{}{{"id": "ws-last", "name": "fc-upg-abc", "tags": []string{"test-cleanup:xyz"}}}
+			}
+			assert.NoError(t, json.NewEncoder(w).Encode(map[string]interface{}{"count": workspacesPageSize + 1, "workspaces": workspaces}))
+		case r.Method == http.MethodDelete && r.URL.Path == "/v1/workspaces/ws-last":
+			assert.Equal(t, "true", r.URL.Query().Get("destroy_resources"))
+			assert.Equal(t, "refresh", r.Header.Get("refresh_token"))
+			fmt.Fprint(w, `"ws-last"`)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+
+	workspaces, err := client.ListWorkspaces(context.Background())
+	require.NoError(t, err)
+	require.Len(t, workspaces, workspacesPageSize+1)
+	assert.Equal(t, Workspace{ID: "ws-last", Name: "fc-upg-abc", Tags: []string{"test-cleanup:xyz"}}, workspaces[workspacesPageSize])
+
+	require.NoError(t, client.DeleteWorkspace(context.Background(), "ws-last", true))
+
+	_, err = client.GetWorkspace(context.Background(), "ws-deleted")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	client.RefreshToken = nil
+	assert.ErrorContains(t, client.DeleteWorkspace(context.Background(), "ws-last", true), "needs an IAM authenticator")
+}
+
+func TestSchematicsClientDeleteTaggedWorkspaces(t *testing.T) {
+	var deleted []string
+	gets := 0
+	client := newTestSchematicsClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/v1/workspaces":
+			fmt.Fprint(w, `{"count":2,"workspaces":[{"id":"ws-1","tags":["test-schematic","test-cleanup:abc"]},{"id":"ws-2","tags":["test-schematic"]}]}`)
+		case r.Method == http.MethodDelete:
+			deleted = append(deleted, r.URL.Path)
+			fmt.Fprint(w, `"deleted"`)
+		case r.Method == http.MethodGet && r.URL.Path == "/v1/workspaces/ws-1":
+			// destroying on the first check
+			if gets++; gets == 1 {
+				fmt.Fprint(w, `{"id":"ws-1"}`)
+				return
+			}
+			http.NotFound(w, r)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+
+	count, err := client.DeleteTaggedWorkspaces(context.Background(), "test-cleanup:abc", time.Millisecond)
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+	assert.Equal(t, []string{"/v1/workspaces/ws-1"}, deleted)
+	assert.Equal(t, 2, gets)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = client.DeleteTaggedWorkspaces(ctx, "test-cleanup:abc", time.Millisecond)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestIamIdentityClient(t *testing.T) {
+	var url string
+	var deleted []string
+	url = newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.URL.Path == "/v1/apikeys/details":
+			assert.Equal(t, "secret", r.Header.Get("IAM-ApiKey"))
+			fmt.Fprint(w, `{"id":"ApiKey-own","account_id":"acct","iam_id":"IBMid-1"}`)
+		case r.URL.Path == "/v1/apikeys" && r.URL.Query().Get("pagetoken") == "":
+			assert.Equal(t, "acct", r.URL.Query().Get("account_id"))
+			assert.Equal(t, "IBMid-1", r.URL.Query().Get("iam_id"))
+			fmt.Fprintf(w, `{"apikeys":[{"id":"ApiKey-1","name":"containers-kubernetes-key","description":"us-south"},{"id":"ApiKey-own","name":"ci"}],"next":"%s/v1/apikeys?pagetoken=page2"}`, url)
+		case r.URL.Path == "/v1/apikeys" && r.URL.Query().Get("pagetoken") == "page2":
+			fmt.Fprint(w, `{"apikeys":[{"id":"ApiKey-2","name":"containers-kubernetes-key","description":"eu-de"}]}`)
+		case r.Method == http.MethodDelete && r.URL.Path == "/v1/apikeys/ApiKey-1":
+			deleted = append(deleted, r.URL.Path)
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+	client, err := newIamIdentityClient(url, &core.NoAuthAuthenticator{})
+	require.NoError(t, err)
+
+	keys, err := client.ListOwnAPIKeys(context.Background(), "secret", ContainersApikeyName)
+	require.NoError(t, err)
+	assert.Equal(t, []APIKey{
+		{ID: "ApiKey-1", Name: ContainersApikeyName, Description: "us-south"},
+		{ID: "ApiKey-2", Name: ContainersApikeyName, Description: "eu-de"},
+	}, keys)
+
+	require.NoError(t, client.DeleteAPIKey(context.Background(), "ApiKey-1"))
+	assert.Equal(t, []string{"/v1/apikeys/ApiKey-1"}, deleted)
+	assert.ErrorIs(t, client.DeleteAPIKey(context.Background(), "ApiKey-gone"), ErrNotFound)
+}
+
+func TestParseCRN(t *testing.T) {
+	crn, err := ParseCRN("crn:v1:bluemix:public:kms:us-south:a/acct:inst:key:k")
+	require.NoError(t, err)
+	assert.Equal(t, "kms", crn.ServiceName)
+	assert.Equal(t, "us-south", crn.Location)
+	assert.Equal(t, "acct", crn.AccountID())
+	assert.Equal(t, "inst", crn.ServiceInstance)
+	assert.Equal(t, "k", crn.Resource)
+	assert.Equal(t, "crn:v1:bluemix:public:kms:us-south:a/acct:inst:key:k", crn.String())
+
+	for _, invalid := range []string{"", "crn:v1:bluemix", "arn:v1:bluemix:public:kms:us-south:a/acct:inst:key:k", "crn::bluemix:public::us-south:a/acct:inst:key:k"} {
+		_, err := ParseCRN(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestParseKubeVersion(t *testing.T) {
+	version, err := ParseKubeVersion("4.17.12_openshift")
+	require.NoError(t, err)
+	assert.Equal(t, KubeVersion{Major: 4, Minor: 17}, version)
+	assert.Equal(t, "4.17", version.String())
+
+	other, err := ParseKubeVersion("4.9")
+	require.NoError(t, err)
+	assert.True(t, other.Less(version))
+	assert.False(t, version.Less(other))
+
+	_, err = ParseKubeVersion("default")
+	assert.ErrorContains(t, err, `invalid kube version "default"`)
+}
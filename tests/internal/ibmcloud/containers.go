@@ -0,0 +1,183 @@
+package ibmcloud
+
+import (
+	"context"
+	"net/url"
+
+	"github.com/IBM/go-sdk-core/v5/core"
+)
+
+const containersURL = "https://containers.cloud.ibm.com/global"
+
+// ContainersClient talks to the IBM Cloud Kubernetes Service v2 API
+type ContainersClient struct {
+	Client
+}
+
+// Cluster is the subset of the v2 getCluster response used by the tests
+type Cluster struct {
+	ID                string          `json:"id"`
+	Name              string          `json:"name"`
+	CRN               string          `json:"crn"`
+	Region            string          `json:"region"`
+	State             string          `json:"state"`
+	MasterKubeVersion string          `json:"masterKubeVersion"`
+	Features          ClusterFeatures `json:"features"`
+	Ingress           ClusterIngress  `json:"ingress"`
+}
+
+// ClusterIngress holds the default ingress subdomain details of a cluster
+type ClusterIngress struct {
+	Hostname   string `json:"hostname"`
+	SecretName string `json:"secretName"`
+	Status     string `json:"status"`
+	Message    string `json:"message"`
+}
+
+// ClusterFeatures holds the feature flags reported for a cluster
+type ClusterFeatures struct {
+	KeyProtectEnabled bool `json:"keyProtectEnabled"`
+}
+
+// Worker is the subset of the v2 getWorkers response used by the tests
+type Worker struct {
+	ID          string            `json:"id"`
+	PoolID      string            `json:"poolID"`
+	PoolName    string            `json:"poolName"`
+	Location    string            `json:"location"`
+	Lifecycle   WorkerLifecycle   `json:"lifecycle"`
+	Health      WorkerHealth      `json:"health"`
+	KubeVersion WorkerKubeVersion `json:"kubeVersion"`
+}
+
+// WorkerLifecycle holds the provisioning state of a worker
+type WorkerLifecycle struct {
+	DesiredState string `json:"desiredState"`
+	ActualState  string `json:"actualState"`
+	Message      string `json:"message"`
+}
+
+// WorkerHealth holds the health state of a worker as seen by the Kubernetes Service
+type WorkerHealth struct {
+	State   string `json:"state"`
+	Message string `json:"message"`
+}
+
+// WorkerKubeVersion holds the version a worker runs and the version it can be updated to
+type WorkerKubeVersion struct {
+	Actual  string `json:"actual"`
+	Desired string `json:"desired"`
+	Target  string `json:"target"`
+}
+
+// ClusterKMS is the KMS configuration of a cluster, as set with /v2/enableKMS. AccountID is empty when the KMS instance
+// is in the account of the cluster.
+type ClusterKMS struct {
+	InstanceID      string `json:"instance_id"`
+	CRKID           string `json:"crk_id"`
+	AccountID       string `json:"account_id"`
+	PrivateEndpoint bool   `json:"private_endpoint"`
+}
+
+// WorkerPool is the subset of the v2 getWorkerPools response used by the tests
+type WorkerPool struct {
+	ID                     string                  `json:"id"`
+	PoolName               string                  `json:"poolName"`
+	WorkerVolumeEncryption *WorkerVolumeEncryption `json:"workerVolumeEncryption,omitempty"`
+}
+
+// WorkerVolumeEncryption is the root key that the boot volumes of the workers of a pool are encrypted with. It is
+// missing for pools with provider managed encryption, and KmsAccountID is empty when the KMS instance is in the account
+// of the cluster.
+type WorkerVolumeEncryption struct {
+	KmsInstanceID     string `json:"kmsInstanceID"`
+	WorkerVolumeCRKID string `json:"workerVolumeCRKID"`
+	KmsAccountID      string `json:"kmsAccountID"`
+}
+
+// IngressInstance is a Secrets Manager instance registered with the cluster ingress
+type IngressInstance struct {
+	Name            string `json:"name"`
+	CRN             string `json:"crn"`
+	SecretGroupID   string `json:"secretGroupID"`
+	SecretGroupName string `json:"secretGroupName"`
+	IsDefault       bool   `json:"isDefault"`
+	Status          string `json:"status"`
+}
+
+// IngressSecret is a certificate or opaque secret that the cluster ingress syncs from Secrets Manager
+type IngressSecret struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+	CRN       string `json:"crn"`
+	Domain    string `json:"domain"`
+	ExpiresOn string `json:"expiresOn"`
+	Status    string `json:"status"`
+	Type      string `json:"type"`
+}
+
+// NewContainersClient returns a client for the global Kubernetes Service endpoint
+func NewContainersClient(authenticator core.Authenticator) *ContainersClient {
+	return &ContainersClient{Client{URL: containersURL, Authenticator: authenticator}}
+}
+
+// GetCluster returns the cluster with the given name or ID
+func (c *ContainersClient) GetCluster(ctx context.Context, cluster string) (*Cluster, error) {
+	var result Cluster
+	if err := c.get(ctx, "/v2/getCluster", url.Values{"cluster": {cluster}}, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// ListWorkers returns all workers of the VPC cluster with the given name or ID
+func (c *ContainersClient) ListWorkers(ctx context.Context, cluster string) ([]Worker, error) {
+	var result []Worker
+	if err := c.get(ctx, "/v2/vpc/getWorkers", url.Values{"cluster": {cluster}}, &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// GetClusterKMS returns the KMS configuration of the cluster with the given name or ID
+func (c *ContainersClient) GetClusterKMS(ctx context.Context, cluster string) (*ClusterKMS, error) {
+	var result ClusterKMS
+	if err := c.get(ctx, "/v2/getKMS", url.Values{"cluster": {cluster}}, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// ListWorkerPools returns the worker pools of the VPC cluster with the given name or ID
+func (c *ContainersClient) ListWorkerPools(ctx context.Context, cluster string) ([]WorkerPool, error) {
+	var result []WorkerPool
+	if err := c.get(ctx, "/v2/vpc/getWorkerPools", url.Values{"cluster": {cluster}}, &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// ReplaceWorker deletes a worker of a VPC cluster and provisions a replacement. If update is true the replacement runs
+// the current master version instead of the version of the deleted worker.
+func (c *ContainersClient) ReplaceWorker(ctx context.Context, cluster string, workerID string, update bool) error {
+	body := map[string]interface{}{"cluster": cluster, "workerID": workerID, "update": update}
+	return c.post(ctx, "/v2/vpc/replaceWorker", body, nil)
+}
+
+// ListIngressInstances returns the Secrets Manager instances registered with the cluster ingress
+func (c *ContainersClient) ListIngressInstances(ctx context.Context, cluster string) ([]IngressInstance, error) {
+	var result []IngressInstance
+	if err := c.get(ctx, "/ingress/v2/secret/getInstances", url.Values{"cluster": {cluster}}, &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// ListIngressSecrets returns the ingress secrets of the cluster
+func (c *ContainersClient) ListIngressSecrets(ctx context.Context, cluster string) ([]IngressSecret, error) {
+	var result []IngressSecret
+	if err := c.get(ctx, "/ingress/v2/secret/getSecrets", url.Values{"cluster": {cluster}}, &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
@@ -0,0 +1,54 @@
+package ibmcloud
+
+import (
+	"fmt"
+	"strings"
+)
+
+// CRN is a parsed IBM Cloud Resource Name
+// crn:version:cname:ctype:service-name:location:scope:service-instance:resource-type:resource
+type CRN struct {
+	Version         string
+	CName           string
+	CType           string
+	ServiceName     string
+	Location        string
+	Scope           string
+	ServiceInstance string
+	ResourceType    string
+	Resource        string
+}
+
+// ParseCRN splits a CRN string into its segments
+func ParseCRN(s string) (CRN, error) {
+	segments := strings.Split(s, ":")
+	if len(segments) != 10 || segments[0] != "crn" {
+		return CRN{}, fmt.Errorf("%q is not a valid CRN: expected 10 colon separated segments starting with \"crn\"", s)
+	}
+	if segments[1] == "" || segments[4] == "" {
+		return CRN{}, fmt.Errorf("%q is not a valid CRN: version and service name must be set", s)
+	}
+	return CRN{
+		Version:         segments[1],
+		CName:           segments[2],
+		CType:           segments[3],
+		ServiceName:     segments[4],
+		Location:        segments[5],
+		Scope:           segments[6],
+		ServiceInstance: segments[7],
+		ResourceType:    segments[8],
+		Resource:        segments[9],
+	}, nil
+}
+
+// AccountID returns the account ID from the scope segment, or an empty string if the CRN is not account scoped
+func (c CRN) AccountID() string {
+	if strings.HasPrefix(c.Scope, "a/") {
+		return strings.TrimPrefix(c.Scope, "a/")
+	}
+	return ""
+}
+
+func (c CRN) String() string {
+	return strings.Join([]string{"crn", c.Version, c.CName, c.CType, c.ServiceName, c.Location, c.Scope, c.ServiceInstance, c.ResourceType, c.Resource}, ":")
+}
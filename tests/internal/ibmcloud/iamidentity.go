@@ -0,0 +1,79 @@
+package ibmcloud
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/IBM/go-sdk-core/v5/core"
+	"github.com/IBM/platform-services-go-sdk/iamidentityv1"
+)
+
+// ContainersApikeyName is the name of the API keys that the Kubernetes Service creates for a region and resource group
+// when its API key is reset
+const ContainersApikeyName = "containers-kubernetes-key"
+
+// IamIdentityClient talks to the global IAM Identity API
+type IamIdentityClient struct {
+	service *iamidentityv1.IamIdentityV1
+}
+
+// APIKey is the subset of an IAM API key used by the tests
+type APIKey struct {
+	ID          string
+	Name        string
+	Description string
+}
+
+// NewIamIdentityClient returns a client for the global IAM Identity endpoint
+func NewIamIdentityClient(authenticator core.Authenticator) (*IamIdentityClient, error) {
+	return newIamIdentityClient(iamidentityv1.DefaultServiceURL, authenticator)
+}
+
+func newIamIdentityClient(url string, authenticator core.Authenticator) (*IamIdentityClient, error) {
+	service, err := iamidentityv1.NewIamIdentityV1(&iamidentityv1.IamIdentityV1Options{URL: url, Authenticator: authenticator})
+	if err != nil {
+		return nil, err
+	}
+	return &IamIdentityClient{service: service}, nil
+}
+
+// ListOwnAPIKeys returns the API keys with the given name that belong to the owner of apiKey
+func (c *IamIdentityClient) ListOwnAPIKeys(ctx context.Context, apiKey string, name string) ([]APIKey, error) {
+	details, _, err := c.service.GetAPIKeysDetailsWithContext(ctx, &iamidentityv1.GetAPIKeysDetailsOptions{IamAPIKey: core.StringPtr(apiKey)})
+	if err != nil {
+		return nil, fmt.Errorf("error getting the owner of the API key: %w", err)
+	}
+	options := &iamidentityv1.ListAPIKeysOptions{AccountID: details.AccountID, IamID: details.IamID, Pagesize: core.Int64Ptr(100)}
+	var keys []APIKey
+	for {
+		result, _, err := c.service.ListAPIKeysWithContext(ctx, options)
+		if err != nil {
+			return nil, err
+		}
+		for _, key := range result.Apikeys {
+			if core.StringNilMapper(key.Name) == name {
+				keys = append(keys, APIKey{ID: core.StringNilMapper(key.ID), Name: name, Description: core.StringNilMapper(key.Description)})
+			}
+		}
+		if result.Next == nil {
+			return keys, nil
+		}
+		// the next page is only linked, with its token in the pagetoken query parameter
+		if options.Pagetoken, err = core.GetQueryParam(result.Next, "pagetoken"); err != nil {
+			return nil, fmt.Errorf("error parsing the next page of API keys: %w", err)
+		}
+		if options.Pagetoken == nil {
+			return nil, fmt.Errorf("next page of API keys has no page token: %s", *result.Next)
+		}
+	}
+}
+
+// DeleteAPIKey deletes the API key with the given ID. It returns ErrNotFound when there is no such key.
+func (c *IamIdentityClient) DeleteAPIKey(ctx context.Context, id string) error {
+	response, err := c.service.DeleteAPIKeyWithContext(ctx, &iamidentityv1.DeleteAPIKeyOptions{ID: core.StringPtr(id)})
+	if response != nil && response.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("API key %s: %w", id, ErrNotFound)
+	}
+	return err
+}
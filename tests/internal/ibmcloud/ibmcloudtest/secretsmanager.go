@@ -0,0 +1,159 @@
+// Package ibmcloudtest provides in-memory httptest stand-ins for the IBM Cloud APIs used by the tests
+package ibmcloudtest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/terraform-ibm-modules/terraform-ibm-base-ocp-vpc/internal/ibmcloud"
+)
+
+// SecretsManager is an in-memory stand-in for the Secrets Manager v2 API
+type SecretsManager struct {
+	*httptest.Server
+
+	mu      sync.Mutex
+	groups  map[string]ibmcloud.SecretGroup
+	secrets map[string]ibmcloud.Secret
+	// deleteFailures holds how many more times deleting a secret fails before it succeeds
+	deleteFailures map[string]int
+	deleted        []string
+}
+
+// NewSecretsManager starts a Secrets Manager stand-in. It is closed when the test ends.
+func NewSecretsManager(t interface{ Cleanup(func()) }) *SecretsManager {
+	sm := &SecretsManager{
+		groups:         map[string]ibmcloud.SecretGroup{},
+		secrets:        map[string]ibmcloud.Secret{},
+		deleteFailures: map[string]int{},
+	}
+	sm.Server = httptest.NewServer(http.HandlerFunc(sm.serveHTTP))
+	t.Cleanup(sm.Close)
+	return sm
+}
+
+// Client returns an unauthenticated client for the stand-in. It retries like the client of a real instance, without
+// the delays.
+func (sm *SecretsManager) Client() *ibmcloud.SecretsManagerClient {
+	client := ibmcloud.NewSecretsManagerClient("", "", "", nil)
+	client.URL = sm.URL
+	client.RetryDelay = time.Millisecond
+	return client
+}
+
+// AddGroup adds a secret group
+func (sm *SecretsManager) AddGroup(group ibmcloud.SecretGroup) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	sm.groups[group.ID] = group
+}
+
+// AddSecret adds a secret
+func (sm *SecretsManager) AddSecret(secret ibmcloud.Secret) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	sm.secrets[secret.ID] = secret
+}
+
+// FailDeletes makes the next n deletes of the given secret return a server error
+func (sm *SecretsManager) FailDeletes(id string, n int) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	sm.deleteFailures[id] = n
+}
+
+// Deleted returns the IDs of the deleted secrets in deletion order
+func (sm *SecretsManager) Deleted() []string {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	return append([]string(nil), sm.deleted...)
+}
+
+func (sm *SecretsManager) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	path := strings.TrimPrefix(r.URL.Path, "/api/v2/")
+	switch {
+	case r.Method == http.MethodGet && path == "secret_groups":
+		groups := []ibmcloud.SecretGroup{}
+		for _, group := range sm.groups {
+			groups = append(groups, group)
+		}
+		sort.Slice(groups, func(i, j int) bool { return groups[i].ID < groups[j].ID })
+		writeJSON(w, http.StatusOK, map[string]interface{}{"secret_groups": groups, "total_count": len(groups)})
+
+	case r.Method == http.MethodGet && path == "secrets":
+		sm.listSecrets(w, r)
+
+	case r.Method == http.MethodGet && strings.HasPrefix(path, "secrets/") && strings.HasSuffix(path, "/metadata"):
+		secret, ok := sm.secrets[strings.TrimSuffix(strings.TrimPrefix(path, "secrets/"), "/metadata")]
+		if !ok {
+			writeJSON(w, http.StatusNotFound, map[string]string{"message": "secret not found"})
+			return
+		}
+		writeJSON(w, http.StatusOK, secret)
+
+	case r.Method == http.MethodDelete && strings.HasPrefix(path, "secrets/"):
+		id := strings.TrimPrefix(path, "secrets/")
+		if _, ok := sm.secrets[id]; !ok {
+			writeJSON(w, http.StatusNotFound, map[string]string{"message": "secret not found"})
+			return
+		}
+		if sm.deleteFailures[id] > 0 {
+			sm.deleteFailures[id]--
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"message": "internal error"})
+			return
+		}
+		delete(sm.secrets, id)
+		sm.deleted = append(sm.deleted, id)
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		writeJSON(w, http.StatusNotFound, map[string]string{"message": "not found"})
+	}
+}
+
+func (sm *SecretsManager) listSecrets(w http.ResponseWriter, r *http.Request) {
+	groups := map[string]bool{}
+	for _, group := range strings.Split(r.URL.Query().Get("groups"), ",") {
+		if group != "" {
+			groups[group] = true
+		}
+	}
+
+	secrets := []ibmcloud.Secret{}
+	for _, secret := range sm.secrets {
+		if len(groups) == 0 || groups[secret.SecretGroupID] {
+			secrets = append(secrets, secret)
+		}
+	}
+	sort.Slice(secrets, func(i, j int) bool { return secrets[i].ID < secrets[j].ID })
+	total := len(secrets)
+
+	offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit <= 0 {
+		limit = 200
+	}
+	if offset > len(secrets) {
+		offset = len(secrets)
+	}
+	secrets = secrets[offset:]
+	if limit < len(secrets) {
+		secrets = secrets[:limit]
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"secrets": secrets, "total_count": total})
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,70 @@
+package ibmcloud
+
+import (
+	"context"
+
+	"github.com/IBM/go-sdk-core/v5/core"
+	"github.com/IBM/platform-services-go-sdk/resourcecontrollerv2"
+)
+
+// COSResourceID is the catalog ID of the Cloud Object Storage service
+const COSResourceID = "dff97f5c-bc5e-4455-b470-411c3edbe49c"
+
+// ResourceControllerClient talks to the global Resource Controller v2 API
+type ResourceControllerClient struct {
+	service *resourcecontrollerv2.ResourceControllerV2
+}
+
+// ResourceInstance is the subset of a Resource Controller service instance used by the tests
+type ResourceInstance struct {
+	ID              string
+	GUID            string
+	CRN             string
+	Name            string
+	State           string
+	ResourceID      string
+	ResourceGroupID string
+}
+
+// NewResourceControllerClient returns a client for the global Resource Controller endpoint
+func NewResourceControllerClient(authenticator core.Authenticator) (*ResourceControllerClient, error) {
+	return newResourceControllerClient(resourcecontrollerv2.DefaultServiceURL, authenticator)
+}
+
+func newResourceControllerClient(url string, authenticator core.Authenticator) (*ResourceControllerClient, error) {
+	service, err := resourcecontrollerv2.NewResourceControllerV2(&resourcecontrollerv2.ResourceControllerV2Options{URL: url, Authenticator: authenticator})
+	if err != nil {
+		return nil, err
+	}
+	return &ResourceControllerClient{service: service}, nil
+}
+
+// ListResourceInstances returns the active service instances of the catalog service resourceID with the given name
+func (c *ResourceControllerClient) ListResourceInstances(ctx context.Context, resourceID string, name string) ([]ResourceInstance, error) {
+	pager, err := c.service.NewResourceInstancesPager(&resourcecontrollerv2.ListResourceInstancesOptions{
+		ResourceID: core.StringPtr(resourceID),
+		Name:       core.StringPtr(name),
+		State:      core.StringPtr("active"),
+		Limit:      core.Int64Ptr(100),
+	})
+	if err != nil {
+		return nil, err
+	}
+	all, err := pager.GetAllWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	instances := make([]ResourceInstance, 0, len(all))
+	for _, instance := range all {
+		instances = append(instances, ResourceInstance{
+			ID:              core.StringNilMapper(instance.ID),
+			GUID:            core.StringNilMapper(instance.GUID),
+			CRN:             core.StringNilMapper(instance.CRN),
+			Name:            core.StringNilMapper(instance.Name),
+			State:           core.StringNilMapper(instance.State),
+			ResourceID:      core.StringNilMapper(instance.ResourceID),
+			ResourceGroupID: core.StringNilMapper(instance.ResourceGroupID),
+		})
+	}
+	return instances, nil
+}
@@ -0,0 +1,188 @@
+package ibmcloud
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/IBM/go-sdk-core/v5/core"
+	"github.com/IBM/schematics-go-sdk/schematicsv1"
+)
+
+// workspacesPageSize is the page size used when listing Schematics workspaces
+const workspacesPageSize = 100
+
+// SchematicsLocations are the locations whose endpoints list the Schematics workspaces of all their regions
+var SchematicsLocations = []string{"us", "eu"}
+
+// SchematicsClient talks to the Schematics v1 API of one location
+type SchematicsClient struct {
+	service *schematicsv1.SchematicsV1
+	// RefreshToken returns the IAM refresh token that Schematics needs to delete a workspace
+	RefreshToken func() (string, error)
+}
+
+// Workspace is the subset of a Schematics workspace used by the tests
+type Workspace struct {
+	ID            string
+	Name          string
+	Location      string
+	ResourceGroup string
+	Tags          []string
+}
+
+// SchematicsURL returns the endpoint of a Schematics location, e.g. us or eu
+func SchematicsURL(location string) string {
+	return fmt.Sprintf("https://%s.schematics.cloud.ibm.com", location)
+}
+
+// NewSchematicsClient returns a client for the given Schematics location. Deleting a workspace requires an IAM
+// authenticator, for its refresh token.
+func NewSchematicsClient(location string, authenticator core.Authenticator) (*SchematicsClient, error) {
+	client, err := newSchematicsClient(SchematicsURL(location), authenticator)
+	if err != nil {
+		return nil, err
+	}
+	if iam, ok := authenticator.(*core.IamAuthenticator); ok {
+		client.RefreshToken = func() (string, error) {
+			token, err := iam.RequestToken()
+			if err != nil {
+				return "", err
+			}
+			return token.RefreshToken, nil
+		}
+	}
+	return client, nil
+}
+
+func newSchematicsClient(url string, authenticator core.Authenticator) (*SchematicsClient, error) {
+	service, err := schematicsv1.NewSchematicsV1(&schematicsv1.SchematicsV1Options{URL: url, Authenticator: authenticator})
+	if err != nil {
+		return nil, err
+	}
+	return &SchematicsClient{service: service}, nil
+}
+
+func newWorkspace(w *schematicsv1.WorkspaceResponse) Workspace {
+	return Workspace{
+		ID:            core.StringNilMapper(w.ID),
+		Name:          core.StringNilMapper(w.Name),
+		Location:      core.StringNilMapper(w.Location),
+		ResourceGroup: core.StringNilMapper(w.ResourceGroup),
+		Tags:          w.Tags,
+	}
+}
+
+// ListWorkspaces returns all workspaces of the location
+func (c *SchematicsClient) ListWorkspaces(ctx context.Context) ([]Workspace, error) {
+	var workspaces []Workspace
+	for offset := int64(0); ; offset += workspacesPageSize {
+		result, _, err := c.service.ListWorkspacesWithContext(ctx, &schematicsv1.ListWorkspacesOptions{
+			Offset: core.Int64Ptr(offset),
+			Limit:  core.Int64Ptr(workspacesPageSize),
+		})
+		if err != nil {
+			return nil, err
+		}
+		for i := range result.Workspaces {
+			workspaces = append(workspaces, newWorkspace(&result.Workspaces[i]))
+		}
+		if len(result.Workspaces) < workspacesPageSize || result.Count == nil || int64(len(workspaces)) >= *result.Count {
+			return workspaces, nil
+		}
+	}
+}
+
+// GetWorkspace returns the workspace with the given ID. It returns ErrNotFound when there is no such workspace.
+func (c *SchematicsClient) GetWorkspace(ctx context.Context, id string) (*Workspace, error) {
+	result, response, err := c.service.GetWorkspaceWithContext(ctx, &schematicsv1.GetWorkspaceOptions{WID: core.StringPtr(id)})
+	if response != nil && response.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("workspace %s: %w", id, ErrNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+	workspace := newWorkspace(result)
+	return &workspace, nil
+}
+
+// DeleteWorkspace deletes a workspace. With destroyResources, Schematics first runs a destroy job for the resources of
+// the workspace; the workspace is gone once that job has finished.
+func (c *SchematicsClient) DeleteWorkspace(ctx context.Context, id string, destroyResources bool) error {
+	if c.RefreshToken == nil {
+		return errors.New("deleting a workspace needs an IAM authenticator")
+	}
+	token, err := c.RefreshToken()
+	if err != nil {
+		return fmt.Errorf("error getting a refresh token: %w", err)
+	}
+	_, _, err = c.service.DeleteWorkspaceWithContext(ctx, &schematicsv1.DeleteWorkspaceOptions{
+		WID:              core.StringPtr(id),
+		RefreshToken:     core.StringPtr(token),
+		DestroyResources: core.StringPtr(strconv.FormatBool(destroyResources)),
+	})
+	return err
+}
+
+// DeleteTaggedWorkspaces deletes the workspaces with the tag, after destroying their resources, and checks every
+// interval until they are gone. It returns the number of workspaces that were deleted.
+func (c *SchematicsClient) DeleteTaggedWorkspaces(ctx context.Context, tag string, interval time.Duration) (int, error) {
+	workspaces, err := c.ListWorkspaces(ctx)
+	if err != nil {
+		return 0, err
+	}
+	deleted := 0
+	var errs []error
+	for _, workspace := range workspaces {
+		if !slices.Contains(workspace.Tags, tag) {
+			continue
+		}
+		if err := c.DeleteWorkspace(ctx, workspace.ID, true); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if err := c.waitDeleted(ctx, workspace, interval); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		deleted++
+	}
+	return deleted, errors.Join(errs...)
+}
+
+// DeleteTaggedWorkspacesInAllLocations deletes the workspaces with the tag, after destroying their resources, in each of
+// SchematicsLocations
+func DeleteTaggedWorkspacesInAllLocations(ctx context.Context, authenticator core.Authenticator, tag string, interval time.Duration) error {
+	var errs []error
+	for _, location := range SchematicsLocations {
+		client, err := NewSchematicsClient(location, authenticator)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		_, err = client.DeleteTaggedWorkspaces(ctx, tag, interval)
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// waitDeleted waits for a deleted workspace to be gone, which is once the job destroying its resources has finished
+func (c *SchematicsClient) waitDeleted(ctx context.Context, workspace Workspace, interval time.Duration) error {
+	for {
+		_, err := c.GetWorkspace(ctx, workspace.ID)
+		if errors.Is(err, ErrNotFound) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("workspace %s (%s) is still destroying its resources: %w", workspace.Name, workspace.ID, ctx.Err())
+		case <-time.After(interval):
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package ibmcloud
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/IBM/go-sdk-core/v5/core"
+)
+
+// secretsPageSize is the page size used when listing secrets
+const secretsPageSize = 200
+
+// secretsManagerRetries is how many times a request is retried after a transient failure, like the curl --retry 3 of
+// solutions/fully-configurable/scripts/delete_secrets.sh
+const secretsManagerRetries = 3
+
+// SecretsManagerClient talks to the v2 API of a single Secrets Manager instance
+type SecretsManagerClient struct {
+	Client
+}
+
+// SecretGroup is a Secrets Manager secret group
+type SecretGroup struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+}
+
+// Secret is the subset of the secret metadata used by the tests
+type Secret struct {
+	ID             string `json:"id"`
+	Name           string `json:"name"`
+	SecretType     string `json:"secret_type"`
+	SecretGroupID  string `json:"secret_group_id"`
+	ExpirationDate string `json:"expiration_date,omitempty"`
+}
+
+// SecretsManagerURL returns the instance endpoint for the given region and endpoint type (public or private)
+func SecretsManagerURL(instanceID, region, endpointType string) string {
+	host := instanceID
+	if endpointType == "private" {
+		host += ".private"
+	}
+	return fmt.Sprintf("https://%s.%s.secrets-manager.appdomain.cloud", host, region)
+}
+
+// NewSecretsManagerClient returns a client for the given Secrets Manager instance, which retries requests that failed
+// transiently
+func NewSecretsManagerClient(instanceID, region, endpointType string, authenticator core.Authenticator) *SecretsManagerClient {
+	return &SecretsManagerClient{Client{URL: SecretsManagerURL(instanceID, region, endpointType), Authenticator: authenticator, Retries: secretsManagerRetries}}
+}
+
+// ListSecretGroups returns all secret groups of the instance
+func (c *SecretsManagerClient) ListSecretGroups(ctx context.Context) ([]SecretGroup, error) {
+	var result struct {
+		SecretGroups []SecretGroup `json:"secret_groups"`
+	}
+	if err := c.get(ctx, "/api/v2/secret_groups", nil, &result); err != nil {
+		return nil, err
+	}
+	return result.SecretGroups, nil
+}
+
+// ListSecrets returns the metadata of all secrets in the given secret group
+func (c *SecretsManagerClient) ListSecrets(ctx context.Context, groupID string) ([]Secret, error) {
+	var secrets []Secret
+	for {
+		var page struct {
+			TotalCount int      `json:"total_count"`
+			Secrets    []Secret `json:"secrets"`
+		}
+		query := url.Values{
+			"groups": {groupID},
+			"limit":  {strconv.Itoa(secretsPageSize)},
+			"offset": {strconv.Itoa(len(secrets))},
+		}
+		if err := c.get(ctx, "/api/v2/secrets", query, &page); err != nil {
+			return nil, err
+		}
+		secrets = append(secrets, page.Secrets...)
+		if len(page.Secrets) == 0 || len(secrets) >= page.TotalCount {
+			return secrets, nil
+		}
+	}
+}
+
+// GetSecretMetadata returns the metadata of the secret with the given ID
+func (c *SecretsManagerClient) GetSecretMetadata(ctx context.Context, id string) (*Secret, error) {
+	var result Secret
+	if err := c.get(ctx, "/api/v2/secrets/"+url.PathEscape(id)+"/metadata", nil, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// DeleteSecret deletes the secret with the given ID
+func (c *SecretsManagerClient) DeleteSecret(ctx context.Context, id string) error {
+	return c.do(ctx, http.MethodDelete, "/api/v2/secrets/"+url.PathEscape(id), nil, nil, nil)
+}
@@ -0,0 +1,43 @@
+package ibmcloud
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// KubeVersion is an OpenShift version as used by the Kubernetes Service, e.g. "4.17" in the list of supported versions
+// or "4.17.12_openshift" for the version a master or worker runs
+type KubeVersion struct {
+	Major int
+	Minor int
+}
+
+// ParseKubeVersion parses the major and minor version, ignoring the patch level and the "_openshift" suffix
+func ParseKubeVersion(s string) (KubeVersion, error) {
+	parts := strings.Split(strings.TrimSuffix(s, "_openshift"), ".")
+	if len(parts) < 2 {
+		return KubeVersion{}, fmt.Errorf("invalid kube version %q", s)
+	}
+	major, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return KubeVersion{}, fmt.Errorf("invalid kube version %q: %w", s, err)
+	}
+	minor, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return KubeVersion{}, fmt.Errorf("invalid kube version %q: %w", s, err)
+	}
+	return KubeVersion{Major: major, Minor: minor}, nil
+}
+
+// Less reports whether v is an older minor version than other
+func (v KubeVersion) Less(other KubeVersion) bool {
+	if v.Major != other.Major {
+		return v.Major < other.Major
+	}
+	return v.Minor < other.Minor
+}
+
+func (v KubeVersion) String() string {
+	return fmt.Sprintf("%d.%d", v.Major, v.Minor)
+}
@@ -0,0 +1,100 @@
+package ibmcloud
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/IBM/go-sdk-core/v5/core"
+	"github.com/IBM/vpc-go-sdk/vpcv1"
+)
+
+// vpcAPIVersion is the dated version sent with every VPC API request
+const vpcAPIVersion = "2025-04-08"
+
+// VpcClient talks to the regional VPC infrastructure API
+type VpcClient struct {
+	service *vpcv1.VpcV1
+}
+
+// LoadBalancer is the subset of a VPC load balancer used by the tests
+type LoadBalancer struct {
+	ID                 string
+	Name               string
+	Hostname           string
+	ProvisioningStatus string
+	OperatingStatus    string
+}
+
+// NewVpcClient returns a client for the public VPC endpoint of the given region
+func NewVpcClient(region string, authenticator core.Authenticator) (*VpcClient, error) {
+	return newVpcClient(fmt.Sprintf("https://%s.iaas.cloud.ibm.com/v1", region), authenticator)
+}
+
+func newVpcClient(url string, authenticator core.Authenticator) (*VpcClient, error) {
+	service, err := vpcv1.NewVpcV1(&vpcv1.VpcV1Options{URL: url, Authenticator: authenticator, Version: core.StringPtr(vpcAPIVersion)})
+	if err != nil {
+		return nil, err
+	}
+	return &VpcClient{service: service}, nil
+}
+
+// ListLoadBalancers returns all load balancers of the region
+func (c *VpcClient) ListLoadBalancers(ctx context.Context) ([]LoadBalancer, error) {
+	options := &vpcv1.ListLoadBalancersOptions{Limit: core.Int64Ptr(100)}
+	var loadBalancers []LoadBalancer
+	for {
+		result, _, err := c.service.ListLoadBalancersWithContext(ctx, options)
+		if err != nil {
+			return nil, err
+		}
+		for _, lb := range result.LoadBalancers {
+			loadBalancers = append(loadBalancers, LoadBalancer{
+				ID:                 core.StringNilMapper(lb.ID),
+				Name:               core.StringNilMapper(lb.Name),
+				Hostname:           core.StringNilMapper(lb.Hostname),
+				ProvisioningStatus: core.StringNilMapper(lb.ProvisioningStatus),
+				OperatingStatus:    core.StringNilMapper(lb.OperatingStatus),
+			})
+		}
+		// the next page is only linked, with its token in the start query parameter
+		if options.Start, err = result.GetNextStart(); err != nil {
+			return nil, fmt.Errorf("error parsing the next page of load balancers: %w", err)
+		}
+		if options.Start == nil {
+			return loadBalancers, nil
+		}
+	}
+}
+
+// Volume is the subset of a VPC block storage volume used by the tests
+type Volume struct {
+	ID   string
+	Name string
+	// EncryptionKeyCRN is the CRN of the customer managed root key, empty for provider managed encryption
+	EncryptionKeyCRN string
+}
+
+// GetInstanceBootVolume returns the boot volume of the virtual server instance with the given name. It returns
+// ErrNotFound when there is no such instance.
+func (c *VpcClient) GetInstanceBootVolume(ctx context.Context, name string) (*Volume, error) {
+	instances, _, err := c.service.ListInstancesWithContext(ctx, &vpcv1.ListInstancesOptions{Name: core.StringPtr(name)})
+	if err != nil {
+		return nil, err
+	}
+	if len(instances.Instances) == 0 {
+		return nil, fmt.Errorf("instance %s: %w", name, ErrNotFound)
+	}
+	attachment := instances.Instances[0].BootVolumeAttachment
+	if attachment == nil || attachment.Volume == nil {
+		return nil, fmt.Errorf("instance %s has no boot volume", name)
+	}
+	volume, _, err := c.service.GetVolumeWithContext(ctx, &vpcv1.GetVolumeOptions{ID: attachment.Volume.ID})
+	if err != nil {
+		return nil, err
+	}
+	result := &Volume{ID: core.StringNilMapper(volume.ID), Name: core.StringNilMapper(volume.Name)}
+	if volume.EncryptionKey != nil {
+		result.EncryptionKeyCRN = core.StringNilMapper(volume.EncryptionKey.CRN)
+	}
+	return result, nil
+}
@@ -0,0 +1,201 @@
+// Package journal records what the tests provision in a durable JSON lines file, one record per line, so that what a
+// run left behind can be cleaned up by cmd/resume-cleanup after the runner died, e.g. when a CI runner is pre-empted.
+//
+// Every line is written and synced before the resource is provisioned, so a crash loses at most a line that was being
+// written. Such a truncated line is skipped when the journal is read.
+package journal
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Kind is the kind of an artifact, which decides how it is cleaned up
+type Kind string
+
+const (
+	// KindTerraform is a Terraform configuration applied in Dir, in the Terraform workspace Workspace
+	KindTerraform Kind = "terraform"
+	// KindSchematics is a Schematics workspace, found by its Tag as its ID is only known to the wrapper
+	KindSchematics Kind = "schematics"
+)
+
+// Event is what happened to an artifact
+type Event string
+
+const (
+	// Created is recorded before an artifact is provisioned
+	Created Event = "created"
+	// Destroyed is recorded once an artifact is destroyed
+	Destroyed Event = "destroyed"
+	// Released is recorded when an artifact is no longer tracked, because something else destroys it or it is
+	// deliberately kept
+	Released Event = "released"
+)
+
+// Artifact is something that a test provisions
+type Artifact struct {
+	// ID identifies the artifact in the journal, it is set by Journal.Create
+	ID   string `json:"id"`
+	Kind Kind   `json:"kind,omitempty"`
+	// Test is the name of the test that created the artifact
+	Test string `json:"test,omitempty"`
+	// Dir is the Terraform directory, a copy made by files.CopyTerraformFolderToTemp that holds the state
+	Dir string `json:"dir,omitempty"`
+	// Workspace is the Terraform workspace of the state
+	Workspace string `json:"workspace,omitempty"`
+	// Vars are the Terraform variables that the configuration was applied with, without secrets
+	Vars map[string]interface{} `json:"vars,omitempty"`
+	// Tag is the tag of a Schematics workspace
+	Tag    string `json:"tag,omitempty"`
+	Prefix string `json:"prefix,omitempty"`
+	Region string `json:"region,omitempty"`
+	// DependsOn are the IDs of artifacts that must be destroyed after this one
+	DependsOn []string `json:"depends_on,omitempty"`
+}
+
+// String describes the artifact well enough to find and delete it manually
+func (a Artifact) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s", a.Kind, a.ID)
+	for _, field := range []struct{ name, value string }{
+		{"test", a.Test}, {"prefix", a.Prefix}, {"region", a.Region}, {"dir", a.Dir}, {"workspace", a.Workspace}, {"tag", a.Tag},
+	} {
+		if field.value != "" {
+			fmt.Fprintf(&b, " %s=%s", field.name, field.value)
+		}
+	}
+	return b.String()
+}
+
+// Record is a line of the journal. Only Created records carry the artifact, the others only its ID.
+type Record struct {
+	Time  time.Time `json:"time"`
+	Event Event     `json:"event"`
+	Artifact
+}
+
+// Journal appends records to a file
+type Journal struct {
+	run  string
+	mu   sync.Mutex
+	file *os.File
+	seq  int
+}
+
+// Open opens the journal at path for appending, creating it if needed. run prefixes the IDs of the artifacts, so that
+// the IDs of several runs appending to the same journal do not collide.
+func Open(path string, run string) (*Journal, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	// a crash may have left a truncated line, which must not swallow the first record of this run
+	if info, err := file.Stat(); err == nil && info.Size() > 0 {
+		last := make([]byte, 1)
+		if _, err := file.ReadAt(last, info.Size()-1); err == nil && last[0] != '\n' {
+			_, err = file.Write([]byte("\n"))
+		}
+		if err != nil {
+			_ = file.Close()
+			return nil, fmt.Errorf("error repairing %s: %w", path, err)
+		}
+	}
+	return &Journal{run: run, file: file}, nil
+}
+
+// Create records an artifact before it is provisioned, and returns it with its ID
+func (j *Journal) Create(artifact Artifact) (Artifact, error) {
+	j.mu.Lock()
+	j.seq++
+	artifact.ID = fmt.Sprintf("%s/%d", j.run, j.seq)
+	j.mu.Unlock()
+	return artifact, j.append(Record{Event: Created, Artifact: artifact})
+}
+
+// Destroy records that the artifact is destroyed
+func (j *Journal) Destroy(id string) error {
+	return j.append(Record{Event: Destroyed, Artifact: Artifact{ID: id}})
+}
+
+// Release records that the artifact is no longer tracked
+func (j *Journal) Release(id string) error {
+	return j.append(Record{Event: Released, Artifact: Artifact{ID: id}})
+}
+
+func (j *Journal) append(record Record) error {
+	if record.Time.IsZero() {
+		record.Time = time.Now().UTC()
+	}
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if _, err := j.file.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	return j.file.Sync()
+}
+
+// Close closes the file of the journal
+func (j *Journal) Close() error {
+	return j.file.Close()
+}
+
+// Read returns the records of a journal. Lines that are not records, such as a line truncated by a crash, are skipped
+// and counted in malformed.
+func Read(r io.Reader) (records []Record, malformed int, err error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1024*1024)
+	for scanner.Scan() {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		var record Record
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil || record.ID == "" || record.Event == "" {
+			malformed++
+			continue
+		}
+		records = append(records, record)
+	}
+	return records, malformed, scanner.Err()
+}
+
+// ReadFile returns the records of the journal at path. A missing journal has no records.
+func ReadFile(path string) ([]Record, int, error) {
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, 0, nil
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	defer file.Close()
+	return Read(file)
+}
+
+// Remaining returns the artifacts that were created and neither destroyed nor released, in the order they were created
+func Remaining(records []Record) []Artifact {
+	ended := map[string]bool{}
+	for _, record := range records {
+		if record.Event == Destroyed || record.Event == Released {
+			ended[record.ID] = true
+		}
+	}
+	var remaining []Artifact
+	for _, record := range records {
+		if record.Event == Created && !ended[record.ID] {
+			remaining = append(remaining, record.Artifact)
+		}
+	}
+	return remaining
+}
@@ -0,0 +1,85 @@
+package journal
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJournal(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "run-journal.jsonl")
+	j, err := Open(path, "run1")
+	require.NoError(t, err)
+
+	existing, err := j.Create(Artifact{Kind: KindTerraform, Test: "TestRunFullyConfigurableInSchematics", Dir: "/tmp/ocp-existing-abc", Workspace: "ocp-existing-abc", Prefix: "ocp-existing-abc", Region: "us-south", Vars: map[string]interface{}{"prefix": "ocp-existing-abc"}})
+	require.NoError(t, err)
+	assert.Equal(t, "run1/1", existing.ID)
+	workspace, err := j.Create(Artifact{Kind: KindSchematics, Test: "TestRunFullyConfigurableInSchematics", Tag: "test-cleanup:xyz", DependsOn: []string{existing.ID}})
+	require.NoError(t, err)
+	upgrade, err := j.Create(Artifact{Kind: KindTerraform, Test: "TestRunOCPVersionUpgrade", Dir: "/tmp/ocp-upg-def/examples/custom_sg"})
+	require.NoError(t, err)
+	require.NoError(t, j.Release(workspace.ID))
+	require.NoError(t, j.Destroy(upgrade.ID))
+	require.NoError(t, j.Close())
+
+	records, malformed, err := ReadFile(path)
+	require.NoError(t, err)
+	assert.Zero(t, malformed)
+	require.Len(t, records, 5)
+	assert.Equal(t, Released, records[3].Event)
+	assert.Equal(t, []Artifact{existing}, Remaining(records))
+}
+
+func TestPartialJournal(t *testing.T) {
+	// the runner died while writing the destroyed record of the cluster, and before the VPC was destroyed
+	journal := strings.Join([]string{
+		`{"time":"2026-10-19T10:00:00Z","event":"created","id":"run1/1","kind":"terraform","dir":"/tmp/vpc"}`,
+		`{"time":"2026-10-19T10:05:00Z","event":"created","id":"run1/2","kind":"terraform","dir":"/tmp/cluster","depends_on":["run1/1"]}`,
+		`{"time":"2026-10-19T10:06:00Z","event":"created","id":"run1/3","kind":"schematics","tag":"test-cleanup:abc"}`,
+		`{"time":"2026-10-19T11:00:00Z","event":"released","id":"run1/3"}`,
+		`{"time":"2026-10-19T12:00:00Z","event":"destr`,
+	}, "\n")
+
+	records, malformed, err := Read(strings.NewReader(journal))
+	require.NoError(t, err)
+	assert.Equal(t, 1, malformed)
+	remaining := Remaining(records)
+	require.Len(t, remaining, 2)
+	assert.Equal(t, "run1/1", remaining[0].ID)
+	assert.Equal(t, Artifact{ID: "run1/2", Kind: KindTerraform, Dir: "/tmp/cluster", DependsOn: []string{"run1/1"}}, remaining[1])
+}
+
+func TestOpenAfterCrash(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "run-journal.jsonl")
+	require.NoError(t, os.WriteFile(path, []byte(`{"time":"2026-10-19T10:00:00Z","event":"created","id":"run1/1","kind":"terraform","dir":"/tmp/vpc"}`+"\n"+`{"event":"cre`), 0o644))
+
+	j, err := Open(path, "run2")
+	require.NoError(t, err)
+	cluster, err := j.Create(Artifact{Kind: KindTerraform, Dir: "/tmp/cluster"})
+	require.NoError(t, err)
+	require.NoError(t, j.Close())
+
+	records, malformed, err := ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, 1, malformed, "the truncated line does not swallow the next record")
+	remaining := Remaining(records)
+	require.Len(t, remaining, 2)
+	assert.Equal(t, "run1/1", remaining[0].ID)
+	assert.Equal(t, cluster, remaining[1])
+}
+
+func TestReadFileMissing(t *testing.T) {
+	records, malformed, err := ReadFile(filepath.Join(t.TempDir(), "missing.jsonl"))
+	require.NoError(t, err)
+	assert.Zero(t, malformed)
+	assert.Empty(t, records)
+}
+
+func TestArtifactString(t *testing.T) {
+	artifact := Artifact{ID: "run1/1", Kind: KindTerraform, Prefix: "ocp-existing-abc", Region: "us-south", Dir: "/tmp/ocp-existing-abc", Workspace: "ocp-existing-abc"}
+	assert.Equal(t, "terraform run1/1 prefix=ocp-existing-abc region=us-south dir=/tmp/ocp-existing-abc workspace=ocp-existing-abc", artifact.String())
+}
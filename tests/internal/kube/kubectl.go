@@ -0,0 +1,62 @@
+// Package kube runs kubectl against the clusters under test. The checks only depend on the Runner interface so that
+// they can be unit tested offline with scripted kubectl output.
+package kube
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// binariesPath is where scripts/install-binaries.sh places kubectl when it is not on the PATH
+const binariesPath = "/tmp"
+
+// Runner runs a kubectl command and returns its stdout
+type Runner interface {
+	Kubectl(ctx context.Context, stdin string, args ...string) (string, error)
+}
+
+// Kubectl runs the kubectl binary against the cluster of a kubeconfig file
+type Kubectl struct {
+	Binary     string
+	Kubeconfig string
+}
+
+// NewKubectl returns a Kubectl using kubectl from the PATH, falling back to the binaries path used by the modules
+func NewKubectl(kubeconfig string) *Kubectl {
+	binary, err := exec.LookPath("kubectl")
+	if err != nil {
+		binary = binariesPath + "/kubectl"
+	}
+	return &Kubectl{Binary: binary, Kubeconfig: kubeconfig}
+}
+
+// Kubectl runs kubectl with the given arguments, feeding stdin to the command if it is not empty
+func (k *Kubectl) Kubectl(ctx context.Context, stdin string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, k.Binary, append([]string{"--kubeconfig", k.Kubeconfig}, args...)...)
+	if stdin != "" {
+		cmd.Stdin = strings.NewReader(stdin)
+	}
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return stdout.String(), fmt.Errorf("kubectl %s: %w: %s", strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
+
+// GetJSON runs kubectl get with JSON output and decodes the result into out
+func GetJSON(ctx context.Context, runner Runner, out interface{}, args ...string) error {
+	stdout, err := runner.Kubectl(ctx, "", append(append([]string{"get"}, args...), "--output", "json")...)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal([]byte(stdout), out); err != nil {
+		return fmt.Errorf("error decoding kubectl get %s: %w", strings.Join(args, " "), err)
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+// Package kubetest provides a scripted kube.Runner for offline tests
+package kubetest
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Handler produces the result of a kubectl call
+type Handler func(args []string, stdin string) (string, error)
+
+// Runner is a scripted kube.Runner. Each call is answered by the handler with the longest prefix matching the space
+// joined kubectl arguments, and recorded in Calls.
+type Runner struct {
+	mu       sync.Mutex
+	handlers map[string]Handler
+	calls    []string
+}
+
+// NewRunner returns a Runner without any handlers
+func NewRunner() *Runner {
+	return &Runner{handlers: map[string]Handler{}}
+}
+
+// On registers a handler for calls starting with prefix
+func (r *Runner) On(prefix string, handler Handler) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.handlers[prefix] = handler
+}
+
+// OnOutput registers a fixed stdout for calls starting with prefix
+func (r *Runner) OnOutput(prefix string, stdout string) {
+	r.On(prefix, func([]string, string) (string, error) { return stdout, nil })
+}
+
+// Calls returns the space joined arguments of all calls so far
+func (r *Runner) Calls() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.calls...)
+}
+
+// Kubectl implements kube.Runner
+func (r *Runner) Kubectl(_ context.Context, stdin string, args ...string) (string, error) {
+	command := strings.Join(args, " ")
+
+	r.mu.Lock()
+	r.calls = append(r.calls, command)
+	var handler Handler
+	longest := -1
+	for prefix, h := range r.handlers {
+		if strings.HasPrefix(command, prefix) && len(prefix) > longest {
+			handler, longest = h, len(prefix)
+		}
+	}
+	r.mu.Unlock()
+
+	if handler == nil {
+		return "", fmt.Errorf("unexpected kubectl call: %s", command)
+	}
+	return handler(args, stdin)
+}
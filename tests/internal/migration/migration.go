@@ -0,0 +1,134 @@
+// Package migration checks that the current code can take over the state of an older release of the module without
+// destroying its long-lived resources, and suggests the moved blocks that are missing when it cannot.
+package migration
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	tfjson "github.com/hashicorp/terraform-json"
+
+	"github.com/terraform-ibm-modules/terraform-ibm-base-ocp-vpc/internal/tfstatic"
+)
+
+// protectedTypes are the resource types that must survive a module upgrade. Destroying any of them deletes the cluster
+// or its workers.
+var protectedTypes = []string{"ibm_container_vpc_cluster", "ibm_container_vpc_worker_pool"}
+
+// Replacement is a protected resource that a plan destroys
+type Replacement struct {
+	Address string
+	Actions tfjson.Actions
+	// Moved is the moved block that keeps the resource, or nil if the plan creates no resource it could move to
+	Moved *tfstatic.Moved
+	// ReplacePaths are the attributes that force an in-place replacement, which a moved block cannot prevent
+	ReplacePaths []interface{}
+}
+
+// ReplacementError lists the protected resources that a plan destroys
+type ReplacementError struct {
+	Replacements []Replacement
+}
+
+func (e *ReplacementError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "plan destroys %d resources that must survive a module upgrade:", len(e.Replacements))
+	for _, replacement := range e.Replacements {
+		fmt.Fprintf(&b, "\n  - %s (%s)", replacement.Address, strings.Join(actionNames(replacement.Actions), ", "))
+		switch {
+		case replacement.Moved != nil:
+			fmt.Fprintf(&b, ": missing moved block\n      moved {\n        from = %s\n        to   = %s\n      }", replacement.Moved.From, replacement.Moved.To)
+		case len(replacement.ReplacePaths) > 0:
+			fmt.Fprintf(&b, ": replaced because of changes to %v", replacement.ReplacePaths)
+		default:
+			b.WriteString(": no resource of the same type is created, so it is not a missing moved block")
+		}
+	}
+	return b.String()
+}
+
+// protected reports whether a resource change is for a resource that must survive a module upgrade. COS instances are
+// protected as well since deleting them loses the internal registry storage of the cluster.
+func protected(change *tfjson.ResourceChange) bool {
+	if slices.Contains(protectedTypes, change.Type) {
+		return true
+	}
+	return change.Type == "ibm_resource_instance" && strings.Contains(change.Name, "cos")
+}
+
+// Check returns a ReplacementError if the plan destroys or replaces a protected resource. modulePrefix is the address
+// of the module under test in the plan, e.g. "module.ocp_base.", and is removed from the suggested moved blocks so that
+// they can be added to the module as they are.
+func Check(plan *terraform.PlanStruct, modulePrefix string) error {
+	var created []*tfjson.ResourceChange
+	for _, change := range plan.RawPlan.ResourceChanges {
+		if change.Change != nil && change.Change.Actions.Create() {
+			created = append(created, change)
+		}
+	}
+
+	var replacements []Replacement
+	for _, change := range plan.RawPlan.ResourceChanges {
+		if change.Change == nil || !protected(change) {
+			continue
+		}
+		actions := change.Change.Actions
+		if !actions.Delete() && !actions.Replace() {
+			continue
+		}
+		replacement := Replacement{Address: change.Address, Actions: actions, ReplacePaths: change.Change.ReplacePaths}
+		if actions.Delete() {
+			if target := moveTarget(change, created); target != nil {
+				replacement.Moved = &tfstatic.Moved{
+					From: strings.TrimPrefix(change.Address, modulePrefix),
+					To:   strings.TrimPrefix(target.Address, modulePrefix),
+				}
+			}
+		}
+		replacements = append(replacements, replacement)
+	}
+
+	if len(replacements) > 0 {
+		return &ReplacementError{Replacements: replacements}
+	}
+	return nil
+}
+
+// moveTarget picks the created resource that a destroyed resource has most likely been moved to: one of the same type,
+// preferring the same name and then the same instance key, as long as the best match is unambiguous
+func moveTarget(destroyed *tfjson.ResourceChange, created []*tfjson.ResourceChange) *tfjson.ResourceChange {
+	var best *tfjson.ResourceChange
+	bestScore, ties := 0, 0
+	for _, candidate := range created {
+		if candidate.Type != destroyed.Type {
+			continue
+		}
+		score := 1
+		if candidate.Name == destroyed.Name {
+			score += 2
+		}
+		if fmt.Sprint(candidate.Index) == fmt.Sprint(destroyed.Index) {
+			score++
+		}
+		switch {
+		case score > bestScore:
+			best, bestScore, ties = candidate, score, 0
+		case score == bestScore:
+			ties++
+		}
+	}
+	if ties > 0 {
+		return nil
+	}
+	return best
+}
+
+func actionNames(actions tfjson.Actions) []string {
+	names := make([]string, len(actions))
+	for i, action := range actions {
+		names[i] = string(action)
+	}
+	return names
+}
@@ -0,0 +1,66 @@
+package migration
+
+import (
+	"os"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/terraform-ibm-modules/terraform-ibm-base-ocp-vpc/internal/tfstatic"
+)
+
+func loadPlan(t *testing.T) *terraform.PlanStruct {
+	data, err := os.ReadFile("testdata/plan.json")
+	require.NoError(t, err)
+	plan, err := terraform.ParsePlanJSON(string(data))
+	require.NoError(t, err)
+	return plan
+}
+
+func TestCheck(t *testing.T) {
+	err := Check(loadPlan(t), "module.ocp_base.")
+
+	var replacementErr *ReplacementError
+	require.ErrorAs(t, err, &replacementErr)
+	require.Len(t, replacementErr.Replacements, 2)
+
+	pool := replacementErr.Replacements[0]
+	assert.Equal(t, `module.ocp_base.ibm_container_vpc_worker_pool.pool["custom-sg"]`, pool.Address)
+	assert.Equal(t, &tfstatic.Moved{From: `ibm_container_vpc_worker_pool.pool["custom-sg"]`, To: `module.worker_pools.ibm_container_vpc_worker_pool.pool["custom-sg"]`}, pool.Moved)
+
+	cos := replacementErr.Replacements[1]
+	assert.Equal(t, tfjson.Actions{tfjson.ActionDelete, tfjson.ActionCreate}, cos.Actions)
+	assert.Nil(t, cos.Moved)
+
+	assert.Contains(t, err.Error(), "plan destroys 2 resources that must survive a module upgrade")
+	assert.Contains(t, err.Error(), "missing moved block\n      moved {\n        from = ibm_container_vpc_worker_pool.pool[\"custom-sg\"]\n        to   = module.worker_pools.ibm_container_vpc_worker_pool.pool[\"custom-sg\"]\n      }")
+	assert.Contains(t, err.Error(), "module.ocp_base.module.cos_instance[0].ibm_resource_instance.cos_instance[0] (delete, create): replaced because of changes to [[resource_group_id]]")
+}
+
+func TestCheckNoReplacements(t *testing.T) {
+	plan := loadPlan(t)
+	var changes []*tfjson.ResourceChange
+	for _, change := range plan.RawPlan.ResourceChanges {
+		if change.Type != "ibm_container_vpc_worker_pool" && change.Type != "ibm_resource_instance" {
+			changes = append(changes, change)
+		}
+	}
+	plan.RawPlan.ResourceChanges = changes
+
+	assert.NoError(t, Check(plan, "module.ocp_base."))
+}
+
+func TestMoveTargetAmbiguous(t *testing.T) {
+	destroyed := &tfjson.ResourceChange{Type: "ibm_container_vpc_worker_pool", Name: "pool", Index: "a"}
+	created := []*tfjson.ResourceChange{
+		{Address: "x", Type: "ibm_container_vpc_worker_pool", Name: "autoscaling_pool", Index: "b"},
+		{Address: "y", Type: "ibm_container_vpc_worker_pool", Name: "autoscaling_pool", Index: "c"},
+	}
+	assert.Nil(t, moveTarget(destroyed, created))
+
+	created = append(created, &tfjson.ResourceChange{Address: "z", Type: "ibm_container_vpc_worker_pool", Name: "pool", Index: "a"})
+	assert.Equal(t, "z", moveTarget(destroyed, created).Address)
+}
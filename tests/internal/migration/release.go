@@ -0,0 +1,36 @@
+package migration
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// CheckoutRelease checks out a release tag of the git repository at repoDir into a new worktree at dir, fetching the
+// tag from origin first if the clone does not have it. The returned function removes the worktree again.
+func CheckoutRelease(repoDir string, tag string, dir string) (func() error, error) {
+	if _, err := git(repoDir, "rev-parse", "--verify", "--quiet", "refs/tags/"+tag); err != nil {
+		if _, err := git(repoDir, "fetch", "--depth", "1", "origin", "refs/tags/"+tag+":refs/tags/"+tag); err != nil {
+			return nil, fmt.Errorf("release %s not found: %w", tag, err)
+		}
+	}
+	if _, err := git(repoDir, "worktree", "add", "--detach", dir, "refs/tags/"+tag); err != nil {
+		return nil, err
+	}
+	return func() error {
+		_, err := git(repoDir, "worktree", "remove", "--force", dir)
+		return err
+	}, nil
+}
+
+func git(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("git %s: %w: %s", strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
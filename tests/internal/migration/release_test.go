@@ -0,0 +1,40 @@
+package migration
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckoutRelease(t *testing.T) {
+	repo := t.TempDir()
+	for _, args := range [][]string{
+		{"init", "--quiet"},
+		{"-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "--quiet", "--allow-empty", "-m", "v1"},
+		{"tag", "v1.0.0"},
+		{"-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "--quiet", "--allow-empty", "-m", "v2"},
+	} {
+		_, err := git(repo, args...)
+		require.NoError(t, err)
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(repo, "main.tf"), []byte("# current\n"), 0o644))
+
+	dir := filepath.Join(t.TempDir(), "release")
+	remove, err := CheckoutRelease(repo, "v1.0.0", dir)
+	require.NoError(t, err)
+
+	head, err := git(dir, "describe", "--tags")
+	require.NoError(t, err)
+	assert.Equal(t, "v1.0.0\n", head)
+	assert.NoFileExists(t, filepath.Join(dir, "main.tf"))
+
+	require.NoError(t, remove())
+	assert.NoDirExists(t, dir)
+
+	// the repository has no origin to fetch the tag from
+	_, err = CheckoutRelease(repo, "v0.1.0", dir)
+	assert.ErrorContains(t, err, "release v0.1.0 not found")
+}
@@ -0,0 +1,232 @@
+// Package mustgather collects the state of the clusters under test into a tarball when their post-apply checks fail,
+// so that the failure can be diagnosed after the clusters are destroyed. Collection is best effort: what could not be
+// collected is listed in the errors.txt file of each cluster instead of failing the collection.
+package mustgather
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/terraform-ibm-modules/terraform-ibm-base-ocp-vpc/internal/ibmcloud"
+	"github.com/terraform-ibm-modules/terraform-ibm-base-ocp-vpc/internal/kube"
+)
+
+// DefaultTailLines is how many of the last log lines of each container are collected
+const DefaultTailLines = 500
+
+// LogNamespaces are the namespaces whose pod logs are collected: the router pods, and the pods of the Calico and OVN
+// network plugins, only one of which is deployed depending on the OCP version
+var LogNamespaces = []string{"openshift-ingress", "calico-system", "openshift-ovn-kubernetes"}
+
+// ClusterAPI is the subset of the Kubernetes Service API used to collect the cluster and worker state
+type ClusterAPI interface {
+	GetCluster(ctx context.Context, cluster string) (*ibmcloud.Cluster, error)
+	ListWorkers(ctx context.Context, cluster string) ([]ibmcloud.Worker, error)
+}
+
+// Cluster is a cluster to collect
+type Cluster struct {
+	// Name is the name or ID of the cluster, and the directory of its files in the tarball
+	Name string
+	// Kubectl runs kubectl against the cluster. It is nil when no kubeconfig could be downloaded, in which case only the
+	// state reported by the Kubernetes Service is collected.
+	Kubectl kube.Runner
+}
+
+// Gatherer collects clusters into a tarball
+type Gatherer struct {
+	Clusters ClusterAPI
+	// Namespaces are the namespaces whose pod logs are collected
+	Namespaces []string
+	// TailLines is how many of the last log lines of each container are collected
+	TailLines int
+}
+
+// nodeList and podList are the subsets of the Kubernetes lists used to pick what to collect
+type nodeList struct {
+	Items []struct {
+		Metadata struct {
+			Name string `json:"name"`
+		} `json:"metadata"`
+		Status struct {
+			Conditions []struct {
+				Type    string `json:"type"`
+				Status  string `json:"status"`
+				Reason  string `json:"reason"`
+				Message string `json:"message"`
+			} `json:"conditions"`
+		} `json:"status"`
+	} `json:"items"`
+}
+
+type podList struct {
+	Items []struct {
+		Metadata struct {
+			Name string `json:"name"`
+		} `json:"metadata"`
+		Status struct {
+			ContainerStatuses []struct {
+				Name         string `json:"name"`
+				RestartCount int    `json:"restartCount"`
+			} `json:"containerStatuses"`
+		} `json:"status"`
+	} `json:"items"`
+}
+
+// archive writes the files of a cluster into the tarball, and records what could not be collected
+type archive struct {
+	tw   *tar.Writer
+	dir  string
+	errs []string
+	// err is the first error writing the tarball, which ends the collection
+	err error
+}
+
+func (a *archive) add(name string, data []byte) {
+	if a.err != nil {
+		return
+	}
+	header := &tar.Header{
+		Name:    path.Join(a.dir, name),
+		Mode:    0o644,
+		Size:    int64(len(data)),
+		ModTime: time.Now(),
+	}
+	if a.err = a.tw.WriteHeader(header); a.err == nil {
+		_, a.err = a.tw.Write(data)
+	}
+}
+
+func (a *archive) addJSON(name string, v interface{}) {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		a.fail(name, err)
+		return
+	}
+	a.add(name, append(data, '\n'))
+}
+
+// addOutput adds the output of a kubectl command
+func (a *archive) addOutput(ctx context.Context, kubectl kube.Runner, name string, args []string) {
+	out, err := kubectl.Kubectl(ctx, "", args...)
+	if err != nil {
+		a.fail(name, err)
+		return
+	}
+	a.add(name, []byte(out))
+}
+
+func (a *archive) fail(what string, err error) {
+	a.errs = append(a.errs, fmt.Sprintf("%s: %v", what, err))
+}
+
+// Write collects the clusters into a gzipped tarball at file, creating its directory if needed
+func (g *Gatherer) Write(ctx context.Context, file string, clusters ...Cluster) (err error) {
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		return err
+	}
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err = errors.Join(err, f.Close())
+	}()
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+	for _, cluster := range clusters {
+		if err := g.collect(ctx, tw, cluster); err != nil {
+			return fmt.Errorf("error writing %s: %w", file, err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// collect writes the files of a cluster into its directory of the tarball
+func (g *Gatherer) collect(ctx context.Context, tw *tar.Writer, cluster Cluster) error {
+	a := &archive{tw: tw, dir: cluster.Name}
+
+	if details, err := g.Clusters.GetCluster(ctx, cluster.Name); err != nil {
+		a.fail("cluster.json", err)
+	} else {
+		a.addJSON("cluster.json", details)
+	}
+	if workers, err := g.Clusters.ListWorkers(ctx, cluster.Name); err != nil {
+		a.fail("workers.json", err)
+	} else {
+		a.addJSON("workers.json", workers)
+	}
+
+	if cluster.Kubectl == nil {
+		a.fail("kubectl", errors.New("no kubeconfig for the cluster, only the Kubernetes Service state was collected"))
+	} else {
+		g.collectKube(ctx, a, cluster.Kubectl)
+	}
+
+	if len(a.errs) > 0 {
+		a.add("errors.txt", []byte(strings.Join(a.errs, "\n")+"\n"))
+	}
+	return a.err
+}
+
+func (g *Gatherer) collectKube(ctx context.Context, a *archive, kubectl kube.Runner) {
+	var nodes nodeList
+	if err := kube.GetJSON(ctx, kubectl, &nodes, "nodes"); err != nil {
+		a.fail("node-conditions.txt", err)
+	} else {
+		var b strings.Builder
+		for _, node := range nodes.Items {
+			for _, c := range node.Status.Conditions {
+				fmt.Fprintf(&b, "%s\t%s=%s\t%s\t%s\n", node.Metadata.Name, c.Type, c.Status, c.Reason, c.Message)
+			}
+		}
+		a.add("node-conditions.txt", []byte(b.String()))
+	}
+
+	for _, file := range []struct {
+		name string
+		args []string
+	}{
+		{"nodes.txt", []string{"get", "nodes", "--output", "wide"}},
+		{"pods.txt", []string{"get", "pods", "--all-namespaces", "--output", "wide"}},
+		{"events.txt", []string{"get", "events", "--all-namespaces", "--sort-by", ".lastTimestamp"}},
+	} {
+		a.addOutput(ctx, kubectl, file.name, file.args)
+	}
+
+	tail := g.TailLines
+	if tail <= 0 {
+		tail = DefaultTailLines
+	}
+	for _, namespace := range g.Namespaces {
+		var pods podList
+		if err := kube.GetJSON(ctx, kubectl, &pods, "pods", "--namespace", namespace); err != nil {
+			a.fail("logs/"+namespace, err)
+			continue
+		}
+		for _, pod := range pods.Items {
+			for _, container := range pod.Status.ContainerStatuses {
+				name := path.Join("logs", namespace, pod.Metadata.Name+"_"+container.Name)
+				args := []string{"logs", pod.Metadata.Name, "--namespace", namespace, "--container", container.Name, "--tail", strconv.Itoa(tail)}
+				a.addOutput(ctx, kubectl, name+".log", args)
+				// the logs of the crashed container usually explain the restarts
+				if container.RestartCount > 0 {
+					a.addOutput(ctx, kubectl, name+".previous.log", append(args, "--previous"))
+				}
+			}
+		}
+	}
+}
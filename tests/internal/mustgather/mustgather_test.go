@@ -0,0 +1,129 @@
+package mustgather
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/terraform-ibm-modules/terraform-ibm-base-ocp-vpc/internal/ibmcloud"
+	"github.com/terraform-ibm-modules/terraform-ibm-base-ocp-vpc/internal/kube/kubetest"
+)
+
+type fakeClusterAPI struct {
+	cluster    *ibmcloud.Cluster
+	workers    []ibmcloud.Worker
+	workersErr error
+}
+
+func (f *fakeClusterAPI) GetCluster(_ context.Context, _ string) (*ibmcloud.Cluster, error) {
+	return f.cluster, nil
+}
+
+func (f *fakeClusterAPI) ListWorkers(_ context.Context, _ string) ([]ibmcloud.Worker, error) {
+	return f.workers, f.workersErr
+}
+
+const nodesJSON = `{"items": [{"metadata": {"name": "10.0.0.1"}, "status": {"conditions": [
+	{"type": "MemoryPressure", "status": "False", "reason": "KubeletHasSufficientMemory", "message": "kubelet has sufficient memory available"},
+	{"type": "Ready", "status": "False", "reason": "KubeletNotReady", "message": "container runtime network not ready"}
+]}}]}`
+
+const routerPodsJSON = `{"items": [{"metadata": {"name": "router-default-abc"}, "status": {"containerStatuses": [
+	{"name": "router", "restartCount": 3}
+]}}]}`
+
+// readTarball returns the files of a tarball by their path
+func readTarball(t *testing.T, file string) map[string]string {
+	f, err := os.Open(file)
+	require.NoError(t, err)
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	require.NoError(t, err)
+	files := map[string]string{}
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return files
+		}
+		require.NoError(t, err)
+		data, err := io.ReadAll(tr)
+		require.NoError(t, err)
+		files[header.Name] = string(data)
+	}
+}
+
+func TestWrite(t *testing.T) {
+	runner := kubetest.NewRunner()
+	runner.OnOutput("get nodes --output json", nodesJSON)
+	runner.OnOutput("get nodes --output wide", "NAME       STATUS     ROLES\n10.0.0.1   NotReady   master,worker\n")
+	runner.OnOutput("get pods --all-namespaces", "NAMESPACE           NAME                 READY   STATUS\nopenshift-ingress   router-default-abc   0/1     CrashLoopBackOff\n")
+	runner.OnOutput("get events --all-namespaces", "LAST SEEN   TYPE      REASON      OBJECT\n1m          Warning   BackOff     pod/router-default-abc\n")
+	runner.OnOutput("get pods --namespace openshift-ingress", routerPodsJSON)
+	runner.OnOutput("get pods --namespace calico-system", `{"items": []}`)
+	runner.OnOutput("logs router-default-abc --namespace openshift-ingress --container router --tail 100 --previous", "panic: cannot bind port\n")
+	runner.OnOutput("logs router-default-abc --namespace openshift-ingress --container router --tail 100", "waiting for the certificate\n")
+
+	gatherer := &Gatherer{
+		Clusters: &fakeClusterAPI{
+			cluster:    &ibmcloud.Cluster{ID: "c1", Name: "ocp-cluster", State: "warning"},
+			workersErr: errors.New("service unavailable"),
+		},
+		Namespaces: []string{"openshift-ingress", "calico-system", "openshift-ovn-kubernetes"},
+		TailLines:  100,
+	}
+	file := filepath.Join(t.TempDir(), "must-gather", "TestRunBasicExample.tar.gz")
+	require.NoError(t, gatherer.Write(context.Background(), file, Cluster{Name: "ocp-cluster", Kubectl: runner}))
+
+	files := readTarball(t, file)
+	assert.ElementsMatch(t, []string{
+		"ocp-cluster/cluster.json",
+		"ocp-cluster/node-conditions.txt",
+		"ocp-cluster/nodes.txt",
+		"ocp-cluster/pods.txt",
+		"ocp-cluster/events.txt",
+		"ocp-cluster/logs/openshift-ingress/router-default-abc_router.log",
+		"ocp-cluster/logs/openshift-ingress/router-default-abc_router.previous.log",
+		"ocp-cluster/errors.txt",
+	}, slices.Collect(maps.Keys(files)))
+	assert.Contains(t, files["ocp-cluster/cluster.json"], `"state": "warning"`)
+	assert.Equal(t, "10.0.0.1\tMemoryPressure=False\tKubeletHasSufficientMemory\tkubelet has sufficient memory available\n"+
+		"10.0.0.1\tReady=False\tKubeletNotReady\tcontainer runtime network not ready\n", files["ocp-cluster/node-conditions.txt"])
+	assert.Contains(t, files["ocp-cluster/events.txt"], "BackOff")
+	assert.Equal(t, "panic: cannot bind port\n", files["ocp-cluster/logs/openshift-ingress/router-default-abc_router.previous.log"])
+	assert.Equal(t, "waiting for the certificate\n", files["ocp-cluster/logs/openshift-ingress/router-default-abc_router.log"])
+
+	// what could not be collected is listed instead of failing the collection
+	assert.Equal(t, "workers.json: service unavailable\n"+
+		"logs/openshift-ovn-kubernetes: unexpected kubectl call: get pods --namespace openshift-ovn-kubernetes --output json\n",
+		files["ocp-cluster/errors.txt"])
+}
+
+func TestWriteWithoutKubeconfig(t *testing.T) {
+	gatherer := &Gatherer{
+		Clusters: &fakeClusterAPI{
+			cluster: &ibmcloud.Cluster{ID: "c1", Name: "cluster-1"},
+			workers: []ibmcloud.Worker{{ID: "w1", Health: ibmcloud.WorkerHealth{State: "critical", Message: "worker is not ready"}}},
+		},
+		Namespaces: LogNamespaces,
+	}
+	file := filepath.Join(t.TempDir(), "multi.tar.gz")
+	require.NoError(t, gatherer.Write(context.Background(), file, Cluster{Name: "cluster-1"}, Cluster{Name: "cluster-2"}))
+
+	files := readTarball(t, file)
+	assert.ElementsMatch(t, []string{
+		"cluster-1/cluster.json", "cluster-1/workers.json", "cluster-1/errors.txt",
+		"cluster-2/cluster.json", "cluster-2/workers.json", "cluster-2/errors.txt",
+	}, slices.Collect(maps.Keys(files)))
+	assert.Contains(t, files["cluster-1/workers.json"], `"message": "worker is not ready"`)
+	assert.Equal(t, "kubectl: no kubeconfig for the cluster, only the Kubernetes Service state was collected\n", files["cluster-2/errors.txt"])
+}
@@ -0,0 +1,158 @@
+// Package permanent loads the permanent resources of the test account from common-permanent-resources.yaml of
+// common-dev-assets. Only the keys used by the tests are loaded, and all of them are required and validated, so that a
+// renamed or malformed entry fails the run at startup instead of passing an empty value to Terraform.
+package permanent
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/terraform-ibm-modules/terraform-ibm-base-ocp-vpc/internal/ibmcloud"
+)
+
+// Resources are the permanent resources used by the tests. The validate tag of a field selects its format:
+//
+//	crn=<service>      CRN of an instance of the service
+//	keycrn=<service>   CRN of a key of an instance of the service
+//	guid               instance GUID or key ID
+//	account            account ID
+//	tags               list of access tags
+type Resources struct {
+	AccessTags           []string `yaml:"accessTags" validate:"tags"`
+	GeOpsAccountID       string   `yaml:"ge_ops_account_id" validate:"account"`
+	HpcsSouth            string   `yaml:"hpcs_south" validate:"guid"`
+	HpcsSouthCRN         string   `yaml:"hpcs_south_crn" validate:"crn=hs-crypto"`
+	HpcsSouthRootKeyCRN  string   `yaml:"hpcs_south_root_key_crn" validate:"keycrn=hs-crypto"`
+	KpUsSouthGUID        string   `yaml:"kp_us_south_guid" validate:"guid"`
+	KpUsSouthRootKeyID   string   `yaml:"kp_us_south_root_key_id" validate:"guid"`
+	PrivateOnlySecMgrCRN string   `yaml:"privateOnlySecMgrCRN" validate:"crn=secrets-manager"`
+	SecretsManagerCRN    string   `yaml:"secretsManagerCRN" validate:"crn=secrets-manager"`
+}
+
+var (
+	guidPattern      = regexp.MustCompile(`^[0-9a-fA-F]{8}(-[0-9a-fA-F]{4}){3}-[0-9a-fA-F]{12}$`)
+	accountPattern   = regexp.MustCompile(`^[0-9a-f]{32}$`)
+	accessTagPattern = regexp.MustCompile(`^[\w\-_.]+:[\w\-_.]+$`)
+)
+
+// Load reads the permanent resources from a YAML file and validates them
+func Load(path string) (*Resources, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var keys map[string]interface{}
+	if err := yaml.Unmarshal(data, &keys); err != nil {
+		return nil, fmt.Errorf("error decoding %s: %w", path, err)
+	}
+	var missing []string
+	for _, key := range Keys() {
+		if _, ok := keys[key]; !ok {
+			missing = append(missing, key)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("%s is missing keys used by the tests: %s", path, strings.Join(missing, ", "))
+	}
+
+	resources := &Resources{}
+	if err := yaml.Unmarshal(data, resources); err != nil {
+		return nil, fmt.Errorf("error decoding %s: %w", path, err)
+	}
+	if err := resources.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid permanent resources in %s: %w", path, err)
+	}
+	return resources, nil
+}
+
+// Keys returns the YAML keys of all permanent resources used by the tests
+func Keys() []string {
+	var keys []string
+	fields := reflect.TypeFor[Resources]()
+	for i := range fields.NumField() {
+		keys = append(keys, fields.Field(i).Tag.Get("yaml"))
+	}
+	return keys
+}
+
+// Validate checks the format of every permanent resource
+func (r *Resources) Validate() error {
+	var errs []error
+	fields := reflect.TypeFor[Resources]()
+	values := reflect.ValueOf(r).Elem()
+	for i := range fields.NumField() {
+		field := fields.Field(i)
+		if err := validate(field.Tag.Get("validate"), values.Field(i).Interface()); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", field.Tag.Get("yaml"), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func validate(format string, value interface{}) error {
+	kind, service, _ := strings.Cut(format, "=")
+	switch kind {
+	case "tags":
+		tags := value.([]string)
+		if len(tags) == 0 {
+			return errors.New("no access tags")
+		}
+		for _, tag := range tags {
+			if !accessTagPattern.MatchString(tag) {
+				return fmt.Errorf("%q is not an access tag of the form key:value", tag)
+			}
+		}
+		return nil
+	case "crn", "keycrn":
+		crn, err := ibmcloud.ParseCRN(value.(string))
+		if err != nil {
+			return err
+		}
+		switch {
+		case crn.ServiceName != service:
+			return fmt.Errorf("%q is a CRN of service %q, expected %q", value, crn.ServiceName, service)
+		case !guidPattern.MatchString(crn.ServiceInstance):
+			return fmt.Errorf("%q does not have a service instance GUID", value)
+		case kind == "crn" && crn.ResourceType != "":
+			return fmt.Errorf("%q is a CRN of a %s, expected a service instance", value, crn.ResourceType)
+		case kind == "keycrn" && (crn.ResourceType != "key" || crn.Resource == ""):
+			return fmt.Errorf("%q is not a CRN of a key", value)
+		}
+		return nil
+	case "guid":
+		if !guidPattern.MatchString(value.(string)) {
+			return fmt.Errorf("%q is not a GUID", value)
+		}
+		return nil
+	case "account":
+		if !accountPattern.MatchString(value.(string)) {
+			return fmt.Errorf("%q is not an account ID", value)
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown format %q", format)
+}
+
+// Fake returns well-formed placeholder resources, for tests that only compile or plan the Terraform offline
+func Fake() *Resources {
+	const account = "0123456789abcdef0123456789abcdef"
+	const hpcs = "11111111-1111-4111-8111-111111111111"
+	const keyProtect = "22222222-2222-4222-8222-222222222222"
+	return &Resources{
+		AccessTags:           []string{"geretain-dev:permanent"},
+		GeOpsAccountID:       account,
+		HpcsSouth:            hpcs,
+		HpcsSouthCRN:         fmt.Sprintf("crn:v1:bluemix:public:hs-crypto:us-south:a/%s:%s::", account, hpcs),
+		HpcsSouthRootKeyCRN:  fmt.Sprintf("crn:v1:bluemix:public:hs-crypto:us-south:a/%s:%s:key:33333333-3333-4333-8333-333333333333", account, hpcs),
+		KpUsSouthGUID:        keyProtect,
+		KpUsSouthRootKeyID:   "44444444-4444-4444-8444-444444444444",
+		PrivateOnlySecMgrCRN: fmt.Sprintf("crn:v1:bluemix:public:secrets-manager:us-south:a/%s:55555555-5555-4555-8555-555555555555::", account),
+		SecretsManagerCRN:    fmt.Sprintf("crn:v1:bluemix:public:secrets-manager:us-south:a/%s:66666666-6666-4666-8666-666666666666::", account),
+	}
+}
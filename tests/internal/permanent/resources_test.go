@@ -0,0 +1,76 @@
+package permanent
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+// writeResources writes the fake resources, with edits applied, next to keys that the tests do not use
+func writeResources(t *testing.T, edit func(keys map[string]interface{})) string {
+	data, err := yaml.Marshal(Fake())
+	require.NoError(t, err)
+	var keys map[string]interface{}
+	require.NoError(t, yaml.Unmarshal(data, &keys))
+	keys["unused_region"] = "us-east"
+	keys["unused_list"] = []int{1, 2}
+	if edit != nil {
+		edit(keys)
+	}
+
+	data, err = yaml.Marshal(keys)
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "common-permanent-resources.yaml")
+	require.NoError(t, os.WriteFile(path, data, 0o644))
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	resources, err := Load(writeResources(t, nil))
+	require.NoError(t, err)
+	assert.Equal(t, Fake(), resources)
+}
+
+func TestLoadMissingKeys(t *testing.T) {
+	path := writeResources(t, func(keys map[string]interface{}) {
+		delete(keys, "hpcs_south_crn")
+		delete(keys, "secretsManagerCRN")
+	})
+	_, err := Load(path)
+	assert.EqualError(t, err, path+" is missing keys used by the tests: hpcs_south_crn, secretsManagerCRN")
+}
+
+func TestLoadInvalidResources(t *testing.T) {
+	fake := Fake()
+	testCases := []struct {
+		key   string
+		value interface{}
+		error string
+	}{
+		{key: "hpcs_south_crn", value: "not-a-crn", error: `hpcs_south_crn: "not-a-crn" is not a valid CRN`},
+		{key: "hpcs_south_crn", value: fake.SecretsManagerCRN, error: `is a CRN of service "secrets-manager", expected "hs-crypto"`},
+		{key: "hpcs_south_crn", value: fake.HpcsSouthRootKeyCRN, error: "is a CRN of a key, expected a service instance"},
+		{key: "hpcs_south_root_key_crn", value: fake.HpcsSouthCRN, error: "is not a CRN of a key"},
+		{key: "secretsManagerCRN", value: "crn:v1:bluemix:public:secrets-manager:us-south:a/123:my-instance::", error: "does not have a service instance GUID"},
+		{key: "kp_us_south_root_key_id", value: "", error: `kp_us_south_root_key_id: "" is not a GUID`},
+		{key: "ge_ops_account_id", value: "a/123", error: `ge_ops_account_id: "a/123" is not an account ID`},
+		{key: "accessTags", value: []string{}, error: "accessTags: no access tags"},
+		{key: "accessTags", value: []string{"permanent"}, error: `"permanent" is not an access tag of the form key:value`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.key, func(t *testing.T) {
+			_, err := Load(writeResources(t, func(keys map[string]interface{}) { keys[tc.key] = tc.value }))
+			assert.ErrorContains(t, err, tc.error)
+		})
+	}
+}
+
+func TestFakeIsValid(t *testing.T) {
+	assert.NoError(t, Fake().Validate())
+	assert.Len(t, Keys(), 9)
+}
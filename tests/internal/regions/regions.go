@@ -0,0 +1,348 @@
+// Package regions hands out the regions that the tests deploy clusters to. Leases are recorded in a JSON file guarded by
+// a lock file, so that tests running in separate go test processes on the same machine share the per-region caps. The
+// file must be on a local filesystem of that machine: flock is not reliable on network filesystems, and process IDs are
+// only checked on the machine and boot that acquired a lease.
+package regions
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// DefaultTTL is how long a lease is kept when its process cannot be checked, because it was acquired on another
+	// machine or before a reboot of the runner
+	DefaultTTL = 8 * time.Hour
+	// DefaultInterval is how often Acquire checks for a free region while all allowed regions are at their cap
+	DefaultInterval = 30 * time.Second
+)
+
+// Feature is a service that a test depends on and that is not available in every region
+type Feature string
+
+// FeatureHPCS is Hyper Protect Crypto Services used for cluster encryption
+const FeatureHPCS Feature = "hpcs"
+
+// Exclusion keeps tests that depend on Feature out of Region
+type Exclusion struct {
+	Region  string
+	Feature Feature
+	Reason  string
+}
+
+// Coordinator leases regions to tests. A lease counts the clusters that a test deploys, and a region is only leased while
+// the clusters of its leases stay within its cap. Among the regions with room left, the one returned by Best is chosen,
+// then the one with the fewest leased clusters, falling back to the order of Regions on ties.
+type Coordinator struct {
+	// Path is the lease file, it is locked through Path + ".lock"
+	Path string
+	// Regions are the candidate regions in order of preference
+	Regions []string
+	// DefaultCap is the number of concurrently leased clusters of regions without an entry in Caps
+	DefaultCap int
+	Caps       map[string]int
+	Exclusions []Exclusion
+	// Best returns the region with the most VPC capacity left in the account, it is not consulted if nil
+	Best func() (string, error)
+	// TTL is the age after which a lease whose process cannot be checked is dropped, DefaultTTL if zero
+	TTL time.Duration
+	// Interval is the poll interval while all allowed regions are at their cap, DefaultInterval if zero
+	Interval time.Duration
+
+	// alive reports whether a process holding a lease still runs, machine identifies the running machine and boot, and
+	// now returns the current time. They are replaced in tests.
+	alive   func(pid int) bool
+	machine func() string
+	now     func() time.Time
+}
+
+// Lease is a region reserved for a test. It must be released once the cluster of the test is destroyed.
+type Lease struct {
+	ID       string `json:"id"`
+	Region   string `json:"region"`
+	Owner    string `json:"owner"`
+	Clusters int    `json:"clusters"`
+	PID      int    `json:"pid"`
+	// Machine identifies the machine and boot of the process, empty if they could not be determined
+	Machine  string    `json:"machine,omitempty"`
+	Acquired time.Time `json:"acquired"`
+
+	coordinator *Coordinator
+}
+
+// leaseFile is the content of the lease file
+type leaseFile struct {
+	Leases []Lease `json:"leases"`
+}
+
+// regionPreference is an entry of the cloudinfo region preferences file of common-dev-assets
+type regionPreference struct {
+	Name         string `yaml:"name"`
+	UseForTest   bool   `yaml:"useForTest"`
+	TestPriority int    `yaml:"testPriority"`
+}
+
+// LoadPreferences returns the regions of a cloudinfo region preferences file that are used for tests, ordered by their
+// test priority
+func LoadPreferences(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var preferences []regionPreference
+	if err := yaml.Unmarshal(data, &preferences); err != nil {
+		return nil, fmt.Errorf("error decoding %s: %w", path, err)
+	}
+	preferences = slices.DeleteFunc(preferences, func(p regionPreference) bool { return !p.UseForTest })
+	slices.SortStableFunc(preferences, func(a, b regionPreference) int { return a.TestPriority - b.TestPriority })
+
+	var regions []string
+	for _, preference := range preferences {
+		regions = append(regions, preference.Name)
+	}
+	if len(regions) == 0 {
+		return nil, fmt.Errorf("no regions for tests in %s", path)
+	}
+	return regions, nil
+}
+
+// Acquire leases a region for a number of clusters in a region allowed for all features, waiting for leases to be
+// released while no allowed region has room for them. It fails right away if no region is allowed at all, and with the
+// error of ctx once it is done.
+func (c *Coordinator) Acquire(ctx context.Context, owner string, clusters int, features ...Feature) (*Lease, error) {
+	if clusters < 1 {
+		return nil, fmt.Errorf("invalid number of clusters %d for %s", clusters, owner)
+	}
+	allowed := c.allowed(features, clusters)
+	if len(allowed) == 0 {
+		return nil, fmt.Errorf("no region allows %d clusters with %v: candidates %v, exclusions %v", clusters, features, c.Regions, c.Exclusions)
+	}
+
+	interval := c.Interval
+	if interval == 0 {
+		interval = DefaultInterval
+	}
+	for {
+		best := ""
+		if c.Best != nil {
+			var err error
+			if best, err = c.Best(); err != nil {
+				return nil, fmt.Errorf("error ranking regions for %s: %w", owner, err)
+			}
+		}
+		lease, err := c.tryAcquire(owner, clusters, allowed, best)
+		if err != nil || lease != nil {
+			return lease, err
+		}
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("no region available for %s among %v: %w", owner, allowed, ctx.Err())
+		case <-time.After(interval):
+		}
+	}
+}
+
+// Leases returns the active leases
+func (c *Coordinator) Leases() ([]Lease, error) {
+	var leases []Lease
+	err := c.update(func(file *leaseFile) bool {
+		leases = file.Leases
+		return false
+	})
+	return leases, err
+}
+
+// Release returns the region of the lease. Releasing a lease twice is not an error.
+func (l *Lease) Release() error {
+	return l.coordinator.update(func(file *leaseFile) bool {
+		before := len(file.Leases)
+		file.Leases = slices.DeleteFunc(file.Leases, func(lease Lease) bool { return lease.ID == l.ID })
+		return len(file.Leases) != before
+	})
+}
+
+// allowed returns the candidate regions that are not excluded for any of the features and whose cap fits the clusters
+func (c *Coordinator) allowed(features []Feature, clusters int) []string {
+	var allowed []string
+	for _, region := range c.Regions {
+		excluded := slices.ContainsFunc(c.Exclusions, func(exclusion Exclusion) bool {
+			return exclusion.Region == region && slices.Contains(features, exclusion.Feature)
+		})
+		if !excluded && c.limit(region) >= clusters {
+			allowed = append(allowed, region)
+		}
+	}
+	return allowed
+}
+
+// limit returns the cap of a region
+func (c *Coordinator) limit(region string) int {
+	if limit, ok := c.Caps[region]; ok {
+		return limit
+	}
+	return c.DefaultCap
+}
+
+// tryAcquire leases the preferred region, or else the least used allowed region, with room for the clusters, or returns
+// nil if there is none
+func (c *Coordinator) tryAcquire(owner string, clusters int, allowed []string, preferred string) (*Lease, error) {
+	id, err := newLeaseID()
+	if err != nil {
+		return nil, err
+	}
+
+	var lease *Lease
+	err = c.update(func(file *leaseFile) bool {
+		counts := map[string]int{}
+		for _, l := range file.Leases {
+			counts[l.Region] += l.Clusters
+		}
+		best := ""
+		for _, region := range allowed {
+			if counts[region]+clusters > c.limit(region) {
+				continue
+			}
+			if region == preferred {
+				best = region
+				break
+			}
+			if best == "" || counts[region] < counts[best] {
+				best = region
+			}
+		}
+		if best == "" {
+			return false
+		}
+		lease = &Lease{ID: id, Region: best, Owner: owner, Clusters: clusters, PID: os.Getpid(), Machine: c.machineID(), Acquired: c.clock()}
+		file.Leases = append(file.Leases, *lease)
+		return true
+	})
+	if err != nil || lease == nil {
+		return nil, err
+	}
+	lease.coordinator = c
+	return lease, nil
+}
+
+// update runs fn on the lease file while holding the lock, after dropping abandoned leases. The file is written back if
+// fn returns true or leases were dropped.
+func (c *Coordinator) update(fn func(file *leaseFile) bool) error {
+	unlock, err := lockFile(c.Path + ".lock")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	var file leaseFile
+	data, err := os.ReadFile(c.Path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+	case err != nil:
+		return err
+	default:
+		if err := json.Unmarshal(data, &file); err != nil {
+			return fmt.Errorf("error decoding lease file %s: %w", c.Path, err)
+		}
+	}
+
+	before := len(file.Leases)
+	file.Leases = slices.DeleteFunc(file.Leases, c.abandoned)
+	if !fn(&file) && len(file.Leases) == before {
+		return nil
+	}
+	data, err = json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return err
+	}
+	// write through a temporary file so that a crash never leaves a truncated lease file behind
+	if err := os.WriteFile(c.Path+".tmp", data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(c.Path+".tmp", c.Path)
+}
+
+// abandoned reports whether the process that acquired a lease exited. A lease acquired on another machine or boot
+// cannot be checked and is abandoned once it outlives its TTL.
+func (c *Coordinator) abandoned(lease Lease) bool {
+	if machine := c.machineID(); machine != "" && lease.Machine == machine {
+		alive := c.alive
+		if alive == nil {
+			alive = processAlive
+		}
+		return !alive(lease.PID)
+	}
+	ttl := c.TTL
+	if ttl == 0 {
+		ttl = DefaultTTL
+	}
+	return c.clock().Sub(lease.Acquired) > ttl
+}
+
+func (c *Coordinator) machineID() string {
+	if c.machine != nil {
+		return c.machine()
+	}
+	return thisMachine()
+}
+
+func (c *Coordinator) clock() time.Time {
+	if c.now != nil {
+		return c.now()
+	}
+	return time.Now()
+}
+
+func newLeaseID() (string, error) {
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(id), nil
+}
+
+// thisMachine returns the host name and boot ID of the running machine, or an empty string if the boot ID is unknown
+var thisMachine = sync.OnceValue(func() string {
+	host, err := os.Hostname()
+	if err != nil {
+		return ""
+	}
+	boot, err := os.ReadFile("/proc/sys/kernel/random/boot_id")
+	if err != nil {
+		return ""
+	}
+	return host + "/" + strings.TrimSpace(string(boot))
+})
+
+// processAlive reports whether a process with the given PID runs on this machine
+func processAlive(pid int) bool {
+	err := syscall.Kill(pid, 0)
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
+
+// lockFile takes an exclusive lock on path, blocking until it is available, and returns the function releasing it
+func lockFile(path string) (func(), error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("error locking %s: %w", path, err)
+	}
+	return func() {
+		_ = syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+		f.Close()
+	}, nil
+}
@@ -0,0 +1,270 @@
+package regions
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// helperLeaseFile makes the test binary act as a separate go test process holding a lease, see TestHelperProcess
+const helperLeaseFile = "REGIONS_HELPER_LEASE_FILE"
+
+func newCoordinator(t *testing.T) *Coordinator {
+	return &Coordinator{
+		Path:       filepath.Join(t.TempDir(), "leases.json"),
+		Regions:    []string{"us-south", "eu-de", "jp-osa"},
+		DefaultCap: 2,
+		Caps:       map[string]int{"jp-osa": 1},
+		Exclusions: []Exclusion{{Region: "jp-osa", Feature: FeatureHPCS, Reason: "no HPCS encryption"}},
+		Interval:   time.Millisecond,
+	}
+}
+
+func acquire(t *testing.T, c *Coordinator, owner string, features ...Feature) *Lease {
+	lease, err := c.Acquire(context.Background(), owner, 1, features...)
+	require.NoError(t, err)
+	return lease
+}
+
+func regionsOf(t *testing.T, c *Coordinator) []string {
+	leases, err := c.Leases()
+	require.NoError(t, err)
+	var regions []string
+	for _, lease := range leases {
+		regions = append(regions, lease.Region)
+	}
+	return regions
+}
+
+func TestAcquireSpreadsLeases(t *testing.T) {
+	c := newCoordinator(t)
+
+	var regions []string
+	for i := range 5 {
+		regions = append(regions, acquire(t, c, fmt.Sprintf("test-%d", i)).Region)
+	}
+	assert.Equal(t, []string{"us-south", "eu-de", "jp-osa", "us-south", "eu-de"}, regions)
+	assert.Equal(t, regions, regionsOf(t, c))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err := c.Acquire(ctx, "test-5", 1)
+	assert.ErrorContains(t, err, "no region available for test-5 among [us-south eu-de jp-osa]: context deadline exceeded")
+}
+
+func TestAcquireExclusions(t *testing.T) {
+	c := newCoordinator(t)
+
+	for range 4 {
+		assert.NotEqual(t, "jp-osa", acquire(t, c, "fscloud", FeatureHPCS).Region)
+	}
+
+	c.Regions = []string{"jp-osa"}
+	_, err := c.Acquire(context.Background(), "fscloud", 1, FeatureHPCS)
+	assert.ErrorContains(t, err, "no region allows 1 clusters with [hpcs]")
+
+	_, err = c.Acquire(context.Background(), "multi-cluster", 2)
+	assert.ErrorContains(t, err, "no region allows 2 clusters with []")
+
+	_, err = c.Acquire(context.Background(), "basic", 0)
+	assert.EqualError(t, err, "invalid number of clusters 0 for basic")
+}
+
+func TestAcquireMultipleClusters(t *testing.T) {
+	c := newCoordinator(t)
+	c.DefaultCap = 3
+
+	acquire(t, c, "basic")
+	lease, err := c.Acquire(context.Background(), "multi-cluster", 2)
+	require.NoError(t, err)
+	assert.Equal(t, "eu-de", lease.Region)
+
+	// us-south has one cluster, eu-de two, and jp-osa is capped at one
+	lease, err = c.Acquire(context.Background(), "multi-cluster", 2)
+	require.NoError(t, err)
+	assert.Equal(t, "us-south", lease.Region)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err = c.Acquire(ctx, "multi-cluster", 2)
+	assert.ErrorContains(t, err, "no region available for multi-cluster among [us-south eu-de]")
+}
+
+func TestAcquireBestRegion(t *testing.T) {
+	c := newCoordinator(t)
+	c.Best = func() (string, error) { return "jp-osa", nil }
+
+	assert.Equal(t, "jp-osa", acquire(t, c, "basic").Region)
+	assert.Equal(t, "us-south", acquire(t, c, "advanced").Region, "the best region is at its cap")
+	assert.Equal(t, "eu-de", acquire(t, c, "fscloud", FeatureHPCS).Region, "the best region is excluded")
+
+	c.Best = func() (string, error) { return "", errors.New("quota lookup failed") }
+	_, err := c.Acquire(context.Background(), "custom-sg", 1)
+	assert.EqualError(t, err, "error ranking regions for custom-sg: quota lookup failed")
+}
+
+func TestRelease(t *testing.T) {
+	c := newCoordinator(t)
+	c.Regions = []string{"us-south"}
+	c.DefaultCap = 1
+
+	lease := acquire(t, c, "first")
+	released := make(chan struct{})
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		assert.NoError(t, lease.Release())
+		close(released)
+	}()
+
+	second := acquire(t, c, "second")
+	<-released
+	assert.Equal(t, "us-south", second.Region)
+	assert.NoError(t, lease.Release(), "releasing twice")
+	assert.Equal(t, []string{"us-south"}, regionsOf(t, c))
+}
+
+func TestAbandonedLeases(t *testing.T) {
+	c := newCoordinator(t)
+	now := time.Now()
+	c.now = func() time.Time { return now }
+	dead := map[int]bool{}
+	c.alive = func(pid int) bool { return !dead[pid] }
+	machine := "runner-1/boot-1"
+	c.machine = func() string { return machine }
+
+	acquire(t, c, "long-running")
+	now = now.Add(DefaultTTL + time.Minute)
+	assert.Equal(t, []string{"us-south"}, regionsOf(t, c), "lease of a live process is kept past the TTL")
+
+	dead[os.Getpid()] = true
+	assert.Empty(t, regionsOf(t, c), "lease of a process that exited is dropped")
+	dead[os.Getpid()] = false
+
+	acquire(t, c, "before-reboot")
+	machine = "runner-1/boot-2"
+	now = now.Add(time.Hour)
+	acquire(t, c, "after-reboot")
+	assert.Equal(t, []string{"us-south", "eu-de"}, regionsOf(t, c), "lease of another boot is kept within the TTL")
+	now = now.Add(DefaultTTL)
+	assert.Equal(t, []string{"eu-de"}, regionsOf(t, c), "lease of another boot is dropped after the TTL")
+
+	machine = ""
+	now = now.Add(time.Hour)
+	assert.Empty(t, regionsOf(t, c), "leases of live processes are dropped after the TTL when the machine is unknown")
+}
+
+// TestAcquireContention has coordinators with separate lock file handles compete for two slots, and checks that the cap
+// of a region is never exceeded
+func TestAcquireContention(t *testing.T) {
+	shared := newCoordinator(t)
+	shared.Regions = []string{"us-south", "eu-de"}
+	shared.DefaultCap = 1
+
+	var mu sync.Mutex
+	active := map[string]int{}
+	peak := map[string]int{}
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c := *shared
+			lease, err := c.Acquire(context.Background(), fmt.Sprintf("test-%d", i), 1)
+			if !assert.NoError(t, err) {
+				return
+			}
+			mu.Lock()
+			active[lease.Region]++
+			peak[lease.Region] = max(peak[lease.Region], active[lease.Region])
+			mu.Unlock()
+
+			time.Sleep(5 * time.Millisecond)
+
+			mu.Lock()
+			active[lease.Region]--
+			mu.Unlock()
+			assert.NoError(t, lease.Release())
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, map[string]int{"us-south": 1, "eu-de": 1}, peak)
+	assert.Empty(t, regionsOf(t, shared))
+}
+
+// TestAcquireAcrossProcesses holds the only slot from a second process and checks that it is honoured until that
+// process releases it
+func TestAcquireAcrossProcesses(t *testing.T) {
+	c := newCoordinator(t)
+	c.Regions = []string{"us-south"}
+	c.DefaultCap = 1
+
+	cmd := exec.Command(os.Args[0], "-test.run=^TestHelperProcess$")
+	cmd.Env = append(os.Environ(), helperLeaseFile+"="+c.Path)
+	stdin, err := cmd.StdinPipe()
+	require.NoError(t, err)
+	stdout, err := cmd.StdoutPipe()
+	require.NoError(t, err)
+	require.NoError(t, cmd.Start())
+	defer func() { _ = cmd.Process.Kill() }()
+
+	line, err := bufio.NewReader(stdout).ReadString('\n')
+	require.NoError(t, err)
+	require.Equal(t, "leased us-south", strings.TrimSpace(line))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err = c.Acquire(ctx, "parent", 1)
+	require.ErrorContains(t, err, "no region available")
+
+	require.NoError(t, stdin.Close())
+	require.NoError(t, cmd.Wait())
+	assert.Equal(t, "us-south", acquire(t, c, "parent").Region)
+}
+
+// TestHelperProcess leases a region for TestAcquireAcrossProcesses and holds it until stdin is closed
+func TestHelperProcess(t *testing.T) {
+	path := os.Getenv(helperLeaseFile)
+	if path == "" {
+		t.Skip("only runs as a helper process")
+	}
+	c := &Coordinator{Path: path, Regions: []string{"us-south"}, DefaultCap: 1}
+	lease := acquire(t, c, "helper")
+	fmt.Printf("leased %s\n", lease.Region)
+	_, _ = bufio.NewReader(os.Stdin).ReadString('\n')
+	require.NoError(t, lease.Release())
+}
+
+func TestLoadPreferences(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "prefs.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+- name: eu-de
+  useForTest: true
+  testPriority: 2
+- name: br-sao
+  useForTest: false
+  testPriority: 1
+- name: us-south
+  useForTest: true
+  testPriority: 1
+`), 0o644))
+
+	regions, err := LoadPreferences(path)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"us-south", "eu-de"}, regions)
+
+	require.NoError(t, os.WriteFile(path, []byte("[]"), 0o644))
+	_, err = LoadPreferences(path)
+	assert.ErrorContains(t, err, "no regions for tests")
+}
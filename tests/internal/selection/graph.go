@@ -0,0 +1,179 @@
+// Package selection maps the files changed by a pull request to the tests of the tests package that deploy them, so
+// that a change to one module only runs the tests whose Terraform sources that module.
+package selection
+
+import (
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// Graph is the graph of the Terraform modules of a repository and the local modules and files that they use. All paths
+// are slash separated and relative to the repository root.
+type Graph struct {
+	Root    string
+	Modules map[string]*Module // keyed by directory, "." for the root module
+}
+
+// Module is a directory of .tf files
+type Module struct {
+	Dir string
+	// Sources are the directories of the local child modules
+	Sources []string
+	// Files are patterns of the files that the module reads: its .tf files and the paths referenced through
+	// path.module, such as scripts and Helm charts
+	Files []string
+}
+
+// LoadGraph parses the .tf files of every directory of the repository at root. Hidden directories and nested
+// repositories, such as git submodules, are skipped.
+func LoadGraph(root string) (*Graph, error) {
+	g := &Graph{Root: root, Modules: map[string]*Module{}}
+	parser := hclparse.NewParser()
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			return err
+		}
+		if p != root {
+			if strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			if _, err := os.Stat(filepath.Join(p, ".git")); err == nil {
+				return filepath.SkipDir
+			}
+		}
+		paths, err := filepath.Glob(filepath.Join(p, "*.tf"))
+		if err != nil || len(paths) == 0 {
+			return err
+		}
+		dir, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		module := &Module{Dir: filepath.ToSlash(dir), Files: []string{path.Join(filepath.ToSlash(dir), "*.tf")}}
+		for _, tf := range paths {
+			file, diags := parser.ParseHCLFile(tf)
+			if diags.HasErrors() {
+				return diags
+			}
+			module.addFile(file.Body.(*hclsyntax.Body))
+		}
+		slices.Sort(module.Sources)
+		module.Sources = slices.Compact(module.Sources)
+		slices.Sort(module.Files)
+		module.Files = slices.Compact(module.Files)
+		g.Modules[module.Dir] = module
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+func (m *Module) addFile(body *hclsyntax.Body) {
+	for _, block := range body.Blocks {
+		if block.Type != "module" {
+			continue
+		}
+		attr, ok := block.Body.Attributes["source"]
+		if !ok {
+			continue
+		}
+		source, diags := attr.Expr.Value(nil)
+		if diags.HasErrors() || source.Type() != cty.String || !source.IsKnown() || source.IsNull() {
+			continue
+		}
+		if s := source.AsString(); strings.HasPrefix(s, "./") || strings.HasPrefix(s, "../") {
+			if dir, ok := m.resolve(s); ok {
+				m.Sources = append(m.Sources, dir)
+			}
+		}
+	}
+
+	_ = hclsyntax.VisitAll(body, func(node hclsyntax.Node) hcl.Diagnostics {
+		if template, ok := node.(*hclsyntax.TemplateExpr); ok {
+			m.addTemplatePaths(template)
+		}
+		return nil
+	})
+}
+
+// addTemplatePaths adds the paths of a template that starts a path with path.module, e.g.
+// "${path.module}/scripts/https_audit.sh ${var.audit_namespace}". The path ends at the first space or interpolation,
+// so a partially interpolated path depends on its whole directory.
+func (m *Module) addTemplatePaths(template *hclsyntax.TemplateExpr) {
+	for i, part := range template.Parts[:max(len(template.Parts)-1, 0)] {
+		traversal, ok := part.(*hclsyntax.ScopeTraversalExpr)
+		if !ok || len(traversal.Traversal) != 2 || traversal.Traversal.RootName() != "path" {
+			continue
+		}
+		if attr, ok := traversal.Traversal[1].(hcl.TraverseAttr); !ok || attr.Name != "module" {
+			continue
+		}
+		literal, ok := template.Parts[i+1].(*hclsyntax.LiteralValueExpr)
+		if !ok || literal.Val.Type() != cty.String || !literal.Val.IsKnown() || literal.Val.IsNull() {
+			continue
+		}
+		rest, _, _ := strings.Cut(literal.Val.AsString(), " ")
+		if !strings.HasPrefix(rest, "/") {
+			continue
+		}
+		if p, ok := m.resolve("." + rest); ok && p != m.Dir {
+			m.Files = append(m.Files, strings.TrimSuffix(p, "/"))
+		}
+	}
+}
+
+// resolve returns a path relative to the module directory as a path relative to the repository root, and false if it
+// is outside the repository
+func (m *Module) resolve(rel string) (string, bool) {
+	p := path.Join(m.Dir, rel)
+	return p, p != ".." && !strings.HasPrefix(p, "../")
+}
+
+// Paths returns the file patterns of a module and of all the local modules that it sources, directly or through other
+// modules
+func (g *Graph) Paths(dir string) []string {
+	var paths []string
+	seen := map[string]bool{}
+	var visit func(dir string)
+	visit = func(dir string) {
+		if seen[dir] {
+			return
+		}
+		seen[dir] = true
+		module, ok := g.Modules[dir]
+		if !ok {
+			// a missing child module, e.g. deleted by the change, depends on everything that was in it
+			paths = append(paths, dir)
+			return
+		}
+		paths = append(paths, module.Files...)
+		for _, source := range module.Sources {
+			visit(source)
+		}
+	}
+	visit(dir)
+	slices.Sort(paths)
+	return slices.Compact(paths)
+}
+
+// Matches reports whether a changed file matches a pattern of Module.Files or TarIncludePatterns. The pattern is
+// matched against the file and all of its parent directories, so that a directory pattern matches the files under it.
+func Matches(pattern, file string) bool {
+	for p := file; p != "." && p != "/"; p = path.Dir(p) {
+		if ok, _ := path.Match(pattern, p); ok {
+			return true
+		}
+	}
+	return false
+}
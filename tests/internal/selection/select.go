@@ -0,0 +1,65 @@
+package selection
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Selector selects the tests affected by a set of changed files
+type Selector struct {
+	Graph *Graph
+	Tests []Test
+	// RunAll are patterns of files whose change selects every test, e.g. the Go sources of the tests
+	RunAll []string
+}
+
+// Selection is a selected test and the changed file that selected it
+type Selection struct {
+	Test string
+	File string
+}
+
+// Select returns the tests affected by the changed files, in the order of s.Tests. A test is affected if a changed file
+// matches its patterns or the paths of a module that it deploys. Tests that are not mapped to any module are selected
+// by any change.
+func (s *Selector) Select(changed []string) []Selection {
+	var selected []Selection
+	for _, test := range s.Tests {
+		patterns := append([]string{}, s.RunAll...)
+		patterns = append(patterns, test.Patterns...)
+		for _, dir := range test.Dirs {
+			patterns = append(patterns, s.Graph.Paths(dir)...)
+		}
+		if file, ok := firstMatch(patterns, changed, !test.Mapped()); ok {
+			selected = append(selected, Selection{Test: test.Name, File: file})
+		}
+	}
+	return selected
+}
+
+func firstMatch(patterns, changed []string, anyFile bool) (string, bool) {
+	for _, file := range changed {
+		if anyFile {
+			return file, true
+		}
+		for _, pattern := range patterns {
+			if Matches(pattern, file) {
+				return file, true
+			}
+		}
+	}
+	return "", false
+}
+
+// RunRegex returns a go test -run regular expression matching exactly the selected tests. It matches no test if none
+// is selected.
+func RunRegex(selected []Selection) string {
+	if len(selected) == 0 {
+		return "^$"
+	}
+	names := make([]string, len(selected))
+	for i, s := range selected {
+		names[i] = regexp.QuoteMeta(s.Test)
+	}
+	return "^(" + strings.Join(names, "|") + ")$"
+}
@@ -0,0 +1,262 @@
+package selection
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/terraform-ibm-modules/terraform-ibm-base-ocp-vpc/internal/catalog"
+)
+
+// repoFiles is a synthetic repository: a root module with a child module, an audit module with scripts, examples and
+// a solution sourcing them, and a tests package deploying them
+var repoFiles = map[string]string{
+	"main.tf": `
+module "worker" {
+  source = "./modules/worker"
+}
+data "external" "versions" {
+  program = ["bash", "${path.module}/scripts/versions.sh"]
+}`,
+	"README.md":                          "# module",
+	"scripts/versions.sh":                "#!/bin/bash",
+	"modules/worker/main.tf":             `resource "null_resource" "pool" {}`,
+	"modules/audit/scripts/audit.sh":     "#!/bin/bash",
+	"modules/audit/kubeconfig/README.md": "# kubeconfig",
+	"modules/audit/main.tf": `
+resource "terraform_data" "audit" {
+  provisioner "local-exec" {
+    command = "${path.module}/scripts/audit.sh ${var.namespace}"
+  }
+}
+locals {
+  config_dir = "${path.module}/kubeconfig"
+  charts     = "${path.module}/../../charts/${var.chart}"
+}`,
+	"examples/basic/main.tf": `
+module "ocp_base" {
+  source = "../.."
+}`,
+	"examples/audit/main.tf": `
+module "audit" {
+  source = "../../modules/audit"
+}
+module "vpc" {
+  source  = "terraform-ibm-modules/landing-zone-vpc/ibm"
+  version = "8.0.0"
+}`,
+	"solutions/da/main.tf": `
+module "ocp_base" {
+  source = "../.."
+}`,
+	"tests/existing/main.tf":     `resource "null_resource" "vpc" {}`,
+	"common-dev-assets/.git":     "gitdir: ../.git/modules/common-dev-assets",
+	"common-dev-assets/main.tf":  `resource "null_resource" "ignored" {}`,
+	".github/workflows/ci.tf":    `resource "null_resource" "ignored" {}`,
+	"tests/other_test.go":        otherTestSource,
+	"tests/pr_test.go":           prTestSource,
+	"tests/static/skip_test.go":  "package static\n\nfunc TestStatic(t *testing.T) {}\n",
+	"ibm_catalog.json":           catalogSource,
+	"docs/ignored_test_names.md": "TestBasic",
+}
+
+const prTestSource = `package test
+
+const basicExampleDir = "examples/basic"
+const daDir = "solutions/" + "da"
+
+func setupOptions(t *testing.T, prefix string, dir string) *testhelper.TestOptions {
+	return testhelper.TestOptionsDefault(&testhelper.TestOptions{Testing: t, Prefix: prefix, TerraformDir: dir})
+}
+
+func TestBasic(t *testing.T) {
+	options := setupOptions(t, "base", basicExampleDir)
+	options.PostApplyHook = checkCluster
+}
+
+func checkCluster(options *testhelper.TestOptions) error {
+	return nil
+}
+
+func TestSchematics(t *testing.T) {
+	options := testschematic.TestSchematicOptionsDefault(&testschematic.TestSchematicOptions{
+		TarIncludePatterns: []string{"*.tf", "modules/audit/scripts/*.sh", daDir + "/*.tf"},
+		TemplateFolder:     daDir,
+	})
+	_ = options
+}
+
+func TestMain(m *testing.M) {
+	_ = "examples/audit"
+}
+`
+
+const otherTestSource = `package test
+
+func TestAudit(t *testing.T) {
+	options := setupOptions(t, "audit", "examples/audit")
+	borrowExisting(t)
+}
+
+func borrowExisting(t *testing.T) {
+	files.CopyTerraformFolderToTemp("./existing", "prefix")
+}
+
+func TestAddon(t *testing.T) {
+	options.AddonConfig = cloudinfo.NewAddonConfigTerraform("prefix", "deploy-arch-example", "standard", nil)
+}
+
+func TestUnmapped(t *testing.T) {
+	t.Log("deploys a remote module")
+}
+
+func TestTable(t *testing.T, name string) {}
+`
+
+const catalogSource = `{
+  "products": [
+    {
+      "name": "deploy-arch-example",
+      "flavors": [
+        {"name": "standard", "working_directory": "solutions/da"},
+        {"name": "other", "working_directory": "examples/basic"}
+      ]
+    }
+  ]
+}`
+
+func writeRepo(t *testing.T) string {
+	root := t.TempDir()
+	for name, content := range repoFiles {
+		p := filepath.Join(root, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(p), 0o755))
+		require.NoError(t, os.WriteFile(p, []byte(content), 0o644))
+	}
+	return root
+}
+
+func loadRepo(t *testing.T) (*Graph, []Test) {
+	root := writeRepo(t)
+	graph, err := LoadGraph(root)
+	require.NoError(t, err)
+	offerings, err := catalog.Load(filepath.Join(root, "ibm_catalog.json"))
+	require.NoError(t, err)
+	tests, err := graph.Tests("tests", offerings)
+	require.NoError(t, err)
+	return graph, tests
+}
+
+func TestLoadGraph(t *testing.T) {
+	graph, _ := loadRepo(t)
+
+	assert.Equal(t, map[string]*Module{
+		".":              {Dir: ".", Sources: []string{"modules/worker"}, Files: []string{"*.tf", "scripts/versions.sh"}},
+		"modules/worker": {Dir: "modules/worker", Files: []string{"modules/worker/*.tf"}},
+		"modules/audit": {Dir: "modules/audit", Files: []string{
+			"charts",
+			"modules/audit/*.tf",
+			"modules/audit/kubeconfig",
+			"modules/audit/scripts/audit.sh",
+		}},
+		"examples/basic": {Dir: "examples/basic", Sources: []string{"."}, Files: []string{"examples/basic/*.tf"}},
+		"examples/audit": {Dir: "examples/audit", Sources: []string{"modules/audit"}, Files: []string{"examples/audit/*.tf"}},
+		"solutions/da":   {Dir: "solutions/da", Sources: []string{"."}, Files: []string{"solutions/da/*.tf"}},
+		"tests/existing": {Dir: "tests/existing", Files: []string{"tests/existing/*.tf"}},
+	}, graph.Modules)
+
+	assert.Equal(t, []string{"*.tf", "examples/basic/*.tf", "modules/worker/*.tf", "scripts/versions.sh"}, graph.Paths("examples/basic"))
+}
+
+func TestTests(t *testing.T) {
+	_, tests := loadRepo(t)
+
+	assert.Equal(t, []Test{
+		{Name: "TestAddon", Dirs: []string{"solutions/da"}, Patterns: []string{"ibm_catalog.json"}},
+		{Name: "TestAudit", Dirs: []string{"examples/audit", "tests/existing"}},
+		{Name: "TestBasic", Dirs: []string{"examples/basic"}},
+		{Name: "TestSchematics", Dirs: []string{"solutions/da"}, Patterns: []string{"*.tf", "modules/audit/scripts/*.sh", "solutions/da/*.tf"}},
+		{Name: "TestUnmapped"},
+	}, tests)
+}
+
+func TestSelect(t *testing.T) {
+	graph, tests := loadRepo(t)
+	selector := &Selector{Graph: graph, Tests: tests, RunAll: []string{"tests/*_test.go"}}
+
+	testCases := []struct {
+		name     string
+		changed  []string
+		selected []string
+	}{
+		{name: "no change"},
+		{
+			name:     "script of a child module",
+			changed:  []string{"modules/audit/scripts/audit.sh"},
+			selected: []string{"TestAudit", "TestSchematics", "TestUnmapped"},
+		},
+		{
+			name:     "directory referenced through path.module",
+			changed:  []string{"modules/audit/kubeconfig/README.md"},
+			selected: []string{"TestAudit", "TestUnmapped"},
+		},
+		{
+			name:     "module sourced through the root module",
+			changed:  []string{"modules/worker/main.tf"},
+			selected: []string{"TestAddon", "TestBasic", "TestSchematics", "TestUnmapped"},
+		},
+		{
+			name:     "script of the root module",
+			changed:  []string{"scripts/versions.sh"},
+			selected: []string{"TestAddon", "TestBasic", "TestSchematics", "TestUnmapped"},
+		},
+		{
+			name:     "fixture of the tests",
+			changed:  []string{"tests/existing/main.tf"},
+			selected: []string{"TestAudit", "TestUnmapped"},
+		},
+		{
+			name:     "catalog",
+			changed:  []string{"ibm_catalog.json"},
+			selected: []string{"TestAddon", "TestUnmapped"},
+		},
+		{
+			name:     "documentation and nested repository",
+			changed:  []string{"README.md", "common-dev-assets/main.tf", "modules/audit/README.md"},
+			selected: []string{"TestUnmapped"},
+		},
+		{
+			name:     "test sources",
+			changed:  []string{"README.md", "tests/other_test.go"},
+			selected: []string{"TestAddon", "TestAudit", "TestBasic", "TestSchematics", "TestUnmapped"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var names []string
+			for _, s := range selector.Select(tc.changed) {
+				names = append(names, s.Test)
+			}
+			assert.Equal(t, tc.selected, names)
+		})
+	}
+
+	assert.Equal(t, []Selection{{Test: "TestAudit", File: "tests/existing/main.tf"}, {Test: "TestUnmapped", File: "README.md"}},
+		selector.Select([]string{"README.md", "tests/existing/main.tf"}))
+}
+
+func TestRunRegex(t *testing.T) {
+	assert.Equal(t, "^$", RunRegex(nil))
+	assert.Equal(t, "^(TestA|TestB)$", RunRegex([]Selection{{Test: "TestA"}, {Test: "TestB"}}))
+}
+
+func TestMatches(t *testing.T) {
+	assert.True(t, Matches("*.tf", "main.tf"))
+	assert.False(t, Matches("*.tf", "examples/basic/main.tf"))
+	assert.True(t, Matches("modules/audit/kubeconfig", "modules/audit/kubeconfig/README.md"))
+	assert.False(t, Matches("modules/audit/kubeconfig", "modules/audit/kubeconfig.tf"))
+	assert.True(t, Matches("modules/*/scripts/*.sh", "modules/audit/scripts/audit.sh"))
+}
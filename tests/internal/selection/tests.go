@@ -0,0 +1,250 @@
+package selection
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/terraform-ibm-modules/terraform-ibm-base-ocp-vpc/internal/catalog"
+)
+
+// Test is a test function and the repository paths that it deploys, found from the string constants used by the test
+// and by the package functions it references
+type Test struct {
+	Name string
+	// Dirs are the Terraform modules that the test deploys, e.g. TerraformDir, TemplateFolder, ./existing-resources or
+	// the working directory of a catalog flavor
+	Dirs []string
+	// Patterns are the TarIncludePatterns of the test and the other files of the repository that it names
+	Patterns []string
+}
+
+// Mapped reports whether the Terraform that the test deploys is known. A test without modules could be affected by any
+// file.
+func (t Test) Mapped() bool {
+	return len(t.Dirs) > 0
+}
+
+// funcRefs are the strings and package functions that a function references
+type funcRefs struct {
+	strings  []string
+	patterns []string
+	funcs    []string
+}
+
+// Tests parses the _test.go files of the tests package in dir, relative to the repository root, and returns its test
+// functions. String constants naming a module of the graph, or a file of the repository, are resolved relative to the
+// repository root as well as to dir, as the tests use both, e.g. "examples/basic" and "./existing-resources". If the
+// repository has a catalog, a test naming a product and one of its flavors, as the addon tests do, deploys the working
+// directory of the flavor and depends on the catalog itself.
+func (g *Graph) Tests(dir string, offerings *catalog.Catalog) ([]Test, error) {
+	paths, err := filepath.Glob(filepath.Join(g.Root, dir, "*_test.go"))
+	if err != nil {
+		return nil, err
+	}
+	fset := token.NewFileSet()
+	var files []*ast.File
+	for _, p := range paths {
+		file, err := parser.ParseFile(fset, p, nil, parser.SkipObjectResolution)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+
+	consts := map[string]ast.Expr{}
+	decls := map[string]*ast.FuncDecl{}
+	for _, file := range files {
+		for _, decl := range file.Decls {
+			switch decl := decl.(type) {
+			case *ast.GenDecl:
+				if decl.Tok != token.CONST {
+					continue
+				}
+				for _, spec := range decl.Specs {
+					spec := spec.(*ast.ValueSpec)
+					for i, name := range spec.Names {
+						if i < len(spec.Values) {
+							consts[name.Name] = spec.Values[i]
+						}
+					}
+				}
+			case *ast.FuncDecl:
+				if decl.Recv == nil && decl.Body != nil {
+					decls[decl.Name.Name] = decl
+				}
+			}
+		}
+	}
+
+	refs := map[string]*funcRefs{}
+	for name, decl := range decls {
+		refs[name] = collectRefs(decl, consts, decls)
+	}
+
+	var tests []Test
+	for name, decl := range decls {
+		if !isTest(decl) {
+			continue
+		}
+		test := Test{Name: name}
+		names := map[string]bool{}
+		for _, ref := range reachable(name, refs) {
+			for _, s := range ref.strings {
+				g.resolveString(dir, s, &test)
+				names[s] = true
+			}
+			test.Patterns = append(test.Patterns, ref.patterns...)
+		}
+		if offerings != nil {
+			g.resolveFlavors(offerings, names, &test)
+		}
+		slices.Sort(test.Dirs)
+		test.Dirs = slices.Compact(test.Dirs)
+		slices.Sort(test.Patterns)
+		test.Patterns = slices.Compact(test.Patterns)
+		tests = append(tests, test)
+	}
+	slices.SortFunc(tests, func(a, b Test) int { return strings.Compare(a.Name, b.Name) })
+	return tests, nil
+}
+
+// resolveString adds s to the test if it names a module or a file of the repository
+func (g *Graph) resolveString(dir, s string, test *Test) {
+	if s == "" || strings.ContainsAny(s, " \n*") || path.IsAbs(s) {
+		return
+	}
+	for _, p := range []string{path.Clean(s), path.Join(dir, s)} {
+		if p == "." || p == ".." || strings.HasPrefix(p, "../") {
+			continue
+		}
+		if _, ok := g.Modules[p]; ok {
+			test.Dirs = append(test.Dirs, p)
+		} else if info, err := os.Stat(filepath.Join(g.Root, p)); err == nil && info.Mode().IsRegular() {
+			test.Patterns = append(test.Patterns, p)
+		}
+	}
+}
+
+// resolveFlavors adds the working directories of the catalog flavors named by the test
+func (g *Graph) resolveFlavors(offerings *catalog.Catalog, names map[string]bool, test *Test) {
+	for _, product := range offerings.Products {
+		if !names[product.Name] {
+			continue
+		}
+		for _, flavor := range product.Flavors {
+			if names[flavor.Name] {
+				test.Dirs = append(test.Dirs, path.Clean(flavor.WorkingDirectory))
+				if rel, err := filepath.Rel(g.Root, offerings.Path); err == nil {
+					test.Patterns = append(test.Patterns, filepath.ToSlash(rel))
+				}
+			}
+		}
+	}
+}
+
+// isTest reports whether decl is a test function, other than TestMain
+func isTest(decl *ast.FuncDecl) bool {
+	name := decl.Name.Name
+	if !strings.HasPrefix(name, "Test") || name == "TestMain" || decl.Type.Params.NumFields() != 1 {
+		return false
+	}
+	star, ok := decl.Type.Params.List[0].Type.(*ast.StarExpr)
+	if !ok {
+		return false
+	}
+	sel, ok := star.X.(*ast.SelectorExpr)
+	return ok && sel.Sel.Name == "T"
+}
+
+// reachable returns the references of a function and of all the functions that it references, directly or indirectly
+func reachable(name string, refs map[string]*funcRefs) []*funcRefs {
+	var result []*funcRefs
+	seen := map[string]bool{}
+	queue := []string{name}
+	for len(queue) > 0 {
+		name, queue = queue[0], queue[1:]
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		result = append(result, refs[name])
+		queue = append(queue, refs[name].funcs...)
+	}
+	return result
+}
+
+// collectRefs collects the string constants of a function body, the elements of TarIncludePatterns and the package
+// functions that the body calls or passes around, e.g. as a PostApplyHook
+func collectRefs(decl *ast.FuncDecl, consts map[string]ast.Expr, decls map[string]*ast.FuncDecl) *funcRefs {
+	refs := &funcRefs{}
+	ast.Inspect(decl.Body, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.KeyValueExpr:
+			key, ok := node.Key.(*ast.Ident)
+			lit, isLit := node.Value.(*ast.CompositeLit)
+			if !ok || key.Name != "TarIncludePatterns" || !isLit {
+				return true
+			}
+			for _, elt := range lit.Elts {
+				if s, ok := constString(elt, consts, 0); ok {
+					refs.patterns = append(refs.patterns, path.Clean(s))
+				}
+			}
+			return false
+		case *ast.Ident:
+			if _, ok := decls[node.Name]; ok {
+				refs.funcs = append(refs.funcs, node.Name)
+				return false
+			}
+		}
+		if expr, ok := node.(ast.Expr); ok {
+			if s, ok := constString(expr, consts, 0); ok {
+				refs.strings = append(refs.strings, s)
+				return false
+			}
+		}
+		return true
+	})
+	return refs
+}
+
+// constString evaluates a string constant expression: a literal, a package constant, or a concatenation of those
+func constString(expr ast.Expr, consts map[string]ast.Expr, depth int) (string, bool) {
+	if depth > 10 {
+		return "", false
+	}
+	switch expr := expr.(type) {
+	case *ast.BasicLit:
+		if expr.Kind != token.STRING {
+			return "", false
+		}
+		s, err := strconv.Unquote(expr.Value)
+		return s, err == nil
+	case *ast.Ident:
+		value, ok := consts[expr.Name]
+		if !ok {
+			return "", false
+		}
+		return constString(value, consts, depth+1)
+	case *ast.ParenExpr:
+		return constString(expr.X, consts, depth+1)
+	case *ast.BinaryExpr:
+		if expr.Op != token.ADD {
+			return "", false
+		}
+		x, ok := constString(expr.X, consts, depth+1)
+		if !ok {
+			return "", false
+		}
+		y, ok := constString(expr.Y, consts, depth+1)
+		return x + y, ok
+	}
+	return "", false
+}
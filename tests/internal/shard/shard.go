@@ -0,0 +1,242 @@
+// Package shard splits the tests of a package between CI runners, balancing the shards by the historical duration of
+// each test rather than by their number, as the suite mixes plans of a few minutes with Schematics jobs of several hours.
+package shard
+
+import (
+	"bufio"
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/parser"
+	"go/token"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Environment variables selecting the shard of a runner. SHARD_INDEX is zero based.
+const (
+	IndexEnv = "SHARD_INDEX"
+	TotalEnv = "SHARD_TOTAL"
+)
+
+// DefaultDuration is assumed for every test when no duration is known at all
+const DefaultDuration = time.Hour
+
+// Durations are the durations of tests by name, stored in JSON as seconds
+type Durations map[string]time.Duration
+
+// Shard is a set of tests run by one runner
+type Shard struct {
+	Tests []string
+	// Duration is the sum of the estimated durations of the tests
+	Duration time.Duration
+}
+
+// LoadDurations reads durations from a JSON object of test names to seconds. A missing file gives no durations.
+func LoadDurations(path string) (Durations, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return Durations{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var seconds map[string]float64
+	if err := json.Unmarshal(data, &seconds); err != nil {
+		return nil, fmt.Errorf("error decoding %s: %w", path, err)
+	}
+	durations := Durations{}
+	for name, s := range seconds {
+		durations[name] = time.Duration(s * float64(time.Second))
+	}
+	return durations, nil
+}
+
+// Save writes the durations as a JSON object of test names to whole seconds
+func (d Durations) Save(path string) error {
+	seconds := map[string]int64{}
+	for name, duration := range d {
+		seconds[name] = int64(math.Round(duration.Seconds()))
+	}
+	data, err := json.MarshalIndent(seconds, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// ReadTestEvents returns the durations of the top level tests that passed or failed in the output of go test -json.
+// Lines that are not test events, e.g. build output, are ignored.
+func ReadTestEvents(r io.Reader) (Durations, error) {
+	durations := Durations{}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1024*1024)
+	for scanner.Scan() {
+		var event struct {
+			Action  string
+			Test    string
+			Elapsed float64
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			continue
+		}
+		if (event.Action == "pass" || event.Action == "fail") && event.Test != "" && !strings.Contains(event.Test, "/") {
+			durations[event.Test] = time.Duration(event.Elapsed * float64(time.Second))
+		}
+	}
+	return durations, scanner.Err()
+}
+
+// estimate returns the duration to assume for tests without history: the median of the known durations
+func (d Durations) estimate() time.Duration {
+	if len(d) == 0 {
+		return DefaultDuration
+	}
+	known := make([]time.Duration, 0, len(d))
+	for _, duration := range d {
+		known = append(known, duration)
+	}
+	slices.Sort(known)
+	return known[len(known)/2]
+}
+
+// Plan packs the tests into total shards, placing the longest tests first, each in the shard with the least work so far.
+// The plan only depends on the set of tests and their durations, so every runner computes the same one.
+func Plan(tests []string, durations Durations, total int) []Shard {
+	estimate := durations.estimate()
+	duration := func(test string) time.Duration {
+		if d, ok := durations[test]; ok {
+			return d
+		}
+		return estimate
+	}
+
+	sorted := slices.Clone(tests)
+	slices.Sort(sorted)
+	sorted = slices.Compact(sorted)
+	slices.SortStableFunc(sorted, func(a, b string) int {
+		return cmp.Compare(duration(b), duration(a))
+	})
+
+	shards := make([]Shard, total)
+	for _, test := range sorted {
+		lightest := 0
+		for i := range shards {
+			if shards[i].Duration < shards[lightest].Duration {
+				lightest = i
+			}
+		}
+		shards[lightest].Tests = append(shards[lightest].Tests, test)
+		shards[lightest].Duration += duration(test)
+	}
+	for i := range shards {
+		slices.Sort(shards[i].Tests)
+	}
+	return shards
+}
+
+// FromEnv returns the shard of this runner from SHARD_INDEX and SHARD_TOTAL. ok is false if neither is set.
+func FromEnv(getenv func(string) string) (index int, total int, ok bool, err error) {
+	indexValue, totalValue := getenv(IndexEnv), getenv(TotalEnv)
+	if indexValue == "" && totalValue == "" {
+		return 0, 0, false, nil
+	}
+	if indexValue == "" || totalValue == "" {
+		return 0, 0, false, fmt.Errorf("%s and %s must be set together", IndexEnv, TotalEnv)
+	}
+	if index, err = strconv.Atoi(indexValue); err != nil {
+		return 0, 0, false, fmt.Errorf("invalid %s %q: %w", IndexEnv, indexValue, err)
+	}
+	if total, err = strconv.Atoi(totalValue); err != nil {
+		return 0, 0, false, fmt.Errorf("invalid %s %q: %w", TotalEnv, totalValue, err)
+	}
+	if total < 1 || index < 0 || index >= total {
+		return 0, 0, false, fmt.Errorf("%s=%d is not a shard of %s=%d", IndexEnv, index, TotalEnv, total)
+	}
+	return index, total, true, nil
+}
+
+// TestNames returns the names of the test functions in the _test.go files of dir. Files that are only built with extra
+// build tags are skipped, as a plain go test run does not know their tests.
+func TestNames(dir string) ([]string, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*_test.go"))
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	fset := token.NewFileSet()
+	for _, path := range paths {
+		match, err := build.Default.MatchFile(dir, filepath.Base(path))
+		if err != nil {
+			return nil, err
+		}
+		if !match {
+			continue
+		}
+		file, err := parser.ParseFile(fset, path, nil, parser.SkipObjectResolution)
+		if err != nil {
+			return nil, err
+		}
+		for _, decl := range file.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok || fn.Recv != nil || !strings.HasPrefix(fn.Name.Name, "Test") || fn.Name.Name == "TestMain" {
+				continue
+			}
+			if params := fn.Type.Params.List; len(params) == 1 && len(params[0].Names) <= 1 && isTestingT(params[0].Type) {
+				names = append(names, fn.Name.Name)
+			}
+		}
+	}
+	slices.Sort(names)
+	return names, nil
+}
+
+func isTestingT(expr ast.Expr) bool {
+	star, ok := expr.(*ast.StarExpr)
+	if !ok {
+		return false
+	}
+	sel, ok := star.X.(*ast.SelectorExpr)
+	return ok && sel.Sel.Name == "T"
+}
+
+// Matching returns the tests selected by a go test -run expression. Only the top level part of the expression, before
+// the first slash, is used, as shards are made of top level tests.
+func Matching(tests []string, run string) ([]string, error) {
+	if run == "" {
+		return tests, nil
+	}
+	pattern, err := regexp.Compile(strings.Split(run, "/")[0])
+	if err != nil {
+		return nil, fmt.Errorf("invalid -run expression %q: %w", run, err)
+	}
+	var matching []string
+	for _, test := range tests {
+		if pattern.MatchString(test) {
+			matching = append(matching, test)
+		}
+	}
+	return matching, nil
+}
+
+// Regex returns a go test -run regular expression matching exactly the tests, or no test if there are none
+func Regex(tests []string) string {
+	if len(tests) == 0 {
+		return "^$"
+	}
+	quoted := make([]string, len(tests))
+	for i, test := range tests {
+		quoted[i] = regexp.QuoteMeta(test)
+	}
+	return "^(" + strings.Join(quoted, "|") + ")$"
+}
@@ -0,0 +1,182 @@
+package shard
+
+import (
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var suite = Durations{
+	"TestRunFullyConfigurableInSchematics": 5 * time.Hour,
+	"TestRunUpgradeFullyConfigurable":      6 * time.Hour,
+	"TestRunQuickstartSchematics":          4 * time.Hour,
+	"TestRoksAddonDefaultConfiguration":    4 * time.Hour,
+	"TestRunBasicExample":                  90 * time.Minute,
+	"TestRunCustomsgExample":               80 * time.Minute,
+	"TestRunAdvancedExample":               2 * time.Hour,
+	"TestQuickstartSizes":                  20 * time.Minute,
+	"TestTerraformPlan":                    20 * time.Minute,
+}
+
+func suiteTests() []string {
+	var tests []string
+	for test := range suite {
+		tests = append(tests, test)
+	}
+	return tests
+}
+
+func TestPlanIsDeterministic(t *testing.T) {
+	tests := suiteTests()
+	want := Plan(tests, suite, 3)
+
+	random := rand.New(rand.NewSource(1))
+	for range 20 {
+		random.Shuffle(len(tests), func(i, j int) { tests[i], tests[j] = tests[j], tests[i] })
+		assert.Equal(t, want, Plan(tests, suite, 3), "order of %v", tests)
+	}
+	assert.Equal(t, want, Plan(append(tests, tests[0]), suite, 3), "duplicate test")
+}
+
+func TestPlanBalancesDurations(t *testing.T) {
+	shards := Plan(suiteTests(), suite, 3)
+
+	assert.Equal(t, []Shard{
+		{Tests: []string{"TestQuickstartSizes", "TestRunBasicExample", "TestRunUpgradeFullyConfigurable", "TestTerraformPlan"}, Duration: 490 * time.Minute},
+		{Tests: []string{"TestRunAdvancedExample", "TestRunCustomsgExample", "TestRunFullyConfigurableInSchematics"}, Duration: 500 * time.Minute},
+		{Tests: []string{"TestRoksAddonDefaultConfiguration", "TestRunQuickstartSchematics"}, Duration: 8 * time.Hour},
+	}, shards)
+
+	var all []string
+	for _, shard := range shards {
+		all = append(all, shard.Tests...)
+	}
+	assert.ElementsMatch(t, suiteTests(), all, "every test runs exactly once")
+}
+
+func TestPlanUnknownDurations(t *testing.T) {
+	// tests without history are assumed to take the median, 2h here
+	shards := Plan([]string{"TestNew", "TestRunAdvancedExample", "TestRunBasicExample", "TestRunUpgradeFullyConfigurable"}, suite, 2)
+	assert.Equal(t, []string{"TestRunUpgradeFullyConfigurable"}, shards[0].Tests)
+	assert.Equal(t, []string{"TestNew", "TestRunAdvancedExample", "TestRunBasicExample"}, shards[1].Tests)
+
+	// without any history, the tests are spread by number
+	shards = Plan([]string{"TestA", "TestB", "TestC", "TestD", "TestE"}, nil, 2)
+	assert.Equal(t, []Shard{
+		{Tests: []string{"TestA", "TestC", "TestE"}, Duration: 3 * DefaultDuration},
+		{Tests: []string{"TestB", "TestD"}, Duration: 2 * DefaultDuration},
+	}, shards)
+
+	shards = Plan([]string{"TestA"}, nil, 3)
+	assert.Empty(t, shards[2].Tests, "more shards than tests")
+}
+
+func TestFromEnv(t *testing.T) {
+	env := func(index, total string) func(string) string {
+		return func(key string) string {
+			return map[string]string{IndexEnv: index, TotalEnv: total}[key]
+		}
+	}
+
+	index, total, ok, err := FromEnv(env("2", "4"))
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, 2, index)
+	assert.Equal(t, 4, total)
+
+	_, _, ok, err = FromEnv(env("", ""))
+	require.NoError(t, err)
+	assert.False(t, ok, "not sharded")
+
+	for _, tc := range []struct{ index, total, error string }{
+		{index: "1", error: "SHARD_INDEX and SHARD_TOTAL must be set together"},
+		{index: "one", total: "2", error: `invalid SHARD_INDEX "one"`},
+		{index: "0", total: "two", error: `invalid SHARD_TOTAL "two"`},
+		{index: "2", total: "2", error: "SHARD_INDEX=2 is not a shard of SHARD_TOTAL=2"},
+		{index: "-1", total: "2", error: "SHARD_INDEX=-1 is not a shard of SHARD_TOTAL=2"},
+		{index: "0", total: "0", error: "SHARD_INDEX=0 is not a shard of SHARD_TOTAL=0"},
+	} {
+		_, _, _, err := FromEnv(env(tc.index, tc.total))
+		assert.ErrorContains(t, err, tc.error)
+	}
+}
+
+func TestDurationsFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test-durations.json")
+	durations, err := LoadDurations(path)
+	require.NoError(t, err)
+	assert.Empty(t, durations, "missing file")
+
+	require.NoError(t, Durations{"TestA": 90*time.Minute + 400*time.Millisecond, "TestB": time.Second}.Save(path))
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"TestA": 5400, "TestB": 1}`, string(data))
+
+	durations, err = LoadDurations(path)
+	require.NoError(t, err)
+	assert.Equal(t, Durations{"TestA": 90 * time.Minute, "TestB": time.Second}, durations)
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"TestA": "90m"}`), 0o644))
+	_, err = LoadDurations(path)
+	assert.ErrorContains(t, err, "error decoding")
+}
+
+func TestReadTestEvents(t *testing.T) {
+	events := `# github.com/terraform-ibm-modules/terraform-ibm-base-ocp-vpc
+{"Action":"run","Test":"TestRunBasicExample"}
+{"Action":"pass","Test":"TestRunBasicExample/consistency","Elapsed":60}
+{"Action":"pass","Test":"TestRunBasicExample","Elapsed":5400.5}
+{"Action":"fail","Test":"TestRunGpuExample","Elapsed":3600}
+{"Action":"skip","Test":"TestRunCustomsgExample","Elapsed":0}
+{"Action":"pass","Elapsed":9000}
+`
+	durations, err := ReadTestEvents(strings.NewReader(events))
+	require.NoError(t, err)
+	assert.Equal(t, Durations{"TestRunBasicExample": 5400500 * time.Millisecond, "TestRunGpuExample": time.Hour}, durations)
+}
+
+func TestTestNames(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "pr_test.go"), []byte(`package test
+
+func TestMain(m *testing.M) {}
+func TestRunBasicExample(t *testing.T) {}
+func TestHelper(t *testing.T, name string) {}
+func BenchmarkPlan(b *testing.B) {}
+func (s suite) TestMethod(t *testing.T) {}
+func setupOptions(t *testing.T) {}
+`), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "other_test.go"), []byte("package test\n\nfunc TestAddonPermutations(t *testing.T) {}\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "helpers.go"), []byte("package test\n\nfunc TestNotATest(t *testing.T) {}\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "autoscaler_test.go"), []byte("//go:build autoscaler\n\npackage test\n\nfunc TestRunAutoscalerExample(t *testing.T) {}\n"), 0o644))
+
+	names, err := TestNames(dir)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"TestAddonPermutations", "TestRunBasicExample"}, names)
+}
+
+func TestMatching(t *testing.T) {
+	tests := []string{"TestRunBasicExample", "TestRunGpuExample", "TestAddonPermutations"}
+
+	matching, err := Matching(tests, "Example$/consistency")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"TestRunBasicExample", "TestRunGpuExample"}, matching)
+
+	matching, err = Matching(tests, "")
+	require.NoError(t, err)
+	assert.Equal(t, tests, matching)
+
+	_, err = Matching(tests, "Test(")
+	assert.ErrorContains(t, err, "invalid -run expression")
+}
+
+func TestRegex(t *testing.T) {
+	assert.Equal(t, "^$", Regex(nil))
+	assert.Equal(t, "^(TestA|TestB)$", Regex([]string{"TestA", "TestB"}))
+}
@@ -0,0 +1,277 @@
+package tfstatic
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// unknownCount is the instance count of resources whose count cannot be evaluated offline
+const unknownCount = -1
+
+// Evaluation is the evaluation of a module for one set of input variables. Resource instances are modelled as objects
+// whose attributes evaluate to "<instance address>.<attribute>", so outputs and locals can be traced back to the
+// instance they read. References to data sources, child modules, path and terraform evaluate to unknown values, as do
+// locals that cannot be evaluated.
+type Evaluation struct {
+	module     *Module
+	variables  cty.Value
+	attributes map[string][]string // attributes of each resource that locals, outputs and counts refer to
+	locals     map[string]cty.Value
+	resources  map[string]cty.Value
+	counts     map[string]int
+	evaluating map[string]bool
+}
+
+// Evaluate evaluates the module with the given input variables. Variables that are not given use their default value.
+// The validation blocks of the given variables are checked. Like Terraform does, conditions whose result depends on
+// unknown values are skipped, but conditions calling a function that is not in functions fail the evaluation.
+func (m *Module) Evaluate(vars map[string]cty.Value) (*Evaluation, error) {
+	values := map[string]cty.Value{}
+	for name, variable := range m.Variables {
+		values[name] = variable.Default
+	}
+	for name, value := range vars {
+		if _, ok := values[name]; !ok {
+			return nil, fmt.Errorf("module %s has no variable %q", m.Dir, name)
+		}
+		values[name] = value
+	}
+	ctx := &hcl.EvalContext{Variables: map[string]cty.Value{"var": cty.ObjectVal(values)}, Functions: functions}
+	for name := range vars {
+		if err := m.Variables[name].validate(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	e := &Evaluation{
+		module:     m,
+		variables:  cty.ObjectVal(values),
+		attributes: map[string][]string{},
+		locals:     map[string]cty.Value{},
+		resources:  map[string]cty.Value{},
+		counts:     map[string]int{},
+		evaluating: map[string]bool{},
+	}
+	var expressions []hcl.Expression
+	expressions = slices.AppendSeq(expressions, maps.Values(m.Locals))
+	expressions = slices.AppendSeq(expressions, maps.Values(m.Outputs))
+	for _, resource := range m.Resources {
+		if resource.Count != nil {
+			expressions = append(expressions, resource.Count)
+		}
+	}
+	for _, expr := range expressions {
+		for _, traversal := range expr.Variables() {
+			address, attribute := e.resourceReference(traversal)
+			if attribute != "" && !slices.Contains(e.attributes[address], attribute) {
+				e.attributes[address] = append(e.attributes[address], attribute)
+			}
+		}
+	}
+	return e, nil
+}
+
+// validate checks the validation blocks of the variable against the variables of ctx
+func (v *Variable) validate(ctx *hcl.EvalContext) error {
+	for _, validation := range v.Validations {
+		result, diags := validation.Condition.Value(ctx)
+		if diags.HasErrors() {
+			return fmt.Errorf("error evaluating validation of variable %q: %w", v.Name, diags)
+		}
+		if !result.IsKnown() || result.IsNull() || result.Type() != cty.Bool {
+			continue
+		}
+		if result.False() {
+			return fmt.Errorf("invalid value for variable %q: %s", v.Name, validation.ErrorMessage)
+		}
+	}
+	return nil
+}
+
+// Instances returns the addresses of the instances of all resources of the given type, sorted. It fails if the count of
+// one of the resources cannot be evaluated offline, or if one of them uses for_each.
+func (e *Evaluation) Instances(resourceType string) ([]string, error) {
+	var instances []string
+	for _, address := range e.module.ResourceTypes(resourceType) {
+		e.resource(address)
+		resource := e.module.Resources[address]
+		switch count := e.counts[address]; {
+		case count == unknownCount:
+			return nil, fmt.Errorf("instances of %s cannot be determined offline", address)
+		case resource.Count == nil:
+			instances = append(instances, address)
+		default:
+			for i := range count {
+				instances = append(instances, fmt.Sprintf("%s[%d]", address, i))
+			}
+		}
+	}
+	return instances, nil
+}
+
+// Output evaluates the value of an output
+func (e *Evaluation) Output(name string) (cty.Value, error) {
+	expr, ok := e.module.Outputs[name]
+	if !ok {
+		return cty.NilVal, fmt.Errorf("module %s has no output %q", e.module.Dir, name)
+	}
+	return e.eval(expr)
+}
+
+// Local evaluates the value of a local value
+func (e *Evaluation) Local(name string) (cty.Value, error) {
+	expr, ok := e.module.Locals[name]
+	if !ok {
+		return cty.NilVal, fmt.Errorf("module %s has no local %q", e.module.Dir, name)
+	}
+	return e.eval(expr)
+}
+
+func (e *Evaluation) eval(expr hcl.Expression) (cty.Value, error) {
+	value, diags := expr.Value(e.context(expr.Variables()))
+	if diags.HasErrors() {
+		return cty.DynamicVal, diags
+	}
+	return value, nil
+}
+
+// context returns an evaluation context holding the values of all locals and resources that traversals refer to
+func (e *Evaluation) context(traversals []hcl.Traversal) *hcl.EvalContext {
+	locals := map[string]cty.Value{}
+	resources := map[string]map[string]cty.Value{}
+	for _, traversal := range traversals {
+		if traversal.RootName() == "local" {
+			if name := stepName(traversal, 1); name != "" {
+				locals[name] = e.local(name)
+			}
+			continue
+		}
+		if address, _ := e.resourceReference(traversal); address != "" {
+			resource := e.module.Resources[address]
+			if resources[resource.Type] == nil {
+				resources[resource.Type] = map[string]cty.Value{}
+			}
+			resources[resource.Type][resource.Name] = e.resource(address)
+		}
+	}
+
+	variables := map[string]cty.Value{
+		"var":       e.variables,
+		"local":     cty.ObjectVal(locals),
+		"data":      cty.DynamicVal,
+		"module":    cty.DynamicVal,
+		"path":      cty.DynamicVal,
+		"terraform": cty.DynamicVal,
+	}
+	for resourceType, values := range resources {
+		variables[resourceType] = cty.ObjectVal(values)
+	}
+	return &hcl.EvalContext{Variables: variables, Functions: functions}
+}
+
+func (e *Evaluation) local(name string) cty.Value {
+	key := "local." + name
+	if value, ok := e.locals[name]; ok {
+		return value
+	}
+	expr, ok := e.module.Locals[name]
+	if !ok || e.evaluating[key] {
+		return cty.DynamicVal
+	}
+	e.evaluating[key] = true
+	defer delete(e.evaluating, key)
+
+	value, err := e.eval(expr)
+	if err != nil {
+		value = cty.DynamicVal
+	}
+	e.locals[name] = value
+	return value
+}
+
+// resource returns the value of a resource block: an instance object, a tuple of instance objects if the block has a
+// count, or an unknown value if the instances cannot be determined
+func (e *Evaluation) resource(address string) cty.Value {
+	if value, ok := e.resources[address]; ok {
+		return value
+	}
+	resource := e.module.Resources[address]
+	if e.evaluating[address] {
+		return cty.DynamicVal
+	}
+	e.evaluating[address] = true
+	defer delete(e.evaluating, address)
+
+	value, count := e.instances(resource)
+	e.resources[address] = value
+	e.counts[address] = count
+	return value
+}
+
+func (e *Evaluation) instances(resource *Resource) (cty.Value, int) {
+	address := resource.Address()
+	switch {
+	case resource.ForEach != nil:
+		return cty.DynamicVal, unknownCount
+	case resource.Count == nil:
+		return e.instance(address, address), 1
+	}
+
+	countValue, err := e.eval(resource.Count)
+	if err != nil || !countValue.IsWhollyKnown() || countValue.IsNull() || countValue.Type() != cty.Number {
+		return cty.DynamicVal, unknownCount
+	}
+	count, _ := countValue.AsBigFloat().Int64()
+	if count == 0 {
+		return cty.EmptyTupleVal, 0
+	}
+	instances := make([]cty.Value, count)
+	for i := range instances {
+		instances[i] = e.instance(address, fmt.Sprintf("%s[%d]", address, i))
+	}
+	return cty.TupleVal(instances), int(count)
+}
+
+func (e *Evaluation) instance(resourceAddress string, instanceAddress string) cty.Value {
+	attributes := map[string]cty.Value{}
+	for _, attribute := range e.attributes[resourceAddress] {
+		attributes[attribute] = cty.StringVal(instanceAddress + "." + attribute)
+	}
+	return cty.ObjectVal(attributes)
+}
+
+// resourceReference returns the resource address and attribute that a traversal refers to. The address is empty if
+// the traversal does not refer to a resource of the module, and the attribute is empty if the traversal refers to the
+// whole resource or instance.
+func (e *Evaluation) resourceReference(traversal hcl.Traversal) (address string, attribute string) {
+	name := stepName(traversal, 1)
+	if name == "" {
+		return "", ""
+	}
+	address = traversal.RootName() + "." + name
+	if _, ok := e.module.Resources[address]; !ok {
+		return "", ""
+	}
+	rest := traversal[2:]
+	if len(rest) > 0 {
+		if _, ok := rest[0].(hcl.TraverseIndex); ok {
+			rest = rest[1:]
+		}
+	}
+	return address, stepName(rest, 0)
+}
+
+// stepName returns the attribute name of the i-th step of a traversal, or an empty string if it is not an attribute
+func stepName(traversal hcl.Traversal, i int) string {
+	if i >= len(traversal) {
+		return ""
+	}
+	if attr, ok := traversal[i].(hcl.TraverseAttr); ok {
+		return attr.Name
+	}
+	return ""
+}
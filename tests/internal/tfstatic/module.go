@@ -0,0 +1,319 @@
+// Package tfstatic evaluates the configuration of a Terraform root module without providers or state, for tests that
+// must run offline. It is not a Terraform plan: it only evaluates variables, their validations, locals, outputs and the
+// count of resources, with the built-in functions that need no providers. Resource attributes evaluate to the address
+// they are read from. Whatever depends on providers, data sources, child modules or for_each is unknown, so a check
+// can only rely on values that are known. Moved blocks are resolved by address, as Terraform matches them against
+// the state, but the state itself is not modelled.
+package tfstatic
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/ext/typeexpr"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// Module is the parsed configuration of a Terraform root module. Child modules are not loaded.
+type Module struct {
+	Dir       string
+	Variables map[string]*Variable
+	Locals    map[string]hcl.Expression
+	Outputs   map[string]hcl.Expression
+	Resources map[string]*Resource // keyed by address, e.g. ibm_container_vpc_cluster.cluster
+	Moved     []Moved
+}
+
+// Variable is an input variable block
+type Variable struct {
+	Name string
+	// Type is the type constraint of the variable, cty.DynamicPseudoType if it has none
+	Type cty.Type
+	// Default is the default value of the variable, cty.DynamicVal if the variable is required
+	Default     cty.Value
+	Required    bool
+	Sensitive   bool
+	Validations []Validation
+	Range       hcl.Range
+}
+
+// Validation is a validation block of a variable
+type Validation struct {
+	Condition    hcl.Expression
+	ErrorMessage string
+}
+
+// Resource is a managed resource block
+type Resource struct {
+	Type    string
+	Name    string
+	Count   hcl.Expression // nil if the block has no count
+	ForEach hcl.Expression // nil if the block has no for_each
+}
+
+// Address returns the address of the resource block, without an instance key
+func (r *Resource) Address() string {
+	return r.Type + "." + r.Name
+}
+
+// Moved is a moved block of the module
+type Moved struct {
+	From string
+	To   string
+}
+
+// LoadModule parses all .tf files in dir
+func LoadModule(dir string) (*Module, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.tf"))
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no Terraform files found in %s", dir)
+	}
+
+	module := &Module{
+		Dir:       dir,
+		Variables: map[string]*Variable{},
+		Locals:    map[string]hcl.Expression{},
+		Outputs:   map[string]hcl.Expression{},
+		Resources: map[string]*Resource{},
+	}
+	parser := hclparse.NewParser()
+	for _, path := range paths {
+		src, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		file, diags := parser.ParseHCL(src, path)
+		if diags.HasErrors() {
+			return nil, diags
+		}
+		if err := module.addFile(file.Body.(*hclsyntax.Body)); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	return module, nil
+}
+
+func (m *Module) addFile(body *hclsyntax.Body) error {
+	for _, block := range body.Blocks {
+		switch block.Type {
+		case "variable":
+			variable, err := parseVariable(block)
+			if err != nil {
+				return err
+			}
+			m.Variables[variable.Name] = variable
+		case "locals":
+			for name, attr := range block.Body.Attributes {
+				m.Locals[name] = attr.Expr
+			}
+		case "output":
+			if attr, ok := block.Body.Attributes["value"]; ok {
+				m.Outputs[block.Labels[0]] = attr.Expr
+			}
+		case "resource":
+			resource := &Resource{Type: block.Labels[0], Name: block.Labels[1]}
+			if attr, ok := block.Body.Attributes["count"]; ok {
+				resource.Count = attr.Expr
+			}
+			if attr, ok := block.Body.Attributes["for_each"]; ok {
+				resource.ForEach = attr.Expr
+			}
+			m.Resources[resource.Address()] = resource
+		case "moved":
+			moved, err := parseMoved(block)
+			if err != nil {
+				return err
+			}
+			m.Moved = append(m.Moved, moved)
+		}
+	}
+	return nil
+}
+
+// ParseMoved returns the moved blocks of Terraform configuration src, e.g. an example of the documentation. The file
+// name is only used in errors.
+func ParseMoved(src []byte, filename string) ([]Moved, error) {
+	file, diags := hclparse.NewParser().ParseHCL(src, filename)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+	var moves []Moved
+	for _, block := range file.Body.(*hclsyntax.Body).Blocks {
+		if block.Type != "moved" {
+			continue
+		}
+		moved, err := parseMoved(block)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", filename, err)
+		}
+		moves = append(moves, moved)
+	}
+	return moves, nil
+}
+
+func parseMoved(block *hclsyntax.Block) (Moved, error) {
+	from, err := addressAttribute(block.Body, "from")
+	if err != nil {
+		return Moved{}, err
+	}
+	to, err := addressAttribute(block.Body, "to")
+	if err != nil {
+		return Moved{}, err
+	}
+	return Moved{From: from, To: to}, nil
+}
+
+func parseVariable(block *hclsyntax.Block) (*Variable, error) {
+	variable := &Variable{
+		Name:     block.Labels[0],
+		Type:     cty.DynamicPseudoType,
+		Default:  cty.DynamicVal,
+		Required: true,
+		Range:    block.DefRange(),
+	}
+	if attr, ok := block.Body.Attributes["type"]; ok {
+		ty, defaults, diags := typeexpr.TypeConstraintWithDefaults(attr.Expr)
+		if diags.HasErrors() {
+			return nil, diags
+		}
+		variable.Type = ty
+		if attr, ok := block.Body.Attributes["default"]; ok {
+			value, diags := attr.Expr.Value(nil)
+			if diags.HasErrors() {
+				return nil, diags
+			}
+			if defaults != nil {
+				value = defaults.Apply(value)
+			}
+			variable.Default, variable.Required = value, false
+		}
+	} else if attr, ok := block.Body.Attributes["default"]; ok {
+		value, diags := attr.Expr.Value(nil)
+		if diags.HasErrors() {
+			return nil, diags
+		}
+		variable.Default, variable.Required = value, false
+	}
+	if attr, ok := block.Body.Attributes["sensitive"]; ok {
+		value, diags := attr.Expr.Value(nil)
+		if diags.HasErrors() {
+			return nil, diags
+		}
+		variable.Sensitive = value.True()
+	}
+	for _, validation := range block.Body.Blocks {
+		if validation.Type != "validation" {
+			continue
+		}
+		condition, ok := validation.Body.Attributes["condition"]
+		if !ok {
+			return nil, fmt.Errorf("validation of variable %s has no condition", variable.Name)
+		}
+		rule := Validation{Condition: condition.Expr}
+		if attr, ok := validation.Body.Attributes["error_message"]; ok {
+			// messages interpolating the variable are kept unevaluated
+			if value, diags := attr.Expr.Value(nil); !diags.HasErrors() && value.Type() == cty.String && value.IsKnown() {
+				rule.ErrorMessage = value.AsString()
+			}
+		}
+		variable.Validations = append(variable.Validations, rule)
+	}
+	return variable, nil
+}
+
+// Moves reports whether the moved blocks of the module move the resource instance at from to the address to,
+// following chained moves. A block moving a whole resource moves each of its instances to the same instance key.
+func (m *Module) Moves(from string, to string) bool {
+	seen := map[string]bool{}
+	for address := from; !seen[address]; {
+		seen[address] = true
+		next := ""
+		for _, moved := range m.Moved {
+			if target, ok := moved.apply(address); ok {
+				next = target
+			}
+		}
+		if next == "" {
+			return false
+		}
+		if next == to {
+			return true
+		}
+		address = next
+	}
+	return false
+}
+
+// apply returns where the block moves the resource instance at address, and whether it moves it
+func (m Moved) apply(address string) (string, bool) {
+	if m.From == address {
+		return m.To, true
+	}
+	// a block for a whole resource keeps the instance key
+	if key, ok := strings.CutPrefix(address, m.From); ok && strings.HasPrefix(key, "[") && !strings.HasSuffix(m.To, "]") {
+		return m.To + key, true
+	}
+	return "", false
+}
+
+// ResourceTypes returns the addresses of all resource blocks of the given type, sorted
+func (m *Module) ResourceTypes(resourceType string) []string {
+	var addresses []string
+	for address, resource := range m.Resources {
+		if resource.Type == resourceType {
+			addresses = append(addresses, address)
+		}
+	}
+	slices.Sort(addresses)
+	return addresses
+}
+
+func addressAttribute(body *hclsyntax.Body, name string) (string, error) {
+	attr, ok := body.Attributes[name]
+	if !ok {
+		return "", fmt.Errorf("moved block at %s has no %s", body.SrcRange, name)
+	}
+	traversal, diags := hcl.AbsTraversalForExpr(attr.Expr)
+	if diags.HasErrors() {
+		return "", diags
+	}
+	return traversalString(traversal), nil
+}
+
+// traversalString formats a traversal the way Terraform prints resource addresses
+func traversalString(traversal hcl.Traversal) string {
+	var b strings.Builder
+	for i, step := range traversal {
+		switch step := step.(type) {
+		case hcl.TraverseRoot:
+			b.WriteString(step.Name)
+		case hcl.TraverseAttr:
+			if i > 0 {
+				b.WriteString(".")
+			}
+			b.WriteString(step.Name)
+		case hcl.TraverseIndex:
+			b.WriteString("[" + keyString(step.Key) + "]")
+		}
+	}
+	return b.String()
+}
+
+func keyString(key cty.Value) string {
+	if key.Type() == cty.String {
+		return fmt.Sprintf("%q", key.AsString())
+	}
+	if key.Type() == cty.Number {
+		return key.AsBigFloat().Text('f', -1)
+	}
+	return key.GoString()
+}
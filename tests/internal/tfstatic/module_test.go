@@ -0,0 +1,159 @@
+package tfstatic
+
+import (
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zclconf/go-cty/cty"
+)
+
+func loadTestModule(t *testing.T) *Module {
+	module, err := LoadModule("testdata/variants")
+	require.NoError(t, err)
+	return module
+}
+
+func TestLoadModule(t *testing.T) {
+	module := loadTestModule(t)
+
+	assert.Equal(t, cty.False, module.Variables["create_b"].Default)
+	assert.Equal(t, cty.Bool, module.Variables["create_b"].Type)
+	assert.False(t, module.Variables["create_b"].Required)
+	assert.Equal(t, cty.DynamicVal, module.Variables["name"].Default)
+	assert.True(t, module.Variables["name"].Required)
+	assert.Equal(t, 6, module.Variables["name"].Range.Start.Line)
+	require.Len(t, module.Variables["name"].Validations, 1)
+	assert.Equal(t, "Name must be at most 8 lowercase letters.", module.Variables["name"].Validations[0].ErrorMessage)
+	assert.Equal(t, []string{"null_resource.a", "null_resource.b", "null_resource.per_zone", "null_resource.single"}, module.ResourceTypes("null_resource"))
+	assert.Equal(t, []Moved{{From: "null_resource.old[0]", To: "null_resource.a[0]"}, {From: "null_resource.a[0]", To: "null_resource.b[0]"}, {From: "null_resource.legacy", To: "null_resource.a"}}, module.Moved)
+
+	_, err := LoadModule("testdata")
+	assert.ErrorContains(t, err, "no Terraform files found")
+}
+
+func TestModuleMoves(t *testing.T) {
+	module := loadTestModule(t)
+
+	assert.True(t, module.Moves("null_resource.a[0]", "null_resource.b[0]"))
+	assert.True(t, module.Moves("null_resource.old[0]", "null_resource.b[0]"))
+	assert.False(t, module.Moves("null_resource.b[0]", "null_resource.a[0]"))
+	assert.True(t, module.Moves("null_resource.legacy[1]", "null_resource.a[1]"), "a resource moves with its instance key")
+	assert.True(t, module.Moves("null_resource.legacy[0]", "null_resource.b[0]"))
+	assert.False(t, module.Moves("null_resource.legacy_old[0]", "null_resource.a[0]"))
+}
+
+func TestParseMoved(t *testing.T) {
+	moves, err := ParseMoved([]byte(`
+moved {
+  from = module.ocp_base.ibm_container_vpc_cluster.cluster[0]
+  to   = module.ocp_base.ibm_container_vpc_cluster.autoscaling_cluster[0]
+}
+
+resource "null_resource" "a" {}
+`), "example.tf")
+	require.NoError(t, err)
+	assert.Equal(t, []Moved{{From: "module.ocp_base.ibm_container_vpc_cluster.cluster[0]", To: "module.ocp_base.ibm_container_vpc_cluster.autoscaling_cluster[0]"}}, moves)
+
+	_, err = ParseMoved([]byte(`moved {
+  from = null_resource.a
+}`), "example.tf")
+	assert.ErrorContains(t, err, "example.tf")
+}
+
+func TestEvaluate(t *testing.T) {
+	module := loadTestModule(t)
+
+	testCases := []struct {
+		name     string
+		createB  bool
+		instance string
+	}{
+		{name: "a", createB: false, instance: "null_resource.a[0]"},
+		{name: "b", createB: true, instance: "null_resource.b[0]"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			evaluation, err := module.Evaluate(map[string]cty.Value{"create_b": cty.BoolVal(tc.createB), "name": cty.StringVal("test")})
+			require.NoError(t, err)
+
+			id, err := evaluation.Output("id")
+			require.NoError(t, err)
+			assert.Equal(t, cty.StringVal(tc.instance+".id"), id)
+
+			name, err := evaluation.Output("name")
+			require.NoError(t, err)
+			assert.Equal(t, cty.StringVal("test-"+tc.instance+".id"), name)
+
+			_, err = evaluation.Instances("null_resource")
+			assert.ErrorContains(t, err, "instances of null_resource.per_zone cannot be determined offline")
+		})
+	}
+}
+
+func TestEvaluateUnknownValues(t *testing.T) {
+	module := loadTestModule(t)
+	delete(module.Resources, "null_resource.per_zone")
+
+	evaluation, err := module.Evaluate(nil)
+	require.NoError(t, err)
+
+	instances, err := evaluation.Instances("null_resource")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"null_resource.a[0]", "null_resource.single"}, instances)
+
+	zones, err := evaluation.Output("zones")
+	require.NoError(t, err)
+	assert.False(t, zones.IsKnown())
+
+	name, err := evaluation.Output("name")
+	require.NoError(t, err)
+	assert.False(t, name.IsKnown(), "name depends on a required variable that was not given")
+
+	_, err = module.Evaluate(map[string]cty.Value{"name": cty.StringVal("too-long-name")})
+	assert.EqualError(t, err, `invalid value for variable "name": Name must be at most 8 lowercase letters.`)
+	_, err = module.Evaluate(map[string]cty.Value{"region": cty.StringVal("us-south")})
+	assert.ErrorContains(t, err, `has no variable "region"`)
+	_, err = evaluation.Output("missing")
+	assert.ErrorContains(t, err, `has no output "missing"`)
+}
+
+func TestEvaluateUnknownFunction(t *testing.T) {
+	module := loadTestModule(t)
+	condition, diags := hclsyntax.ParseExpression([]byte(`startswith(var.name, "a")`), "main.tf", hcl.InitialPos)
+	require.False(t, diags.HasErrors())
+	module.Variables["name"].Validations[0].Condition = condition
+
+	_, err := module.Evaluate(map[string]cty.Value{"name": cty.StringVal("test")})
+	assert.ErrorContains(t, err, `error evaluating validation of variable "name"`)
+}
+
+func TestLengthFunc(t *testing.T) {
+	length, err := lengthFunc.Call([]cty.Value{cty.StringVal("mini")})
+	require.NoError(t, err)
+	assert.True(t, length.Equals(cty.NumberIntVal(4)).True())
+
+	length, err = lengthFunc.Call([]cty.Value{cty.TupleVal([]cty.Value{cty.True, cty.False})})
+	require.NoError(t, err)
+	assert.True(t, length.Equals(cty.NumberIntVal(2)).True())
+}
+
+func TestBoolReduceFuncs(t *testing.T) {
+	list := func(values ...cty.Value) []cty.Value { return []cty.Value{cty.ListVal(values)} }
+	call := func(name string, args []cty.Value) cty.Value {
+		value, err := functions[name].Call(args)
+		require.NoError(t, err)
+		return value
+	}
+
+	assert.Equal(t, cty.True, call("alltrue", list(cty.True, cty.True)))
+	assert.Equal(t, cty.False, call("alltrue", list(cty.True, cty.False, cty.UnknownVal(cty.Bool))))
+	assert.False(t, call("alltrue", list(cty.True, cty.UnknownVal(cty.Bool))).IsKnown())
+	assert.Equal(t, cty.True, call("alltrue", []cty.Value{cty.ListValEmpty(cty.Bool)}))
+	assert.Equal(t, cty.True, call("anytrue", list(cty.False, cty.True)))
+	assert.Equal(t, cty.False, call("anytrue", list(cty.False, cty.NullVal(cty.Bool))))
+	assert.False(t, call("anytrue", list(cty.False, cty.UnknownVal(cty.Bool))).IsKnown())
+}
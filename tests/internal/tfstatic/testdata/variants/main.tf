@@ -0,0 +1,59 @@
+variable "create_b" {
+  type    = bool
+  default = false
+}
+
+variable "name" {
+  type = string
+
+  validation {
+    condition     = can(regex("^[a-z]{1,8}$", var.name))
+    error_message = "Name must be at most 8 lowercase letters."
+  }
+}
+
+locals {
+  id   = var.create_b ? try(null_resource.b[0].id, null) : try(null_resource.a[0].id, null)
+  name = "${var.name}-${local.id}"
+}
+
+resource "null_resource" "a" {
+  count = var.create_b ? 0 : 1
+}
+
+resource "null_resource" "b" {
+  count = var.create_b ? 1 : 0
+}
+
+resource "null_resource" "single" {}
+
+resource "null_resource" "per_zone" {
+  for_each = toset(["1", "2"])
+}
+
+output "id" {
+  value = local.id
+}
+
+output "name" {
+  value = local.name
+}
+
+output "zones" {
+  value = data.example.zones.names
+}
+
+moved {
+  from = null_resource.old[0]
+  to   = null_resource.a[0]
+}
+
+moved {
+  from = null_resource.a[0]
+  to   = null_resource.b[0]
+}
+
+moved {
+  from = null_resource.legacy
+  to   = null_resource.a
+}
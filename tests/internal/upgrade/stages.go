@@ -0,0 +1,48 @@
+package upgrade
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Stage is a named step of an upgrade test and how long it took
+type Stage struct {
+	Name     string
+	Duration time.Duration
+	Err      error
+}
+
+// Stages runs the steps of an upgrade test and records how long each one took
+type Stages struct {
+	Stages []Stage
+	now    func() time.Time
+}
+
+// Run runs fn as the stage name and records its duration and result
+func (s *Stages) Run(name string, fn func() error) error {
+	now := s.now
+	if now == nil {
+		now = time.Now
+	}
+	start := now()
+	err := fn()
+	s.Stages = append(s.Stages, Stage{Name: name, Duration: now().Sub(start), Err: err})
+	return err
+}
+
+// String formats the recorded stages as a table, one stage per line
+func (s *Stages) String() string {
+	var b strings.Builder
+	var total time.Duration
+	for _, stage := range s.Stages {
+		result := "ok"
+		if stage.Err != nil {
+			result = "failed"
+		}
+		fmt.Fprintf(&b, "%-30s %10s  %s\n", stage.Name, stage.Duration.Round(time.Second), result)
+		total += stage.Duration
+	}
+	fmt.Fprintf(&b, "%-30s %10s\n", "total", total.Round(time.Second))
+	return b.String()
+}
@@ -0,0 +1,106 @@
+// Package upgrade drives OpenShift minor version upgrades of the clusters under test and records how long each stage
+// of an upgrade took.
+package upgrade
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/terraform-ibm-modules/terraform-ibm-base-ocp-vpc/internal/ibmcloud"
+	"github.com/terraform-ibm-modules/terraform-ibm-base-ocp-vpc/internal/verify"
+)
+
+// WorkerAPI is the subset of the Kubernetes Service API used to update workers
+type WorkerAPI interface {
+	ListWorkers(ctx context.Context, cluster string) ([]ibmcloud.Worker, error)
+	ReplaceWorker(ctx context.Context, cluster string, workerID string, update bool) error
+}
+
+// Path returns the second newest and the newest version of a list of supported OpenShift versions, so that a cluster
+// can be created at N-1 and upgraded to N
+func Path(versions []string) (from string, to string, err error) {
+	type parsedVersion struct {
+		name    string
+		version ibmcloud.KubeVersion
+	}
+	var parsed []parsedVersion
+	for _, v := range versions {
+		version, err := ibmcloud.ParseKubeVersion(v)
+		if err != nil {
+			return "", "", err
+		}
+		parsed = append(parsed, parsedVersion{name: v, version: version})
+	}
+	slices.SortFunc(parsed, func(a, b parsedVersion) int {
+		switch {
+		case a.version.Less(b.version):
+			return -1
+		case b.version.Less(a.version):
+			return 1
+		}
+		return 0
+	})
+	parsed = slices.CompactFunc(parsed, func(a, b parsedVersion) bool { return a.version == b.version })
+	if len(parsed) < 2 {
+		return "", "", fmt.Errorf("need at least two OpenShift versions to test an upgrade, got %v", versions)
+	}
+	return parsed[len(parsed)-2].name, parsed[len(parsed)-1].name, nil
+}
+
+// UpdateWorkers replaces every worker that does not run version with an updated one. Workers are replaced one at a
+// time, and each replacement must be deployed and healthy before the next worker is replaced, so the cluster keeps
+// enough capacity to serve ingress traffic during the update.
+func UpdateWorkers(ctx context.Context, api WorkerAPI, cluster string, version string, interval time.Duration) error {
+	want, err := ibmcloud.ParseKubeVersion(version)
+	if err != nil {
+		return err
+	}
+	workers, err := api.ListWorkers(ctx, cluster)
+	if err != nil {
+		return fmt.Errorf("error listing workers of cluster %s: %w", cluster, err)
+	}
+
+	for _, worker := range workers {
+		if actual, err := ibmcloud.ParseKubeVersion(worker.KubeVersion.Actual); err == nil && actual == want {
+			continue
+		}
+		if err := api.ReplaceWorker(ctx, cluster, worker.ID, true); err != nil {
+			return fmt.Errorf("error replacing worker %s: %w", worker.ID, err)
+		}
+		err := verify.Eventually(ctx, interval, func(ctx context.Context) error {
+			return workerReplaced(ctx, api, cluster, worker.ID, len(workers))
+		})
+		if err != nil {
+			return fmt.Errorf("worker %s was not replaced: %w", worker.ID, err)
+		}
+	}
+	return nil
+}
+
+// workerReplaced checks that the replaced worker is gone and that the cluster is back to count deployed and healthy
+// workers
+func workerReplaced(ctx context.Context, api WorkerAPI, cluster string, replaced string, count int) error {
+	workers, err := api.ListWorkers(ctx, cluster)
+	if err != nil {
+		return err
+	}
+	var notReady []string
+	for _, worker := range workers {
+		if worker.ID == replaced {
+			return fmt.Errorf("worker %s is still %s", replaced, worker.Lifecycle.ActualState)
+		}
+		if worker.Lifecycle.ActualState != "deployed" || worker.Health.State != "normal" {
+			notReady = append(notReady, fmt.Sprintf("%s (%s, %s)", worker.ID, worker.Lifecycle.ActualState, worker.Health.State))
+		}
+	}
+	if len(notReady) > 0 {
+		return fmt.Errorf("workers not ready: %s", strings.Join(notReady, ", "))
+	}
+	if len(workers) < count {
+		return fmt.Errorf("cluster has %d workers, waiting for %d", len(workers), count)
+	}
+	return nil
+}
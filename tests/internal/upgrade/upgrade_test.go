@@ -0,0 +1,123 @@
+package upgrade
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/terraform-ibm-modules/terraform-ibm-base-ocp-vpc/internal/ibmcloud"
+)
+
+func TestPath(t *testing.T) {
+	from, to, err := Path([]string{"4.18", "4.16", "4.17", "4.19"})
+	require.NoError(t, err)
+	assert.Equal(t, "4.18", from)
+	assert.Equal(t, "4.19", to)
+
+	_, _, err = Path([]string{"4.18", "4.18"})
+	assert.ErrorContains(t, err, "need at least two OpenShift versions")
+
+	_, _, err = Path([]string{"4.18", "latest"})
+	assert.ErrorContains(t, err, `invalid kube version "latest"`)
+}
+
+// fakeWorkerAPI replaces a worker by removing it and adding an updated replacement that becomes ready on the next list
+type fakeWorkerAPI struct {
+	mu       sync.Mutex
+	version  string
+	workers  []ibmcloud.Worker
+	replaced []string
+}
+
+func (f *fakeWorkerAPI) ListWorkers(_ context.Context, _ string) ([]ibmcloud.Worker, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	workers := append([]ibmcloud.Worker(nil), f.workers...)
+	for i := range f.workers {
+		f.workers[i].Lifecycle.ActualState = "deployed"
+		f.workers[i].Health.State = "normal"
+	}
+	return workers, nil
+}
+
+func (f *fakeWorkerAPI) ReplaceWorker(_ context.Context, _ string, workerID string, update bool) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !update {
+		return errors.New("expected an update")
+	}
+	for i, worker := range f.workers {
+		if worker.ID == workerID {
+			f.workers[i] = ibmcloud.Worker{ID: workerID + "-new", Lifecycle: ibmcloud.WorkerLifecycle{ActualState: "provisioning"}, KubeVersion: ibmcloud.WorkerKubeVersion{Actual: f.version}}
+			f.replaced = append(f.replaced, workerID)
+			return nil
+		}
+	}
+	return errors.New("worker not found")
+}
+
+func readyWorker(id, version string) ibmcloud.Worker {
+	return ibmcloud.Worker{
+		ID:          id,
+		Lifecycle:   ibmcloud.WorkerLifecycle{ActualState: "deployed"},
+		Health:      ibmcloud.WorkerHealth{State: "normal"},
+		KubeVersion: ibmcloud.WorkerKubeVersion{Actual: version},
+	}
+}
+
+func TestUpdateWorkers(t *testing.T) {
+	api := &fakeWorkerAPI{
+		version: "4.18.3_openshift",
+		workers: []ibmcloud.Worker{
+			readyWorker("w-1", "4.17.20_openshift"),
+			readyWorker("w-2", "4.18.3_openshift"),
+			readyWorker("w-3", "4.17.20_openshift"),
+		},
+	}
+
+	require.NoError(t, UpdateWorkers(context.Background(), api, "my-cluster", "4.18", time.Millisecond))
+	assert.Equal(t, []string{"w-1", "w-3"}, api.replaced)
+}
+
+func TestUpdateWorkersTimeout(t *testing.T) {
+	api := &stuckWorkerAPI{workers: []ibmcloud.Worker{readyWorker("w-1", "4.17.20_openshift")}}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	err := UpdateWorkers(ctx, api, "my-cluster", "4.18", time.Millisecond)
+	assert.ErrorContains(t, err, "worker w-1 was not replaced: worker w-1 is still deployed")
+}
+
+// stuckWorkerAPI accepts replace requests but never replaces the worker
+type stuckWorkerAPI struct {
+	workers []ibmcloud.Worker
+}
+
+func (s *stuckWorkerAPI) ListWorkers(_ context.Context, _ string) ([]ibmcloud.Worker, error) {
+	return s.workers, nil
+}
+
+func (s *stuckWorkerAPI) ReplaceWorker(_ context.Context, _ string, _ string, _ bool) error {
+	return nil
+}
+
+func TestStages(t *testing.T) {
+	clock := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	stages := &Stages{now: func() time.Time {
+		clock = clock.Add(90 * time.Second)
+		return clock
+	}}
+
+	require.NoError(t, stages.Run("apply", func() error { return nil }))
+	require.Error(t, stages.Run("upgrade", func() error { return errors.New("boom") }))
+
+	require.Len(t, stages.Stages, 2)
+	assert.Equal(t, 90*time.Second, stages.Stages[0].Duration)
+	assert.Equal(t, "apply                               1m30s  ok\n"+
+		"upgrade                             1m30s  failed\n"+
+		"total                                3m0s\n", stages.String())
+}
@@ -0,0 +1,297 @@
+package verify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"text/template"
+	"time"
+
+	"github.com/terraform-ibm-modules/terraform-ibm-base-ocp-vpc/internal/kube"
+)
+
+// Where the cluster-autoscaler add-on reads its configuration, which the module writes cluster_autoscaler_config and
+// the autoscaling worker pools to
+const (
+	autoscalerConfigMap       = "iks-ca-configmap"
+	autoscalerConfigNamespace = "kube-system"
+)
+
+// defaultScaleDownDelay is the scaleDownUnneededTime and scaleDownDelayAfterAdd of the add-on when they are not set
+const defaultScaleDownDelay = 10 * time.Minute
+
+// autoscalerLoadName names the deployment of the autoscaler load
+const autoscalerLoadName = "autoscaler-load"
+
+// autoscalerLoadManifest runs one replica per node of the pool. The required anti-affinity keeps every replica on a
+// node of its own, so replicas beyond the size of the pool stay pending until the autoscaler adds nodes, whatever the
+// machine type of the pool.
+var autoscalerLoadManifest = template.Must(template.New("autoscaler").Parse(`apiVersion: v1
+kind: Namespace
+metadata:
+  name: {{.Namespace}}
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: {{.Name}}
+  namespace: {{.Namespace}}
+spec:
+  replicas: {{.Replicas}}
+  selector:
+    matchLabels:
+      app: {{.Name}}
+  template:
+    metadata:
+      labels:
+        app: {{.Name}}
+    spec:
+      nodeSelector:
+        {{.PoolLabel}}: "{{.Pool}}"
+      affinity:
+        podAntiAffinity:
+          requiredDuringSchedulingIgnoredDuringExecution:
+            - labelSelector:
+                matchLabels:
+                  app: {{.Name}}
+              topologyKey: kubernetes.io/hostname
+      terminationGracePeriodSeconds: 0
+      containers:
+        - name: load
+          image: {{.Image}}
+          command: ["sleep", "infinity"]
+          resources:
+            requests:
+              cpu: 10m
+              memory: 16Mi
+          securityContext:
+            allowPrivilegeEscalation: false
+            runAsNonRoot: true
+            capabilities:
+              drop: ["ALL"]
+            seccompProfile:
+              type: RuntimeDefault
+`))
+
+// AutoscalerPool is the autoscaling configuration of a worker pool. The sizes are per zone.
+type AutoscalerPool struct {
+	Name    string `json:"name"`
+	MinSize int    `json:"minSize"`
+	MaxSize int    `json:"maxSize"`
+	Enabled bool   `json:"enabled"`
+}
+
+// AutoscalerConfig is the configuration of the cluster-autoscaler add-on
+type AutoscalerConfig struct {
+	Pools                  []AutoscalerPool
+	ScaleDownUnneededTime  time.Duration
+	ScaleDownDelayAfterAdd time.Duration
+}
+
+// ScaleDownDelay is how long the autoscaler waits before it removes a node that it added and that is no longer needed
+func (c AutoscalerConfig) ScaleDownDelay() time.Duration {
+	return max(c.ScaleDownUnneededTime, c.ScaleDownDelayAfterAdd)
+}
+
+// AutoscalerLoad is the deployment that AutoscalerScaling runs on the autoscaled pool
+type AutoscalerLoad struct {
+	// Namespace is created for the load, and deleted with it
+	Namespace string
+	// Image only has to provide sleep
+	Image string
+	Pool  string
+	// ExtraNodes is how many nodes the load needs beyond the current size of the pool
+	ExtraNodes int
+	// ScaleDownGrace is how long removing the nodes may take after the scale down delay
+	ScaleDownGrace time.Duration
+}
+
+// AutoscalerReport is what AutoscalerScaling observed
+type AutoscalerReport struct {
+	Pool           AutoscalerPool
+	Zones          int
+	ScaleDownDelay time.Duration
+	InitialNodes   int
+	PeakNodes      int
+	FinalNodes     int
+	ScaleUp        time.Duration
+	ScaleDown      time.Duration
+}
+
+func (r AutoscalerReport) String() string {
+	return fmt.Sprintf("pool %s (%d to %d nodes per zone in %d zones): scaled up from %d to %d nodes in %s, down to %d nodes in %s (scale down delay %s)",
+		r.Pool.Name, r.Pool.MinSize, r.Pool.MaxSize, r.Zones, r.InitialNodes, r.PeakNodes, r.ScaleUp.Round(time.Second), r.FinalNodes, r.ScaleDown.Round(time.Second), r.ScaleDownDelay)
+}
+
+// poolNodes and loadDeployment are the subsets of the Kubernetes objects read by the autoscaler check
+type poolNodes struct {
+	Items []struct {
+		Metadata struct {
+			Labels map[string]string `json:"labels"`
+		} `json:"metadata"`
+		Status struct {
+			Conditions []struct {
+				Type   string `json:"type"`
+				Status string `json:"status"`
+			} `json:"conditions"`
+		} `json:"status"`
+	} `json:"items"`
+}
+
+type loadDeployment struct {
+	Status struct {
+		AvailableReplicas int `json:"availableReplicas"`
+	} `json:"status"`
+}
+
+// ReadAutoscalerConfig reads the configuration of the cluster-autoscaler add-on
+func ReadAutoscalerConfig(ctx context.Context, kubectl kube.Runner) (AutoscalerConfig, error) {
+	config := AutoscalerConfig{ScaleDownUnneededTime: defaultScaleDownDelay, ScaleDownDelayAfterAdd: defaultScaleDownDelay}
+	var configMap struct {
+		Data map[string]string `json:"data"`
+	}
+	if err := kube.GetJSON(ctx, kubectl, &configMap, "configmap", autoscalerConfigMap, "--namespace", autoscalerConfigNamespace); err != nil {
+		return config, fmt.Errorf("error getting the autoscaler config: %w", err)
+	}
+	if err := json.Unmarshal([]byte(configMap.Data["workerPoolsConfig.json"]), &config.Pools); err != nil {
+		return config, fmt.Errorf("error parsing workerPoolsConfig.json of %s: %w", autoscalerConfigMap, err)
+	}
+	for _, setting := range []struct {
+		key   string
+		value *time.Duration
+	}{
+		{key: "scaleDownUnneededTime", value: &config.ScaleDownUnneededTime},
+		{key: "scaleDownDelayAfterAdd", value: &config.ScaleDownDelayAfterAdd},
+	} {
+		if configMap.Data[setting.key] == "" {
+			continue
+		}
+		d, err := time.ParseDuration(configMap.Data[setting.key])
+		if err != nil {
+			return config, fmt.Errorf("error parsing %s of %s: %w", setting.key, autoscalerConfigMap, err)
+		}
+		*setting.value = d
+	}
+	return config, nil
+}
+
+// AutoscalerScaling deploys a load that needs more nodes than the pool has, and waits every interval for the autoscaler
+// to add them without growing the pool beyond its maximum size. It then deletes the load and waits for the pool to
+// shrink back to its initial size within the configured scale down delay and the grace period. The load is deleted
+// afterwards, also when the check fails.
+func AutoscalerScaling(ctx context.Context, kubectl kube.Runner, load AutoscalerLoad, interval time.Duration) (report AutoscalerReport, err error) {
+	config, err := ReadAutoscalerConfig(ctx, kubectl)
+	if err != nil {
+		return report, err
+	}
+	for _, pool := range config.Pools {
+		if pool.Name == load.Pool {
+			report.Pool = pool
+		}
+	}
+	if !report.Pool.Enabled {
+		return report, fmt.Errorf("autoscaling is not enabled for pool %s in %s", load.Pool, autoscalerConfigMap)
+	}
+	report.ScaleDownDelay = config.ScaleDownDelay()
+
+	initial, zones, err := countPoolNodes(ctx, kubectl, load.Pool)
+	if err != nil {
+		return report, err
+	}
+	report.InitialNodes, report.PeakNodes, report.FinalNodes, report.Zones = initial, initial, initial, zones
+	maxNodes := report.Pool.MaxSize * zones
+	target := initial + load.ExtraNodes
+	if target > maxNodes {
+		return report, fmt.Errorf("pool %s has %d nodes and can grow to %d, which is not enough for %d more", load.Pool, initial, maxNodes, load.ExtraNodes)
+	}
+
+	var manifest bytes.Buffer
+	if err := autoscalerLoadManifest.Execute(&manifest, struct {
+		AutoscalerLoad
+		Name      string
+		PoolLabel string
+		Replicas  int
+	}{load, autoscalerLoadName, workerPoolLabel, target}); err != nil {
+		return report, err
+	}
+
+	defer func() {
+		// ctx may be done by now, which must not leave the load behind and the pool scaled up
+		cleanupCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), workloadCleanupTimeout)
+		defer cancel()
+		if _, deleteErr := kubectl.Kubectl(cleanupCtx, "", "delete", "namespace", load.Namespace, "--ignore-not-found", "--wait=true"); deleteErr != nil {
+			err = errors.Join(err, fmt.Errorf("error deleting the autoscaler load: %w", deleteErr))
+		}
+	}()
+	start := time.Now()
+	if _, err := kubectl.Kubectl(ctx, manifest.String(), "apply", "--filename", "-"); err != nil {
+		return report, fmt.Errorf("error deploying the autoscaler load: %w", err)
+	}
+
+	var errs []error
+	err = Eventually(ctx, interval, func(ctx context.Context) error {
+		ready, _, err := countPoolNodes(ctx, kubectl, load.Pool)
+		if err != nil {
+			return err
+		}
+		report.PeakNodes = max(report.PeakNodes, ready)
+		var deployment loadDeployment
+		if err := kube.GetJSON(ctx, kubectl, &deployment, "deployment", autoscalerLoadName, "--namespace", load.Namespace); err != nil {
+			return err
+		}
+		if ready < target || deployment.Status.AvailableReplicas < target {
+			return fmt.Errorf("pool %s has %d of %d ready nodes for %d of %d available replicas", load.Pool, ready, target, deployment.Status.AvailableReplicas, target)
+		}
+		return nil
+	})
+	report.ScaleUp = time.Since(start)
+	if err != nil {
+		return report, fmt.Errorf("autoscaler did not scale up: %w", err)
+	}
+	if report.PeakNodes > maxNodes {
+		errs = append(errs, fmt.Errorf("pool %s grew to %d nodes, beyond its maximum of %d", load.Pool, report.PeakNodes, maxNodes))
+	}
+
+	start = time.Now()
+	if _, err := kubectl.Kubectl(ctx, "", "delete", "namespace", load.Namespace, "--wait=true"); err != nil {
+		return report, errors.Join(append(errs, fmt.Errorf("error deleting the autoscaler load: %w", err))...)
+	}
+	limit := report.ScaleDownDelay + load.ScaleDownGrace
+	scaleDownCtx, cancel := context.WithTimeout(ctx, limit-time.Since(start))
+	defer cancel()
+	err = Eventually(scaleDownCtx, interval, func(ctx context.Context) error {
+		var err error
+		if report.FinalNodes, _, err = countPoolNodes(ctx, kubectl, load.Pool); err != nil {
+			return err
+		}
+		if report.FinalNodes > initial {
+			return fmt.Errorf("pool %s has %d nodes, expected %d", load.Pool, report.FinalNodes, initial)
+		}
+		return nil
+	})
+	report.ScaleDown = time.Since(start)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("autoscaler did not scale down within %s: %w", limit, err))
+	}
+	return report, errors.Join(errs...)
+}
+
+// countPoolNodes returns how many nodes of the pool are ready, and how many zones the pool spans
+func countPoolNodes(ctx context.Context, kubectl kube.Runner, pool string) (ready int, zones int, err error) {
+	var nodes poolNodes
+	if err := kube.GetJSON(ctx, kubectl, &nodes, "nodes", "--selector", workerPoolLabel+"="+pool); err != nil {
+		return 0, 0, fmt.Errorf("error listing nodes of pool %s: %w", pool, err)
+	}
+	seen := map[string]bool{}
+	for _, node := range nodes.Items {
+		seen[node.Metadata.Labels[zoneLabel]] = true
+		for _, c := range node.Status.Conditions {
+			if c.Type == "Ready" && c.Status == "True" {
+				ready++
+			}
+		}
+	}
+	return ready, len(seen), nil
+}
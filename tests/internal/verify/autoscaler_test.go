@@ -0,0 +1,118 @@
+package verify
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/terraform-ibm-modules/terraform-ibm-base-ocp-vpc/internal/kube/kubetest"
+)
+
+const testAutoscalerConfig = `{"data": {
+	"workerPoolsConfig.json": "[{\"name\":\"default\",\"minSize\":1,\"maxSize\":2,\"enabled\":true},{\"name\":\"zone-2\",\"minSize\":1,\"maxSize\":1,\"enabled\":false}]",
+	"scaleDownUnneededTime": "50ms",
+	"scaleDownDelayAfterAdd": "20ms"
+}}`
+
+// autoscaledPool simulates a pool of nodes in two zones that grows by one node per poll while the load is deployed,
+// and shrinks by one node per poll once it is deleted, unless it is stuck
+type autoscaledPool struct {
+	nodes   int
+	target  int
+	initial int
+	stuck   bool
+}
+
+func (p *autoscaledPool) runner(config string) *kubetest.Runner {
+	runner := kubetest.NewRunner()
+	runner.OnOutput("get configmap iks-ca-configmap --namespace kube-system", config)
+	runner.On("get nodes --selector ibm-cloud.kubernetes.io/worker-pool-name=default", func([]string, string) (string, error) {
+		var nodes []string
+		for i := range p.nodes {
+			nodes = append(nodes, fmt.Sprintf(`{"metadata": {"labels": {"topology.kubernetes.io/zone": "us-south-%d"}}, "status": {"conditions": [{"type": "Ready", "status": "True"}]}}`, i%2+1))
+		}
+		switch {
+		case p.nodes < p.target:
+			p.nodes++
+		case p.target == 0 && p.nodes > p.initial && !p.stuck:
+			p.nodes--
+		}
+		return `{"items": [` + strings.Join(nodes, ",") + `]}`, nil
+	})
+	runner.On("apply --filename -", func([]string, string) (string, error) {
+		p.target = p.initial + 1
+		return "namespace/autoscaler created", nil
+	})
+	runner.On("get deployment autoscaler-load --namespace autoscaler", func([]string, string) (string, error) {
+		return fmt.Sprintf(`{"status": {"availableReplicas": %d}}`, p.nodes), nil
+	})
+	runner.On("delete namespace autoscaler", func([]string, string) (string, error) {
+		p.target = 0
+		return `namespace "autoscaler" deleted`, nil
+	})
+	return runner
+}
+
+func TestReadAutoscalerConfig(t *testing.T) {
+	runner := (&autoscaledPool{}).runner(testAutoscalerConfig)
+	config, err := ReadAutoscalerConfig(context.Background(), runner)
+	require.NoError(t, err)
+	assert.Equal(t, []AutoscalerPool{{Name: "default", MinSize: 1, MaxSize: 2, Enabled: true}, {Name: "zone-2", MinSize: 1, MaxSize: 1}}, config.Pools)
+	assert.Equal(t, 50*time.Millisecond, config.ScaleDownDelay())
+
+	runner.OnOutput("get configmap iks-ca-configmap --namespace kube-system", `{"data": {"workerPoolsConfig.json": "[]"}}`)
+	config, err = ReadAutoscalerConfig(context.Background(), runner)
+	require.NoError(t, err)
+	assert.Equal(t, 10*time.Minute, config.ScaleDownDelay(), "the add-on defaults apply to settings that are not configured")
+}
+
+func TestAutoscalerScaling(t *testing.T) {
+	pool := &autoscaledPool{nodes: 2, initial: 2}
+	runner := pool.runner(testAutoscalerConfig)
+	load := AutoscalerLoad{Namespace: "autoscaler", Image: testToolsImage, Pool: "default", ExtraNodes: 1, ScaleDownGrace: time.Second}
+
+	report, err := AutoscalerScaling(context.Background(), runner, load, time.Millisecond)
+	require.NoError(t, err)
+	assert.Equal(t, AutoscalerReport{
+		Pool:           AutoscalerPool{Name: "default", MinSize: 1, MaxSize: 2, Enabled: true},
+		Zones:          2,
+		ScaleDownDelay: 50 * time.Millisecond,
+		InitialNodes:   2,
+		PeakNodes:      3,
+		FinalNodes:     2,
+		ScaleUp:        report.ScaleUp,
+		ScaleDown:      report.ScaleDown,
+	}, report)
+	assert.Contains(t, report.String(), "pool default (1 to 2 nodes per zone in 2 zones): scaled up from 2 to 3 nodes in ")
+	calls := runner.Calls()
+	assert.Equal(t, "delete namespace autoscaler --ignore-not-found --wait=true", calls[len(calls)-1], "the load is cleaned up")
+}
+
+func TestAutoscalerScalingFailures(t *testing.T) {
+	t.Run("pool not autoscaled", func(t *testing.T) {
+		runner := (&autoscaledPool{nodes: 2, initial: 2}).runner(strings.Replace(testAutoscalerConfig, `\"enabled\":true`, `\"enabled\":false`, 1))
+		_, err := AutoscalerScaling(context.Background(), runner, AutoscalerLoad{Namespace: "autoscaler", Pool: "default", ExtraNodes: 1}, time.Millisecond)
+		assert.EqualError(t, err, "autoscaling is not enabled for pool default in iks-ca-configmap")
+	})
+
+	t.Run("pool at its maximum size", func(t *testing.T) {
+		runner := (&autoscaledPool{nodes: 4, initial: 4}).runner(testAutoscalerConfig)
+		_, err := AutoscalerScaling(context.Background(), runner, AutoscalerLoad{Namespace: "autoscaler", Pool: "default", ExtraNodes: 1}, time.Millisecond)
+		assert.EqualError(t, err, "pool default has 4 nodes and can grow to 4, which is not enough for 1 more")
+	})
+
+	t.Run("no scale down within the delay", func(t *testing.T) {
+		pool := &autoscaledPool{nodes: 2, initial: 2, stuck: true}
+		runner := pool.runner(testAutoscalerConfig)
+		report, err := AutoscalerScaling(context.Background(), runner, AutoscalerLoad{Namespace: "autoscaler", Pool: "default", ExtraNodes: 1}, time.Millisecond)
+		require.Error(t, err)
+		assert.True(t, strings.HasPrefix(err.Error(), "autoscaler did not scale down within 50ms: pool default has 3 nodes, expected 2"), err.Error())
+		assert.Equal(t, 3, report.FinalNodes)
+		calls := runner.Calls()
+		assert.Equal(t, "delete namespace autoscaler --ignore-not-found --wait=true", calls[len(calls)-1])
+	})
+}
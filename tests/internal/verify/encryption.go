@@ -0,0 +1,179 @@
+// Package verify contains post-apply checks that compare what was deployed in IBM Cloud with what the Terraform inputs
+// asked for. The checks only depend on small interfaces so they can be unit tested offline.
+package verify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/terraform-ibm-modules/terraform-ibm-base-ocp-vpc/internal/ibmcloud"
+)
+
+// ClusterAPI is the subset of the Kubernetes Service API used by the encryption and version checks
+type ClusterAPI interface {
+	GetCluster(ctx context.Context, cluster string) (*ibmcloud.Cluster, error)
+	ListWorkers(ctx context.Context, cluster string) ([]ibmcloud.Worker, error)
+	GetClusterKMS(ctx context.Context, cluster string) (*ibmcloud.ClusterKMS, error)
+	ListWorkerPools(ctx context.Context, cluster string) ([]ibmcloud.WorkerPool, error)
+}
+
+// BootVolumeAPI is the subset of the VPC API used to read the boot volumes of the workers
+type BootVolumeAPI interface {
+	GetInstanceBootVolume(ctx context.Context, name string) (*ibmcloud.Volume, error)
+}
+
+// KmsKey identifies a customer managed root key. KeyID may be left empty to accept any key of the KMS instance.
+type KmsKey struct {
+	AccountID  string
+	InstanceID string
+	KeyID      string
+}
+
+// KmsKeyFromCRN builds a KmsKey from a KMS instance or key CRN
+func KmsKeyFromCRN(crn string) (KmsKey, error) {
+	parsed, err := ibmcloud.ParseCRN(crn)
+	if err != nil {
+		return KmsKey{}, err
+	}
+	key := KmsKey{AccountID: parsed.AccountID(), InstanceID: parsed.ServiceInstance}
+	if parsed.ResourceType == "key" {
+		key.KeyID = parsed.Resource
+	}
+	return key, nil
+}
+
+func (k KmsKey) String() string {
+	key := k.KeyID
+	if key == "" {
+		key = "*"
+	}
+	return fmt.Sprintf("account %s, instance %s, key %s", k.AccountID, k.InstanceID, key)
+}
+
+// mismatch describes how actual differs from the expected key, or returns an empty string if it matches
+func (k KmsKey) mismatch(actual KmsKey) string {
+	switch {
+	case k.AccountID != "" && k.AccountID != actual.AccountID:
+		return fmt.Sprintf("account ID is %q, expected %q", actual.AccountID, k.AccountID)
+	case k.InstanceID != "" && k.InstanceID != actual.InstanceID:
+		return fmt.Sprintf("KMS instance is %q, expected %q", actual.InstanceID, k.InstanceID)
+	case k.KeyID != "" && k.KeyID != actual.KeyID:
+		return fmt.Sprintf("key is %q, expected %q", actual.KeyID, k.KeyID)
+	}
+	return ""
+}
+
+// EncryptionExpectation lists the keys that the cluster secrets and the worker boot volumes must be encrypted with.
+// A nil entry means customer managed encryption was not requested and is not checked.
+type EncryptionExpectation struct {
+	Cluster    *KmsKey
+	BootVolume *KmsKey
+}
+
+// Encryption checks that the cluster has KMS enabled with the expected key, and that the boot volumes of every worker
+// pool are encrypted with the expected customer managed key. The boot volume key is checked both in the worker pool
+// configuration of the Kubernetes Service and on the VPC boot volume of each worker, so a key that was requested but
+// not applied is caught. All mismatches are returned together.
+func Encryption(ctx context.Context, clusters ClusterAPI, volumes BootVolumeAPI, cluster string, expected EncryptionExpectation) error {
+	info, err := clusters.GetCluster(ctx, cluster)
+	if err != nil {
+		return fmt.Errorf("error getting cluster %s: %w", cluster, err)
+	}
+	// a KMS instance in the account of the cluster is reported without an account ID
+	clusterCRN, err := ibmcloud.ParseCRN(info.CRN)
+	if err != nil {
+		return fmt.Errorf("cluster %s: %w", cluster, err)
+	}
+	account := clusterCRN.AccountID()
+
+	var errs []error
+	if expected.Cluster != nil {
+		errs = append(errs, clusterEncryption(ctx, clusters, info, account, *expected.Cluster))
+	}
+	if expected.BootVolume != nil {
+		errs = append(errs, bootVolumeEncryption(ctx, clusters, cluster, account, *expected.BootVolume)...)
+		errs = append(errs, workerBootVolumes(ctx, clusters, volumes, cluster, *expected.BootVolume)...)
+	}
+
+	return errors.Join(errs...)
+}
+
+func clusterEncryption(ctx context.Context, clusters ClusterAPI, info *ibmcloud.Cluster, account string, expected KmsKey) error {
+	if !info.Features.KeyProtectEnabled {
+		return fmt.Errorf("cluster %s secrets use provider managed encryption, expected customer managed key (%s)", info.Name, expected)
+	}
+	kms, err := clusters.GetClusterKMS(ctx, info.ID)
+	if err != nil {
+		return fmt.Errorf("error getting the KMS configuration of cluster %s: %w", info.Name, err)
+	}
+	actual := KmsKey{AccountID: kms.AccountID, InstanceID: kms.InstanceID, KeyID: kms.CRKID}
+	if actual.AccountID == "" {
+		actual.AccountID = account
+	}
+	if msg := expected.mismatch(actual); msg != "" {
+		return fmt.Errorf("cluster %s KMS %s", info.Name, msg)
+	}
+	return nil
+}
+
+func bootVolumeEncryption(ctx context.Context, clusters ClusterAPI, cluster string, account string, expected KmsKey) []error {
+	pools, err := clusters.ListWorkerPools(ctx, cluster)
+	if err != nil {
+		return []error{fmt.Errorf("error listing worker pools of cluster %s: %w", cluster, err)}
+	}
+	if len(pools) == 0 {
+		return []error{fmt.Errorf("cluster %s has no worker pools to check boot volume encryption on", cluster)}
+	}
+
+	var errs []error
+	for _, pool := range pools {
+		encryption := pool.WorkerVolumeEncryption
+		if encryption == nil || encryption.WorkerVolumeCRKID == "" {
+			errs = append(errs, fmt.Errorf("worker pool %s: boot volumes use provider managed encryption, expected customer managed key (%s)", pool.PoolName, expected))
+			continue
+		}
+		actual := KmsKey{AccountID: encryption.KmsAccountID, InstanceID: encryption.KmsInstanceID, KeyID: encryption.WorkerVolumeCRKID}
+		if actual.AccountID == "" {
+			actual.AccountID = account
+		}
+		if msg := expected.mismatch(actual); msg != "" {
+			errs = append(errs, fmt.Errorf("worker pool %s: boot volume %s", pool.PoolName, msg))
+		}
+	}
+	return errs
+}
+
+// workerBootVolumes checks the key that the VPC boot volume of each worker is actually encrypted with. The virtual
+// server instance of a worker is named after the worker ID.
+func workerBootVolumes(ctx context.Context, clusters ClusterAPI, volumes BootVolumeAPI, cluster string, expected KmsKey) []error {
+	workers, err := clusters.ListWorkers(ctx, cluster)
+	if err != nil {
+		return []error{fmt.Errorf("error listing workers of cluster %s: %w", cluster, err)}
+	}
+	if len(workers) == 0 {
+		return []error{fmt.Errorf("cluster %s has no workers to check boot volume encryption on", cluster)}
+	}
+
+	var errs []error
+	for _, worker := range workers {
+		volume, err := volumes.GetInstanceBootVolume(ctx, worker.ID)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("worker %s: error getting the boot volume: %w", worker.ID, err))
+			continue
+		}
+		if volume.EncryptionKeyCRN == "" {
+			errs = append(errs, fmt.Errorf("worker %s: boot volume %s uses provider managed encryption, expected customer managed key (%s)", worker.ID, volume.Name, expected))
+			continue
+		}
+		actual, err := KmsKeyFromCRN(volume.EncryptionKeyCRN)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("worker %s: boot volume %s: %w", worker.ID, volume.Name, err))
+			continue
+		}
+		if msg := expected.mismatch(actual); msg != "" {
+			errs = append(errs, fmt.Errorf("worker %s: boot volume %s %s", worker.ID, volume.Name, msg))
+		}
+	}
+	return errs
+}
@@ -0,0 +1,193 @@
+package verify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/terraform-ibm-modules/terraform-ibm-base-ocp-vpc/internal/ibmcloud"
+)
+
+const (
+	testAccount  = "abac0df06b644a9cabc6e44f55b3880e"
+	testInstance = "e6dce284-e80f-46e1-a3c1-830f7adff7a9"
+	testKey      = "0e4b5d7a-6f2a-4b8e-9a3c-2d1f0e9b8c7a"
+)
+
+func testKeyCRN(account, instance, key string) string {
+	return fmt.Sprintf("crn:v1:bluemix:public:kms:us-south:a/%s:%s:key:%s", account, instance, key)
+}
+
+type fakeClusterAPI struct {
+	cluster *ibmcloud.Cluster
+	workers []ibmcloud.Worker
+	kms     *ibmcloud.ClusterKMS
+	pools   []ibmcloud.WorkerPool
+}
+
+func (f *fakeClusterAPI) GetCluster(_ context.Context, _ string) (*ibmcloud.Cluster, error) {
+	return f.cluster, nil
+}
+
+func (f *fakeClusterAPI) ListWorkers(_ context.Context, _ string) ([]ibmcloud.Worker, error) {
+	return f.workers, nil
+}
+
+func (f *fakeClusterAPI) GetClusterKMS(_ context.Context, _ string) (*ibmcloud.ClusterKMS, error) {
+	if f.kms == nil {
+		return nil, errors.New("KMS is not enabled")
+	}
+	return f.kms, nil
+}
+
+func (f *fakeClusterAPI) ListWorkerPools(_ context.Context, _ string) ([]ibmcloud.WorkerPool, error) {
+	return f.pools, nil
+}
+
+// fakeBootVolumeAPI returns the boot volume of each worker by the worker ID
+type fakeBootVolumeAPI map[string]*ibmcloud.Volume
+
+func (f fakeBootVolumeAPI) GetInstanceBootVolume(_ context.Context, name string) (*ibmcloud.Volume, error) {
+	volume, ok := f[name]
+	if !ok {
+		return nil, ibmcloud.ErrNotFound
+	}
+	return volume, nil
+}
+
+func encryptedPool(name, account, instance, key string) ibmcloud.WorkerPool {
+	return ibmcloud.WorkerPool{PoolName: name, WorkerVolumeEncryption: &ibmcloud.WorkerVolumeEncryption{KmsAccountID: account, KmsInstanceID: instance, WorkerVolumeCRKID: key}}
+}
+
+func TestKmsKeyFromCRN(t *testing.T) {
+	key, err := KmsKeyFromCRN(testKeyCRN(testAccount, testInstance, testKey))
+	require.NoError(t, err)
+	assert.Equal(t, KmsKey{AccountID: testAccount, InstanceID: testInstance, KeyID: testKey}, key)
+
+	instance, err := KmsKeyFromCRN(fmt.Sprintf("crn:v1:bluemix:public:hs-crypto:us-south:a/%s:%s::", testAccount, testInstance))
+	require.NoError(t, err)
+	assert.Equal(t, KmsKey{AccountID: testAccount, InstanceID: testInstance}, instance)
+
+	_, err = KmsKeyFromCRN("not-a-crn")
+	assert.Error(t, err)
+}
+
+func TestEncryption(t *testing.T) {
+	expectedKey := &KmsKey{AccountID: testAccount, InstanceID: testInstance, KeyID: testKey}
+
+	testCases := []struct {
+		name    string
+		enabled bool
+		kms     *ibmcloud.ClusterKMS
+		pools   []ibmcloud.WorkerPool
+		// bootKeys are the key CRNs of the boot volumes of the workers, one worker each
+		bootKeys []string
+		expected EncryptionExpectation
+		errors   []string
+	}{
+		{
+			name:     "customer managed everywhere, in the account of the cluster",
+			enabled:  true,
+			kms:      &ibmcloud.ClusterKMS{InstanceID: testInstance, CRKID: testKey},
+			pools:    []ibmcloud.WorkerPool{encryptedPool("default", "", testInstance, testKey), encryptedPool("zone-2", "", testInstance, testKey)},
+			bootKeys: []string{testKeyCRN(testAccount, testInstance, testKey), testKeyCRN(testAccount, testInstance, testKey)},
+			expected: EncryptionExpectation{Cluster: expectedKey, BootVolume: expectedKey},
+		},
+		{
+			name:     "customer managed with a key of another account",
+			enabled:  true,
+			kms:      &ibmcloud.ClusterKMS{InstanceID: testInstance, CRKID: testKey, AccountID: "other"},
+			pools:    []ibmcloud.WorkerPool{encryptedPool("default", "other", testInstance, testKey)},
+			bootKeys: []string{testKeyCRN("other", testInstance, testKey)},
+			expected: EncryptionExpectation{Cluster: &KmsKey{AccountID: "other", InstanceID: testInstance, KeyID: testKey}, BootVolume: &KmsKey{AccountID: "other", InstanceID: testInstance}},
+		},
+		{
+			name:     "cluster uses provider managed encryption",
+			enabled:  false,
+			expected: EncryptionExpectation{Cluster: expectedKey},
+			errors:   []string{"cluster test secrets use provider managed encryption"},
+		},
+		{
+			name:     "cluster KMS in the wrong account",
+			enabled:  true,
+			kms:      &ibmcloud.ClusterKMS{InstanceID: testInstance, CRKID: testKey, AccountID: "other"},
+			expected: EncryptionExpectation{Cluster: expectedKey},
+			errors:   []string{`cluster test KMS account ID is "other"`},
+		},
+		{
+			name:     "cluster KMS configuration cannot be read",
+			enabled:  true,
+			expected: EncryptionExpectation{Cluster: expectedKey},
+			errors:   []string{"error getting the KMS configuration of cluster test: KMS is not enabled"},
+		},
+		{
+			name:     "boot volumes provider managed or with wrong key",
+			pools:    []ibmcloud.WorkerPool{{PoolName: "default"}, encryptedPool("zone-2", "", testInstance, "other-key")},
+			bootKeys: []string{testKeyCRN(testAccount, testInstance, testKey)},
+			expected: EncryptionExpectation{BootVolume: expectedKey},
+			errors: []string{
+				"worker pool default: boot volumes use provider managed encryption",
+				`worker pool zone-2: boot volume key is "other-key"`,
+			},
+		},
+		{
+			name:     "any key of the instance is accepted when no key ID is expected",
+			pools:    []ibmcloud.WorkerPool{encryptedPool("default", "", testInstance, "boot-key")},
+			bootKeys: []string{testKeyCRN(testAccount, testInstance, "boot-key")},
+			expected: EncryptionExpectation{BootVolume: &KmsKey{AccountID: testAccount, InstanceID: testInstance}},
+		},
+		{
+			name:     "cluster without worker pools",
+			expected: EncryptionExpectation{BootVolume: expectedKey},
+			errors: []string{
+				"cluster test has no worker pools to check boot volume encryption on",
+				"cluster test has no workers to check boot volume encryption on",
+			},
+		},
+		{
+			name:     "worker pools request the key but a boot volume has another key",
+			pools:    []ibmcloud.WorkerPool{encryptedPool("default", "", testInstance, testKey)},
+			bootKeys: []string{testKeyCRN(testAccount, testInstance, testKey), testKeyCRN(testAccount, testInstance, "other-key"), ""},
+			expected: EncryptionExpectation{BootVolume: expectedKey},
+			errors: []string{
+				`worker worker-2: boot volume worker-2-boot key is "other-key"`,
+				"worker worker-3: boot volume worker-3-boot uses provider managed encryption",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			clusters := &fakeClusterAPI{
+				cluster: &ibmcloud.Cluster{
+					ID:       "abc123",
+					Name:     "test",
+					CRN:      fmt.Sprintf("crn:v1:bluemix:public:containers-kubernetes:us-south:a/%s:abc123::", testAccount),
+					Features: ibmcloud.ClusterFeatures{KeyProtectEnabled: tc.enabled},
+				},
+				kms:   tc.kms,
+				pools: tc.pools,
+			}
+			volumes := fakeBootVolumeAPI{}
+			for i, key := range tc.bootKeys {
+				id := fmt.Sprintf("worker-%d", i+1)
+				clusters.workers = append(clusters.workers, ibmcloud.Worker{ID: id})
+				volumes[id] = &ibmcloud.Volume{ID: id + "-vol", Name: id + "-boot", EncryptionKeyCRN: key}
+			}
+			err := Encryption(context.Background(), clusters, volumes, "test", tc.expected)
+			if len(tc.errors) == 0 {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Len(t, strings.Split(err.Error(), "\n"), len(tc.errors))
+			for _, msg := range tc.errors {
+				assert.Contains(t, err.Error(), msg)
+			}
+		})
+	}
+}
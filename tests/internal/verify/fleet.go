@@ -0,0 +1,138 @@
+package verify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/terraform-ibm-modules/terraform-ibm-base-ocp-vpc/internal/ibmcloud"
+	"github.com/terraform-ibm-modules/terraform-ibm-base-ocp-vpc/internal/kube"
+)
+
+// Values of the operating_system of a worker pool
+const (
+	OperatingSystemRHCOS = "RHCOS"
+	OperatingSystemRHEL8 = "REDHAT_8_64"
+	OperatingSystemRHEL9 = "RHEL_9_64"
+)
+
+// DefaultNetworkPlugin is the network plugin of a cluster that the module does not set network_plugin for
+const DefaultNetworkPlugin = "Calico"
+
+// Labels that the Kubernetes Service sets on the nodes of a cluster
+const (
+	workerPoolLabel    = "ibm-cloud.kubernetes.io/worker-pool-name"
+	workerVersionLabel = "ibm-cloud.kubernetes.io/worker-version"
+	zoneLabel          = "topology.kubernetes.io/zone"
+)
+
+// kubeMinorOffset is the difference between the minor version of OpenShift and the Kubernetes version it is based on,
+// e.g. OpenShift 4.18 runs Kubernetes 1.31
+const kubeMinorOffset = 13
+
+// osImagePrefixes are the prefixes of the OS image that nodes of each operating_system report
+var osImagePrefixes = map[string]string{
+	OperatingSystemRHCOS: "Red Hat Enterprise Linux CoreOS ",
+	OperatingSystemRHEL8: "Red Hat Enterprise Linux 8.",
+	OperatingSystemRHEL9: "Red Hat Enterprise Linux 9.",
+}
+
+// FleetExpectation is what the nodes of a cluster should run according to the inputs of the module
+type FleetExpectation struct {
+	// Version is the requested OpenShift version, of which only the minor is compared
+	Version string
+	// OperatingSystems is the operating_system of each worker pool by pool name
+	OperatingSystems map[string]string
+	// NetworkPlugin is the network plugin the cluster should run, see ExpectedNetworkPlugin
+	NetworkPlugin string
+}
+
+// fleetNodes and clusterNetwork are the subsets of the Kubernetes objects read by the fleet check
+type fleetNodes struct {
+	Items []struct {
+		Metadata struct {
+			Name   string            `json:"name"`
+			Labels map[string]string `json:"labels"`
+		} `json:"metadata"`
+		Status struct {
+			NodeInfo struct {
+				KubeletVersion string `json:"kubeletVersion"`
+				OSImage        string `json:"osImage"`
+			} `json:"nodeInfo"`
+		} `json:"status"`
+	} `json:"items"`
+}
+
+type clusterNetwork struct {
+	Status struct {
+		NetworkType string `json:"networkType"`
+	} `json:"status"`
+}
+
+// ExpectedNetworkPlugin returns the network plugin of a cluster of the given version, whose default worker pool runs
+// defaultPoolOS, that requested network_plugin. Like local.network_plugin of the module, the requested plugin only
+// applies from OpenShift 4.20 with RHCOS, other clusters run the default plugin.
+func ExpectedNetworkPlugin(version string, defaultPoolOS string, requested string) (string, error) {
+	v, err := ibmcloud.ParseKubeVersion(version)
+	if err != nil {
+		return "", err
+	}
+	if !v.Less(ibmcloud.KubeVersion{Major: 4, Minor: 20}) && defaultPoolOS == OperatingSystemRHCOS {
+		return requested, nil
+	}
+	return DefaultNetworkPlugin, nil
+}
+
+// NodeFleet checks that every node runs the requested OpenShift minor version, with the matching kubelet, and the
+// operating system of its worker pool, and that the cluster runs the expected network plugin. Every mismatch is
+// returned, prefixed with the node it was found on.
+func NodeFleet(ctx context.Context, kubectl kube.Runner, expected FleetExpectation) error {
+	want, err := ibmcloud.ParseKubeVersion(expected.Version)
+	if err != nil {
+		return err
+	}
+	wantKubelet := ibmcloud.KubeVersion{Major: 1, Minor: want.Minor + kubeMinorOffset}
+
+	var nodes fleetNodes
+	if err := kube.GetJSON(ctx, kubectl, &nodes, "nodes"); err != nil {
+		return fmt.Errorf("error listing nodes: %w", err)
+	}
+	var errs []error
+	if len(nodes.Items) == 0 {
+		errs = append(errs, errors.New("cluster has no nodes"))
+	}
+	for _, node := range nodes.Items {
+		pool := node.Metadata.Labels[workerPoolLabel]
+		nodeErr := func(format string, args ...interface{}) {
+			errs = append(errs, fmt.Errorf("node %s of pool %s: %s", node.Metadata.Name, pool, fmt.Sprintf(format, args...)))
+		}
+
+		if err := sameVersion(node.Metadata.Labels[workerVersionLabel], want); err != nil {
+			nodeErr("%v", err)
+		}
+		if err := sameVersion(strings.TrimPrefix(node.Status.NodeInfo.KubeletVersion, "v"), wantKubelet); err != nil {
+			nodeErr("kubelet %v", err)
+		}
+
+		operatingSystem, ok := expected.OperatingSystems[pool]
+		if !ok {
+			nodeErr("pool is not one of the requested worker pools")
+			continue
+		}
+		prefix, ok := osImagePrefixes[operatingSystem]
+		if !ok {
+			nodeErr("unknown operating system %q", operatingSystem)
+		} else if !strings.HasPrefix(node.Status.NodeInfo.OSImage, prefix) {
+			nodeErr("runs OS image %q, expected %s", node.Status.NodeInfo.OSImage, operatingSystem)
+		}
+	}
+
+	var network clusterNetwork
+	if err := kube.GetJSON(ctx, kubectl, &network, "network.config.openshift.io", "cluster"); err != nil {
+		errs = append(errs, fmt.Errorf("error getting the cluster network config: %w", err))
+	} else if network.Status.NetworkType != expected.NetworkPlugin {
+		errs = append(errs, fmt.Errorf("cluster runs network plugin %q, expected %q", network.Status.NetworkType, expected.NetworkPlugin))
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,102 @@
+package verify
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/terraform-ibm-modules/terraform-ibm-base-ocp-vpc/internal/kube/kubetest"
+)
+
+func fleetNode(name string, pool string, version string, kubelet string, osImage string) string {
+	return fmt.Sprintf(`{"metadata": {"name": %q, "labels": {"ibm-cloud.kubernetes.io/worker-pool-name": %q, "ibm-cloud.kubernetes.io/worker-version": %q}},
+		"status": {"nodeInfo": {"kubeletVersion": %q, "osImage": %q}}}`, name, pool, version, kubelet, osImage)
+}
+
+func TestExpectedNetworkPlugin(t *testing.T) {
+	testCases := []struct {
+		version string
+		os      string
+		want    string
+	}{
+		{version: "4.20", os: OperatingSystemRHCOS, want: "OVNKubernetes"},
+		{version: "4.21.3_openshift", os: OperatingSystemRHCOS, want: "OVNKubernetes"},
+		{version: "4.19", os: OperatingSystemRHCOS, want: DefaultNetworkPlugin},
+		{version: "4.20", os: OperatingSystemRHEL9, want: DefaultNetworkPlugin},
+	}
+	for _, tc := range testCases {
+		plugin, err := ExpectedNetworkPlugin(tc.version, tc.os, "OVNKubernetes")
+		require.NoError(t, err)
+		assert.Equal(t, tc.want, plugin, "%s on %s", tc.version, tc.os)
+	}
+
+	_, err := ExpectedNetworkPlugin("default", OperatingSystemRHCOS, "OVNKubernetes")
+	assert.Error(t, err)
+}
+
+func TestNodeFleet(t *testing.T) {
+	const (
+		rhcos = "Red Hat Enterprise Linux CoreOS 418.94.202503101525-0"
+		rhel9 = "Red Hat Enterprise Linux 9.4 (Plow)"
+	)
+	expected := FleetExpectation{
+		Version:          "4.18",
+		OperatingSystems: map[string]string{"default": OperatingSystemRHCOS, "extra": OperatingSystemRHEL9},
+		NetworkPlugin:    DefaultNetworkPlugin,
+	}
+
+	testCases := []struct {
+		name    string
+		nodes   []string
+		network string
+		errors  []string
+	}{
+		{
+			name: "matching",
+			nodes: []string{
+				fleetNode("10.0.1.4", "default", "4.18.10_1544_openshift", "v1.31.6", rhcos),
+				fleetNode("10.0.2.4", "extra", "4.18.10_1544_openshift", "v1.31.6+8f8b1b2", rhel9),
+			},
+			network: "Calico",
+		},
+		{
+			name: "mismatches are reported per node",
+			nodes: []string{
+				fleetNode("10.0.1.4", "default", "4.17.20_1540_openshift", "v1.30.9", rhcos),
+				fleetNode("10.0.2.4", "extra", "4.18.10_1544_openshift", "v1.31.6", rhcos),
+				fleetNode("10.0.3.4", "unknown", "4.18.10_1544_openshift", "v1.31.6", rhcos),
+			},
+			network: "OVNKubernetes",
+			errors: []string{
+				"node 10.0.1.4 of pool default: runs version 4.17.20_1540_openshift, expected 4.18",
+				"node 10.0.1.4 of pool default: kubelet runs version 1.30.9, expected 1.31",
+				`node 10.0.2.4 of pool extra: runs OS image "` + rhcos + `", expected RHEL_9_64`,
+				"node 10.0.3.4 of pool unknown: pool is not one of the requested worker pools",
+				`cluster runs network plugin "OVNKubernetes", expected "Calico"`,
+			},
+		},
+		{
+			name:    "no nodes",
+			network: "Calico",
+			errors:  []string{"cluster has no nodes"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			runner := kubetest.NewRunner()
+			runner.OnOutput("get nodes", `{"items": [`+strings.Join(tc.nodes, ",")+`]}`)
+			runner.OnOutput("get network.config.openshift.io cluster", `{"status": {"networkType": "`+tc.network+`"}}`)
+
+			err := NodeFleet(context.Background(), runner, expected)
+			if len(tc.errors) == 0 {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Equal(t, tc.errors, strings.Split(err.Error(), "\n"))
+		})
+	}
+}
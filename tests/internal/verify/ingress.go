@@ -0,0 +1,323 @@
+package verify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/terraform-ibm-modules/terraform-ibm-base-ocp-vpc/internal/ibmcloud"
+	"github.com/terraform-ibm-modules/terraform-ibm-base-ocp-vpc/internal/kube"
+)
+
+// Where the default IngressController and its router pods and service live
+const (
+	ingressOperatorNamespace = "openshift-ingress-operator"
+	routerNamespace          = "openshift-ingress"
+	routerService            = "router-default"
+	routerPodSelector        = "ingresscontroller.operator.openshift.io/deployment-ingresscontroller=default"
+)
+
+// healthyIngressStatus is the ingress status that the Kubernetes Service reports for a healthy cluster
+const healthyIngressStatus = "healthy"
+
+// expectedIngressConditions are the status conditions of a healthy default IngressController. Available must be
+// reported, the others are only checked when the ingress operator reports them.
+var expectedIngressConditions = []struct {
+	Type     string
+	Status   string
+	Required bool
+}{
+	{"Available", "True", true},
+	{"Degraded", "False", false},
+	{"LoadBalancerReady", "True", false},
+	{"DNSReady", "True", false},
+}
+
+// IngressStatusAPI is the subset of the Kubernetes Service API used by the ingress diagnostics
+type IngressStatusAPI interface {
+	GetCluster(ctx context.Context, cluster string) (*ibmcloud.Cluster, error)
+	ListIngressSecrets(ctx context.Context, cluster string) ([]ibmcloud.IngressSecret, error)
+}
+
+// LoadBalancerAPI is the subset of the VPC API used by the ingress diagnostics
+type LoadBalancerAPI interface {
+	ListLoadBalancers(ctx context.Context) ([]ibmcloud.LoadBalancer, error)
+}
+
+// IngressStatus is the state of each component of the default ingress of a cluster, as read by IngressDiagnostics
+type IngressStatus struct {
+	Cluster string
+	// Status and Message are the ingress health reported by the Kubernetes Service
+	Status  string
+	Message string
+	// Conditions are the status conditions of the default IngressController by type
+	Conditions map[string]IngressCondition
+	// Routers is the readiness of the default router pods by zone
+	Routers map[string]RouterReadiness
+	// LoadBalancerHostname is the hostname of the VPC load balancer of the router service, and LoadBalancer the load
+	// balancer with that hostname
+	LoadBalancerHostname string
+	LoadBalancer         *ibmcloud.LoadBalancer
+	// Certificate is the default ingress certificate secret, and CertificateExpiry when it expires
+	Certificate       *ibmcloud.IngressSecret
+	CertificateExpiry time.Time
+	// ReadErrors are the components that could not be read
+	ReadErrors []string
+}
+
+// IngressCondition is a status condition of an IngressController
+type IngressCondition struct {
+	Status  string `json:"status"`
+	Reason  string `json:"reason"`
+	Message string `json:"message"`
+}
+
+// RouterReadiness counts the router pods of a zone
+type RouterReadiness struct {
+	Ready int
+	Total int
+}
+
+// ingressController, routerPods, nodeZones and loadBalancerService are the subsets of the Kubernetes objects read by the
+// ingress diagnostics
+type ingressController struct {
+	Status struct {
+		Conditions []struct {
+			Type string `json:"type"`
+			IngressCondition
+		} `json:"conditions"`
+	} `json:"status"`
+}
+
+type routerPods struct {
+	Items []struct {
+		Metadata struct {
+			Name string `json:"name"`
+		} `json:"metadata"`
+		Spec struct {
+			NodeName string `json:"nodeName"`
+		} `json:"spec"`
+		Status struct {
+			Conditions []struct {
+				Type   string `json:"type"`
+				Status string `json:"status"`
+			} `json:"conditions"`
+		} `json:"status"`
+	} `json:"items"`
+}
+
+type nodeZones struct {
+	Items []struct {
+		Metadata struct {
+			Name   string            `json:"name"`
+			Labels map[string]string `json:"labels"`
+		} `json:"metadata"`
+	} `json:"items"`
+}
+
+type loadBalancerService struct {
+	Status struct {
+		LoadBalancer struct {
+			Ingress []struct {
+				Hostname string `json:"hostname"`
+			} `json:"ingress"`
+		} `json:"loadBalancer"`
+	} `json:"status"`
+}
+
+// IngressDiagnostics reads the state of each component of the default ingress of a cluster: the ingress health from
+// the Kubernetes Service, the conditions of the default IngressController, the readiness of the router pods in each
+// zone, the provisioning state of the VPC load balancer of the router service, and the expiry of the default ingress
+// certificate. It only fails if the cluster cannot be read; other components that cannot be read are listed in
+// ReadErrors.
+func IngressDiagnostics(ctx context.Context, clusters IngressStatusAPI, kubectl kube.Runner, loadBalancers LoadBalancerAPI, cluster string) (*IngressStatus, error) {
+	info, err := clusters.GetCluster(ctx, cluster)
+	if err != nil {
+		return nil, fmt.Errorf("error getting cluster %s: %w", cluster, err)
+	}
+	status := &IngressStatus{Cluster: info.Name, Status: info.Ingress.Status, Message: info.Ingress.Message}
+	readErr := func(component string, err error) {
+		status.ReadErrors = append(status.ReadErrors, fmt.Sprintf("%s: %v", component, err))
+	}
+
+	var controller ingressController
+	if err := kube.GetJSON(ctx, kubectl, &controller, "ingresscontroller", "default", "--namespace", ingressOperatorNamespace); err != nil {
+		readErr("ingresscontroller", err)
+	} else {
+		status.Conditions = map[string]IngressCondition{}
+		for _, c := range controller.Status.Conditions {
+			status.Conditions[c.Type] = c.IngressCondition
+		}
+	}
+
+	if routers, err := readRouters(ctx, kubectl); err != nil {
+		readErr("router pods", err)
+	} else {
+		status.Routers = routers
+	}
+
+	var svc loadBalancerService
+	if err := kube.GetJSON(ctx, kubectl, &svc, "service", routerService, "--namespace", routerNamespace); err != nil {
+		readErr("router service", err)
+	} else if len(svc.Status.LoadBalancer.Ingress) > 0 {
+		status.LoadBalancerHostname = svc.Status.LoadBalancer.Ingress[0].Hostname
+	}
+	if status.LoadBalancerHostname != "" {
+		if lbs, err := loadBalancers.ListLoadBalancers(ctx); err != nil {
+			readErr("load balancer", err)
+		} else if i := slices.IndexFunc(lbs, func(lb ibmcloud.LoadBalancer) bool { return lb.Hostname == status.LoadBalancerHostname }); i >= 0 {
+			status.LoadBalancer = &lbs[i]
+		}
+	}
+
+	if info.Ingress.SecretName != "" {
+		if secrets, err := clusters.ListIngressSecrets(ctx, info.ID); err != nil {
+			readErr("certificate", err)
+		} else if i := slices.IndexFunc(secrets, func(s ibmcloud.IngressSecret) bool { return s.Name == info.Ingress.SecretName }); i >= 0 {
+			status.Certificate = &secrets[i]
+			if expiresOn := secrets[i].ExpiresOn; expiresOn != "" {
+				if status.CertificateExpiry, err = parseExpiry(expiresOn); err != nil {
+					readErr("certificate", err)
+				}
+			}
+		}
+	}
+	return status, nil
+}
+
+// readRouters counts the ready router pods of the default IngressController in each zone
+func readRouters(ctx context.Context, kubectl kube.Runner) (map[string]RouterReadiness, error) {
+	var pods routerPods
+	if err := kube.GetJSON(ctx, kubectl, &pods, "pods", "--namespace", routerNamespace, "--selector", routerPodSelector); err != nil {
+		return nil, err
+	}
+	var nodes nodeZones
+	if err := kube.GetJSON(ctx, kubectl, &nodes, "nodes"); err != nil {
+		return nil, err
+	}
+	zones := map[string]string{}
+	for _, node := range nodes.Items {
+		zones[node.Metadata.Name] = node.Metadata.Labels[zoneLabel]
+	}
+
+	routers := map[string]RouterReadiness{}
+	for _, pod := range pods.Items {
+		zone := zones[pod.Spec.NodeName]
+		if zone == "" {
+			// not scheduled yet, or on a node without a zone label
+			zone = "unknown"
+		}
+		r := routers[zone]
+		r.Total++
+		for _, c := range pod.Status.Conditions {
+			if c.Type == "Ready" && c.Status == "True" {
+				r.Ready++
+			}
+		}
+		routers[zone] = r
+	}
+	return routers, nil
+}
+
+// parseExpiry parses the expiry of an ingress secret, which the Kubernetes Service reports with a numeric zone offset
+// without a colon
+func parseExpiry(expiresOn string) (time.Time, error) {
+	for _, layout := range []string{time.RFC3339, "2006-01-02T15:04:05-0700"} {
+		if t, err := time.Parse(layout, expiresOn); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unexpected certificate expiry %q", expiresOn)
+}
+
+// Err returns an error for each component of the ingress that is not healthy at time now, or nil if the ingress is
+// healthy
+func (s *IngressStatus) Err(now time.Time) error {
+	var errs []error
+	for _, e := range s.ReadErrors {
+		errs = append(errs, fmt.Errorf("could not read %s", e))
+	}
+	if s.Status != healthyIngressStatus {
+		errs = append(errs, fmt.Errorf("kubernetes service reports ingress status %q: %s", s.Status, s.Message))
+	}
+
+	if s.Conditions != nil {
+		for _, expected := range expectedIngressConditions {
+			c, ok := s.Conditions[expected.Type]
+			if !ok {
+				if expected.Required {
+					errs = append(errs, fmt.Errorf("ingresscontroller default does not report condition %s", expected.Type))
+				}
+				continue
+			}
+			if c.Status != expected.Status {
+				errs = append(errs, fmt.Errorf("ingresscontroller default is %s=%s, expected %s: %s: %s", expected.Type, c.Status, expected.Status, c.Reason, c.Message))
+			}
+		}
+	}
+
+	if s.Routers != nil {
+		if len(s.Routers) == 0 {
+			errs = append(errs, errors.New("no router pods of the default ingresscontroller"))
+		}
+		for _, zone := range slices.Sorted(maps.Keys(s.Routers)) {
+			if r := s.Routers[zone]; r.Ready < r.Total {
+				errs = append(errs, fmt.Errorf("%d of %d router pods ready in zone %s", r.Ready, r.Total, zone))
+			}
+		}
+	}
+
+	switch {
+	case s.LoadBalancerHostname == "":
+		errs = append(errs, fmt.Errorf("service %s/%s has no load balancer hostname yet", routerNamespace, routerService))
+	case s.LoadBalancer == nil:
+		errs = append(errs, fmt.Errorf("no VPC load balancer with hostname %s", s.LoadBalancerHostname))
+	case s.LoadBalancer.ProvisioningStatus != "active" || s.LoadBalancer.OperatingStatus != "online":
+		errs = append(errs, fmt.Errorf("load balancer %s is %s and %s, expected active and online", s.LoadBalancer.Name, s.LoadBalancer.ProvisioningStatus, s.LoadBalancer.OperatingStatus))
+	}
+
+	switch {
+	case s.Certificate == nil:
+		errs = append(errs, errors.New("default ingress certificate is not reported yet"))
+	case !s.CertificateExpiry.IsZero() && !now.Before(s.CertificateExpiry):
+		errs = append(errs, fmt.Errorf("default ingress certificate %s expired on %s", s.Certificate.Name, s.CertificateExpiry.UTC().Format(time.RFC3339)))
+	}
+	return errors.Join(errs...)
+}
+
+// String summarizes the status one component per line
+func (s *IngressStatus) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "cluster %s ingress: %s", s.Cluster, s.Status)
+	if s.Message != "" {
+		fmt.Fprintf(&b, " (%s)", s.Message)
+	}
+	var conditions []string
+	for _, t := range slices.Sorted(maps.Keys(s.Conditions)) {
+		conditions = append(conditions, t+"="+s.Conditions[t].Status)
+	}
+	fmt.Fprintf(&b, "\ningresscontroller default: %s", strings.Join(conditions, ", "))
+	var routers []string
+	for _, zone := range slices.Sorted(maps.Keys(s.Routers)) {
+		routers = append(routers, fmt.Sprintf("%s %d/%d ready", zone, s.Routers[zone].Ready, s.Routers[zone].Total))
+	}
+	fmt.Fprintf(&b, "\nrouters: %s", strings.Join(routers, ", "))
+	if s.LoadBalancer != nil {
+		fmt.Fprintf(&b, "\nload balancer %s: %s, %s", s.LoadBalancer.Hostname, s.LoadBalancer.ProvisioningStatus, s.LoadBalancer.OperatingStatus)
+	} else {
+		fmt.Fprintf(&b, "\nload balancer %s: not found", s.LoadBalancerHostname)
+	}
+	if s.Certificate != nil {
+		fmt.Fprintf(&b, "\ncertificate %s: %s, expires %s", s.Certificate.Name, s.Certificate.Status, s.Certificate.ExpiresOn)
+	} else {
+		b.WriteString("\ncertificate: not reported")
+	}
+	for _, e := range s.ReadErrors {
+		fmt.Fprintf(&b, "\nnot read: %s", e)
+	}
+	return b.String()
+}
@@ -0,0 +1,186 @@
+package verify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/terraform-ibm-modules/terraform-ibm-base-ocp-vpc/internal/ibmcloud"
+	"github.com/terraform-ibm-modules/terraform-ibm-base-ocp-vpc/internal/kube/kubetest"
+)
+
+const testRouterHostname = "abcd1234-us-south.lb.appdomain.cloud"
+
+type fakeIngressStatusAPI struct {
+	cluster *ibmcloud.Cluster
+	secrets []ibmcloud.IngressSecret
+}
+
+func (f *fakeIngressStatusAPI) GetCluster(_ context.Context, _ string) (*ibmcloud.Cluster, error) {
+	return f.cluster, nil
+}
+
+func (f *fakeIngressStatusAPI) ListIngressSecrets(_ context.Context, _ string) ([]ibmcloud.IngressSecret, error) {
+	return f.secrets, nil
+}
+
+type fakeLoadBalancerAPI struct {
+	loadBalancers []ibmcloud.LoadBalancer
+	err           error
+}
+
+func (f *fakeLoadBalancerAPI) ListLoadBalancers(_ context.Context) ([]ibmcloud.LoadBalancer, error) {
+	return f.loadBalancers, f.err
+}
+
+// ingressFixture is a cluster with a healthy ingress, that the test cases break one component of
+type ingressFixture struct {
+	clusters      *fakeIngressStatusAPI
+	loadBalancers *fakeLoadBalancerAPI
+	conditions    string
+	routerPods    []string
+	serviceStatus string
+}
+
+func newIngressFixture() *ingressFixture {
+	return &ingressFixture{
+		clusters: &fakeIngressStatusAPI{
+			cluster: &ibmcloud.Cluster{ID: testClusterID, Name: "my-cluster", Ingress: ibmcloud.ClusterIngress{Status: "healthy", SecretName: testIngressSecret}},
+			secrets: []ibmcloud.IngressSecret{{Name: testIngressSecret, Status: "created", ExpiresOn: "2027-01-15T10:00:00+0000"}},
+		},
+		loadBalancers: &fakeLoadBalancerAPI{loadBalancers: []ibmcloud.LoadBalancer{
+			{ID: "lb-other", Name: "other", Hostname: "other.lb.appdomain.cloud", ProvisioningStatus: "active", OperatingStatus: "online"},
+			{ID: "lb-1", Name: "kube-" + testClusterID + "-router", Hostname: testRouterHostname, ProvisioningStatus: "active", OperatingStatus: "online"},
+		}},
+		conditions:    `{"type": "Available", "status": "True"}, {"type": "Degraded", "status": "False"}, {"type": "LoadBalancerReady", "status": "True"}`,
+		routerPods:    []string{routerPod("router-default-a", "10.0.1.4", "True"), routerPod("router-default-b", "10.0.2.4", "True")},
+		serviceStatus: fmt.Sprintf(`{"loadBalancer": {"ingress": [{"hostname": %q}]}}`, testRouterHostname),
+	}
+}
+
+func routerPod(name string, node string, ready string) string {
+	return fmt.Sprintf(`{"metadata": {"name": %q}, "spec": {"nodeName": %q}, "status": {"conditions": [{"type": "Ready", "status": %q}]}}`, name, node, ready)
+}
+
+func (f *ingressFixture) runner() *kubetest.Runner {
+	runner := kubetest.NewRunner()
+	runner.OnOutput("get ingresscontroller default --namespace openshift-ingress-operator", `{"status": {"conditions": [`+f.conditions+`]}}`)
+	runner.OnOutput("get pods --namespace openshift-ingress", `{"items": [`+strings.Join(f.routerPods, ",")+`]}`)
+	runner.OnOutput("get nodes", `{"items": [
+		{"metadata": {"name": "10.0.1.4", "labels": {"topology.kubernetes.io/zone": "us-south-1"}}},
+		{"metadata": {"name": "10.0.2.4", "labels": {"topology.kubernetes.io/zone": "us-south-2"}}}
+	]}`)
+	runner.OnOutput("get service router-default --namespace openshift-ingress", `{"status": `+f.serviceStatus+`}`)
+	return runner
+}
+
+func TestIngressDiagnostics(t *testing.T) {
+	now := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name   string
+		mutate func(f *ingressFixture)
+		errors []string
+	}{
+		{
+			name:   "healthy",
+			mutate: func(f *ingressFixture) {},
+		},
+		{
+			name: "kubernetes service reports a warning",
+			mutate: func(f *ingressFixture) {
+				f.clusters.cluster.Ingress.Status = "warning"
+				f.clusters.cluster.Ingress.Message = "Could not upload certificates to Certificate Manager instance"
+			},
+			errors: []string{`kubernetes service reports ingress status "warning": Could not upload certificates to Certificate Manager instance`},
+		},
+		{
+			name: "ingresscontroller degraded",
+			mutate: func(f *ingressFixture) {
+				f.conditions = `{"type": "Available", "status": "False", "reason": "DeploymentUnavailable", "message": "0/2 of replicas are available"},
+					{"type": "Degraded", "status": "True", "reason": "DeploymentUnavailable", "message": "router deployment is unavailable"}`
+			},
+			errors: []string{
+				"ingresscontroller default is Available=False, expected True: DeploymentUnavailable: 0/2 of replicas are available",
+				"ingresscontroller default is Degraded=True, expected False: DeploymentUnavailable: router deployment is unavailable",
+			},
+		},
+		{
+			name:   "router not ready in a zone",
+			mutate: func(f *ingressFixture) { f.routerPods[1] = routerPod("router-default-b", "10.0.2.4", "False") },
+			errors: []string{"0 of 1 router pods ready in zone us-south-2"},
+		},
+		{
+			name:   "no router pods",
+			mutate: func(f *ingressFixture) { f.routerPods = nil },
+			errors: []string{"no router pods of the default ingresscontroller"},
+		},
+		{
+			name:   "load balancer still provisioning",
+			mutate: func(f *ingressFixture) { f.loadBalancers.loadBalancers[1].ProvisioningStatus = "create_pending" },
+			errors: []string{"load balancer kube-" + testClusterID + "-router is create_pending and online, expected active and online"},
+		},
+		{
+			name:   "load balancer not created yet",
+			mutate: func(f *ingressFixture) { f.serviceStatus = `{"loadBalancer": {}}` },
+			errors: []string{"service openshift-ingress/router-default has no load balancer hostname yet"},
+		},
+		{
+			name:   "load balancer deleted",
+			mutate: func(f *ingressFixture) { f.loadBalancers.loadBalancers = f.loadBalancers.loadBalancers[:1] },
+			errors: []string{"no VPC load balancer with hostname " + testRouterHostname},
+		},
+		{
+			name:   "load balancers not readable",
+			mutate: func(f *ingressFixture) { f.loadBalancers.err = errors.New("forbidden") },
+			errors: []string{"could not read load balancer: forbidden", "no VPC load balancer with hostname " + testRouterHostname},
+		},
+		{
+			name:   "certificate expired",
+			mutate: func(f *ingressFixture) { f.clusters.secrets[0].ExpiresOn = "2026-09-30T10:00:00+0000" },
+			errors: []string{"default ingress certificate " + testIngressSecret + " expired on 2026-09-30T10:00:00Z"},
+		},
+		{
+			name:   "certificate not reported",
+			mutate: func(f *ingressFixture) { f.clusters.cluster.Ingress.SecretName = "" },
+			errors: []string{"default ingress certificate is not reported yet"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			f := newIngressFixture()
+			tc.mutate(f)
+
+			status, err := IngressDiagnostics(context.Background(), f.clusters, f.runner(), f.loadBalancers, "my-cluster")
+			require.NoError(t, err)
+			err = status.Err(now)
+			if len(tc.errors) == 0 {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Equal(t, tc.errors, strings.Split(err.Error(), "\n"))
+		})
+	}
+}
+
+func TestIngressStatusString(t *testing.T) {
+	f := newIngressFixture()
+	f.routerPods[1] = routerPod("router-default-b", "10.0.2.4", "False")
+
+	status, err := IngressDiagnostics(context.Background(), f.clusters, f.runner(), f.loadBalancers, "my-cluster")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]RouterReadiness{"us-south-1": {Ready: 1, Total: 1}, "us-south-2": {Ready: 0, Total: 1}}, status.Routers)
+	assert.Equal(t, "True", status.Conditions["Available"].Status)
+	assert.Equal(t, time.Date(2027, 1, 15, 10, 0, 0, 0, time.UTC), status.CertificateExpiry.UTC())
+	assert.Equal(t, `cluster my-cluster ingress: healthy
+ingresscontroller default: Available=True, Degraded=False, LoadBalancerReady=True
+routers: us-south-1 1/1 ready, us-south-2 0/1 ready
+load balancer `+testRouterHostname+`: active, online
+certificate `+testIngressSecret+`: created, expires 2027-01-15T10:00:00+0000`, status.String())
+}
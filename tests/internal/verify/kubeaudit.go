@@ -0,0 +1,124 @@
+package verify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/terraform-ibm-modules/terraform-ibm-base-ocp-vpc/internal/kube"
+)
+
+// kubeAuditProbeNamespace is where the marked ConfigMap is created. It is not excluded by either audit policy profile.
+const kubeAuditProbeNamespace = "default"
+
+// auditLevelsByPolicy lists the audit levels that a ConfigMap create event is logged with for each audit_log_policy of
+// the kube-audit module. The verbose profile adds the request body, the default profile only logs metadata.
+var auditLevelsByPolicy = map[string][]string{
+	"default": {"Metadata"},
+	"verbose": {"Request", "RequestResponse"},
+}
+
+// KubeAudit describes a kube-audit deployment and the audit_log_policy it was registered with
+type KubeAudit struct {
+	Namespace  string
+	Deployment string
+	Policy     string
+}
+
+// auditEvent is the subset of an audit.k8s.io/v1 Event used by the delivery check
+type auditEvent struct {
+	AuditID   string          `json:"auditID"`
+	Level     string          `json:"level"`
+	Verb      string          `json:"verb"`
+	ObjectRef *auditObjectRef `json:"objectRef"`
+	Request   json.RawMessage `json:"requestObject"`
+}
+
+type auditObjectRef struct {
+	Resource  string `json:"resource"`
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+}
+
+// KubeAuditDelivery makes a marked API request by creating and deleting a ConfigMap named marker, then reads the logs of
+// the kube-audit deployment until the create event shows up or ctx is done. It returns how long the event took to
+// arrive, and fails if the event was logged with a level that does not match the audit policy.
+func KubeAuditDelivery(ctx context.Context, kubectl kube.Runner, audit KubeAudit, marker string, interval time.Duration) (time.Duration, error) {
+	levels, ok := auditLevelsByPolicy[audit.Policy]
+	if !ok {
+		return 0, fmt.Errorf("unknown audit log policy %q", audit.Policy)
+	}
+
+	start := time.Now()
+	if _, err := kubectl.Kubectl(ctx, "", "create", "configmap", marker, "--namespace", kubeAuditProbeNamespace, "--from-literal", "marker="+marker); err != nil {
+		return 0, fmt.Errorf("error creating audit probe configmap: %w", err)
+	}
+	if _, err := kubectl.Kubectl(ctx, "", "delete", "configmap", marker, "--namespace", kubeAuditProbeNamespace, "--ignore-not-found"); err != nil {
+		return 0, fmt.Errorf("error deleting audit probe configmap: %w", err)
+	}
+	// allow for clock skew between the test runner and the cluster
+	since := start.Add(-time.Minute).UTC().Format(time.RFC3339)
+
+	var event *auditEvent
+	err := Eventually(ctx, interval, func(ctx context.Context) error {
+		logs, err := kubectl.Kubectl(ctx, "", "logs", "deployment/"+audit.Deployment, "--namespace", audit.Namespace, "--all-containers", "--since-time", since)
+		if err != nil {
+			return err
+		}
+		event = findAuditEvent(logs, marker)
+		if event == nil {
+			return fmt.Errorf("audit event for configmap %s/%s not found in logs of %s/%s", kubeAuditProbeNamespace, marker, audit.Namespace, audit.Deployment)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	latency := time.Since(start)
+
+	if !slices.Contains(levels, event.Level) {
+		return latency, fmt.Errorf("audit event %s was logged at level %q, expected one of %v for audit log policy %q", event.AuditID, event.Level, levels, audit.Policy)
+	}
+	hasRequestBody := len(event.Request) > 0 && string(event.Request) != "null"
+	if wantRequestBody := audit.Policy == "verbose"; hasRequestBody != wantRequestBody {
+		return latency, fmt.Errorf("audit event %s has request body logged: %t, expected %t for audit log policy %q", event.AuditID, hasRequestBody, wantRequestBody, audit.Policy)
+	}
+	return latency, nil
+}
+
+// findAuditEvent returns the create event of the marked ConfigMap from the webhook listener logs. Lines may hold a
+// single event or an EventList, optionally preceded by a log prefix.
+func findAuditEvent(logs string, marker string) *auditEvent {
+	for _, line := range strings.Split(logs, "\n") {
+		if !strings.Contains(line, marker) {
+			continue
+		}
+		start := strings.Index(line, "{")
+		if start < 0 {
+			continue
+		}
+		for _, event := range decodeAuditEvents([]byte(line[start:])) {
+			if event.Verb == "create" && event.ObjectRef != nil && event.ObjectRef.Resource == "configmaps" && event.ObjectRef.Name == marker {
+				return &event
+			}
+		}
+	}
+	return nil
+}
+
+func decodeAuditEvents(data []byte) []auditEvent {
+	var list struct {
+		Items []auditEvent `json:"items"`
+	}
+	if err := json.Unmarshal(data, &list); err == nil && len(list.Items) > 0 {
+		return list.Items
+	}
+	var event auditEvent
+	if err := json.Unmarshal(data, &event); err == nil && event.AuditID != "" {
+		return []auditEvent{event}
+	}
+	return nil
+}
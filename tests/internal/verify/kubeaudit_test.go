@@ -0,0 +1,102 @@
+package verify
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/terraform-ibm-modules/terraform-ibm-base-ocp-vpc/internal/kube/kubetest"
+)
+
+const testMarker = "kube-audit-probe-x1y2z3"
+
+func auditEventJSON(level string, withRequest bool) string {
+	request := ""
+	if withRequest {
+		request = fmt.Sprintf(`,"requestObject":{"kind":"ConfigMap","metadata":{"name":%q}}`, testMarker)
+	}
+	return fmt.Sprintf(`{"kind":"Event","auditID":"a-1","level":%q,"verb":"create","objectRef":{"resource":"configmaps","namespace":"default","name":%q}%s}`, level, testMarker, request)
+}
+
+func newAuditRunner(logs ...string) *kubetest.Runner {
+	runner := kubetest.NewRunner()
+	runner.OnOutput("create configmap "+testMarker, "configmap/"+testMarker+" created")
+	runner.OnOutput("delete configmap "+testMarker, "configmap \""+testMarker+"\" deleted")
+	calls := 0
+	runner.On("logs deployment/ibmcloud-kube-audit", func([]string, string) (string, error) {
+		// return the scripted logs one poll at a time, repeating the last one
+		out := logs[min(calls, len(logs)-1)]
+		calls++
+		return out, nil
+	})
+	return runner
+}
+
+func TestKubeAuditDelivery(t *testing.T) {
+	unrelated := `{"kind":"Event","auditID":"a-0","level":"Metadata","verb":"get","objectRef":{"resource":"pods","namespace":"default","name":"x"}}`
+
+	testCases := []struct {
+		name   string
+		policy string
+		logs   []string
+		error  string
+	}{
+		{
+			name:   "default policy logs metadata",
+			policy: "default",
+			logs:   []string{unrelated, unrelated + "\n" + auditEventJSON("Metadata", false)},
+		},
+		{
+			name:   "verbose policy logs request body in an event list",
+			policy: "verbose",
+			logs:   []string{`2025-01-01T00:00:00Z INFO received {"kind":"EventList","items":[` + auditEventJSON("RequestResponse", true) + `]}`},
+		},
+		{
+			name:   "verbose policy with metadata only event",
+			policy: "verbose",
+			logs:   []string{auditEventJSON("Metadata", false)},
+			error:  `was logged at level "Metadata", expected one of [Request RequestResponse]`,
+		},
+		{
+			name:   "default policy with request body",
+			policy: "default",
+			logs:   []string{auditEventJSON("Metadata", true)},
+			error:  "has request body logged: true, expected false",
+		},
+		{
+			name:   "event never arrives",
+			policy: "default",
+			logs:   []string{unrelated},
+			error:  "audit event for configmap default/" + testMarker + " not found",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			runner := newAuditRunner(tc.logs...)
+			ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+			defer cancel()
+
+			_, err := KubeAuditDelivery(ctx, runner, KubeAudit{Namespace: "ibm-kube-audit", Deployment: "ibmcloud-kube-audit", Policy: tc.policy}, testMarker, time.Millisecond)
+			if tc.error == "" {
+				assert.NoError(t, err)
+			} else {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tc.error)
+			}
+
+			calls := runner.Calls()
+			require.GreaterOrEqual(t, len(calls), 3)
+			assert.Contains(t, calls[0], "create configmap "+testMarker)
+			assert.Contains(t, calls[1], "delete configmap "+testMarker)
+		})
+	}
+}
+
+func TestKubeAuditDeliveryUnknownPolicy(t *testing.T) {
+	_, err := KubeAuditDelivery(context.Background(), kubetest.NewRunner(), KubeAudit{Policy: "none"}, testMarker, time.Millisecond)
+	assert.ErrorContains(t, err, `unknown audit log policy "none"`)
+}
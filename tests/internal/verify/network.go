@@ -0,0 +1,272 @@
+package verify
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/netip"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/terraform-ibm-modules/terraform-ibm-base-ocp-vpc/internal/kube"
+)
+
+// Pod and service subnets of a cluster that the module does not set pod_subnet_cidr and service_subnet_cidr for
+const (
+	DefaultPodSubnet     = "172.30.0.0/16"
+	DefaultServiceSubnet = "172.21.0.0/16"
+)
+
+// EgressProbeURL is requested by the egress probe. It is only reachable over the public network.
+const EgressProbeURL = "https://www.redhat.com"
+
+// egressProbeName names the job of the egress probe
+const egressProbeName = "egress-probe"
+
+// Lines the egress probe logs with the result of the request
+const (
+	egressAllowed = "egress: allowed"
+	egressBlocked = "egress: blocked: "
+)
+
+// egressProbeManifest is a job that requests the URL once and logs whether the request got through. The job succeeds
+// either way, so that a blocked request is told apart from a probe that could not run.
+var egressProbeManifest = template.Must(template.New("egress").Parse(`apiVersion: v1
+kind: Namespace
+metadata:
+  name: {{.Namespace}}
+---
+apiVersion: batch/v1
+kind: Job
+metadata:
+  name: {{.Name}}
+  namespace: {{.Namespace}}
+spec:
+  backoffLimit: 0
+  activeDeadlineSeconds: 300
+  template:
+    spec:
+      restartPolicy: Never
+      containers:
+        - name: probe
+          image: {{.Image}}
+          command: ["/bin/sh", "-c", "if out=$(curl --silent --show-error --max-time 20 --output /dev/null \"$URL\" 2>&1); then echo \"{{.Allowed}}\"; else echo \"{{.Blocked}}$out\"; fi"]
+          env:
+            - name: URL
+              value: "{{.URL}}"
+          resources:
+            requests:
+              cpu: 10m
+              memory: 32Mi
+            limits:
+              memory: 128Mi
+          securityContext:
+            allowPrivilegeEscalation: false
+            runAsNonRoot: true
+            capabilities:
+              drop: ["ALL"]
+            seccompProfile:
+              type: RuntimeDefault
+`))
+
+// EgressProbe is the job deployed by NetworkProbe to request a public URL from a pod
+type EgressProbe struct {
+	// Namespace is created for the probe, and deleted with it
+	Namespace string
+	// Image must provide curl. Clusters with outbound traffic protection cannot pull from public registries, see
+	// ToolsImage.
+	Image string
+	URL   string
+}
+
+// NetworkExpectation is what the network of a cluster should look like according to the inputs of the module
+type NetworkExpectation struct {
+	// OutboundTraffic is the disable_outbound_traffic_protection input, whether pods can reach the public network
+	OutboundTraffic bool
+	// PodSubnet and ServiceSubnet are the pod_subnet_cidr and service_subnet_cidr inputs, the defaults when empty
+	PodSubnet     string
+	ServiceSubnet string
+}
+
+// NetworkReport is what NetworkProbe observed
+type NetworkReport struct {
+	// Egress is the result logged by the egress probe
+	Egress   string
+	Pods     int
+	Services int
+}
+
+// probeJob, podAddresses and serviceAddresses are the subsets of the Kubernetes objects read by the network probe
+type probeJob struct {
+	Status struct {
+		Succeeded int `json:"succeeded"`
+		Failed    int `json:"failed"`
+	} `json:"status"`
+}
+
+type podAddresses struct {
+	Items []struct {
+		Metadata struct {
+			Namespace string `json:"namespace"`
+			Name      string `json:"name"`
+		} `json:"metadata"`
+		Spec struct {
+			HostNetwork bool `json:"hostNetwork"`
+		} `json:"spec"`
+		Status struct {
+			PodIP string `json:"podIP"`
+		} `json:"status"`
+	} `json:"items"`
+}
+
+type serviceAddresses struct {
+	Items []struct {
+		Metadata struct {
+			Namespace string `json:"namespace"`
+			Name      string `json:"name"`
+		} `json:"metadata"`
+		Spec struct {
+			ClusterIP string `json:"clusterIP"`
+		} `json:"spec"`
+	} `json:"items"`
+}
+
+// ToolsImage returns the image of the tools imagestream of the cluster, which provides curl and is pulled like the
+// other images of the OpenShift release, also when the cluster has no public outbound access
+func ToolsImage(ctx context.Context, kubectl kube.Runner) (string, error) {
+	var tag struct {
+		Image struct {
+			DockerImageReference string `json:"dockerImageReference"`
+		} `json:"image"`
+	}
+	if err := kube.GetJSON(ctx, kubectl, &tag, "imagestreamtag", "tools:latest", "--namespace", "openshift"); err != nil {
+		return "", fmt.Errorf("error getting the tools image: %w", err)
+	}
+	if tag.Image.DockerImageReference == "" {
+		return "", errors.New("imagestreamtag openshift/tools:latest has no image")
+	}
+	return tag.Image.DockerImageReference, nil
+}
+
+// NetworkProbe runs the egress probe and waits every interval for it to complete, then checks that the public egress
+// is allowed or blocked as expected, and that the addresses of all pods and services are in the expected subnets. Every
+// mismatch is returned. The namespace of the probe is deleted afterwards, also when the check fails.
+func NetworkProbe(ctx context.Context, kubectl kube.Runner, probe EgressProbe, expected NetworkExpectation, interval time.Duration) (report NetworkReport, err error) {
+	podSubnet, err := parseSubnet(expected.PodSubnet, DefaultPodSubnet)
+	if err != nil {
+		return report, err
+	}
+	serviceSubnet, err := parseSubnet(expected.ServiceSubnet, DefaultServiceSubnet)
+	if err != nil {
+		return report, err
+	}
+
+	var manifest bytes.Buffer
+	if err := egressProbeManifest.Execute(&manifest, struct {
+		EgressProbe
+		Name    string
+		Allowed string
+		Blocked string
+	}{probe, egressProbeName, egressAllowed, egressBlocked}); err != nil {
+		return report, err
+	}
+
+	defer func() {
+		// ctx may be done by now, which must not leave the probe behind
+		cleanupCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), workloadCleanupTimeout)
+		defer cancel()
+		if _, deleteErr := kubectl.Kubectl(cleanupCtx, "", "delete", "namespace", probe.Namespace, "--ignore-not-found", "--wait=true"); deleteErr != nil {
+			err = errors.Join(err, fmt.Errorf("error deleting the egress probe: %w", deleteErr))
+		}
+	}()
+	if _, err := kubectl.Kubectl(ctx, manifest.String(), "apply", "--filename", "-"); err != nil {
+		return report, fmt.Errorf("error deploying the egress probe: %w", err)
+	}
+
+	var job probeJob
+	err = Eventually(ctx, interval, func(ctx context.Context) error {
+		if err := kube.GetJSON(ctx, kubectl, &job, "job", egressProbeName, "--namespace", probe.Namespace); err != nil {
+			return err
+		}
+		if job.Status.Succeeded == 0 && job.Status.Failed == 0 {
+			return fmt.Errorf("job %s has not completed yet", egressProbeName)
+		}
+		return nil
+	})
+	if err != nil {
+		return report, fmt.Errorf("egress probe did not complete: %w", err)
+	}
+	if job.Status.Failed > 0 {
+		return report, fmt.Errorf("egress probe job %s failed to run", egressProbeName)
+	}
+	logs, err := kubectl.Kubectl(ctx, "", "logs", "job/"+egressProbeName, "--namespace", probe.Namespace)
+	if err != nil {
+		return report, fmt.Errorf("error reading the egress probe result: %w", err)
+	}
+	report.Egress = strings.TrimSpace(logs)
+
+	var errs []error
+	switch allowed := report.Egress == egressAllowed; {
+	case !allowed && !strings.HasPrefix(report.Egress, egressBlocked):
+		errs = append(errs, fmt.Errorf("egress probe logged an unexpected result %q", report.Egress))
+	case allowed && !expected.OutboundTraffic:
+		errs = append(errs, fmt.Errorf("public egress to %s is allowed, expected it to be blocked by outbound traffic protection", probe.URL))
+	case !allowed && expected.OutboundTraffic:
+		errs = append(errs, fmt.Errorf("public egress to %s is blocked (%s), expected it to be allowed", probe.URL, strings.TrimPrefix(report.Egress, egressBlocked)))
+	}
+
+	var pods podAddresses
+	if err := kube.GetJSON(ctx, kubectl, &pods, "pods", "--all-namespaces"); err != nil {
+		errs = append(errs, fmt.Errorf("error listing pods: %w", err))
+	}
+	for _, pod := range pods.Items {
+		// pods on the host network use the address of their node
+		if pod.Spec.HostNetwork || pod.Status.PodIP == "" {
+			continue
+		}
+		report.Pods++
+		if err := inSubnet(pod.Status.PodIP, podSubnet); err != nil {
+			errs = append(errs, fmt.Errorf("pod %s/%s: %w", pod.Metadata.Namespace, pod.Metadata.Name, err))
+		}
+	}
+
+	var services serviceAddresses
+	if err := kube.GetJSON(ctx, kubectl, &services, "services", "--all-namespaces"); err != nil {
+		errs = append(errs, fmt.Errorf("error listing services: %w", err))
+	}
+	for _, service := range services.Items {
+		// headless services have no cluster IP
+		if service.Spec.ClusterIP == "" || service.Spec.ClusterIP == "None" {
+			continue
+		}
+		report.Services++
+		if err := inSubnet(service.Spec.ClusterIP, serviceSubnet); err != nil {
+			errs = append(errs, fmt.Errorf("service %s/%s: %w", service.Metadata.Namespace, service.Metadata.Name, err))
+		}
+	}
+	return report, errors.Join(errs...)
+}
+
+func parseSubnet(cidr string, fallback string) (netip.Prefix, error) {
+	if cidr == "" {
+		cidr = fallback
+	}
+	subnet, err := netip.ParsePrefix(cidr)
+	if err != nil {
+		return netip.Prefix{}, fmt.Errorf("invalid subnet %q: %w", cidr, err)
+	}
+	return subnet.Masked(), nil
+}
+
+func inSubnet(address string, subnet netip.Prefix) error {
+	ip, err := netip.ParseAddr(address)
+	if err != nil {
+		return fmt.Errorf("invalid address %q: %w", address, err)
+	}
+	if !subnet.Contains(ip) {
+		return fmt.Errorf("address %s is outside of subnet %s", address, subnet)
+	}
+	return nil
+}
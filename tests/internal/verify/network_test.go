@@ -0,0 +1,129 @@
+package verify
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/terraform-ibm-modules/terraform-ibm-base-ocp-vpc/internal/kube/kubetest"
+)
+
+const testToolsImage = "quay.io/openshift-release-dev/ocp-v4.0-art-dev@sha256:0123456789abcdef"
+
+// newNetworkRunner scripts kubectl for an egress probe that completes on the second poll and logs result
+func newNetworkRunner(result string) (*kubetest.Runner, *string) {
+	runner := kubetest.NewRunner()
+	manifest := new(string)
+	runner.On("apply --filename -", func(_ []string, stdin string) (string, error) {
+		*manifest = stdin
+		return "namespace/egress created", nil
+	})
+	polls := 0
+	runner.On("get job egress-probe --namespace egress", func([]string, string) (string, error) {
+		polls++
+		if polls == 1 {
+			return `{"status": {"active": 1}}`, nil
+		}
+		return `{"status": {"succeeded": 1}}`, nil
+	})
+	runner.OnOutput("logs job/egress-probe --namespace egress", result+"\n")
+	runner.OnOutput("get pods --all-namespaces", `{"items": [`+testPods+`]}`)
+	runner.OnOutput("get services --all-namespaces", `{"items": [`+testServices+`]}`)
+	runner.OnOutput("delete namespace egress", `namespace "egress" deleted`)
+	return runner, manifest
+}
+
+const (
+	testPods = `{"metadata": {"namespace": "openshift-dns", "name": "dns-default-abc"}, "spec": {}, "status": {"podIP": "172.30.4.12"}},
+		{"metadata": {"namespace": "openshift-dns", "name": "node-resolver-abc"}, "spec": {"hostNetwork": true}, "status": {"podIP": "10.240.0.4"}},
+		{"metadata": {"namespace": "egress", "name": "egress-probe-xyz"}, "spec": {}, "status": {}}`
+	testServices = `{"metadata": {"namespace": "default", "name": "kubernetes"}, "spec": {"clusterIP": "172.21.0.1"}},
+		{"metadata": {"namespace": "openshift-dns", "name": "dns-default"}, "spec": {"clusterIP": "172.21.0.10"}},
+		{"metadata": {"namespace": "openshift-monitoring", "name": "prometheus-operated"}, "spec": {"clusterIP": "None"}}`
+)
+
+func TestNetworkProbe(t *testing.T) {
+	testCases := []struct {
+		name     string
+		result   string
+		expected NetworkExpectation
+		errors   []string
+	}{
+		{
+			name:     "egress allowed with the default subnets",
+			result:   egressAllowed,
+			expected: NetworkExpectation{OutboundTraffic: true},
+		},
+		{
+			name:     "egress blocked by outbound traffic protection",
+			result:   "egress: blocked: curl: (28) Connection timed out after 20001 milliseconds",
+			expected: NetworkExpectation{},
+		},
+		{
+			name:     "egress not blocked",
+			result:   egressAllowed,
+			expected: NetworkExpectation{},
+			errors:   []string{"public egress to " + EgressProbeURL + " is allowed, expected it to be blocked by outbound traffic protection"},
+		},
+		{
+			name:     "egress not allowed",
+			result:   "egress: blocked: curl: (28) Connection timed out after 20001 milliseconds",
+			expected: NetworkExpectation{OutboundTraffic: true},
+			errors:   []string{"public egress to " + EgressProbeURL + " is blocked (curl: (28) Connection timed out after 20001 milliseconds), expected it to be allowed"},
+		},
+		{
+			name:     "addresses outside of the requested subnets",
+			result:   egressAllowed,
+			expected: NetworkExpectation{OutboundTraffic: true, PodSubnet: "10.128.0.0/16", ServiceSubnet: "172.21.0.0/24"},
+			errors:   []string{"pod openshift-dns/dns-default-abc: address 172.30.4.12 is outside of subnet 10.128.0.0/16"},
+		},
+		{
+			name:     "unexpected probe output",
+			result:   "sh: curl: not found",
+			expected: NetworkExpectation{OutboundTraffic: true},
+			errors:   []string{`egress probe logged an unexpected result "sh: curl: not found"`},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			runner, manifest := newNetworkRunner(tc.result)
+			probe := EgressProbe{Namespace: "egress", Image: testToolsImage, URL: EgressProbeURL}
+
+			report, err := NetworkProbe(context.Background(), runner, probe, tc.expected, time.Millisecond)
+			calls := runner.Calls()
+			assert.Equal(t, "delete namespace egress --ignore-not-found --wait=true", calls[len(calls)-1], "the probe is cleaned up")
+			assert.Contains(t, *manifest, "image: "+testToolsImage)
+			assert.Contains(t, *manifest, `value: "`+EgressProbeURL+`"`)
+			assert.Equal(t, tc.result, report.Egress)
+			assert.Equal(t, 1, report.Pods, "pods on the host network or without address are skipped")
+			assert.Equal(t, 2, report.Services, "headless services are skipped")
+			if len(tc.errors) == 0 {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Equal(t, tc.errors, strings.Split(err.Error(), "\n"))
+		})
+	}
+}
+
+func TestNetworkProbeJobFailed(t *testing.T) {
+	runner, _ := newNetworkRunner(egressAllowed)
+	runner.OnOutput("get job egress-probe --namespace egress", `{"status": {"failed": 1}}`)
+
+	_, err := NetworkProbe(context.Background(), runner, EgressProbe{Namespace: "egress", URL: EgressProbeURL}, NetworkExpectation{}, time.Millisecond)
+	assert.EqualError(t, err, "egress probe job egress-probe failed to run")
+	calls := runner.Calls()
+	assert.Equal(t, "delete namespace egress --ignore-not-found --wait=true", calls[len(calls)-1])
+}
+
+func TestToolsImage(t *testing.T) {
+	runner := kubetest.NewRunner()
+	runner.OnOutput("get imagestreamtag tools:latest --namespace openshift", `{"image": {"dockerImageReference": "`+testToolsImage+`"}}`)
+	image, err := ToolsImage(context.Background(), runner)
+	require.NoError(t, err)
+	assert.Equal(t, testToolsImage, image)
+}
@@ -0,0 +1,261 @@
+package verify
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/terraform-ibm-modules/terraform-ibm-base-ocp-vpc/internal/ibmcloud"
+	"github.com/terraform-ibm-modules/terraform-ibm-base-ocp-vpc/internal/kube"
+)
+
+// InstanceAPI is the subset of the Resource Controller API used by the registry storage check
+type InstanceAPI interface {
+	ListResourceInstances(ctx context.Context, resourceID string, name string) ([]ibmcloud.ResourceInstance, error)
+}
+
+// internalRegistry is the host of the internal image registry within the cluster
+const internalRegistry = "image-registry.openshift-image-registry.svc:5000/"
+
+// registryProbeName names the image stream and build config of the registry push
+const registryProbeName = "registry-probe"
+
+// registryProbeManifest builds an image without layers from an inline Dockerfile and pushes it to an image stream,
+// which stores its manifest and config in the storage of the internal registry
+var registryProbeManifest = template.Must(template.New("registry").Parse(`apiVersion: v1
+kind: Namespace
+metadata:
+  name: {{.Namespace}}
+---
+apiVersion: image.openshift.io/v1
+kind: ImageStream
+metadata:
+  name: {{.Name}}
+  namespace: {{.Namespace}}
+---
+apiVersion: build.openshift.io/v1
+kind: BuildConfig
+metadata:
+  name: {{.Name}}
+  namespace: {{.Namespace}}
+spec:
+  runPolicy: Serial
+  source:
+    dockerfile: |
+      FROM scratch
+      LABEL io.terraform-ibm-modules.registry-probe="{{.Marker}}"
+  strategy:
+    type: Docker
+    dockerStrategy: {}
+  output:
+    to:
+      kind: ImageStreamTag
+      name: {{.Name}}:latest
+  triggers:
+    - type: ConfigChange
+`))
+
+// Phases of an OpenShift build that it does not leave
+var finalBuildPhases = []string{"Complete", "Failed", "Error", "Cancelled"}
+
+// expectedRegistryConditions are the status conditions of an image registry that can store images
+var expectedRegistryConditions = []struct {
+	Type   string
+	Status string
+}{
+	{Type: "Available", Status: "True"},
+	{Type: "Degraded", Status: "False"},
+}
+
+// RegistryExpectation is the Object Storage instance that the internal registry should use according to the inputs of
+// the module
+type RegistryExpectation struct {
+	// ExistingCOSInstance is the CRN of the existing_cos_id input. When it is empty the module creates an instance.
+	ExistingCOSInstance string
+	// NewCOSInstanceName is the name of the instance the module creates, see DefaultCOSInstanceName
+	NewCOSInstanceName string
+}
+
+// DefaultCOSInstanceName is the name of the Object Storage instance that the module creates for the registry of the
+// cluster when cos_name is not set
+func DefaultCOSInstanceName(cluster string) string {
+	return cluster + "_cos"
+}
+
+// RegistryStorage is the storage of the internal image registry as reported by the image registry operator
+type RegistryStorage struct {
+	ManagementState    string
+	Bucket             string
+	Location           string
+	ServiceInstanceCRN string
+	Conditions         map[string]IngressCondition
+}
+
+// RegistryProbe is the image build pushed by RegistryPush
+type RegistryProbe struct {
+	// Namespace is created for the build, and deleted with it
+	Namespace string
+	// Marker is set as a label of the image, so that every push has a new digest
+	Marker string
+}
+
+// imageRegistryConfig and registryBuilds are the subsets of the Kubernetes objects read by the registry checks
+type imageRegistryConfig struct {
+	Spec struct {
+		ManagementState string `json:"managementState"`
+	} `json:"spec"`
+	Status struct {
+		Storage struct {
+			IBMCOS *struct {
+				Bucket             string `json:"bucket"`
+				Location           string `json:"location"`
+				ServiceInstanceCRN string `json:"serviceInstanceCRN"`
+			} `json:"ibmcos"`
+		} `json:"storage"`
+		Conditions []struct {
+			Type string `json:"type"`
+			IngressCondition
+		} `json:"conditions"`
+	} `json:"status"`
+}
+
+type registryBuilds struct {
+	Items []struct {
+		Metadata struct {
+			Name string `json:"name"`
+		} `json:"metadata"`
+		Status struct {
+			Phase   string `json:"phase"`
+			Reason  string `json:"reason"`
+			Message string `json:"message"`
+		} `json:"status"`
+	} `json:"items"`
+}
+
+// RegistryStorageCheck reads the storage of the internal registry, and checks that the registry is available and
+// stores images in the supplied Object Storage instance, or in the one the module created for it. When an existing
+// instance is supplied it also checks that the module did not create one.
+func RegistryStorageCheck(ctx context.Context, kubectl kube.Runner, instances InstanceAPI, expected RegistryExpectation) (RegistryStorage, error) {
+	var storage RegistryStorage
+	var config imageRegistryConfig
+	if err := kube.GetJSON(ctx, kubectl, &config, "configs.imageregistry.operator.openshift.io", "cluster"); err != nil {
+		return storage, fmt.Errorf("error getting the image registry config: %w", err)
+	}
+	storage.ManagementState = config.Spec.ManagementState
+	storage.Conditions = map[string]IngressCondition{}
+	for _, c := range config.Status.Conditions {
+		storage.Conditions[c.Type] = c.IngressCondition
+	}
+	if cos := config.Status.Storage.IBMCOS; cos != nil {
+		storage.Bucket, storage.Location, storage.ServiceInstanceCRN = cos.Bucket, cos.Location, cos.ServiceInstanceCRN
+	}
+
+	var errs []error
+	if storage.ManagementState != "Managed" {
+		errs = append(errs, fmt.Errorf("image registry is %s, expected Managed", storage.ManagementState))
+	}
+	for _, want := range expectedRegistryConditions {
+		if c := storage.Conditions[want.Type]; c.Status != want.Status {
+			errs = append(errs, fmt.Errorf("image registry is %s=%s, expected %s: %s", want.Type, c.Status, want.Status, c.Message))
+		}
+	}
+	if storage.ServiceInstanceCRN == "" {
+		return storage, errors.Join(append(errs, errors.New("image registry does not store images in Object Storage"))...)
+	}
+	registryInstance, err := ibmcloud.ParseCRN(storage.ServiceInstanceCRN)
+	if err != nil {
+		return storage, errors.Join(append(errs, fmt.Errorf("image registry storage: %w", err))...)
+	}
+
+	created, err := instances.ListResourceInstances(ctx, ibmcloud.COSResourceID, expected.NewCOSInstanceName)
+	if err != nil {
+		return storage, errors.Join(append(errs, fmt.Errorf("error listing Object Storage instances: %w", err))...)
+	}
+	if expected.ExistingCOSInstance != "" {
+		existing, err := ibmcloud.ParseCRN(expected.ExistingCOSInstance)
+		if err != nil {
+			return storage, errors.Join(append(errs, fmt.Errorf("existing Object Storage instance: %w", err))...)
+		}
+		if registryInstance.ServiceInstance != existing.ServiceInstance {
+			errs = append(errs, fmt.Errorf("image registry stores images in Object Storage instance %s, expected the existing instance %s", registryInstance.ServiceInstance, existing.ServiceInstance))
+		}
+		for _, instance := range created {
+			errs = append(errs, fmt.Errorf("Object Storage instance %s (%s) was created although an existing instance was supplied", instance.Name, instance.GUID))
+		}
+		return storage, errors.Join(errs...)
+	}
+
+	switch {
+	case len(created) == 0:
+		errs = append(errs, fmt.Errorf("no Object Storage instance named %s was created", expected.NewCOSInstanceName))
+	case len(created) > 1:
+		errs = append(errs, fmt.Errorf("%d Object Storage instances are named %s, expected one", len(created), expected.NewCOSInstanceName))
+	case created[0].GUID != registryInstance.ServiceInstance:
+		errs = append(errs, fmt.Errorf("image registry stores images in Object Storage instance %s, expected the created instance %s", registryInstance.ServiceInstance, created[0].GUID))
+	}
+	return storage, errors.Join(errs...)
+}
+
+// RegistryPush builds an image in the cluster and pushes it to the internal registry, waiting every interval for the
+// build to finish. It returns the pull spec of the pushed image. The namespace of the build is deleted afterwards, also
+// when the push fails.
+func RegistryPush(ctx context.Context, kubectl kube.Runner, probe RegistryProbe, interval time.Duration) (image string, err error) {
+	var manifest bytes.Buffer
+	if err := registryProbeManifest.Execute(&manifest, struct {
+		RegistryProbe
+		Name string
+	}{probe, registryProbeName}); err != nil {
+		return "", err
+	}
+
+	defer func() {
+		// ctx may be done by now, which must not leave the build behind
+		cleanupCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), workloadCleanupTimeout)
+		defer cancel()
+		if _, deleteErr := kubectl.Kubectl(cleanupCtx, "", "delete", "namespace", probe.Namespace, "--ignore-not-found", "--wait=true"); deleteErr != nil {
+			err = errors.Join(err, fmt.Errorf("error deleting the registry probe: %w", deleteErr))
+		}
+	}()
+	if _, err := kubectl.Kubectl(ctx, manifest.String(), "apply", "--filename", "-"); err != nil {
+		return "", fmt.Errorf("error deploying the registry probe: %w", err)
+	}
+
+	var builds registryBuilds
+	err = Eventually(ctx, interval, func(ctx context.Context) error {
+		if err := kube.GetJSON(ctx, kubectl, &builds, "builds", "--namespace", probe.Namespace, "--selector", "openshift.io/build-config.name="+registryProbeName); err != nil {
+			return err
+		}
+		if len(builds.Items) == 0 {
+			return fmt.Errorf("build config %s has not started a build yet", registryProbeName)
+		}
+		if build := builds.Items[0]; !slices.Contains(finalBuildPhases, build.Status.Phase) {
+			return fmt.Errorf("build %s is %s", build.Metadata.Name, build.Status.Phase)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("image was not pushed to the internal registry: %w", err)
+	}
+	if build := builds.Items[0]; build.Status.Phase != "Complete" {
+		return "", fmt.Errorf("build %s is %s: %s: %s", build.Metadata.Name, build.Status.Phase, build.Status.Reason, build.Status.Message)
+	}
+
+	var tag struct {
+		Image struct {
+			DockerImageReference string `json:"dockerImageReference"`
+		} `json:"image"`
+	}
+	if err := kube.GetJSON(ctx, kubectl, &tag, "imagestreamtag", registryProbeName+":latest", "--namespace", probe.Namespace); err != nil {
+		return "", fmt.Errorf("error getting the pushed image: %w", err)
+	}
+	image = tag.Image.DockerImageReference
+	if !strings.HasPrefix(image, internalRegistry) {
+		return image, fmt.Errorf("image %q was not pushed to the internal registry", image)
+	}
+	return image, nil
+}
@@ -0,0 +1,161 @@
+package verify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/terraform-ibm-modules/terraform-ibm-base-ocp-vpc/internal/ibmcloud"
+	"github.com/terraform-ibm-modules/terraform-ibm-base-ocp-vpc/internal/kube/kubetest"
+)
+
+const (
+	testExistingCOS = "crn:v1:bluemix:public:cloud-object-storage:global:a/acct:cos-existing::"
+	testCreatedCOS  = "crn:v1:bluemix:public:cloud-object-storage:global:a/acct:cos-created::"
+)
+
+type fakeInstanceAPI struct {
+	instances map[string][]ibmcloud.ResourceInstance
+	err       error
+}
+
+func (f *fakeInstanceAPI) ListResourceInstances(_ context.Context, resourceID string, name string) ([]ibmcloud.ResourceInstance, error) {
+	if resourceID != ibmcloud.COSResourceID {
+		return nil, fmt.Errorf("unexpected resource ID %s", resourceID)
+	}
+	return f.instances[name], f.err
+}
+
+func registryConfig(managementState string, serviceInstanceCRN string, available string) string {
+	return fmt.Sprintf(`{"spec": {"managementState": %q}, "status": {
+		"storage": {"ibmcos": {"bucket": "my-cluster-registry", "location": "us-south", "serviceInstanceCRN": %q}},
+		"conditions": [{"type": "Available", "status": %q, "message": "The registry is ready"}, {"type": "Degraded", "status": "False"}]
+	}}`, managementState, serviceInstanceCRN, available)
+}
+
+func TestRegistryStorageCheck(t *testing.T) {
+	created := ibmcloud.ResourceInstance{GUID: "cos-created", Name: "my-cluster_cos", State: "active"}
+
+	testCases := []struct {
+		name      string
+		config    string
+		instances []ibmcloud.ResourceInstance
+		expected  RegistryExpectation
+		errors    []string
+	}{
+		{
+			name:     "existing instance",
+			config:   registryConfig("Managed", testExistingCOS, "True"),
+			expected: RegistryExpectation{ExistingCOSInstance: testExistingCOS, NewCOSInstanceName: "my-cluster_cos"},
+		},
+		{
+			name:      "existing instance with an extra instance created",
+			config:    registryConfig("Managed", testCreatedCOS, "True"),
+			instances: []ibmcloud.ResourceInstance{created},
+			expected:  RegistryExpectation{ExistingCOSInstance: testExistingCOS, NewCOSInstanceName: "my-cluster_cos"},
+			errors: []string{
+				"image registry stores images in Object Storage instance cos-created, expected the existing instance cos-existing",
+				"Object Storage instance my-cluster_cos (cos-created) was created although an existing instance was supplied",
+			},
+		},
+		{
+			name:      "created instance",
+			config:    registryConfig("Managed", testCreatedCOS, "True"),
+			instances: []ibmcloud.ResourceInstance{created},
+			expected:  RegistryExpectation{NewCOSInstanceName: "my-cluster_cos"},
+		},
+		{
+			name:     "instance not created",
+			config:   registryConfig("Managed", testExistingCOS, "True"),
+			expected: RegistryExpectation{NewCOSInstanceName: "my-cluster_cos"},
+			errors:   []string{"no Object Storage instance named my-cluster_cos was created"},
+		},
+		{
+			name:     "registry without Object Storage",
+			config:   `{"spec": {"managementState": "Removed"}, "status": {"storage": {}, "conditions": [{"type": "Available", "status": "True"}, {"type": "Degraded", "status": "False"}]}}`,
+			expected: RegistryExpectation{ExistingCOSInstance: testExistingCOS, NewCOSInstanceName: "my-cluster_cos"},
+			errors:   []string{"image registry is Removed, expected Managed", "image registry does not store images in Object Storage"},
+		},
+		{
+			name:     "registry unavailable",
+			config:   registryConfig("Managed", testExistingCOS, "False"),
+			expected: RegistryExpectation{ExistingCOSInstance: testExistingCOS, NewCOSInstanceName: "my-cluster_cos"},
+			errors:   []string{"image registry is Available=False, expected True: The registry is ready"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			runner := kubetest.NewRunner()
+			runner.OnOutput("get configs.imageregistry.operator.openshift.io cluster", tc.config)
+			instances := &fakeInstanceAPI{instances: map[string][]ibmcloud.ResourceInstance{"my-cluster_cos": tc.instances}}
+
+			_, err := RegistryStorageCheck(context.Background(), runner, instances, tc.expected)
+			if len(tc.errors) == 0 {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Equal(t, tc.errors, strings.Split(err.Error(), "\n"))
+		})
+	}
+}
+
+func TestRegistryStorageCheckReport(t *testing.T) {
+	runner := kubetest.NewRunner()
+	runner.OnOutput("get configs.imageregistry.operator.openshift.io cluster", registryConfig("Managed", testExistingCOS, "True"))
+
+	storage, err := RegistryStorageCheck(context.Background(), runner, &fakeInstanceAPI{err: errors.New("forbidden")}, RegistryExpectation{ExistingCOSInstance: testExistingCOS})
+	assert.EqualError(t, err, "error listing Object Storage instances: forbidden")
+	assert.Equal(t, "my-cluster-registry", storage.Bucket)
+	assert.Equal(t, "us-south", storage.Location)
+	assert.Equal(t, testExistingCOS, storage.ServiceInstanceCRN)
+	assert.Equal(t, "True", storage.Conditions["Available"].Status)
+}
+
+// newRegistryRunner scripts kubectl for a registry push whose build reaches phase on the second poll
+func newRegistryRunner(phase string) (*kubetest.Runner, *string) {
+	runner := kubetest.NewRunner()
+	manifest := new(string)
+	runner.On("apply --filename -", func(_ []string, stdin string) (string, error) {
+		*manifest = stdin
+		return "namespace/registry created", nil
+	})
+	polls := 0
+	runner.On("get builds --namespace registry --selector openshift.io/build-config.name=registry-probe", func([]string, string) (string, error) {
+		polls++
+		if polls == 1 {
+			return `{"items": []}`, nil
+		}
+		return `{"items": [{"metadata": {"name": "registry-probe-1"}, "status": {"phase": "` + phase + `", "reason": "PushImageToRegistryFailed", "message": "Failed to push the image to the registry."}}]}`, nil
+	})
+	runner.OnOutput("get imagestreamtag registry-probe:latest --namespace registry",
+		`{"image": {"dockerImageReference": "image-registry.openshift-image-registry.svc:5000/registry/registry-probe@sha256:abc"}}`)
+	runner.OnOutput("delete namespace registry", `namespace "registry" deleted`)
+	return runner, manifest
+}
+
+func TestRegistryPush(t *testing.T) {
+	runner, manifest := newRegistryRunner("Complete")
+
+	image, err := RegistryPush(context.Background(), runner, RegistryProbe{Namespace: "registry", Marker: "registry-probe-x1y2"}, time.Millisecond)
+	require.NoError(t, err)
+	assert.Equal(t, "image-registry.openshift-image-registry.svc:5000/registry/registry-probe@sha256:abc", image)
+	assert.Contains(t, *manifest, "kind: BuildConfig")
+	assert.Contains(t, *manifest, `LABEL io.terraform-ibm-modules.registry-probe="registry-probe-x1y2"`)
+	calls := runner.Calls()
+	assert.Equal(t, "delete namespace registry --ignore-not-found --wait=true", calls[len(calls)-1], "the build is cleaned up")
+}
+
+func TestRegistryPushFailed(t *testing.T) {
+	runner, _ := newRegistryRunner("Failed")
+
+	_, err := RegistryPush(context.Background(), runner, RegistryProbe{Namespace: "registry", Marker: "registry-probe-x1y2"}, time.Millisecond)
+	assert.EqualError(t, err, "build registry-probe-1 is Failed: PushImageToRegistryFailed: Failed to push the image to the registry.")
+	calls := runner.Calls()
+	assert.Equal(t, "delete namespace registry --ignore-not-found --wait=true", calls[len(calls)-1])
+}
@@ -0,0 +1,22 @@
+package verify
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Eventually runs check every interval until it returns nil or ctx is done, in which case the last error is returned
+func Eventually(ctx context.Context, interval time.Duration, check func(ctx context.Context) error) error {
+	for {
+		err := check(ctx)
+		if err == nil {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%w (gave up: %v)", err, ctx.Err())
+		case <-time.After(interval):
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package verify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/terraform-ibm-modules/terraform-ibm-base-ocp-vpc/internal/ibmcloud"
+)
+
+// IngressAPI is the subset of the Kubernetes Service API used by the Secrets Manager ingress check
+type IngressAPI interface {
+	GetCluster(ctx context.Context, cluster string) (*ibmcloud.Cluster, error)
+	ListIngressInstances(ctx context.Context, cluster string) ([]ibmcloud.IngressInstance, error)
+	ListIngressSecrets(ctx context.Context, cluster string) ([]ibmcloud.IngressSecret, error)
+}
+
+// SecretsAPI is the subset of the Secrets Manager API used by the Secrets Manager ingress check
+type SecretsAPI interface {
+	ListSecretGroups(ctx context.Context) ([]ibmcloud.SecretGroup, error)
+	GetSecretMetadata(ctx context.Context, id string) (*ibmcloud.Secret, error)
+}
+
+// SecretsManagerIngress checks the Secrets Manager integration of the fully-configurable solution: a secret group named
+// after the cluster ID exists, the Secrets Manager instance is registered as the default ingress instance using that
+// group, and the default ingress certificate is stored in that group.
+func SecretsManagerIngress(ctx context.Context, ingress IngressAPI, secrets SecretsAPI, cluster string, instanceCRN string) error {
+	info, err := ingress.GetCluster(ctx, cluster)
+	if err != nil {
+		return fmt.Errorf("error getting cluster %s: %w", cluster, err)
+	}
+
+	groups, err := secrets.ListSecretGroups(ctx)
+	if err != nil {
+		return fmt.Errorf("error listing secret groups: %w", err)
+	}
+	var group *ibmcloud.SecretGroup
+	for i := range groups {
+		if groups[i].Name == info.ID {
+			group = &groups[i]
+			break
+		}
+	}
+	if group == nil {
+		return fmt.Errorf("no secret group named after cluster ID %s found in %s", info.ID, instanceCRN)
+	}
+
+	instances, err := ingress.ListIngressInstances(ctx, cluster)
+	if err != nil {
+		return fmt.Errorf("error listing ingress instances of cluster %s: %w", cluster, err)
+	}
+	var instance *ibmcloud.IngressInstance
+	for i := range instances {
+		if instances[i].CRN == instanceCRN {
+			instance = &instances[i]
+			break
+		}
+	}
+	if instance == nil {
+		return fmt.Errorf("secrets manager %s is not registered as an ingress instance of cluster %s", instanceCRN, cluster)
+	}
+
+	var errs []error
+	if !instance.IsDefault {
+		errs = append(errs, fmt.Errorf("ingress instance %s is not the default instance of cluster %s", instance.Name, cluster))
+	}
+	if instance.SecretGroupID != group.ID {
+		errs = append(errs, fmt.Errorf("ingress instance %s uses secret group %q, expected %q (%s)", instance.Name, instance.SecretGroupID, group.ID, group.Name))
+	}
+	errs = append(errs, defaultCertificateInGroup(ctx, ingress, secrets, info, instanceCRN, group))
+	return errors.Join(errs...)
+}
+
+func defaultCertificateInGroup(ctx context.Context, ingress IngressAPI, secrets SecretsAPI, cluster *ibmcloud.Cluster, instanceCRN string, group *ibmcloud.SecretGroup) error {
+	if cluster.Ingress.SecretName == "" {
+		return fmt.Errorf("cluster %s does not report a default ingress secret yet", cluster.Name)
+	}
+	instance, err := ibmcloud.ParseCRN(instanceCRN)
+	if err != nil {
+		return err
+	}
+
+	ingressSecrets, err := ingress.ListIngressSecrets(ctx, cluster.ID)
+	if err != nil {
+		return fmt.Errorf("error listing ingress secrets of cluster %s: %w", cluster.Name, err)
+	}
+	for _, secret := range ingressSecrets {
+		if secret.Name != cluster.Ingress.SecretName {
+			continue
+		}
+		crn, err := ibmcloud.ParseCRN(secret.CRN)
+		if err != nil || crn.ServiceInstance != instance.ServiceInstance {
+			continue
+		}
+
+		metadata, err := secrets.GetSecretMetadata(ctx, crn.Resource)
+		if err != nil {
+			return fmt.Errorf("error getting default ingress certificate %s from secrets manager: %w", crn.Resource, err)
+		}
+		if metadata.SecretGroupID != group.ID {
+			return fmt.Errorf("default ingress certificate %s is in secret group %q, expected %q (%s)", metadata.Name, metadata.SecretGroupID, group.ID, group.Name)
+		}
+		return nil
+	}
+	return fmt.Errorf("default ingress certificate %s of cluster %s is not stored in %s", cluster.Ingress.SecretName, cluster.Name, instanceCRN)
+}
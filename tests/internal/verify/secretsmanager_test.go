@@ -0,0 +1,117 @@
+package verify
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/terraform-ibm-modules/terraform-ibm-base-ocp-vpc/internal/ibmcloud"
+	"github.com/terraform-ibm-modules/terraform-ibm-base-ocp-vpc/internal/ibmcloud/ibmcloudtest"
+)
+
+const (
+	testSecretsManagerCRN = "crn:v1:bluemix:public:secrets-manager:us-south:a/acct:sm-guid::"
+	testClusterID         = "cq1b2c3d0abc"
+	testIngressSecret     = "my-cluster-6f2c0000"
+)
+
+type fakeIngressAPI struct {
+	instances []ibmcloud.IngressInstance
+	secrets   []ibmcloud.IngressSecret
+}
+
+func (f *fakeIngressAPI) GetCluster(_ context.Context, _ string) (*ibmcloud.Cluster, error) {
+	return &ibmcloud.Cluster{ID: testClusterID, Name: "my-cluster", Ingress: ibmcloud.ClusterIngress{SecretName: testIngressSecret}}, nil
+}
+
+func (f *fakeIngressAPI) ListIngressInstances(_ context.Context, _ string) ([]ibmcloud.IngressInstance, error) {
+	return f.instances, nil
+}
+
+func (f *fakeIngressAPI) ListIngressSecrets(_ context.Context, _ string) ([]ibmcloud.IngressSecret, error) {
+	return f.secrets, nil
+}
+
+func TestSecretsManagerIngress(t *testing.T) {
+	certificateCRN := "crn:v1:bluemix:public:secrets-manager:us-south:a/acct:sm-guid:secret:cert-1"
+
+	testCases := []struct {
+		name         string
+		instances    []ibmcloud.IngressInstance
+		certGroup    string
+		clusterGroup bool
+		errors       []string
+	}{
+		{
+			name:         "certificate in cluster secret group",
+			instances:    []ibmcloud.IngressInstance{{Name: "sm", CRN: testSecretsManagerCRN, IsDefault: true, SecretGroupID: "group-cluster"}},
+			certGroup:    "group-cluster",
+			clusterGroup: true,
+		},
+		{
+			name:         "certificate in default secret group",
+			instances:    []ibmcloud.IngressInstance{{Name: "sm", CRN: testSecretsManagerCRN, IsDefault: true, SecretGroupID: "group-cluster"}},
+			certGroup:    "default",
+			clusterGroup: true,
+			errors:       []string{`default ingress certificate my-cluster-cert is in secret group "default", expected "group-cluster"`},
+		},
+		{
+			name:         "instance not registered",
+			certGroup:    "group-cluster",
+			clusterGroup: true,
+			errors:       []string{"is not registered as an ingress instance"},
+		},
+		{
+			name:         "instance not default and using another group",
+			instances:    []ibmcloud.IngressInstance{{Name: "sm", CRN: testSecretsManagerCRN, SecretGroupID: "default"}},
+			certGroup:    "group-cluster",
+			clusterGroup: true,
+			errors:       []string{"is not the default instance", `uses secret group "default"`},
+		},
+		{
+			name:      "secret group missing",
+			instances: []ibmcloud.IngressInstance{{Name: "sm", CRN: testSecretsManagerCRN, IsDefault: true, SecretGroupID: "group-cluster"}},
+			certGroup: "default",
+			errors:    []string{"no secret group named after cluster ID " + testClusterID},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			sm := ibmcloudtest.NewSecretsManager(t)
+			sm.AddGroup(ibmcloud.SecretGroup{ID: "default", Name: "default"})
+			if tc.clusterGroup {
+				sm.AddGroup(ibmcloud.SecretGroup{ID: "group-cluster", Name: testClusterID})
+			}
+			sm.AddSecret(ibmcloud.Secret{ID: "cert-1", Name: "my-cluster-cert", SecretType: "public_cert", SecretGroupID: tc.certGroup})
+
+			ingress := &fakeIngressAPI{
+				instances: tc.instances,
+				secrets:   []ibmcloud.IngressSecret{{Name: testIngressSecret, Namespace: "openshift-ingress", CRN: certificateCRN}},
+			}
+
+			err := SecretsManagerIngress(context.Background(), ingress, sm.Client(), "my-cluster", testSecretsManagerCRN)
+			if len(tc.errors) == 0 {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			for _, msg := range tc.errors {
+				assert.Contains(t, err.Error(), msg)
+			}
+		})
+	}
+}
+
+func TestSecretsManagerIngressCertificateNotSynced(t *testing.T) {
+	sm := ibmcloudtest.NewSecretsManager(t)
+	sm.AddGroup(ibmcloud.SecretGroup{ID: "group-cluster", Name: testClusterID})
+	ingress := &fakeIngressAPI{
+		instances: []ibmcloud.IngressInstance{{Name: "sm", CRN: testSecretsManagerCRN, IsDefault: true, SecretGroupID: "group-cluster"}},
+	}
+
+	err := SecretsManagerIngress(context.Background(), ingress, sm.Client(), "my-cluster", testSecretsManagerCRN)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "default ingress certificate "+testIngressSecret+" of cluster my-cluster is not stored in")
+}
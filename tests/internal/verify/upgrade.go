@@ -0,0 +1,65 @@
+package verify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/terraform-ibm-modules/terraform-ibm-base-ocp-vpc/internal/ibmcloud"
+)
+
+// InPlaceUpdate checks that the plan updates the resource at address without destroying and re-creating it
+func InPlaceUpdate(plan *terraform.PlanStruct, address string) error {
+	change, ok := plan.ResourceChangesMap[address]
+	if !ok || change.Change == nil {
+		return fmt.Errorf("plan has no change for %s", address)
+	}
+	if !change.Change.Actions.Update() {
+		return fmt.Errorf("%s is planned to %v, expected an in-place update", address, change.Change.Actions)
+	}
+	return nil
+}
+
+// ClusterVersion checks that the master and every worker of the cluster run the given minor version. All mismatches
+// are returned together.
+func ClusterVersion(ctx context.Context, clusters ClusterAPI, cluster string, version string) error {
+	want, err := ibmcloud.ParseKubeVersion(version)
+	if err != nil {
+		return err
+	}
+
+	details, err := clusters.GetCluster(ctx, cluster)
+	if err != nil {
+		return fmt.Errorf("error getting cluster %s: %w", cluster, err)
+	}
+	var errs []error
+	if err := sameVersion(details.MasterKubeVersion, want); err != nil {
+		errs = append(errs, fmt.Errorf("master of cluster %s: %w", cluster, err))
+	}
+
+	workers, err := clusters.ListWorkers(ctx, cluster)
+	if err != nil {
+		return errors.Join(append(errs, fmt.Errorf("error listing workers of cluster %s: %w", cluster, err))...)
+	}
+	if len(workers) == 0 {
+		errs = append(errs, fmt.Errorf("cluster %s has no workers", cluster))
+	}
+	for _, worker := range workers {
+		if err := sameVersion(worker.KubeVersion.Actual, want); err != nil {
+			errs = append(errs, fmt.Errorf("worker %s: %w", worker.ID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func sameVersion(actual string, want ibmcloud.KubeVersion) error {
+	version, err := ibmcloud.ParseKubeVersion(actual)
+	if err != nil {
+		return err
+	}
+	if version != want {
+		return fmt.Errorf("runs version %s, expected %s", actual, want)
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package verify
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/terraform-ibm-modules/terraform-ibm-base-ocp-vpc/internal/ibmcloud"
+)
+
+const clusterAddress = "module.ocp_base.ibm_container_vpc_cluster.cluster_with_upgrade[0]"
+
+func TestInPlaceUpdate(t *testing.T) {
+	plan, err := terraform.ParsePlanJSON(`{"format_version":"1.2","resource_changes":[
+		{"address":"module.ocp_base.ibm_container_vpc_cluster.cluster_with_upgrade[0]","change":{"actions":["update"]}},
+		{"address":"module.ocp_base.null_resource.confirm_network_healthy[0]","change":{"actions":["delete","create"]}}
+	]}`)
+	require.NoError(t, err)
+
+	assert.NoError(t, InPlaceUpdate(plan, clusterAddress))
+	assert.EqualError(t, InPlaceUpdate(plan, "module.ocp_base.null_resource.confirm_network_healthy[0]"), "module.ocp_base.null_resource.confirm_network_healthy[0] is planned to [delete create], expected an in-place update")
+	assert.EqualError(t, InPlaceUpdate(plan, "module.ocp_base.ibm_container_vpc_cluster.cluster[0]"), "plan has no change for module.ocp_base.ibm_container_vpc_cluster.cluster[0]")
+}
+
+func TestClusterVersion(t *testing.T) {
+	worker := func(id, version string) ibmcloud.Worker {
+		return ibmcloud.Worker{ID: id, KubeVersion: ibmcloud.WorkerKubeVersion{Actual: version}}
+	}
+
+	testCases := []struct {
+		name    string
+		master  string
+		workers []ibmcloud.Worker
+		errors  []string
+	}{
+		{
+			name:    "upgraded",
+			master:  "4.18.3_openshift",
+			workers: []ibmcloud.Worker{worker("w-1", "4.18.3_openshift"), worker("w-2", "4.18.1_openshift")},
+		},
+		{
+			name:    "workers not updated",
+			master:  "4.18.3_openshift",
+			workers: []ibmcloud.Worker{worker("w-1", "4.17.20_openshift"), worker("w-2", "4.18.3_openshift")},
+			errors:  []string{"worker w-1: runs version 4.17.20_openshift, expected 4.18"},
+		},
+		{
+			name:   "master not upgraded and no workers",
+			master: "4.17.20_openshift",
+			errors: []string{"master of cluster my-cluster: runs version 4.17.20_openshift, expected 4.18", "cluster my-cluster has no workers"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			clusters := &fakeClusterAPI{cluster: &ibmcloud.Cluster{MasterKubeVersion: tc.master}, workers: tc.workers}
+			err := ClusterVersion(context.Background(), clusters, "my-cluster", "4.18")
+			if len(tc.errors) == 0 {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			for _, msg := range tc.errors {
+				assert.Contains(t, err.Error(), msg)
+			}
+		})
+	}
+}
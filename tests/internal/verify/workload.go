@@ -0,0 +1,244 @@
+package verify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+)
+
+// SampleWorkloadImage serves the sample workload. It is pulled from the Red Hat registry that every OpenShift cluster
+// can reach, and runs under the restricted security context constraint.
+const SampleWorkloadImage = "registry.access.redhat.com/ubi9/python-311"
+
+// sampleWorkloadName names the deployment, service and route of the sample workload
+const sampleWorkloadName = "smoke-test"
+
+// workloadCleanupTimeout is how long deleting the namespace of the sample workload may take
+const workloadCleanupTimeout = 10 * time.Minute
+
+// RouteResource is the OpenShift Route API. client-go has no typed client for it, so routes are managed through the
+// dynamic client.
+var RouteResource = schema.GroupVersionResource{Group: "route.openshift.io", Version: "v1", Resource: "routes"}
+
+// SampleWorkload is the HTTP workload deployed by WorkloadSmoke
+type SampleWorkload struct {
+	// Namespace is created for the workload, and deleted with it
+	Namespace string
+	Image     string
+	// Marker is the response body of the workload, to tell its responses from those of the router
+	Marker string
+}
+
+// WorkloadClients are the clients of the cluster that the sample workload is deployed to
+type WorkloadClients struct {
+	Kubernetes kubernetes.Interface
+	// Dynamic manages the route of the workload
+	Dynamic dynamic.Interface
+}
+
+// route is the subset of an OpenShift Route used by the smoke test
+type route struct {
+	Spec struct {
+		Host string `json:"host"`
+	} `json:"spec"`
+	Status struct {
+		Ingress []struct {
+			RouterName string `json:"routerName"`
+			Conditions []struct {
+				Type    string `json:"type"`
+				Status  string `json:"status"`
+				Message string `json:"message"`
+			} `json:"conditions"`
+		} `json:"ingress"`
+	} `json:"status"`
+}
+
+// WorkloadSmoke deploys the sample workload, exposes it with a route on the default ingress domain, and requests it
+// through the route every interval until it answers with the marker or ctx is done. It returns the URL of the route.
+// The workload is deleted afterwards, also when the check fails.
+func WorkloadSmoke(ctx context.Context, clients WorkloadClients, client *http.Client, workload SampleWorkload, interval time.Duration) (url string, err error) {
+	namespaces := clients.Kubernetes.CoreV1().Namespaces()
+	namespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: workload.Namespace}}
+	if _, err := namespaces.Create(ctx, namespace, metav1.CreateOptions{}); err != nil {
+		return "", fmt.Errorf("error creating the namespace of the sample workload: %w", err)
+	}
+	defer func() {
+		// ctx may be done by now, which must not leave the workload behind
+		cleanupCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), workloadCleanupTimeout)
+		defer cancel()
+		if deleteErr := deleteNamespace(cleanupCtx, clients.Kubernetes, workload.Namespace, interval); deleteErr != nil {
+			err = errors.Join(err, fmt.Errorf("error deleting the sample workload: %w", deleteErr))
+		}
+	}()
+	if err := deploySampleWorkload(ctx, clients, workload); err != nil {
+		return "", fmt.Errorf("error deploying the sample workload: %w", err)
+	}
+
+	var host string
+	err = Eventually(ctx, interval, func(ctx context.Context) error {
+		object, err := clients.Dynamic.Resource(RouteResource).Namespace(workload.Namespace).Get(ctx, sampleWorkloadName, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		var r route
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(object.Object, &r); err != nil {
+			return fmt.Errorf("error decoding route %s: %w", sampleWorkloadName, err)
+		}
+		host, err = admittedHost(r)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+
+	url = "https://" + host + "/"
+	err = Eventually(ctx, interval, func(ctx context.Context) error {
+		return expectBody(ctx, client, url, workload.Marker)
+	})
+	if err != nil {
+		return url, fmt.Errorf("sample workload is not reachable through its route: %w", err)
+	}
+	return url, nil
+}
+
+// deploySampleWorkload creates a deployment answering HTTP requests with the marker, exposed by a route with a host on
+// the default ingress domain
+func deploySampleWorkload(ctx context.Context, clients WorkloadClients, workload SampleWorkload) error {
+	labels := map[string]string{"app": sampleWorkloadName}
+	replicas, privilegeEscalation, nonRoot := int32(1), false, true
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: sampleWorkloadName, Namespace: workload.Namespace},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			Selector: &metav1.LabelSelector{MatchLabels: labels},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: labels},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{
+						Name:    "http",
+						Image:   workload.Image,
+						Command: []string{"/bin/sh", "-c", `mkdir -p /tmp/www && echo "$MARKER" > /tmp/www/index.html && exec python3 -m http.server 8080 --directory /tmp/www`},
+						Env:     []corev1.EnvVar{{Name: "MARKER", Value: workload.Marker}},
+						Ports:   []corev1.ContainerPort{{Name: "http", ContainerPort: 8080}},
+						ReadinessProbe: &corev1.Probe{
+							ProbeHandler: corev1.ProbeHandler{HTTPGet: &corev1.HTTPGetAction{Path: "/", Port: intstr.FromString("http")}},
+						},
+						Resources: corev1.ResourceRequirements{
+							Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("10m"), corev1.ResourceMemory: resource.MustParse("32Mi")},
+							Limits:   corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("128Mi")},
+						},
+						SecurityContext: &corev1.SecurityContext{
+							AllowPrivilegeEscalation: &privilegeEscalation,
+							RunAsNonRoot:             &nonRoot,
+							Capabilities:             &corev1.Capabilities{Drop: []corev1.Capability{"ALL"}},
+							SeccompProfile:           &corev1.SeccompProfile{Type: corev1.SeccompProfileTypeRuntimeDefault},
+						},
+					}},
+				},
+			},
+		},
+	}
+	if _, err := clients.Kubernetes.AppsV1().Deployments(workload.Namespace).Create(ctx, deployment, metav1.CreateOptions{}); err != nil {
+		return err
+	}
+
+	service := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: sampleWorkloadName, Namespace: workload.Namespace},
+		Spec: corev1.ServiceSpec{
+			Selector: labels,
+			Ports:    []corev1.ServicePort{{Name: "http", Port: 8080, TargetPort: intstr.FromString("http")}},
+		},
+	}
+	if _, err := clients.Kubernetes.CoreV1().Services(workload.Namespace).Create(ctx, service, metav1.CreateOptions{}); err != nil {
+		return err
+	}
+
+	routeObject := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "route.openshift.io/v1",
+		"kind":       "Route",
+		"metadata":   map[string]interface{}{"name": sampleWorkloadName, "namespace": workload.Namespace},
+		"spec": map[string]interface{}{
+			"to":   map[string]interface{}{"kind": "Service", "name": sampleWorkloadName},
+			"port": map[string]interface{}{"targetPort": "http"},
+			"tls":  map[string]interface{}{"termination": "edge", "insecureEdgeTerminationPolicy": "Redirect"},
+		},
+	}}
+	_, err := clients.Dynamic.Resource(RouteResource).Namespace(workload.Namespace).Create(ctx, routeObject, metav1.CreateOptions{})
+	return err
+}
+
+// deleteNamespace deletes a namespace and waits every interval until it is gone
+func deleteNamespace(ctx context.Context, clientset kubernetes.Interface, name string, interval time.Duration) error {
+	namespaces := clientset.CoreV1().Namespaces()
+	propagation := metav1.DeletePropagationForeground
+	err := namespaces.Delete(ctx, name, metav1.DeleteOptions{PropagationPolicy: &propagation})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+	return Eventually(ctx, interval, func(ctx context.Context) error {
+		_, err := namespaces.Get(ctx, name, metav1.GetOptions{})
+		switch {
+		case apierrors.IsNotFound(err):
+			return nil
+		case err != nil:
+			return err
+		}
+		return fmt.Errorf("namespace %s is still terminating", name)
+	})
+}
+
+// admittedHost returns the host of the route once it is admitted by a router
+func admittedHost(r route) (string, error) {
+	for _, ingress := range r.Status.Ingress {
+		for _, c := range ingress.Conditions {
+			if c.Type != "Admitted" {
+				continue
+			}
+			if c.Status != "True" {
+				return "", fmt.Errorf("route %s is not admitted by router %s: %s", sampleWorkloadName, ingress.RouterName, c.Message)
+			}
+			return r.Spec.Host, nil
+		}
+	}
+	return "", fmt.Errorf("route %s is not admitted by a router yet", sampleWorkloadName)
+}
+
+// expectBody requests url and checks that the response is successful and contains marker
+func expectBody(ctx context.Context, client *http.Client, url string, marker string) error {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	response, err := client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(response.Body, 64*1024))
+	if err != nil {
+		return err
+	}
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s returned %s", url, response.Status)
+	}
+	if !strings.Contains(string(body), marker) {
+		return fmt.Errorf("GET %s did not return the marker of the sample workload", url)
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+package verify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	kubernetesfake "k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+const testWorkloadMarker = "smoke-test-x1y2z3"
+
+func admittedRoute(host string, status string) *unstructured.Unstructured {
+	var object map[string]interface{}
+	if err := json.Unmarshal([]byte(fmt.Sprintf(`{"spec": {"host": %q}, "status": {"ingress": [{"routerName": "default", "conditions": [
+		{"type": "Admitted", "status": %q, "message": "route host is already claimed"}
+	]}]}}`, host, status)), &object); err != nil {
+		panic(err)
+	}
+	return &unstructured.Unstructured{Object: object}
+}
+
+// newWorkloadClients fakes a cluster whose route of the workload is admitted with the host of server on the second poll
+func newWorkloadClients(server *httptest.Server, admitted string) (WorkloadClients, *kubernetesfake.Clientset, *dynamicfake.FakeDynamicClient) {
+	host := strings.TrimPrefix(server.URL, "https://")
+	clientset := kubernetesfake.NewClientset()
+	routes := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{RouteResource: "RouteList"})
+	polls := 0
+	routes.PrependReactor("get", "routes", func(k8stesting.Action) (bool, runtime.Object, error) {
+		polls++
+		if polls == 1 {
+			return true, &unstructured.Unstructured{Object: map[string]interface{}{"spec": map[string]interface{}{"host": ""}}}, nil
+		}
+		return true, admittedRoute(host, admitted), nil
+	})
+	return WorkloadClients{Kubernetes: clientset, Dynamic: routes}, clientset, routes
+}
+
+// assertNamespaceDeleted checks that the namespace of the sample workload is gone
+func assertNamespaceDeleted(t *testing.T, clientset *kubernetesfake.Clientset, msgAndArgs ...interface{}) {
+	_, err := clientset.CoreV1().Namespaces().Get(context.Background(), "smoke", metav1.GetOptions{})
+	assert.True(t, apierrors.IsNotFound(err), msgAndArgs...)
+}
+
+func TestWorkloadSmoke(t *testing.T) {
+	requests := 0
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// the router answers before the endpoints of the workload are ready
+		requests++
+		if requests == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprintln(w, testWorkloadMarker)
+	}))
+	t.Cleanup(server.Close)
+	clients, clientset, routes := newWorkloadClients(server, "True")
+
+	workload := SampleWorkload{Namespace: "smoke", Image: SampleWorkloadImage, Marker: testWorkloadMarker}
+	url, err := WorkloadSmoke(context.Background(), clients, server.Client(), workload, time.Millisecond)
+	require.NoError(t, err)
+	assert.Equal(t, server.URL+"/", url)
+	assert.Equal(t, 2, requests)
+
+	// the fake clientset does not delete the objects of a deleted namespace
+	deployment, err := clientset.AppsV1().Deployments("smoke").Get(context.Background(), sampleWorkloadName, metav1.GetOptions{})
+	require.NoError(t, err)
+	container := deployment.Spec.Template.Spec.Containers[0]
+	assert.Equal(t, SampleWorkloadImage, container.Image)
+	assert.Equal(t, testWorkloadMarker, container.Env[0].Value)
+	_, err = clientset.CoreV1().Services("smoke").Get(context.Background(), sampleWorkloadName, metav1.GetOptions{})
+	assert.NoError(t, err)
+
+	var created *unstructured.Unstructured
+	for _, action := range routes.Actions() {
+		if create, ok := action.(k8stesting.CreateAction); ok {
+			created = create.GetObject().(*unstructured.Unstructured)
+		}
+	}
+	require.NotNil(t, created, "the route is created")
+	termination, _, _ := unstructured.NestedString(created.Object, "spec", "tls", "termination")
+	assert.Equal(t, "edge", termination)
+	assertNamespaceDeleted(t, clientset, "the workload is cleaned up")
+}
+
+func TestWorkloadSmokeFailures(t *testing.T) {
+	t.Run("route not admitted", func(t *testing.T) {
+		server := httptest.NewTLSServer(http.NotFoundHandler())
+		t.Cleanup(server.Close)
+		clients, clientset, _ := newWorkloadClients(server, "False")
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		_, err := WorkloadSmoke(ctx, clients, server.Client(), SampleWorkload{Namespace: "smoke", Marker: testWorkloadMarker}, time.Millisecond)
+		assert.ErrorContains(t, err, "route smoke-test is not admitted by router default: route host is already claimed")
+		assertNamespaceDeleted(t, clientset)
+	})
+
+	t.Run("router answers without the workload", func(t *testing.T) {
+		server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintln(w, "Application is not available")
+		}))
+		t.Cleanup(server.Close)
+		clients, clientset, _ := newWorkloadClients(server, "True")
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		_, err := WorkloadSmoke(ctx, clients, server.Client(), SampleWorkload{Namespace: "smoke", Marker: testWorkloadMarker}, time.Millisecond)
+		assert.ErrorContains(t, err, "sample workload is not reachable through its route")
+		assertNamespaceDeleted(t, clientset, "the workload is cleaned up after ctx is done")
+	})
+}
@@ -2,28 +2,56 @@
 package test
 
 import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/IBM/go-sdk-core/v5/core"
+	"github.com/gruntwork-io/terratest/modules/files"
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/random"
+	"github.com/gruntwork-io/terratest/modules/terraform"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/terraform-ibm-modules/ibmcloud-terratest-wrapper/cloudinfo"
 	"github.com/terraform-ibm-modules/ibmcloud-terratest-wrapper/testaddons"
 	"github.com/terraform-ibm-modules/ibmcloud-terratest-wrapper/testhelper"
 	"github.com/terraform-ibm-modules/ibmcloud-terratest-wrapper/testschematic"
+
+	"github.com/terraform-ibm-modules/terraform-ibm-base-ocp-vpc/internal/catalog"
+	"github.com/terraform-ibm-modules/terraform-ibm-base-ocp-vpc/internal/deadline"
+	"github.com/terraform-ibm-modules/terraform-ibm-base-ocp-vpc/internal/governor"
+	"github.com/terraform-ibm-modules/terraform-ibm-base-ocp-vpc/internal/ibmcloud"
+	"github.com/terraform-ibm-modules/terraform-ibm-base-ocp-vpc/internal/journal"
+	"github.com/terraform-ibm-modules/terraform-ibm-base-ocp-vpc/internal/kube"
+	"github.com/terraform-ibm-modules/terraform-ibm-base-ocp-vpc/internal/migration"
+	"github.com/terraform-ibm-modules/terraform-ibm-base-ocp-vpc/internal/regions"
+	"github.com/terraform-ibm-modules/terraform-ibm-base-ocp-vpc/internal/upgrade"
+	"github.com/terraform-ibm-modules/terraform-ibm-base-ocp-vpc/internal/verify"
 )
 
 const advancedExampleDir = "examples/advanced"
 const fscloudExampleDir = "examples/fscloud"
 const crossKmsSupportExampleDir = "examples/cross_kms_support"
 const gpuExampleDir = "examples/gpu"
+const catalogPath = "../ibm_catalog.json"
 
 func TestRunMultiClusterExample(t *testing.T) {
 	t.Parallel()
+	acquireResources(t)
 	options := testhelper.TestOptionsDefaultWithVars(&testhelper.TestOptions{
 		Testing:       t,
 		TerraformDir:  "examples/multiple_mzr_clusters",
 		Prefix:        "multi-clusters",
 		ResourceGroup: resourceGroup,
+		Region:        leaseRegion(t, 2),
 		IgnoreDestroys: testhelper.Exemptions{ // Ignore for consistency check
 			List: []string{
 				"module.ocp_base_cluster_1.null_resource.confirm_network_healthy",
@@ -37,7 +65,7 @@ func TestRunMultiClusterExample(t *testing.T) {
 		},
 		CloudInfoService: sharedInfoSvc,
 	})
-	options.PostApplyHook = getMultiClusterIngress
+	options.PostApplyHook = mustGatherOnFailure(getMultiClusterIngress, "cluster_name_1", "cluster_name_2")
 	output, err := options.RunTestConsistency()
 	assert.Nil(t, err, "This should not have errored")
 	assert.NotNil(t, output, "Expected some output")
@@ -45,11 +73,13 @@ func TestRunMultiClusterExample(t *testing.T) {
 
 func TestRunAddRulesToSGExample(t *testing.T) {
 	t.Parallel()
+	acquireResources(t)
 	options := testhelper.TestOptionsDefaultWithVars(&testhelper.TestOptions{
 		Testing:       t,
 		TerraformDir:  "examples/add_rules_to_sg",
 		Prefix:        "sg-rules",
 		ResourceGroup: resourceGroup,
+		Region:        leaseRegion(t, 1),
 		ImplicitDestroy: []string{
 			"module.ocp_base.null_resource.confirm_network_healthy",
 		},
@@ -60,28 +90,57 @@ func TestRunAddRulesToSGExample(t *testing.T) {
 		},
 		CloudInfoService: sharedInfoSvc,
 	})
-	options.PostApplyHook = getClusterIngress
+	options.PostApplyHook = mustGatherOnFailure(getClusterIngress, "cluster_name")
 	output, err := options.RunTestConsistency()
 	assert.Nil(t, err, "This should not have errored")
 	assert.NotNil(t, output, "Expected some output")
 }
 
+// getClusterIngressAndCrossKmsEncryption runs the ingress health check, then verifies that the cluster and the worker
+// boot volumes are encrypted with the root key owned by the cross account
+func getClusterIngressAndCrossKmsEncryption(options *testhelper.TestOptions) error {
+	if err := getClusterIngress(options); err != nil {
+		return err
+	}
+
+	outputs, outputErr := terraform.OutputAllContextE(options.Testing, deadline.New(options.Testing, destroyBudget).Context(), options.TerraformOptions)
+	if !assert.NoError(options.Testing, outputErr, "error getting last terraform apply outputs: %s", outputErr) {
+		return outputErr
+	}
+	_, ValidationErr := testhelper.ValidateTerraformOutputs(outputs, "cluster_name")
+	if !assert.NoErrorf(options.Testing, ValidationErr, "Some outputs not found or nil: %s", ValidationErr) {
+		return ValidationErr
+	}
+
+	crossAccountKey := verify.KmsKey{
+		AccountID:  permanentResources.GeOpsAccountID,
+		InstanceID: permanentResources.KpUsSouthGUID,
+		KeyID:      permanentResources.KpUsSouthRootKeyID,
+	}
+	return checkClusterEncryption(options.Testing, outputs["cluster_name"].(string), options.Region, verify.EncryptionExpectation{
+		Cluster:    &crossAccountKey,
+		BootVolume: &crossAccountKey,
+	})
+}
+
 func TestCrossKmsSupportExample(t *testing.T) {
 	t.Parallel()
+	acquireResources(t)
 
 	options := testhelper.TestOptionsDefaultWithVars(&testhelper.TestOptions{
 		Testing:      t,
 		TerraformDir: crossKmsSupportExampleDir,
 		Prefix:       "cross-kp",
+		Region:       leaseRegion(t, 1),
 		TerraformVars: map[string]interface{}{
-			"kms_instance_guid":    permanentResources["kp_us_south_guid"],
-			"kms_key_id":           permanentResources["kp_us_south_root_key_id"],
-			"kms_cross_account_id": permanentResources["ge_ops_account_id"],
+			"kms_instance_guid":    permanentResources.KpUsSouthGUID,
+			"kms_key_id":           permanentResources.KpUsSouthRootKeyID,
+			"kms_cross_account_id": permanentResources.GeOpsAccountID,
 			"ocp_version":          ocpVersion3,
 		},
 		CloudInfoService: sharedInfoSvc,
 	})
-	options.PostApplyHook = getClusterIngress
+	options.PostApplyHook = mustGatherOnFailure(getClusterIngressAndCrossKmsEncryption, "cluster_name")
 
 	output, err := options.RunTestConsistency()
 
@@ -90,11 +149,36 @@ func TestCrossKmsSupportExample(t *testing.T) {
 
 }
 
+// getClusterIngressAndKubeAudit runs the ingress health check, then proves that API server audit events reach the
+// kube-audit webhook listener deployed by the advanced example with the verbose audit policy
+func getClusterIngressAndKubeAudit(options *testhelper.TestOptions) error {
+	if err := getClusterIngress(options); err != nil {
+		return err
+	}
+
+	outputs, outputErr := terraform.OutputAllContextE(options.Testing, context.Background(), options.TerraformOptions)
+	if !assert.NoError(options.Testing, outputErr, "error getting last terraform apply outputs: %s", outputErr) {
+		return nil
+	}
+	_, ValidationErr := testhelper.ValidateTerraformOutputs(outputs, "cluster_id")
+	if !assert.NoErrorf(options.Testing, ValidationErr, "Some outputs not found or nil: %s", ValidationErr) {
+		return nil
+	}
+
+	checkKubeAuditDelivery(options.Testing, outputs["cluster_id"].(string), options.Region, verify.KubeAudit{
+		Namespace:  kubeAuditNamespace,
+		Deployment: kubeAuditDeployment,
+		Policy:     "verbose",
+	})
+	return nil
+}
+
 func TestRunAdvancedExample(t *testing.T) {
 	t.Parallel()
+	acquireResources(t)
 
 	options := setupOptions(t, "base-ocp-adv", advancedExampleDir, ocpVersion3)
-	options.PostApplyHook = getClusterIngress
+	options.PostApplyHook = mustGatherOnFailure(getClusterIngressAndKubeAudit, "cluster_name")
 
 	options.IgnoreUpdates = testhelper.Exemptions{List: []string{"module.logs_agents.helm_release.logs_agent"}}
 	options.IgnoreDestroys = testhelper.Exemptions{List: []string{"module.logs_agents.terraform_data.install_required_binaries[0]"}}
@@ -106,10 +190,13 @@ func TestRunAdvancedExample(t *testing.T) {
 
 func TestFSCloudInSchematic(t *testing.T) {
 	t.Parallel()
+	acquireResources(t)
 
 	options := testschematic.TestSchematicOptionsDefault(&testschematic.TestSchematicOptions{
 		Testing: t,
 		Prefix:  "base-ocp-fscloud",
+		// the cluster is encrypted with HPCS
+		Region: leaseRegion(t, 1, regions.FeatureHPCS),
 		TarIncludePatterns: []string{
 			"*.tf",
 			"scripts/*.*",
@@ -127,23 +214,18 @@ func TestFSCloudInSchematic(t *testing.T) {
 		CloudInfoService:       sharedInfoSvc,
 	})
 
-	// If "jp-osa" was the best region selected, default to us-south instead.
-	// "jp-osa" is currently not allowing hs-crypto be used for encrypting in that region.
-	if options.Region == "jp-osa" {
-		options.Region = "us-south"
-	}
-
 	options.TerraformVars = []testschematic.TestSchematicTerraformVar{
 		{Name: "ibmcloud_api_key", Value: options.RequiredEnvironmentVars["TF_VAR_ibmcloud_api_key"], DataType: "string", Secure: true},
 		{Name: "region", Value: options.Region, DataType: "string"},
 		{Name: "prefix", Value: options.Prefix, DataType: "string"},
 		{Name: "resource_group", Value: options.ResourceGroup, DataType: "string"},
-		{Name: "hpcs_instance_guid", Value: permanentResources["hpcs_south"], DataType: "string"},
-		{Name: "hpcs_key_crn_cluster", Value: permanentResources["hpcs_south_root_key_crn"], DataType: "string"},
-		{Name: "hpcs_key_crn_worker_pool", Value: permanentResources["hpcs_south_root_key_crn"], DataType: "string"},
+		{Name: "hpcs_instance_guid", Value: permanentResources.HpcsSouth, DataType: "string"},
+		{Name: "hpcs_key_crn_cluster", Value: permanentResources.HpcsSouthRootKeyCRN, DataType: "string"},
+		{Name: "hpcs_key_crn_worker_pool", Value: permanentResources.HpcsSouthRootKeyCRN, DataType: "string"},
 		{Name: "ocp_version", Value: ocpVersion1, DataType: "string"},
 		{Name: "ocp_entitlement", Value: "cloud_pak", DataType: "string"},
 	}
+	trackSchematicsWorkspace(t, options)
 
 	err := options.RunSchematicTest()
 	assert.Nil(t, err, "This should not have errored")
@@ -151,12 +233,14 @@ func TestFSCloudInSchematic(t *testing.T) {
 
 func TestRunGpuExample(t *testing.T) {
 	t.Parallel()
+	acquireResources(t)
 
 	options := testhelper.TestOptionsDefaultWithVars(&testhelper.TestOptions{
 		Testing:       t,
 		TerraformDir:  gpuExampleDir,
 		Prefix:        "gpu-test",
 		ResourceGroup: resourceGroup,
+		Region:        leaseRegion(t, 1),
 		ImplicitDestroy: []string{
 			"module.ocp_base.null_resource.confirm_network_healthy",
 			"module.ocp_base.null_resource.reset_api_key",
@@ -167,7 +251,7 @@ func TestRunGpuExample(t *testing.T) {
 			"ocp_version":                      ocpVersion4,
 			"default_worker_pool_machine_type": "bx2.4x16",
 			"gpu_worker_pool_machine_type":     "bx2.4x16", // Use bx2.4x16 instead of gx3.16x80.l4 to reduce cost
-			"access_tags":                      permanentResources["accessTags"],
+			"access_tags":                      permanentResources.AccessTags,
 			"ocp_entitlement":                  "cloud_pak",
 		},
 	})
@@ -176,152 +260,58 @@ func TestRunGpuExample(t *testing.T) {
 	assert.NotNil(t, output, "Expected some output")
 }
 
-func TestAddonPermutations(t *testing.T) {
-	testCases := []testaddons.AddonTestCase{
-		{
-			Name:   "no-addons",
-			Prefix: "no-addons",
-			Dependencies: []cloudinfo.AddonConfig{
-				{
-					OfferingName:   "deploy-arch-ibm-slz-vpc",
-					OfferingFlavor: "fully-configurable",
-					Enabled:        core.BoolPtr(true), // required addon
-				},
-				{
-					OfferingName:   "deploy-arch-ibm-kms",
-					OfferingFlavor: "fully-configurable",
-					Enabled:        core.BoolPtr(false),
-				},
-				{
-					OfferingName:   "deploy-arch-ibm-cos",
-					OfferingFlavor: "instance",
-					Enabled:        core.BoolPtr(true), // required addon
-				},
-				{
-					OfferingName:   "deploy-arch-ibm-cloud-logs",
-					OfferingFlavor: "fully-configurable",
-					Enabled:        core.BoolPtr(false),
-				},
-				{
-					OfferingName:   "deploy-arch-ibm-cloud-monitoring",
-					OfferingFlavor: "fully-configurable",
-					Enabled:        core.BoolPtr(false),
-				},
-				{
-					OfferingName:   "deploy-arch-ibm-activity-tracker",
-					OfferingFlavor: "fully-configurable",
-					Enabled:        core.BoolPtr(false),
-				},
-				{
-					OfferingName:   "deploy-arch-ibm-secrets-manager",
-					OfferingFlavor: "fully-configurable",
-					Enabled:        core.BoolPtr(false),
-				},
-				{
-					OfferingName:   "deploy-arch-ibm-scc-workload-protection",
-					OfferingFlavor: "fully-configurable",
-					Enabled:        core.BoolPtr(false),
-				},
-			},
-		},
-		{
-			Name:   "all-addons",
-			Prefix: "all-addons",
-			Dependencies: []cloudinfo.AddonConfig{
-				{
-					OfferingName:   "deploy-arch-ibm-slz-vpc",
-					OfferingFlavor: "fully-configurable",
-					Enabled:        core.BoolPtr(true),
-				},
-				{
-					OfferingName:   "deploy-arch-ibm-kms",
-					OfferingFlavor: "fully-configurable",
-					Enabled:        core.BoolPtr(true),
-				},
-				{
-					OfferingName:   "deploy-arch-ibm-cos",
-					OfferingFlavor: "instance",
-					Enabled:        core.BoolPtr(true),
-				},
-				{
-					OfferingName:   "deploy-arch-ibm-cloud-logs",
-					OfferingFlavor: "fully-configurable",
-					Enabled:        core.BoolPtr(true),
-				},
-				{
-					OfferingName:   "deploy-arch-ibm-cloud-monitoring",
-					OfferingFlavor: "fully-configurable",
-					Enabled:        core.BoolPtr(true),
-				},
-				{
-					OfferingName:   "deploy-arch-ibm-activity-tracker",
-					OfferingFlavor: "fully-configurable",
-					Enabled:        core.BoolPtr(true),
-				},
-				{
-					OfferingName:   "deploy-arch-ibm-secrets-manager",
-					OfferingFlavor: "fully-configurable",
-					Enabled:        core.BoolPtr(true),
-				},
-				{
-					OfferingName:   "deploy-arch-ibm-scc-workload-protection",
-					OfferingFlavor: "fully-configurable",
-					Enabled:        core.BoolPtr(true),
-				},
-			},
-		},
-		{
-			Name:   "observability-with-no-deps",
-			Prefix: "obs-no-dep",
-			Dependencies: []cloudinfo.AddonConfig{
-				{
-					OfferingName:   "deploy-arch-ibm-slz-vpc",
-					OfferingFlavor: "fully-configurable",
-					Enabled:        core.BoolPtr(true), // required addon
-				},
-				{
-					OfferingName:   "deploy-arch-ibm-kms",
-					OfferingFlavor: "fully-configurable",
-					Enabled:        core.BoolPtr(false),
-				},
-				{
-					OfferingName:   "deploy-arch-ibm-cos",
-					OfferingFlavor: "instance",
-					Enabled:        core.BoolPtr(true), // required addon
-				},
-				{
-					OfferingName:   "deploy-arch-ibm-cloud-logs",
-					OfferingFlavor: "fully-configurable",
-					Enabled:        core.BoolPtr(true),
-				},
-				{
-					OfferingName:   "deploy-arch-ibm-cloud-monitoring",
-					OfferingFlavor: "fully-configurable",
-					Enabled:        core.BoolPtr(true),
-				},
-				{
-					OfferingName:   "deploy-arch-ibm-activity-tracker",
-					OfferingFlavor: "fully-configurable",
-					Enabled:        core.BoolPtr(true),
-				},
-				{
-					OfferingName:   "deploy-arch-ibm-secrets-manager",
-					OfferingFlavor: "fully-configurable",
-					Enabled:        core.BoolPtr(false),
-				},
-				{
-					OfferingName:   "deploy-arch-ibm-scc-workload-protection",
-					OfferingFlavor: "fully-configurable",
-					Enabled:        core.BoolPtr(false),
-				},
-				{
-					OfferingName:   "deploy-arch-ibm-event-notifications",
-					OfferingFlavor: "fully-configurable",
-					Enabled:        core.BoolPtr(false),
-				},
-			},
-		},
+// addonCoverage selects the permutations of TestAddonPermutations. PR runs default to the two smoke cases, as every case
+// deploys a full addon stack. Nightly runs opt in to more, e.g. go test -run TestAddonPermutations -addon-coverage=pairwise
+var addonCoverage = flag.String("addon-coverage", string(catalog.CoverageSmoke), "addon permutations to test: full, pairwise or smoke")
+
+// addonTestCases generates the addon test cases from the dependencies of the fully-configurable flavor in the catalog
+func addonTestCases(t *testing.T, coverage string) []testaddons.AddonTestCase {
+	parsed, err := catalog.ParseCoverage(coverage)
+	require.NoError(t, err)
+	cat, err := catalog.Load(catalogPath)
+	require.NoError(t, err)
+	flavor, err := cat.Flavor("deploy-arch-ibm-slz-ocp", "fully-configurable")
+	require.NoError(t, err)
+	permutations, err := flavor.AddonPermutations(parsed)
+	require.NoError(t, err)
+
+	var testCases []testaddons.AddonTestCase
+	for _, permutation := range permutations {
+		testCase := testaddons.AddonTestCase{Name: permutation.Name, Prefix: permutation.Prefix}
+		for _, addon := range permutation.Addons {
+			testCase.Dependencies = append(testCase.Dependencies, cloudinfo.AddonConfig{
+				OfferingName:   addon.Name,
+				OfferingFlavor: addon.Flavor,
+				Enabled:        core.BoolPtr(addon.Enabled),
+			})
+		}
+		testCases = append(testCases, testCase)
 	}
+	return testCases
+}
+
+// acquireAddonResources queues an addon test case until the account has room for the stack that it deploys. The
+// resources are released after the undeploy of the case, or at the latest at the end of the test.
+func acquireAddonResources(t *testing.T, testCase testaddons.AddonTestCase) (*governor.Permit, error) {
+	var addons []string
+	for _, dependency := range testCase.Dependencies {
+		if dependency.Enabled != nil && *dependency.Enabled {
+			addons = append(addons, dependency.OfferingName)
+		}
+	}
+	weight := resourceLimits.Weight(t.Name(), addons...)
+	permit, err := resourceGovernor.Acquire(t.Context(), t.Name()+"/"+testCase.Name, weight)
+	if err != nil {
+		return nil, err
+	}
+	logger.Logf(t, "Test case %s waited %s for account resources %s", testCase.Name, permit.Waited.Round(time.Second), weight)
+	t.Cleanup(permit.Release)
+	return permit, nil
+}
+
+func TestAddonPermutations(t *testing.T) {
+	testCases := addonTestCases(t, *addonCoverage)
+	t.Logf("Testing %d addon permutations with %s coverage", len(testCases), *addonCoverage)
 
 	baseOptions := testaddons.TestAddonsOptionsDefault(&testaddons.TestAddonOptions{
 		Testing:              t,
@@ -334,12 +324,26 @@ func TestAddonPermutations(t *testing.T) {
 		BaseOptions: baseOptions,
 		TestCases:   testCases,
 		BaseSetupFunc: func(baseOptions *testaddons.TestAddonOptions, testCase testaddons.AddonTestCase) *testaddons.TestAddonOptions {
-			return testaddons.TestAddonsOptionsDefault(&testaddons.TestAddonOptions{
+			options := testaddons.TestAddonsOptionsDefault(&testaddons.TestAddonOptions{
 				Testing:          t,
 				Prefix:           testCase.Prefix,
 				ResourceGroup:    resourceGroup,
 				VerboseOnFailure: true,
 			})
+			// every test case deploys a whole stack, so each one holds the account resources of its addons
+			var permit *governor.Permit
+			options.PreDeployHook = func(options *testaddons.TestAddonOptions) error {
+				var err error
+				permit, err = acquireAddonResources(t, testCase)
+				return err
+			}
+			options.PostUndeployHook = func(options *testaddons.TestAddonOptions) error {
+				if permit != nil {
+					permit.Release()
+				}
+				return nil
+			}
+			return options
 		},
 		AddonConfigFunc: func(options *testaddons.TestAddonOptions, testCase testaddons.AddonTestCase) cloudinfo.AddonConfig {
 			return cloudinfo.NewAddonConfigTerraform(
@@ -353,3 +357,248 @@ func TestAddonPermutations(t *testing.T) {
 
 	baseOptions.RunAddonTestMatrix(matrix)
 }
+
+// Address of the cluster resource that is used when enable_openshift_version_upgrade is true
+const ocpUpgradeClusterAddress = "module.ocp_base.ibm_container_vpc_cluster.cluster_with_upgrade[0]"
+
+// checkNetworkHealthy runs the network health check of the module against the cluster, using the network plugin that
+// the cluster reports
+func checkNetworkHealthy(t *testing.T, ctx context.Context, kubeconfig string) error {
+	kubectl := kube.NewKubectl(kubeconfig)
+	plugin, err := kubectl.Kubectl(ctx, "", "get", "network.config.openshift.io", "cluster", "--output", "jsonpath={.spec.networkType}")
+	if err != nil {
+		return err
+	}
+	cmd := exec.CommandContext(ctx, "bash", "../scripts/confirm_network_healthy.sh", strings.TrimSpace(plugin), filepath.Dir(kubectl.Binary))
+	cmd.Env = append(os.Environ(), "KUBECONFIG="+kubeconfig)
+	output, err := cmd.CombinedOutput()
+	logger.Log(t, string(output))
+	return err
+}
+
+// checkUpgradedCluster verifies that the master and all workers run version, and that the worker network and the
+// cluster ingress are healthy
+func checkUpgradedCluster(t *testing.T, clusterName string, region string, version string) error {
+	authenticator, err := ibmcloud.NewIamAuthenticator(validateEnvVariable(t, "TF_VAR_ibmcloud_api_key"))
+	if err != nil {
+		return err
+	}
+	ctx := deadline.New(t, destroyBudget).Context()
+	containers := ibmcloud.NewContainersClient(authenticator)
+	if err := verify.ClusterVersion(ctx, containers, clusterName, version); err != nil {
+		return err
+	}
+	// the cluster is in a non-default resource group, so the kubeconfig data source needs its ID
+	cluster, err := containers.GetCluster(ctx, clusterName)
+	if err != nil {
+		return err
+	}
+	kubeconfig, err := getClusterKubeconfigE(t, ctx, cluster.ID, region)
+	if err != nil {
+		return fmt.Errorf("failed to download the kubeconfig of cluster %s: %w", clusterName, err)
+	}
+	if err := checkNetworkHealthy(t, ctx, kubeconfig); err != nil {
+		return fmt.Errorf("network health check failed: %w", err)
+	}
+	if err := checkClusterIngress(t, clusterName, region); err != nil {
+		return fmt.Errorf("cluster ingress failed to become healthy: %w", err)
+	}
+	return nil
+}
+
+// TestRunOCPVersionUpgrade creates the custom_sg example with enable_openshift_version_upgrade at OCP version N-1 and
+// upgrades it to version N by changing ocp_version. The module only upgrades the master, so the workers are replaced
+// with updated ones afterwards. The duration of each stage is logged at the end of the test.
+func TestRunOCPVersionUpgrade(t *testing.T) {
+	t.Parallel()
+	acquireResources(t)
+
+	fromVersion, toVersion, err := upgrade.Path(validOCPVersions)
+	require.NoError(t, err, "Failed to pick the OCP versions to upgrade between")
+	prefix := fmt.Sprintf("ocp-upg-%s", strings.ToLower(random.UniqueID()))
+
+	// the example references the module with a relative path, so the whole repo is copied
+	tempRepoDir, err := files.CopyTerraformFolderToTemp("..", prefix)
+	require.NoError(t, err, "Failed to create temporary Terraform folder")
+	region := leaseRegion(t, 1)
+
+	options := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
+		TerraformDir: filepath.Join(tempRepoDir, customsgExampleDir),
+		Vars: map[string]interface{}{
+			"prefix":                           prefix,
+			"region":                           region,
+			"resource_group":                   resourceGroup,
+			"ocp_version":                      fromVersion,
+			"access_tags":                      permanentResources.AccessTags,
+			"ocp_entitlement":                  "cloud_pak",
+			"enable_openshift_version_upgrade": true,
+		},
+		Upgrade:     true,
+		Lock:        true,
+		LockTimeout: terraformLockTimeout,
+	})
+	runner := &terratestRunner{t: t, options: options, workspace: prefix}
+	// Temp workaround for https://github.com/terraform-ibm-modules/terraform-ibm-base-ocp-vpc?tab=readme-ov-file#the-specified-api-key-could-not-be-found
+	apikey := createContainersApikey(t, region, resourceGroup)
+	tracked, err := track(t, journal.Artifact{
+		Kind:      journal.KindTerraform,
+		Dir:       options.TerraformDir,
+		Workspace: prefix,
+		Vars:      options.Vars,
+		Prefix:    prefix,
+		Region:    region,
+	}, runner.Destroy, apikey)
+	require.NoError(t, err)
+	// the upgrade takes hours, so it is cancelled in time for the deferred destroy to finish before the test times out
+	budget := deadline.New(t, destroyBudget)
+	terraform.WorkspaceSelectOrNewContext(t, budget.Context(), options, prefix)
+
+	stages := &upgrade.Stages{}
+	defer func() {
+		_ = stages.Run("destroy", func() error {
+			options.PlanFilePath = ""
+			cleanupTerraform(t, tracked)
+			return nil
+		})
+		logger.Log(t, fmt.Sprintf("OCP upgrade from %s to %s stage durations:\n%s", fromVersion, toVersion, stages))
+	}()
+
+	err = stages.Run("apply "+fromVersion, func() error {
+		_, err := terraform.InitAndApplyContextE(t, budget.Context(), options)
+		return budget.Wrap(err)
+	})
+	require.NoError(t, err, "Init and Apply at OCP version %s failed", fromVersion)
+	clusterName := terraform.OutputContext(t, budget.Context(), options, "cluster_name")
+
+	err = stages.Run("verify "+fromVersion, func() error {
+		return checkUpgradedCluster(t, clusterName, region, fromVersion)
+	})
+	if err != nil {
+		collectMustGather(t, region, clusterName)
+	}
+	require.NoError(t, err, "Cluster is not healthy at OCP version %s", fromVersion)
+
+	options.Vars["ocp_version"] = toVersion
+	options.PlanFilePath = filepath.Join(options.TerraformDir, "upgrade.tfplan")
+	err = stages.Run("plan "+toVersion, func() error {
+		plan, err := terraform.InitAndPlanAndShowWithStructContextE(t, budget.Context(), options)
+		if err != nil {
+			return budget.Wrap(err)
+		}
+		return verify.InPlaceUpdate(plan, ocpUpgradeClusterAddress)
+	})
+	require.NoError(t, err, "Upgrade to OCP version %s is not planned as an in-place update", toVersion)
+
+	// applies the plan file that was checked above
+	err = stages.Run("upgrade master to "+toVersion, func() error {
+		_, err := terraform.ApplyContextE(t, budget.Context(), options)
+		return budget.Wrap(err)
+	})
+	require.NoError(t, err, "Apply at OCP version %s failed", toVersion)
+	options.PlanFilePath = ""
+
+	authenticator, err := ibmcloud.NewIamAuthenticator(validateEnvVariable(t, "TF_VAR_ibmcloud_api_key"))
+	require.NoError(t, err, "Failed to create IAM authenticator")
+	err = stages.Run("update workers to "+toVersion, func() error {
+		ctx, cancel := context.WithTimeout(budget.Context(), 3*time.Hour)
+		defer cancel()
+		return budget.Wrap(upgrade.UpdateWorkers(ctx, ibmcloud.NewContainersClient(authenticator), clusterName, toVersion, time.Minute))
+	})
+	require.NoError(t, err, "Failed to update workers to OCP version %s", toVersion)
+
+	err = stages.Run("verify "+toVersion, func() error {
+		return checkUpgradedCluster(t, clusterName, region, toVersion)
+	})
+	if err != nil {
+		collectMustGather(t, region, clusterName)
+	}
+	assert.NoError(t, err, "Cluster is not healthy at OCP version %s", toVersion)
+}
+
+// TestReleaseStateMigration checks that the current code takes over the state of the custom_sg example created by an
+// older release, without destroying the cluster, its worker pools or the COS instance. Set UPGRADE_FROM_RELEASE to the
+// release tag to migrate from. See state-fixtures/README.md for how recorded states are used.
+func TestReleaseStateMigration(t *testing.T) {
+	tag := os.Getenv("UPGRADE_FROM_RELEASE")
+	if tag == "" {
+		t.Skip("UPGRADE_FROM_RELEASE is not set")
+	}
+	t.Parallel()
+	acquireResources(t)
+
+	prefix := fmt.Sprintf("ocp-mig-%s", strings.ToLower(random.UniqueID()))
+	budget := deadline.New(t, destroyBudget)
+	fixture, err := filepath.Abs(filepath.Join("state-fixtures", fmt.Sprintf("%s-%s", filepath.Base(customsgExampleDir), tag)))
+	require.NoError(t, err)
+
+	// the example references the module with a relative path, so the whole repo is copied
+	tempRepoDir, err := files.CopyTerraformFolderToTemp("..", prefix)
+	require.NoError(t, err, "Failed to create temporary Terraform folder")
+	options := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
+		TerraformDir: filepath.Join(tempRepoDir, customsgExampleDir),
+		PlanFilePath: filepath.Join(tempRepoDir, "migration.tfplan"),
+		Upgrade:      true,
+	})
+	statePath := filepath.Join(options.TerraformDir, "terraform.tfstate")
+
+	if files.FileExists(fixture + ".tfstate") {
+		logger.Log(t, fmt.Sprintf("Planning against the state recorded for release %s in %s", tag, fixture+".tfstate"))
+		require.NoError(t, files.CopyFile(fixture+".tfstate", statePath), "Failed to copy the state fixture")
+		options.VarFiles = []string{fixture + ".tfvars.json"}
+		// the recorded resources no longer exist, so they must not be refreshed
+		options.ExtraArgs.Plan = []string{"-refresh=false"}
+	} else {
+		releaseDir := filepath.Join(t.TempDir(), "release")
+		removeRelease, err := migration.CheckoutRelease("..", tag, releaseDir)
+		require.NoError(t, err, "Failed to check out release %s", tag)
+		defer func() {
+			assert.NoError(t, removeRelease(), "Failed to remove the checkout of release %s", tag)
+		}()
+
+		region := leaseRegion(t, 1)
+		vars := map[string]interface{}{
+			"prefix":          prefix,
+			"region":          region,
+			"resource_group":  resourceGroup,
+			"access_tags":     permanentResources.AccessTags,
+			"ocp_entitlement": "cloud_pak",
+		}
+		releaseOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
+			TerraformDir: filepath.Join(releaseDir, customsgExampleDir),
+			Vars:         vars,
+			Upgrade:      true,
+			Lock:         true,
+			LockTimeout:  terraformLockTimeout,
+		})
+		// Temp workaround for https://github.com/terraform-ibm-modules/terraform-ibm-base-ocp-vpc?tab=readme-ov-file#the-specified-api-key-could-not-be-found
+		apikey := createContainersApikey(t, region, resourceGroup)
+		tracked, err := track(t, journal.Artifact{
+			Kind:   journal.KindTerraform,
+			Dir:    releaseOptions.TerraformDir,
+			Vars:   vars,
+			Prefix: prefix,
+			Region: region,
+		}, func(ctx context.Context) error {
+			_, err := terraform.DestroyContextE(t, ctx, releaseOptions)
+			return err
+		}, apikey)
+		require.NoError(t, err)
+		defer cleanupTerraform(t, tracked)
+
+		_, err = terraform.InitAndApplyContextE(t, budget.Context(), releaseOptions)
+		require.NoError(t, budget.Wrap(err), "Init and Apply of release %s failed", tag)
+		require.NoError(t, files.CopyFile(filepath.Join(releaseOptions.TerraformDir, "terraform.tfstate"), statePath), "Failed to copy the state of release %s", tag)
+		options.Vars = vars
+
+		if strings.ToLower(os.Getenv("RECORD_STATE_FIXTURE")) == "true" {
+			require.NoError(t, files.CopyFile(statePath, fixture+".tfstate"), "Failed to record the state fixture")
+			varsJSON, err := json.MarshalIndent(vars, "", "  ")
+			require.NoError(t, err)
+			require.NoError(t, os.WriteFile(fixture+".tfvars.json", varsJSON, 0o644), "Failed to record the state fixture variables")
+		}
+	}
+
+	plan, err := terraform.InitAndPlanAndShowWithStructContextE(t, budget.Context(), options)
+	require.NoError(t, budget.Wrap(err), "Plan of the current code against the state of release %s failed", tag)
+	assert.NoError(t, migration.Check(plan, "module.ocp_base."), "The current code does not take over the state of release %s", tag)
+}
@@ -4,19 +4,28 @@ package test
 import (
 	"bytes"
 	"context"
+	"errors"
+	"flag"
 	"fmt"
 	"log"
+	"net/http"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/IBM/go-sdk-core/v5/core"
 	"github.com/gruntwork-io/terratest/modules/files"
 	"github.com/gruntwork-io/terratest/modules/logger"
 	"github.com/gruntwork-io/terratest/modules/random"
 	"github.com/gruntwork-io/terratest/modules/terraform"
-	"github.com/terraform-ibm-modules/ibmcloud-terratest-wrapper/common"
+	terratesting "github.com/gruntwork-io/terratest/modules/testing"
 	"github.com/terraform-ibm-modules/ibmcloud-terratest-wrapper/testaddons"
 	"github.com/terraform-ibm-modules/ibmcloud-terratest-wrapper/testschematic"
 
@@ -24,6 +33,22 @@ import (
 	"github.com/stretchr/testify/require"
 	"github.com/terraform-ibm-modules/ibmcloud-terratest-wrapper/cloudinfo"
 	"github.com/terraform-ibm-modules/ibmcloud-terratest-wrapper/testhelper"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"github.com/terraform-ibm-modules/terraform-ibm-base-ocp-vpc/internal/cleanup"
+	"github.com/terraform-ibm-modules/terraform-ibm-base-ocp-vpc/internal/deadline"
+	"github.com/terraform-ibm-modules/terraform-ibm-base-ocp-vpc/internal/fixture"
+	"github.com/terraform-ibm-modules/terraform-ibm-base-ocp-vpc/internal/governor"
+	"github.com/terraform-ibm-modules/terraform-ibm-base-ocp-vpc/internal/ibmcloud"
+	"github.com/terraform-ibm-modules/terraform-ibm-base-ocp-vpc/internal/journal"
+	"github.com/terraform-ibm-modules/terraform-ibm-base-ocp-vpc/internal/kube"
+	"github.com/terraform-ibm-modules/terraform-ibm-base-ocp-vpc/internal/mustgather"
+	"github.com/terraform-ibm-modules/terraform-ibm-base-ocp-vpc/internal/permanent"
+	"github.com/terraform-ibm-modules/terraform-ibm-base-ocp-vpc/internal/regions"
+	"github.com/terraform-ibm-modules/terraform-ibm-base-ocp-vpc/internal/shard"
+	"github.com/terraform-ibm-modules/terraform-ibm-base-ocp-vpc/internal/verify"
 )
 
 const fullyConfigurableTerraformDir = "solutions/fully-configurable"
@@ -32,39 +57,163 @@ const basicExampleDir = "examples/basic"
 const quickStartTerraformDir = "solutions/quickstart"
 const resourceGroup = "geretain-test-base-ocp-vpc"
 
-// Define a struct with fields that match the structure of the YAML data
+// Default namespace and deployment name of the kube-audit module
+const kubeAuditNamespace = "ibm-kube-audit"
+const kubeAuditDeployment = "ibmcloud-kube-audit"
+
+// Default location of the permanent resources of the test account, it can be overridden with PERMANENT_RESOURCES_YAML.
+// Set PERMANENT_RESOURCES_YAML=fake to use well-formed placeholders when only compiling or planning offline.
 const yamlLocation = "../common-dev-assets/common-go-assets/common-permanent-resources.yaml"
 
+// Regions that the tests may deploy to, in order of preference
+const regionPrefsLocation = "../common-dev-assets/common-go-assets/cloudinfo-region-vpc-gen2-prefs.yaml"
+
+// Durations of the previous runs of the tests, used to balance the shards when SHARD_INDEX and SHARD_TOTAL are set. It
+// is written by cmd/test-durations and can be overridden with SHARD_DURATIONS.
+const testDurationsLocation = "test-durations.json"
+
+// Limits of the resources that the tests create at the same time in the test account, and the weight of each test. It
+// can be overridden with RESOURCE_LIMITS_YAML.
+const resourceLimitsLocation = "resource-limits.yaml"
+
+// Time kept at the end of a test, before go test -timeout ends the run, to destroy what the test created. Applies and
+// checks still running when only this is left are cancelled.
+const destroyBudget = 45 * time.Minute
+
+// Resources that could not be destroyed when the run was interrupted are listed here, for manual deletion. It can be
+// overridden with CLEANUP_LEFTOVERS.
+const cleanupLeftoversLocation = "cleanup-leftovers.json"
+
+// What the tests provision is recorded here, for cmd/resume-cleanup to clean up after a runner that died. It can be
+// overridden with RUN_JOURNAL.
+const runJournalLocation = "run-journal.jsonl"
+
+// Must-gather tarballs of the clusters whose post-apply checks failed are written here, to be kept as CI artifacts. It
+// can be overridden with MUST_GATHER_DIR.
+const mustGatherLocation = "must-gather"
+
+// How long collecting the must-gather of a test may take
+const mustGatherTimeout = 15 * time.Minute
+
+// How long the ingress of a cluster may take to become healthy after the apply
+const ingressHealthyTimeout = 30 * time.Minute
+
+// How long deploying the sample workload and reaching it through its route may take
+const sampleWorkloadTimeout = 20 * time.Minute
+
+// How long the nodes of a cluster may take to match the requested version, operating systems and network plugin
+const nodeFleetTimeout = 15 * time.Minute
+
+// How long running the egress probe and checking the pod and service addresses may take
+const networkProbeTimeout = 15 * time.Minute
+
+// How long the internal registry may take to become available and to store a pushed image
+const registryStorageTimeout = 20 * time.Minute
+
+// How many resources are destroyed at the same time when the run is interrupted
+const cleanupConcurrency = 4
+
+// Lock timeout of the Terraform commands of the tests, so that the destroy started on interrupt waits for the apply
+// that Terraform is stopping to release the state, see applyInterruptible
+const terraformLockTimeout = "20m"
+
+// How long Terraform may take to stop a cancelled apply before it is killed, which leaves what it was creating out of
+// the state
+const terraformInterruptGrace = 15 * time.Minute
+
 // Ensure there is one test per supported OCP version
-const terraformVersion = "terraform_v1.12.2" // This should match the version in the ibm_catalog.json
+const terraformVersion = "terraform_v1.12.2" // This should match the version in the ibm_catalog.json, checked by static.TestTerraformVersionMatchesCatalog
 
 var (
 	sharedInfoSvc      *cloudinfo.CloudInfoService
-	permanentResources map[string]interface{}
-	ocpVersion1        string // used by TestRunFullyConfigurable, TestRunUpgradeFullyConfigurable, TestFSCloudInSchematic and TestRunMultiClusterExample
-	ocpVersion2        string // used by TestCustomSGExample and TestRunCustomsgExample
-	ocpVersion3        string // used by TestRunAdvancedExample and TestCrossKmsSupportExample
-	ocpVersion4        string // used by TestRunAddRulesToSGExample and TestRunBasicExample
+	permanentResources *permanent.Resources
+	validOCPVersions   []string // all supported OCP versions, used by TestRunOCPVersionUpgrade
+	ocpVersion1        string   // used by TestRunFullyConfigurable, TestRunUpgradeFullyConfigurable, TestFSCloudInSchematic and TestRunMultiClusterExample
+	ocpVersion2        string   // used by TestCustomSGExample and TestRunCustomsgExample
+	ocpVersion3        string   // used by TestRunAdvancedExample and TestCrossKmsSupportExample
+	ocpVersion4        string   // used by TestRunAddRulesToSGExample and TestRunBasicExample
+	resourceLimits     *governor.Config
+	resourceGovernor   *governor.Governor
 )
 
+// cleanupRegistry holds what the tests have provisioned and not destroyed yet, which is destroyed if the run is
+// interrupted with SIGINT or SIGTERM
+var cleanupRegistry = cleanup.NewRegistry(cleanupConcurrency)
+
+// runJournal records what the tests provision until it is destroyed, opened by TestMain
+var runJournal *journal.Journal
+
+// regionLeases hands out the regions of the tests. The lease file is shared by all go test processes on the runner, it
+// can be moved with REGION_LEASE_FILE to another local path. It must not be shared between runners, e.g. on a network
+// volume: locks are not reliable there, and leases of other machines are only dropped after their TTL. REGION_CLUSTER_CAP
+// overrides the number of clusters per region.
+var regionLeases = &regions.Coordinator{
+	Path:       filepath.Join(os.TempDir(), "terraform-ibm-base-ocp-vpc-region-leases.json"),
+	DefaultCap: 4,
+	Exclusions: []regions.Exclusion{
+		{Region: "jp-osa", Feature: regions.FeatureHPCS, Reason: "jp-osa does not allow hs-crypto to be used for encryption"},
+	},
+}
+
+// fakeOCPVersions stand in for the supported OCP versions when the tests run offline with fake permanent resources
+var fakeOCPVersions = []string{"4.16", "4.17", "4.18", "4.19"}
+
 // TestMain will be run before any parallel tests, used to set up a shared InfoService object to track region usage
 // for multiple tests
 func TestMain(m *testing.M) {
+	flag.Parse()
+	if err := applyShard(); err != nil {
+		log.Fatal(err)
+	}
+
+	// with fake permanent resources the tests only compile and plan, so nothing may need credentials or the network
+	fake := os.Getenv("PERMANENT_RESOURCES_YAML") == "fake"
 	var err error
-	sharedInfoSvc, err = cloudinfo.NewCloudInfoServiceFromEnv("TF_VAR_ibmcloud_api_key", cloudinfo.CloudInfoServiceOptions{})
+	if !fake {
+		sharedInfoSvc, err = cloudinfo.NewCloudInfoServiceFromEnv("TF_VAR_ibmcloud_api_key", cloudinfo.CloudInfoServiceOptions{})
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	permanentResources, err = loadPermanentResources()
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	permanentResources, err = common.LoadMapFromYaml(yamlLocation)
+	regionLeases.Regions, err = regions.LoadPreferences(regionPrefsLocation)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if path := os.Getenv("REGION_LEASE_FILE"); path != "" {
+		regionLeases.Path = path
+	}
+	if limit := os.Getenv("REGION_CLUSTER_CAP"); limit != "" {
+		if regionLeases.DefaultCap, err = strconv.Atoi(limit); err != nil {
+			log.Fatalf("invalid REGION_CLUSTER_CAP %q: %v", limit, err)
+		}
+	}
+	if !fake {
+		regionLeases.Best = func() (string, error) {
+			return testhelper.GetBestVpcRegion(os.Getenv("TF_VAR_ibmcloud_api_key"), regionPrefsLocation, "eu-de")
+		}
+	}
+
+	limitsLocation := resourceLimitsLocation
+	if override := os.Getenv("RESOURCE_LIMITS_YAML"); override != "" {
+		limitsLocation = override
+	}
+	resourceLimits, err = governor.LoadConfig(limitsLocation)
 	if err != nil {
 		log.Fatal(err)
 	}
+	resourceGovernor = governor.New(resourceLimits.Limits)
 
 	// Get kube versions
 	expectedOCPVersions := 4
-	validOCPVersions, _, err := sharedInfoSvc.GetKubeVersions("openshift")
-	if err != nil {
+	if fake {
+		validOCPVersions = fakeOCPVersions
+	} else if validOCPVersions, _, err = sharedInfoSvc.GetKubeVersions("openshift"); err != nil {
 		log.Fatalf("failed to get kube versions: %v", err)
 	}
 	ocpVersionCount := len(validOCPVersions)
@@ -86,7 +235,81 @@ func TestMain(m *testing.M) {
 		*ocpVars[i] = validOCPVersions[idx]
 	}
 
-	os.Exit(m.Run())
+	journalLocation := runJournalLocation
+	if override := os.Getenv("RUN_JOURNAL"); override != "" {
+		journalLocation = override
+	}
+	runJournal, err = journal.Open(journalLocation, time.Now().UTC().Format("20060102T150405")+"-"+strings.ToLower(random.UniqueID()))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	leftoversLocation := cleanupLeftoversLocation
+	if override := os.Getenv("CLEANUP_LEFTOVERS"); override != "" {
+		leftoversLocation = override
+	}
+	stopSignals := cleanupRegistry.HandleSignals(leftoversLocation)
+	code := m.Run()
+	stopSignals()
+	if err := runJournal.Close(); err != nil {
+		log.Println(err)
+	}
+	log.Println(resourceGovernor.Report())
+	os.Exit(code)
+}
+
+// applyShard restricts -run to the tests of this runner when SHARD_INDEX and SHARD_TOTAL are set. The tests matching
+// -run are packed into shards by their previous durations, so that every runner gets a similar amount of work.
+func applyShard() error {
+	index, total, ok, err := shard.FromEnv(os.Getenv)
+	if err != nil || !ok {
+		return err
+	}
+	tests, err := shard.TestNames(".")
+	if err != nil {
+		return err
+	}
+	run := flag.Lookup("test.run")
+	if tests, err = shard.Matching(tests, run.Value.String()); err != nil {
+		return err
+	}
+	location := testDurationsLocation
+	if override := os.Getenv("SHARD_DURATIONS"); override != "" {
+		location = override
+	}
+	durations, err := shard.LoadDurations(location)
+	if err != nil {
+		return err
+	}
+
+	shards := shard.Plan(tests, durations, total)
+	for i, s := range shards {
+		log.Printf("Shard %d/%d, estimated %s: %s", i, total, s.Duration, strings.Join(s.Tests, ", "))
+	}
+	return run.Value.Set(shard.Regex(shards[index].Tests))
+}
+
+// acquireResources queues the test until the account has room for the resources that it creates, as weighted in
+// resource-limits.yaml. They are held until the test, including the destroy in its cleanup, is done.
+func acquireResources(t *testing.T) {
+	weight := resourceLimits.Weight(t.Name())
+	permit, err := resourceGovernor.Acquire(t.Context(), t.Name(), weight)
+	require.NoError(t, err, "Failed to acquire the account resources of the test")
+	logger.Logf(t, "Waited %s for account resources %s", permit.Waited.Round(time.Second), weight)
+	t.Cleanup(permit.Release)
+}
+
+// loadPermanentResources loads the permanent resources from yamlLocation or the file named by PERMANENT_RESOURCES_YAML.
+// It fails if any key used by the tests is missing or malformed.
+func loadPermanentResources() (*permanent.Resources, error) {
+	location := yamlLocation
+	if override := os.Getenv("PERMANENT_RESOURCES_YAML"); override == "fake" {
+		log.Println("Using fake permanent resources, the tests cannot deploy")
+		return permanent.Fake(), nil
+	} else if override != "" {
+		location = override
+	}
+	return permanent.Load(location)
 }
 
 func validateEnvVariable(t *testing.T, varName string) string {
@@ -96,14 +319,60 @@ func validateEnvVariable(t *testing.T, varName string) string {
 	return val
 }
 
-func setupTerraform(t *testing.T, prefix, realTerraformDir string) *terraform.Options {
-	tempTerraformDir, err := files.CopyTerraformFolderToTemp(realTerraformDir, prefix)
-	require.NoError(t, err, "Failed to create temporary Terraform folder")
-	apiKey := validateEnvVariable(t, "TF_VAR_ibmcloud_api_key")
-	region, err := testhelper.GetBestVpcRegion(apiKey, "../common-dev-assets/common-go-assets/cloudinfo-region-vpc-gen2-prefs.yaml", "eu-de")
-	require.NoError(t, err, "Failed to get best VPC region")
+// leaseRegion reserves a region for the clusters of a test, with the features it depends on. The region is released
+// once the test and its cleanup functions are done, so the clusters must be destroyed by then.
+func leaseRegion(t *testing.T, clusters int, features ...regions.Feature) string {
+	lease, err := regionLeases.Acquire(t.Context(), t.Name(), clusters, features...)
+	require.NoError(t, err, "Failed to lease a region")
+	logger.Logf(t, "Leased region %s for %d clusters", lease.Region, clusters)
+	t.Cleanup(func() {
+		if err := lease.Release(); err != nil {
+			logger.Logf(t, "Failed to release region %s: %v", lease.Region, err)
+		}
+	})
+	return lease.Region
+}
+
+// existingResources is the ./existing-resources fixture of a region, shared by the tests through existingResourcesPool
+type existingResources struct {
+	tracked           *trackedResource
+	resourceGroupName string
+	vpcCRN            string
+	cosInstanceID     string
+}
 
-	existingTerraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
+// existingResourcesPool holds the existing resources of each region that tests of this run deploy to
+var existingResourcesPool = fixture.NewPool[*existingResources]()
+
+// borrowExistingResources returns the existing resources of a region, provisioning them if no other test of the run
+// uses them yet. They are destroyed by the last test returning them, from t.Cleanup so that this also happens when a
+// test fails or panics.
+func borrowExistingResources(t *testing.T, region string) *existingResources {
+	resources, err := existingResourcesPool.Borrow(region, func() (*existingResources, error) {
+		return setupExistingResources(t, region)
+	})
+	require.NoError(t, err, "Failed to provision the existing resources in %s", region)
+	t.Cleanup(func() {
+		if resources, last := existingResourcesPool.Return(region); last {
+			cleanupTerraform(t, resources.tracked)
+		}
+	})
+	return resources
+}
+
+// setupExistingResources applies ./existing-resources in a region. Errors are returned rather than failing the test, as
+// other tests may be waiting for the resources; a failed apply is destroyed right away.
+func setupExistingResources(t *testing.T, region string) (*existingResources, error) {
+	prefix := fmt.Sprintf("ocp-existing-%s", strings.ToLower(random.UniqueID()))
+	tempTerraformDir, err := files.CopyTerraformFolderToTemp("./existing-resources", prefix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary Terraform folder: %w", err)
+	}
+
+	// the resources are destroyed by the last test returning them, or on interrupt, when the test provisioning them may
+	// have completed long ago, so Terraform runs with a logger of the fixture rather than of this test
+	fixtureLogger := &fixtureT{name: prefix}
+	existingTerraformOptions := terraform.WithDefaultRetryableErrors(fixtureLogger, &terraform.Options{
 		TerraformDir: tempTerraformDir,
 		Vars: map[string]interface{}{
 			"prefix": prefix,
@@ -111,19 +380,210 @@ func setupTerraform(t *testing.T, prefix, realTerraformDir string) *terraform.Op
 		},
 		// Set Upgrade to true to ensure latest version of providers and modules are used by terratest.
 		// This is the same as setting the -upgrade=true flag with terraform.
-		Upgrade: true,
+		Upgrade:     true,
+		Lock:        true,
+		LockTimeout: terraformLockTimeout,
 	})
 
-	terraform.WorkspaceSelectOrNewContext(t, context.Background(), existingTerraformOptions, prefix)
-	_, err = terraform.InitAndApplyContextE(t, context.Background(), existingTerraformOptions)
-	require.NoError(t, err, "Init and Apply of temp existing resource failed")
+	runner := &terratestRunner{t: fixtureLogger, options: existingTerraformOptions, workspace: prefix}
+	// tracked before the apply, so that a partial apply is destroyed too
+	tracked, err := track(t, journal.Artifact{
+		Kind:      journal.KindTerraform,
+		Dir:       tempTerraformDir,
+		Workspace: prefix,
+		Vars:      existingTerraformOptions.Vars,
+		Prefix:    prefix,
+		Region:    region,
+	}, runner.Destroy)
+	if err != nil {
+		return nil, err
+	}
+	resources := &existingResources{tracked: tracked}
+	// other tests wait for the fixture, so neither the end nor the failure of this test cancels its provisioning
+	budget, cancel := deadline.NewDetached(t, destroyBudget)
+	defer cancel()
+	if err := budget.Apply(runner); err != nil {
+		logger.Log(fixtureLogger, "Init and Apply of temp existing resource failed, destroyed")
+		return nil, err
+	}
+
+	for key, value := range map[string]*string{
+		"resource_group_name": &resources.resourceGroupName,
+		"vpc_crn":             &resources.vpcCRN,
+		"cos_instance_id":     &resources.cosInstanceID,
+	} {
+		if *value, err = terraform.OutputContextE(fixtureLogger, budget.Context(), existingTerraformOptions, key); err != nil {
+			return nil, err
+		}
+	}
+	return resources, nil
+}
 
-	return existingTerraformOptions
+// kubeconfigKey identifies the kubeconfig of a cluster downloaded by a test
+type kubeconfigKey struct {
+	t       *testing.T
+	cluster string
 }
+
+// kubeconfigs holds the kubeconfigs that the running tests downloaded, see getClusterKubeconfigE
+var (
+	kubeconfigsMu sync.Mutex
+	kubeconfigs   = map[kubeconfigKey]string{}
+)
+
+// getClusterKubeconfigE downloads an admin kubeconfig for the cluster using the ibm_container_cluster_config data
+// source in ./cluster-config, and returns its path. The kubeconfig is downloaded once per test, into a temporary
+// directory that is removed when the test ends.
+func getClusterKubeconfigE(t *testing.T, ctx context.Context, clusterID string, region string) (string, error) {
+	key := kubeconfigKey{t: t, cluster: clusterID}
+	kubeconfigsMu.Lock()
+	kubeconfig, ok := kubeconfigs[key]
+	kubeconfigsMu.Unlock()
+	if ok {
+		return kubeconfig, nil
+	}
+
+	tempTerraformDir, err := files.CopyTerraformFolderToDest("./cluster-config", t.TempDir(), "kubeconfig")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temporary Terraform folder: %w", err)
+	}
+
+	options := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
+		TerraformDir: tempTerraformDir,
+		Vars: map[string]interface{}{
+			"cluster_id": clusterID,
+			"region":     region,
+		},
+	})
+	if _, err := terraform.InitAndApplyContextE(t, ctx, options); err != nil {
+		return "", err
+	}
+	if kubeconfig, err = terraform.OutputContextE(t, ctx, options, "config_file_path"); err != nil {
+		return "", err
+	}
+
+	kubeconfigsMu.Lock()
+	kubeconfigs[key] = kubeconfig
+	kubeconfigsMu.Unlock()
+	t.Cleanup(func() {
+		kubeconfigsMu.Lock()
+		defer kubeconfigsMu.Unlock()
+		delete(kubeconfigs, key)
+	})
+	return kubeconfig, nil
+}
+
+// clusterKubectl returns the cluster with the given name or ID, and kubectl with an admin kubeconfig for it
+func clusterKubectl(t *testing.T, ctx context.Context, containers *ibmcloud.ContainersClient, cluster string, region string) (*ibmcloud.Cluster, *kube.Kubectl, error) {
+	// the kubeconfig data source needs the ID, or the resource group of a cluster name
+	info, err := containers.GetCluster(ctx, cluster)
+	if err != nil {
+		return nil, nil, err
+	}
+	kubeconfig, err := getClusterKubeconfigE(t, ctx, info.ID, region)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to download the kubeconfig of cluster %s: %w", cluster, err)
+	}
+	return info, kube.NewKubectl(kubeconfig), nil
+}
+
+// mustGatherOnFailure wraps a post-apply hook to collect a must-gather of the clusters named by the clusterOutputs
+// outputs when the hook fails, by returning an error or failing the test
+func mustGatherOnFailure(hook func(*testhelper.TestOptions) error, clusterOutputs ...string) func(*testhelper.TestOptions) error {
+	return func(options *testhelper.TestOptions) (err error) {
+		failed := options.Testing.Failed()
+		// deferred, so that it also runs when the hook stops the test with require
+		defer func() {
+			if err == nil && (failed || !options.Testing.Failed()) {
+				return
+			}
+			outputs, outputErr := terraform.OutputAllContextE(options.Testing, context.Background(), options.TerraformOptions)
+			if outputErr != nil {
+				logger.Logf(options.Testing, "Failed to get the clusters to collect a must-gather of: %v", outputErr)
+				return
+			}
+			var clusters []string
+			for _, output := range clusterOutputs {
+				if cluster, ok := outputs[output].(string); ok {
+					clusters = append(clusters, cluster)
+				}
+			}
+			collectMustGather(options.Testing, options.Region, clusters...)
+		}()
+		return hook(options)
+	}
+}
+
+// mustGatherOnFailureSchematics is mustGatherOnFailure for the post-apply hooks of Schematics tests
+func mustGatherOnFailureSchematics(hook func(*testschematic.TestSchematicOptions) error, clusterOutputs ...string) func(*testschematic.TestSchematicOptions) error {
+	return func(options *testschematic.TestSchematicOptions) (err error) {
+		failed := options.Testing.Failed()
+		defer func() {
+			if err == nil && (failed || !options.Testing.Failed()) {
+				return
+			}
+			var clusters []string
+			for _, output := range clusterOutputs {
+				if value, ok := options.LastTestTerraformOutputs[output].(map[string]interface{}); ok {
+					if cluster, ok := value["value"].(string); ok {
+						clusters = append(clusters, cluster)
+					}
+				}
+			}
+			collectMustGather(options.Testing, options.Region, clusters...)
+		}()
+		return hook(options)
+	}
+}
+
+// collectMustGather writes the state of the clusters to a tarball named after the test in the must-gather directory,
+// for diagnosing a failed check after the clusters are destroyed. It only logs what goes wrong, as the test has already
+// failed.
+func collectMustGather(t *testing.T, region string, clusters ...string) {
+	if len(clusters) == 0 {
+		logger.Log(t, "No cluster to collect a must-gather of")
+		return
+	}
+	dir := mustGatherLocation
+	if override := os.Getenv("MUST_GATHER_DIR"); override != "" {
+		dir = override
+	}
+	file := filepath.Join(dir, strings.ReplaceAll(t.Name(), "/", "_")+".tar.gz")
+
+	authenticator, err := ibmcloud.NewIamAuthenticator(os.Getenv("TF_VAR_ibmcloud_api_key"))
+	if err != nil {
+		logger.Logf(t, "Failed to collect the must-gather: %v", err)
+		return
+	}
+	containers := ibmcloud.NewContainersClient(authenticator)
+	ctx, cancel := context.WithTimeout(deadline.New(t, destroyBudget).Context(), mustGatherTimeout)
+	defer cancel()
+
+	var targets []mustgather.Cluster
+	for _, name := range clusters {
+		target := mustgather.Cluster{Name: name}
+		if _, kubectl, err := clusterKubectl(t, ctx, containers, name, region); err != nil {
+			logger.Logf(t, "Collecting the must-gather of cluster %s without kubectl: %v", name, err)
+		} else {
+			target.Kubectl = kubectl
+		}
+		targets = append(targets, target)
+	}
+
+	gatherer := &mustgather.Gatherer{
+		Clusters:   containers,
+		Namespaces: append(slices.Clone(mustgather.LogNamespaces), kubeAuditNamespace),
+		TailLines:  mustgather.DefaultTailLines,
+	}
+	if err := gatherer.Write(ctx, file, targets...); err != nil {
+		logger.Logf(t, "Failed to write the must-gather: %v", err)
+		return
+	}
+	logger.Logf(t, "Wrote the must-gather of %s to %s", strings.Join(clusters, ", "), file)
+}
+
 func setupQuickstartOptions(t *testing.T, prefix string) *testschematic.TestSchematicOptions {
-	apiKey := validateEnvVariable(t, "TF_VAR_ibmcloud_api_key")
-	region, err := testhelper.GetBestVpcRegion(apiKey, "../common-dev-assets/common-go-assets/cloudinfo-region-vpc-gen2-prefs.yaml", "eu-de")
-	require.NoError(t, err, "Failed to get best VPC region")
+	region := leaseRegion(t, 1)
 	options := testschematic.TestSchematicOptionsDefault(&testschematic.TestSchematicOptions{
 		Testing:       t,
 		Prefix:        prefix,
@@ -151,36 +611,297 @@ func setupQuickstartOptions(t *testing.T, prefix string) *testschematic.TestSche
 		{Name: "size", Value: "mini", DataType: "string"},
 		{Name: "ocp_entitlement", Value: "cloud_pak", DataType: "string"},
 	}
+	trackSchematicsWorkspace(t, options)
 	return options
 }
 
-func cleanupTerraform(t *testing.T, options *terraform.Options, prefix string) {
+// trackedResource is provisioned by a test. It is registered with cleanupRegistry and, unless it cannot be cleaned up
+// by cmd/resume-cleanup, recorded in runJournal until it is destroyed or released.
+type trackedResource struct {
+	*cleanup.Entry
+	// id is the ID of the resource in runJournal, empty when it is not recorded there
+	id string
+}
+
+// track records an artifact in runJournal and registers it with cleanupRegistry. It must be called before the artifact
+// is provisioned, so that a partial provisioning is cleaned up too. The resources that it depends on are only destroyed
+// after it.
+func track(t *testing.T, artifact journal.Artifact, destroy cleanup.DestroyFunc, dependsOn ...*trackedResource) (*trackedResource, error) {
+	artifact.Test = t.Name()
+	var entries []*cleanup.Entry
+	for _, d := range dependsOn {
+		if d.id != "" {
+			artifact.DependsOn = append(artifact.DependsOn, d.id)
+		}
+		entries = append(entries, d.Entry)
+	}
+	artifact, err := runJournal.Create(artifact)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record %s in the run journal: %w", artifact, err)
+	}
+	entry := cleanupRegistry.Register(artifact.String(), func(ctx context.Context) error {
+		if err := destroy(ctx); err != nil {
+			return err
+		}
+		if err := runJournal.Destroy(artifact.ID); err != nil {
+			log.Printf("Failed to record the destroy of %s in the run journal: %v", artifact, err)
+		}
+		return nil
+	}, entries...)
+	return &trackedResource{Entry: entry, id: artifact.ID}, nil
+}
+
+// Release stops tracking the resource without destroying it
+func (r *trackedResource) Release() {
+	r.Entry.Release()
+	if r.id == "" {
+		return
+	}
+	if err := runJournal.Release(r.id); err != nil {
+		log.Printf("Failed to record the release of %s in the run journal: %v", r.Name, err)
+	}
+}
+
+// cleanupTerraform destroys a tracked Terraform configuration. It is not destroyed again if the destroy started on
+// interrupt already did.
+func cleanupTerraform(t *testing.T, tracked *trackedResource) {
 	if t.Failed() && strings.ToLower(os.Getenv("DO_NOT_DESTROY_ON_FAILURE")) == "true" {
-		fmt.Println("Terratest failed. Debug the test and delete resources manually.")
+		logger.Log(t, "Terratest failed. Debug the test and delete resources manually.")
+		tracked.Release()
 		return
 	}
-	logger.Log(t, "START: Destroy (existing resources)")
-	terraform.DestroyContext(t, context.Background(), options)
-	terraform.WorkspaceDeleteContext(t, context.Background(), options, prefix)
-	logger.Log(t, "END: Destroy (existing resources)")
+	logger.Log(t, fmt.Sprintf("START: Destroy (%s)", tracked.Name))
+	// the context of the test is already cancelled when this runs from t.Cleanup
+	ctx, cancel := deadline.DestroyContext(t)
+	defer cancel()
+	assert.NoError(t, tracked.Destroy(ctx), "Failed to destroy %s, delete it manually", tracked.Name)
+	logger.Log(t, fmt.Sprintf("END: Destroy (%s)", tracked.Name))
+}
+
+// trackSchematicsWorkspace tags the Schematics workspace of the test so that it can be found and deleted, with its
+// resources, if the run is interrupted or the runner dies while the test runs. Otherwise the wrapper deletes the
+// workspace itself.
+func trackSchematicsWorkspace(t *testing.T, options *testschematic.TestSchematicOptions, dependsOn ...*trackedResource) {
+	tag := "test-cleanup:" + strings.ToLower(random.UniqueID())
+	options.Tags = append(options.Tags, tag)
+	tracked, err := track(t, journal.Artifact{Kind: journal.KindSchematics, Tag: tag, Prefix: options.Prefix, Region: options.Region}, func(ctx context.Context) error {
+		return deleteSchematicsWorkspaces(ctx, tag)
+	}, dependsOn...)
+	require.NoError(t, err)
+	t.Cleanup(tracked.Release)
+}
+
+// deleteSchematicsWorkspaces deletes the workspaces with the tag in every Schematics location, after destroying their
+// resources
+func deleteSchematicsWorkspaces(ctx context.Context, tag string) error {
+	authenticator, err := ibmcloud.NewIamAuthenticator(os.Getenv("TF_VAR_ibmcloud_api_key"))
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Hour)
+	defer cancel()
+	return ibmcloud.DeleteTaggedWorkspacesInAllLocations(ctx, authenticator, tag, time.Minute)
+}
+
+// terratestRunner applies and destroys a Terraform configuration in its own workspace with terratest
+type terratestRunner struct {
+	t         terratesting.TestingT
+	options   *terraform.Options
+	workspace string
+}
+
+func (r *terratestRunner) Apply(ctx context.Context) error {
+	if _, err := terraform.WorkspaceSelectOrNewContextE(r.t, ctx, r.options, r.workspace); err != nil {
+		return err
+	}
+	if _, err := terraform.InitContextE(r.t, ctx, r.options); err != nil {
+		return err
+	}
+	return applyInterruptible(r.t, ctx, r.options)
+}
+
+// applyInterruptible runs terraform apply like terratest, but stops it with SIGINT rather than SIGKILL when ctx is done.
+// Terraform then waits for the operations in flight, records them in the state and releases the state lock, so that
+// the destroy that follows finds everything that was created. It is killed if it does not exit within
+// terraformInterruptGrace.
+func applyInterruptible(t terratesting.TestingT, ctx context.Context, options *terraform.Options) error {
+	options, args := terraform.GetCommonOptions(options, terraform.FormatArgs(options, append([]string{"apply", "-input=false", "-auto-approve"}, options.ExtraArgs.Apply...)...)...)
+	cmd := exec.CommandContext(ctx, options.TerraformBinary, args...)
+	cmd.Dir = options.TerraformDir
+	cmd.Env = os.Environ()
+	for name, value := range options.EnvVars {
+		cmd.Env = append(cmd.Env, name+"="+value)
+	}
+	cmd.Stdout = testLogWriter{t}
+	cmd.Stderr = testLogWriter{t}
+	cmd.Cancel = func() error {
+		logger.Logf(t, "Interrupting terraform apply in %s: %v", options.TerraformDir, context.Cause(ctx))
+		return cmd.Process.Signal(os.Interrupt)
+	}
+	cmd.WaitDelay = terraformInterruptGrace
+
+	logger.Logf(t, "Running command %s with args %v", options.TerraformBinary, args)
+	return cmd.Run()
+}
+
+// testLogWriter logs the output of a command to the test as it is written
+type testLogWriter struct {
+	t terratesting.TestingT
+}
+
+// fixtureT is the terratest TestingT of a fixture shared between tests. Terratest only uses it to log under the name of
+// the fixture, as the runner calls the functions returning errors, which reach the test provisioning or destroying it.
+type fixtureT struct {
+	name string
+}
+
+func (f *fixtureT) Fail() {}
+
+func (f *fixtureT) FailNow() { runtime.Goexit() }
+
+func (f *fixtureT) Fatal(args ...any) {
+	f.Error(args...)
+	runtime.Goexit()
+}
+
+func (f *fixtureT) Fatalf(format string, args ...any) {
+	f.Errorf(format, args...)
+	runtime.Goexit()
+}
+
+func (f *fixtureT) Error(args ...any) { logger.Log(f, args...) }
+
+func (f *fixtureT) Errorf(format string, args ...any) { logger.Logf(f, format, args...) }
+
+func (f *fixtureT) Name() string { return f.name }
+
+func (f *fixtureT) Helper() {}
+
+func (w testLogWriter) Write(p []byte) (int, error) {
+	logger.Log(w.t, strings.TrimSuffix(string(p), "\n"))
+	return len(p), nil
+}
+
+func (r *terratestRunner) Destroy(ctx context.Context) error {
+	if _, err := terraform.DestroyContextE(r.t, ctx, r.options); err != nil {
+		return err
+	}
+	_, err := terraform.WorkspaceDeleteContextE(r.t, ctx, r.options, r.workspace)
+	return err
+}
+
+// containersApikeys are the Kubernetes Service API keys that the running tests reset, by region and resource group. A
+// reset replaces the key that every cluster in the region and resource group uses, so the tests in them share the key,
+// and the last of them destroys it.
+var (
+	containersApikeysMu sync.Mutex
+	containersApikeys   = map[string]*containersApikey{}
+)
+
+// containersApikey is a Kubernetes Service API key that the tests reset
+type containersApikey struct {
+	tracked *trackedResource
+	users   int
+	// keep is set when a test that uses the key failed and its resources are kept for debugging
+	keep bool
+}
+
+// createContainersApikey resets the API key of the Kubernetes Service for the region and resource group, unless a
+// running test already did. The key is registered with cleanupRegistry, and is destroyed when the last test that uses
+// it ends. The reset is stopped if the run is interrupted, so that it does not delay the cleanup. Resources that the
+// key is needed to destroy, like the clusters in the region and resource group, can depend on the returned key.
+func createContainersApikey(t *testing.T, region string, rg string) *trackedResource {
+	name := region + "/" + rg
+	containersApikeysMu.Lock()
+	defer containersApikeysMu.Unlock()
+	apikey, ok := containersApikeys[name]
+	if !ok {
+		tracked, err := resetContainersApikey(t, region, rg)
+		require.NoError(t, err, "Failed to reset the Kubernetes Service API key of %s", name)
+		apikey = &containersApikey{tracked: tracked}
+		containersApikeys[name] = apikey
+	}
+	apikey.users++
+	t.Cleanup(func() { releaseContainersApikey(t, name) })
+	return apikey.tracked
 }
 
-func createContainersApikey(t *testing.T, region string, rg string) {
+// resetContainersApikey runs the reset script, and registers the key that it created with cleanupRegistry. The key
+// is told apart from the keys of the other regions and resource groups, which have the same name, by comparing the
+// keys before and after the reset.
+func resetContainersApikey(t *testing.T, region string, rg string) (*trackedResource, error) {
+	apiKey := validateEnvVariable(t, "TF_VAR_ibmcloud_api_key")
+	authenticator, err := ibmcloud.NewIamAuthenticator(apiKey)
+	if err != nil {
+		return nil, err
+	}
+	iam, err := ibmcloud.NewIamIdentityClient(authenticator)
+	if err != nil {
+		return nil, err
+	}
+	ctx := cleanupRegistry.Context()
+	before, err := iam.ListOwnAPIKeys(ctx, apiKey, ibmcloud.ContainersApikeyName)
+	if err != nil {
+		return nil, err
+	}
 
-	err := os.Setenv("IBMCLOUD_API_KEY", validateEnvVariable(t, "TF_VAR_ibmcloud_api_key"))
-	require.NoError(t, err, "Failed to set IBMCLOUD_API_KEY environment variable")
+	if err := os.Setenv("IBMCLOUD_API_KEY", apiKey); err != nil {
+		return nil, fmt.Errorf("failed to set IBMCLOUD_API_KEY environment variable: %w", err)
+	}
 	scriptPath := "../common-dev-assets/scripts/iks-api-key-reset/reset_iks_api_key.sh"
-	cmd := exec.Command("bash", scriptPath, region, rg)
+	cmd := exec.CommandContext(ctx, "bash", scriptPath, region, rg)
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
-
-	// Execute the command
+	// A failure fails the test rather than exiting, so that the cleanup of the other tests runs.
 	if err := cmd.Run(); err != nil {
-		log.Fatalf("Failed to execute script: %v\nStderr: %s", err, stderr.String())
+		return nil, fmt.Errorf("failed to execute script: %w\nStderr: %s", err, stderr.String())
 	}
 	// Print script output
 	fmt.Println(stdout.String())
+
+	after, err := iam.ListOwnAPIKeys(ctx, apiKey, ibmcloud.ContainersApikeyName)
+	if err != nil {
+		return nil, err
+	}
+	var created []string
+	for _, key := range after {
+		if !slices.ContainsFunc(before, func(k ibmcloud.APIKey) bool { return k.ID == key.ID }) {
+			created = append(created, key.ID)
+		}
+	}
+	if len(created) != 1 {
+		return nil, fmt.Errorf("found %d new %s API keys after the reset, expected one: %v", len(created), ibmcloud.ContainersApikeyName, created)
+	}
+	id := created[0]
+	entry := cleanupRegistry.Register(fmt.Sprintf("%s API key %s region=%s resource_group=%s", ibmcloud.ContainersApikeyName, id, region, rg), func(ctx context.Context) error {
+		// a later reset, e.g. by another run, deletes the key it replaces
+		if err := iam.DeleteAPIKey(ctx, id); err != nil && !errors.Is(err, ibmcloud.ErrNotFound) {
+			return err
+		}
+		return nil
+	})
+	return &trackedResource{Entry: entry}, nil
+}
+
+// releaseContainersApikey destroys the Kubernetes Service API key of the region and resource group named name once no
+// running test uses it anymore
+func releaseContainersApikey(t *testing.T, name string) {
+	containersApikeysMu.Lock()
+	defer containersApikeysMu.Unlock()
+	apikey := containersApikeys[name]
+	apikey.keep = apikey.keep || (t.Failed() && strings.ToLower(os.Getenv("DO_NOT_DESTROY_ON_FAILURE")) == "true")
+	if apikey.users--; apikey.users > 0 {
+		return
+	}
+	delete(containersApikeys, name)
+	if apikey.keep {
+		apikey.tracked.Release()
+		return
+	}
+	ctx, cancel := deadline.DestroyContext(t)
+	defer cancel()
+	assert.NoError(t, apikey.tracked.Destroy(ctx), "Failed to destroy %s, delete it manually", apikey.tracked.Name)
 }
 
 func getClusterIngress(options *testhelper.TestOptions) error {
@@ -196,11 +917,17 @@ func getClusterIngress(options *testhelper.TestOptions) error {
 	_, ValidationErr := testhelper.ValidateTerraformOutputs(outputs, expectedOutputs...)
 
 	// Proceed with the cluster ingress health check if "cluster_name" is valid
-	if assert.NoErrorf(options.Testing, ValidationErr, "Some outputs not found or nil: %s", ValidationErr) {
-		healthy := options.CloudInfoService.CheckClusterIngressHealthyDefaultTimeout(outputs["cluster_name"].(string), log.Println)
-		assert.True(options.Testing, healthy, "Cluster ingress failed to become healthy")
+	if !assert.NoErrorf(options.Testing, ValidationErr, "Some outputs not found or nil: %s", ValidationErr) {
+		return nil
 	}
-	return nil
+	clusterName := outputs["cluster_name"].(string)
+	err := checkClusterIngress(options.Testing, clusterName, options.Region)
+	if !assert.NoError(options.Testing, err, "Cluster ingress failed to become healthy") {
+		return err
+	}
+	err = checkSampleWorkload(options.Testing, clusterName, options.Region)
+	assert.NoError(options.Testing, err, "Sample workload is not reachable through the cluster ingress")
+	return err
 }
 
 func getMultiClusterIngress(options *testhelper.TestOptions) error {
@@ -216,12 +943,19 @@ func getMultiClusterIngress(options *testhelper.TestOptions) error {
 	_, ValidationErr := testhelper.ValidateTerraformOutputs(outputs, expectedOutputs...)
 
 	// Proceed with the cluster ingress health check if "cluster_name_1" and "cluster_name_2" are valid
-	if assert.NoErrorf(options.Testing, ValidationErr, "Some outputs not found or nil: %s", ValidationErr) {
-		healthy_cluster1 := options.CloudInfoService.CheckClusterIngressHealthyDefaultTimeout(outputs["cluster_name_1"].(string), log.Println)
-		healthy_cluster2 := options.CloudInfoService.CheckClusterIngressHealthyDefaultTimeout(outputs["cluster_name_2"].(string), log.Println)
-		assert.True(options.Testing, (healthy_cluster1 && healthy_cluster2), "Cluster ingress failed to become healthy")
+	if !assert.NoErrorf(options.Testing, ValidationErr, "Some outputs not found or nil: %s", ValidationErr) {
+		return nil
 	}
-	return nil
+	var errs []error
+	for _, output := range expectedOutputs {
+		err := checkClusterIngress(options.Testing, outputs[output].(string), options.Region)
+		if assert.NoError(options.Testing, err, "Cluster ingress of %s failed to become healthy", output) {
+			err = checkSampleWorkload(options.Testing, outputs[output].(string), options.Region)
+			assert.NoError(options.Testing, err, "Sample workload is not reachable through the cluster ingress of %s", output)
+		}
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
 }
 
 func getClusterIngressSchematics(options *testschematic.TestSchematicOptions) error {
@@ -233,20 +967,392 @@ func getClusterIngressSchematics(options *testschematic.TestSchematicOptions) er
 	_, ValidationErr := testhelper.ValidateTerraformOutputs(outputs, expectedOutputs...)
 
 	// Proceed with the cluster ingress health check if "cluster_id" is valid
-	if assert.NoErrorf(options.Testing, ValidationErr, "Some outputs not found or nil: %s", ValidationErr) {
-		clusterId := outputs["cluster_name"].(map[string]interface{})["value"]
-		healthy := options.CloudInfoService.CheckClusterIngressHealthyDefaultTimeout(clusterId.(string), log.Println)
-		assert.True(options.Testing, healthy, "Cluster ingress failed to become healthy")
+	if !assert.NoErrorf(options.Testing, ValidationErr, "Some outputs not found or nil: %s", ValidationErr) {
+		return nil
+	}
+	clusterName := outputs["cluster_name"].(map[string]interface{})["value"].(string)
+	err := checkClusterIngress(options.Testing, clusterName, options.Region)
+	if !assert.NoError(options.Testing, err, "Cluster ingress failed to become healthy") {
+		return err
+	}
+	err = checkSampleWorkload(options.Testing, clusterName, options.Region)
+	assert.NoError(options.Testing, err, "Sample workload is not reachable through the cluster ingress")
+	return err
+}
+
+// checkClusterIngress waits for every component of the default ingress of the cluster to become healthy. It logs the
+// state of the components, and returns which of them are not healthy when it gives up.
+func checkClusterIngress(t *testing.T, cluster string, region string) error {
+	authenticator, err := ibmcloud.NewIamAuthenticator(validateEnvVariable(t, "TF_VAR_ibmcloud_api_key"))
+	if err != nil {
+		return err
+	}
+	containers := ibmcloud.NewContainersClient(authenticator)
+	ctx, cancel := context.WithTimeout(deadline.New(t, destroyBudget).Context(), ingressHealthyTimeout)
+	defer cancel()
+
+	info, kubectl, err := clusterKubectl(t, ctx, containers, cluster, region)
+	if err != nil {
+		return err
+	}
+	loadBalancers, err := ibmcloud.NewVpcClient(region, authenticator)
+	if err != nil {
+		return err
+	}
+
+	var status *verify.IngressStatus
+	err = verify.Eventually(ctx, time.Minute, func(ctx context.Context) error {
+		var err error
+		if status, err = verify.IngressDiagnostics(ctx, containers, kubectl, loadBalancers, info.ID); err != nil {
+			return err
+		}
+		return status.Err(time.Now())
+	})
+	if status != nil {
+		logger.Logf(t, "Ingress of cluster %s:\n%s", cluster, status)
+	}
+	return err
+}
+
+// checkClusterEncryption verifies that the cluster secrets and the boot volumes of all workers are encrypted with the
+// expected customer managed keys
+func checkClusterEncryption(t *testing.T, cluster string, region string, expected verify.EncryptionExpectation) error {
+	authenticator, err := ibmcloud.NewIamAuthenticator(validateEnvVariable(t, "TF_VAR_ibmcloud_api_key"))
+	if !assert.NoError(t, err, "Failed to create IAM authenticator") {
+		return err
+	}
+	volumes, err := ibmcloud.NewVpcClient(region, authenticator)
+	if !assert.NoError(t, err, "Failed to create VPC client") {
+		return err
+	}
+
+	err = verify.Encryption(deadline.New(t, destroyBudget).Context(), ibmcloud.NewContainersClient(authenticator), volumes, cluster, expected)
+	assert.NoError(t, err, "Cluster encryption does not match the requested KMS configuration")
+	return err
+}
+
+// getClusterIngressAndEncryptionSchematics runs the ingress health check, then verifies that the cluster and the worker
+// boot volumes are encrypted with keys from the existing KMS instance passed to the fully-configurable solution
+func getClusterIngressAndEncryptionSchematics(options *testschematic.TestSchematicOptions) error {
+	if err := getClusterIngressSchematics(options); err != nil {
+		return err
+	}
+
+	outputs := options.LastTestTerraformOutputs
+	_, ValidationErr := testhelper.ValidateTerraformOutputs(outputs, "cluster_name")
+	if !assert.NoErrorf(options.Testing, ValidationErr, "Some outputs not found or nil: %s", ValidationErr) {
+		return ValidationErr
+	}
+
+	// keys are created by the solution in the existing KMS instance, so only the instance and account are known upfront
+	kmsInstance, err := verify.KmsKeyFromCRN(permanentResources.HpcsSouthCRN)
+	if !assert.NoError(options.Testing, err, "Failed to parse hpcs_south_crn") {
+		return err
+	}
+	clusterName := outputs["cluster_name"].(map[string]interface{})["value"].(string)
+
+	return checkClusterEncryption(options.Testing, clusterName, options.Region, verify.EncryptionExpectation{
+		Cluster:    &kmsInstance,
+		BootVolume: &kmsInstance,
+	})
+}
+
+// checkSampleWorkload deploys a sample HTTP workload to the cluster, and requests it through a route on the default
+// ingress domain to prove that applications can run and be reached. The workload is deleted afterwards.
+func checkSampleWorkload(t *testing.T, cluster string, region string) error {
+	authenticator, err := ibmcloud.NewIamAuthenticator(validateEnvVariable(t, "TF_VAR_ibmcloud_api_key"))
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(deadline.New(t, destroyBudget).Context(), sampleWorkloadTimeout)
+	defer cancel()
+	_, kubectl, err := clusterKubectl(t, ctx, ibmcloud.NewContainersClient(authenticator), cluster, region)
+	if err != nil {
+		return err
+	}
+	config, err := clientcmd.BuildConfigFromFlags("", kubectl.Kubeconfig)
+	if err != nil {
+		return fmt.Errorf("failed to load the kubeconfig of cluster %s: %w", cluster, err)
 	}
+	var clients verify.WorkloadClients
+	if clients.Kubernetes, err = kubernetes.NewForConfig(config); err != nil {
+		return err
+	}
+	if clients.Dynamic, err = dynamic.NewForConfig(config); err != nil {
+		return err
+	}
+
+	id := strings.ToLower(random.UniqueID())
+	workload := verify.SampleWorkload{
+		Namespace: "smoke-test-" + id,
+		Image:     verify.SampleWorkloadImage,
+		Marker:    "smoke-test-" + id,
+	}
+	url, err := verify.WorkloadSmoke(ctx, clients, &http.Client{Timeout: 30 * time.Second}, workload, 15*time.Second)
+	if err == nil {
+		logger.Logf(t, "Sample workload of cluster %s answered at %s", cluster, url)
+	}
+	return err
+}
+
+// expectedNodeFleet returns what the nodes of a cluster created with the given inputs should run. pools maps the name of
+// every worker pool to its operating_system, and requestedPlugin is the network_plugin input of the module.
+func expectedNodeFleet(t *testing.T, version string, pools map[string]string, requestedPlugin string) verify.FleetExpectation {
+	plugin, err := verify.ExpectedNetworkPlugin(version, pools["default"], requestedPlugin)
+	require.NoError(t, err, "Failed to determine the expected network plugin")
+	return verify.FleetExpectation{Version: version, OperatingSystems: pools, NetworkPlugin: plugin}
+}
+
+// checkNodeFleet waits for every node of the cluster to run the requested OpenShift version and the operating system of
+// its worker pool, and for the cluster to run the expected network plugin. It returns the mismatches per node when it
+// gives up.
+func checkNodeFleet(t *testing.T, cluster string, region string, expected verify.FleetExpectation) error {
+	authenticator, err := ibmcloud.NewIamAuthenticator(validateEnvVariable(t, "TF_VAR_ibmcloud_api_key"))
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(deadline.New(t, destroyBudget).Context(), nodeFleetTimeout)
+	defer cancel()
+	_, kubectl, err := clusterKubectl(t, ctx, ibmcloud.NewContainersClient(authenticator), cluster, region)
+	if err != nil {
+		return err
+	}
+
+	return verify.Eventually(ctx, time.Minute, func(ctx context.Context) error {
+		return verify.NodeFleet(ctx, kubectl, expected)
+	})
+}
+
+// withNodeFleetCheck wraps a post-apply hook to check the nodes of the cluster_name output against expected after it
+func withNodeFleetCheck(hook func(*testhelper.TestOptions) error, expected verify.FleetExpectation) func(*testhelper.TestOptions) error {
+	return func(options *testhelper.TestOptions) error {
+		if err := hook(options); err != nil {
+			return err
+		}
+		clusterName, err := terraform.OutputContextE(options.Testing, context.Background(), options.TerraformOptions, "cluster_name")
+		if !assert.NoError(options.Testing, err, "error getting the cluster_name output") {
+			return nil
+		}
+		err = checkNodeFleet(options.Testing, clusterName, options.Region, expected)
+		assert.NoError(options.Testing, err, "Nodes do not match the requested version, operating systems and network plugin")
+		return err
+	}
+}
+
+// checkClusterNetwork runs a job requesting a public URL to check that outbound traffic protection allows or blocks it
+// as expected, and checks that all pods and services have addresses in the expected subnets. The job is deleted
+// afterwards.
+func checkClusterNetwork(t *testing.T, cluster string, region string, expected verify.NetworkExpectation) error {
+	authenticator, err := ibmcloud.NewIamAuthenticator(validateEnvVariable(t, "TF_VAR_ibmcloud_api_key"))
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(deadline.New(t, destroyBudget).Context(), networkProbeTimeout)
+	defer cancel()
+	_, kubectl, err := clusterKubectl(t, ctx, ibmcloud.NewContainersClient(authenticator), cluster, region)
+	if err != nil {
+		return err
+	}
+	image, err := verify.ToolsImage(ctx, kubectl)
+	if err != nil {
+		return err
+	}
+
+	probe := verify.EgressProbe{
+		Namespace: "egress-probe-" + strings.ToLower(random.UniqueID()),
+		Image:     image,
+		URL:       verify.EgressProbeURL,
+	}
+	report, err := verify.NetworkProbe(ctx, kubectl, probe, expected, 15*time.Second)
+	if err == nil {
+		logger.Logf(t, "Network of cluster %s: %q, %d pod and %d service addresses checked", cluster, report.Egress, report.Pods, report.Services)
+	}
+	return err
+}
+
+// withNetworkCheck wraps a post-apply hook to check the network of the cluster_name output against expected after it
+func withNetworkCheck(hook func(*testhelper.TestOptions) error, expected verify.NetworkExpectation) func(*testhelper.TestOptions) error {
+	return func(options *testhelper.TestOptions) error {
+		if err := hook(options); err != nil {
+			return err
+		}
+		clusterName, err := terraform.OutputContextE(options.Testing, context.Background(), options.TerraformOptions, "cluster_name")
+		if !assert.NoError(options.Testing, err, "error getting the cluster_name output") {
+			return nil
+		}
+		err = checkClusterNetwork(options.Testing, clusterName, options.Region, expected)
+		assert.NoError(options.Testing, err, "Cluster network does not match the outbound traffic and subnet inputs")
+		return err
+	}
+}
+
+// checkRegistryStorage waits for the internal registry to be available on the expected Object Storage instance, and
+// pushes an image to it to prove that the storage works. The build of the image is deleted afterwards.
+func checkRegistryStorage(t *testing.T, cluster string, region string, expected verify.RegistryExpectation) error {
+	authenticator, err := ibmcloud.NewIamAuthenticator(validateEnvVariable(t, "TF_VAR_ibmcloud_api_key"))
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(deadline.New(t, destroyBudget).Context(), registryStorageTimeout)
+	defer cancel()
+	_, kubectl, err := clusterKubectl(t, ctx, ibmcloud.NewContainersClient(authenticator), cluster, region)
+	if err != nil {
+		return err
+	}
+	instances, err := ibmcloud.NewResourceControllerClient(authenticator)
+	if err != nil {
+		return err
+	}
+
+	var storage verify.RegistryStorage
+	err = verify.Eventually(ctx, time.Minute, func(ctx context.Context) error {
+		var err error
+		storage, err = verify.RegistryStorageCheck(ctx, kubectl, instances, expected)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	logger.Logf(t, "Registry of cluster %s stores images in bucket %s of %s", cluster, storage.Bucket, storage.ServiceInstanceCRN)
+
+	id := strings.ToLower(random.UniqueID())
+	image, err := verify.RegistryPush(ctx, kubectl, verify.RegistryProbe{Namespace: "registry-probe-" + id, Marker: id}, 15*time.Second)
+	if err == nil {
+		logger.Logf(t, "Pushed %s to the registry of cluster %s", image, cluster)
+	}
+	return err
+}
+
+// withRegistryCheck wraps a post-apply hook to check the registry storage of the cluster_name output after it. The
+// registry must use existingCOS when it is set, or the instance the module created otherwise.
+func withRegistryCheck(hook func(*testhelper.TestOptions) error, existingCOS string) func(*testhelper.TestOptions) error {
+	return func(options *testhelper.TestOptions) error {
+		if err := hook(options); err != nil {
+			return err
+		}
+		clusterName, err := terraform.OutputContextE(options.Testing, context.Background(), options.TerraformOptions, "cluster_name")
+		if !assert.NoError(options.Testing, err, "error getting the cluster_name output") {
+			return nil
+		}
+		err = checkRegistryStorage(options.Testing, clusterName, options.Region, verify.RegistryExpectation{
+			ExistingCOSInstance: existingCOS,
+			NewCOSInstanceName:  verify.DefaultCOSInstanceName(clusterName),
+		})
+		assert.NoError(options.Testing, err, "Internal registry does not store images in the expected Object Storage instance")
+		return err
+	}
+}
+
+// schematicsVar returns the value of the named input of the Schematics test, or fallback when the test does not set it
+func schematicsVar(options *testschematic.TestSchematicOptions, name string, fallback string) string {
+	for _, v := range options.TerraformVars {
+		if v.Name == name {
+			return fmt.Sprint(v.Value)
+		}
+	}
+	return fallback
+}
+
+// checkSecretsManagerIngressSchematics verifies that the existing Secrets Manager instance is registered as the default
+// ingress instance and that the default ingress certificate is stored in the secret group named after the cluster ID
+func checkSecretsManagerIngressSchematics(options *testschematic.TestSchematicOptions) error {
+	outputs := options.LastTestTerraformOutputs
+	_, ValidationErr := testhelper.ValidateTerraformOutputs(outputs, "cluster_id")
+	if !assert.NoErrorf(options.Testing, ValidationErr, "Some outputs not found or nil: %s", ValidationErr) {
+		return ValidationErr
+	}
+	clusterID := outputs["cluster_id"].(map[string]interface{})["value"].(string)
+
+	secretsManagerCRN := permanentResources.SecretsManagerCRN
+	secretsManager, err := ibmcloud.ParseCRN(secretsManagerCRN)
+	if !assert.NoError(options.Testing, err, "Failed to parse secretsManagerCRN") {
+		return err
+	}
+
+	authenticator, err := ibmcloud.NewIamAuthenticator(validateEnvVariable(options.Testing, "TF_VAR_ibmcloud_api_key"))
+	if !assert.NoError(options.Testing, err, "Failed to create IAM authenticator") {
+		return err
+	}
+	containers := ibmcloud.NewContainersClient(authenticator)
+	secrets := ibmcloud.NewSecretsManagerClient(secretsManager.ServiceInstance, secretsManager.Location, "public", authenticator)
+
+	// the default certificate is copied into Secrets Manager asynchronously after the instance is registered
+	ctx, cancel := context.WithTimeout(deadline.New(options.Testing, destroyBudget).Context(), 15*time.Minute)
+	defer cancel()
+	err = verify.Eventually(ctx, time.Minute, func(ctx context.Context) error {
+		return verify.SecretsManagerIngress(ctx, containers, secrets, clusterID, secretsManagerCRN)
+	})
+	assert.NoError(options.Testing, err, "Secrets Manager ingress integration is not configured as expected")
+	return err
+}
+
+// checkKubeAuditDelivery proves that API server audit events reach the kube-audit webhook listener by making a marked
+// API request and waiting for its event in the listener logs
+func checkKubeAuditDelivery(t *testing.T, clusterID string, region string, audit verify.KubeAudit) {
+	ctx, cancel := context.WithTimeout(deadline.New(t, destroyBudget).Context(), 10*time.Minute)
+	defer cancel()
+	kubeconfig, err := getClusterKubeconfigE(t, ctx, clusterID, region)
+	require.NoError(t, err, "Failed to download the cluster kubeconfig")
+	kubectl := kube.NewKubectl(kubeconfig)
+	marker := fmt.Sprintf("kube-audit-probe-%s", strings.ToLower(random.UniqueID()))
+
+	latency, err := verify.KubeAuditDelivery(ctx, kubectl, audit, marker, 15*time.Second)
+	if assert.NoError(t, err, "API server audit event did not reach the kube-audit webhook listener") {
+		logger.Log(t, fmt.Sprintf("Audit event for %s reached %s/%s after %s", marker, audit.Namespace, audit.Deployment, latency.Round(time.Second)))
+	}
+}
+
+// getFullyConfigurableChecksSchematics runs all post-apply checks of the fully-configurable solution tests
+func getFullyConfigurableChecksSchematics(options *testschematic.TestSchematicOptions) error {
+	if err := getClusterIngressAndEncryptionSchematics(options); err != nil {
+		return err
+	}
+	if err := checkSecretsManagerIngressSchematics(options); err != nil {
+		return err
+	}
+
+	// the defaults of the fully-configurable solution apply to the inputs the test does not set
+	clusterName := options.LastTestTerraformOutputs["cluster_name"].(map[string]interface{})["value"].(string)
+	expected := expectedNodeFleet(options.Testing, schematicsVar(options, "openshift_version", ""),
+		map[string]string{"default": schematicsVar(options, "default_worker_pool_operating_system", verify.OperatingSystemRHCOS)},
+		schematicsVar(options, "network_plugin", verify.DefaultNetworkPlugin))
+	if err := checkNodeFleet(options.Testing, clusterName, options.Region, expected); !assert.NoError(options.Testing, err, "Nodes do not match the requested version, operating systems and network plugin") {
+		return err
+	}
+	err := checkClusterNetwork(options.Testing, clusterName, options.Region, verify.NetworkExpectation{
+		OutboundTraffic: schematicsVar(options, "allow_outbound_traffic", "true") == "true",
+		PodSubnet:       schematicsVar(options, "pod_subnet_cidr", ""),
+		ServiceSubnet:   schematicsVar(options, "service_subnet_cidr", ""),
+	})
+	if !assert.NoError(options.Testing, err, "Cluster network does not match the outbound traffic and subnet inputs") {
+		return err
+	}
+	// the solution always passes an existing Object Storage instance to the module
+	err = checkRegistryStorage(options.Testing, clusterName, options.Region, verify.RegistryExpectation{
+		ExistingCOSInstance: schematicsVar(options, "existing_cos_instance_crn", ""),
+		NewCOSInstanceName:  verify.DefaultCOSInstanceName(clusterName),
+	})
+	if !assert.NoError(options.Testing, err, "Internal registry does not store images in the existing Object Storage instance") {
+		return err
+	}
+
+	// kube-audit is enabled by default in the fully-configurable solution with the default audit policy
+	clusterID := options.LastTestTerraformOutputs["cluster_id"].(map[string]interface{})["value"].(string)
+	checkKubeAuditDelivery(options.Testing, clusterID, options.Region, verify.KubeAudit{
+		Namespace:  kubeAuditNamespace,
+		Deployment: kubeAuditDeployment,
+		Policy:     "default",
+	})
 	return nil
 }
 
 func TestRunFullyConfigurableInSchematics(t *testing.T) {
 	t.Parallel()
+	acquireResources(t)
 
-	// Provision resources first
-	prefix := fmt.Sprintf("ocp-fc-%s", strings.ToLower(random.UniqueID()))
-	existingTerraformOptions := setupTerraform(t, prefix, "./existing-resources")
+	// Borrow the existing resources of the region, they are provisioned by the first test using them
+	region := leaseRegion(t, 1, regions.FeatureHPCS)
+	existing := borrowExistingResources(t, region)
 
 	options := testschematic.TestSchematicOptionsDefault(&testschematic.TestSchematicOptions{
 		Testing:               t,
@@ -256,11 +1362,11 @@ func TestRunFullyConfigurableInSchematics(t *testing.T) {
 		Tags:                  []string{"test-schematic"},
 		DeleteWorkspaceOnFail: false,
 		TerraformVersion:      terraformVersion,
-		Region:                terraform.OutputContext(t, context.Background(), existingTerraformOptions, "region"),
+		Region:                region,
 		CloudInfoService:      sharedInfoSvc,
 	})
 
-	rg := terraform.OutputContext(t, context.Background(), existingTerraformOptions, "resource_group_name")
+	rg := existing.resourceGroupName
 
 	options.TerraformVars = []testschematic.TestSchematicTerraformVar{
 		{Name: "ibmcloud_api_key", Value: options.RequiredEnvironmentVars["TF_VAR_ibmcloud_api_key"], DataType: "string", Secure: true},
@@ -269,29 +1375,29 @@ func TestRunFullyConfigurableInSchematics(t *testing.T) {
 		{Name: "openshift_version", Value: ocpVersion1, DataType: "string"},
 		{Name: "ocp_entitlement", Value: "cloud_pak", DataType: "string"},
 		{Name: "existing_resource_group_name", Value: rg, DataType: "string"},
-		{Name: "existing_cos_instance_crn", Value: terraform.OutputContext(t, context.Background(), existingTerraformOptions, "cos_instance_id"), DataType: "string"},
-		{Name: "existing_vpc_crn", Value: terraform.OutputContext(t, context.Background(), existingTerraformOptions, "vpc_crn"), DataType: "string"},
+		{Name: "existing_cos_instance_crn", Value: existing.cosInstanceID, DataType: "string"},
+		{Name: "existing_vpc_crn", Value: existing.vpcCRN, DataType: "string"},
 		{Name: "kms_encryption_enabled_cluster", Value: "true", DataType: "bool"},
-		{Name: "existing_kms_instance_crn", Value: permanentResources["hpcs_south_crn"], DataType: "string"},
+		{Name: "existing_kms_instance_crn", Value: permanentResources.HpcsSouthCRN, DataType: "string"},
 		{Name: "kms_encryption_enabled_boot_volume", Value: "true", DataType: "bool"},
 		{Name: "enable_secrets_manager_integration", Value: "true", DataType: "bool"},
-		{Name: "existing_secrets_manager_instance_crn", Value: permanentResources["secretsManagerCRN"], DataType: "string"},
+		{Name: "existing_secrets_manager_instance_crn", Value: permanentResources.SecretsManagerCRN, DataType: "string"},
 		{Name: "network_plugin", Value: "OVNKubernetes", DataType: "string"},
 	}
-	options.PostApplyHook = getClusterIngressSchematics
-
+	options.PostApplyHook = mustGatherOnFailureSchematics(getFullyConfigurableChecksSchematics, "cluster_name")
 	// Temp workaround for https://github.com/terraform-ibm-modules/terraform-ibm-base-ocp-vpc?tab=readme-ov-file#the-specified-api-key-could-not-be-found
-	createContainersApikey(t, options.Region, rg)
+	apikey := createContainersApikey(t, options.Region, rg)
+	trackSchematicsWorkspace(t, options, existing.tracked, apikey)
 
 	require.NoError(t, options.RunSchematicTest(), "This should not have errored")
-	cleanupTerraform(t, existingTerraformOptions, prefix)
 }
 
 func TestRunUpgradeFullyConfigurable(t *testing.T) {
 	t.Parallel()
-	// Provision existing resources first
-	prefix := fmt.Sprintf("ocp-existing-%s", strings.ToLower(random.UniqueID()))
-	existingTerraformOptions := setupTerraform(t, prefix, "./existing-resources")
+	acquireResources(t)
+	// Borrow the existing resources of the region, they are provisioned by the first test using them
+	region := leaseRegion(t, 1, regions.FeatureHPCS)
+	existing := borrowExistingResources(t, region)
 	options := testschematic.TestSchematicOptionsDefault(&testschematic.TestSchematicOptions{
 		Testing:                    t,
 		Prefix:                     "fc-upg",
@@ -301,10 +1407,10 @@ func TestRunUpgradeFullyConfigurable(t *testing.T) {
 		DeleteWorkspaceOnFail:      false,
 		TerraformVersion:           terraformVersion,
 		CheckApplyResultForUpgrade: true,
-		Region:                     terraform.OutputContext(t, context.Background(), existingTerraformOptions, "region"),
+		Region:                     region,
 		CloudInfoService:           sharedInfoSvc,
 	})
-	rg := terraform.OutputContext(t, context.Background(), existingTerraformOptions, "resource_group_name")
+	rg := existing.resourceGroupName
 	options.IgnoreUpdates = testhelper.Exemptions{List: []string{"module.kube_audit[0].helm_release.kube_audit"}}
 	options.IgnoreDestroys = testhelper.Exemptions{List: []string{"module.kube_audit[0].terraform_data.install_required_binaries[0]"}}
 	options.TerraformVars = []testschematic.TestSchematicTerraformVar{
@@ -313,20 +1419,20 @@ func TestRunUpgradeFullyConfigurable(t *testing.T) {
 		{Name: "prefix", Value: options.Prefix, DataType: "string"},
 		{Name: "cluster_name", Value: "cluster", DataType: "string"},
 		{Name: "openshift_version", Value: ocpVersion1, DataType: "string"},
-		{Name: "existing_resource_group_name", Value: terraform.OutputContext(t, context.Background(), existingTerraformOptions, "resource_group_name"), DataType: "string"},
-		{Name: "existing_cos_instance_crn", Value: terraform.OutputContext(t, context.Background(), existingTerraformOptions, "cos_instance_id"), DataType: "string"},
-		{Name: "existing_vpc_crn", Value: terraform.OutputContext(t, context.Background(), existingTerraformOptions, "vpc_crn"), DataType: "string"},
+		{Name: "existing_resource_group_name", Value: rg, DataType: "string"},
+		{Name: "existing_cos_instance_crn", Value: existing.cosInstanceID, DataType: "string"},
+		{Name: "existing_vpc_crn", Value: existing.vpcCRN, DataType: "string"},
 		{Name: "enable_secrets_manager_integration", Value: "true", DataType: "bool"},
-		{Name: "existing_secrets_manager_instance_crn", Value: permanentResources["secretsManagerCRN"], DataType: "string"},
+		{Name: "existing_secrets_manager_instance_crn", Value: permanentResources.SecretsManagerCRN, DataType: "string"},
 		{Name: "kms_encryption_enabled_cluster", Value: "true", DataType: "bool"},
-		{Name: "existing_kms_instance_crn", Value: permanentResources["hpcs_south_crn"], DataType: "string"},
+		{Name: "existing_kms_instance_crn", Value: permanentResources.HpcsSouthCRN, DataType: "string"},
 		{Name: "kms_encryption_enabled_boot_volume", Value: "true", DataType: "bool"},
 	}
-	options.PostApplyHook = getClusterIngressSchematics
+	options.PostApplyHook = mustGatherOnFailureSchematics(getFullyConfigurableChecksSchematics, "cluster_name")
 	// Temp workaround for https://github.com/terraform-ibm-modules/terraform-ibm-base-ocp-vpc?tab=readme-ov-file#the-specified-api-key-could-not-be-found
-	createContainersApikey(t, options.Region, rg)
+	apikey := createContainersApikey(t, options.Region, rg)
+	trackSchematicsWorkspace(t, options, existing.tracked, apikey)
 	require.NoError(t, options.RunSchematicUpgradeTest(), "This should not have errored")
-	cleanupTerraform(t, existingTerraformOptions, prefix)
 }
 
 // Adding the custom_sg example test to PR test.
@@ -334,12 +1440,14 @@ func TestRunUpgradeFullyConfigurable(t *testing.T) {
 // so we want to keep testing this use-case in the PR pipelines.
 func TestRunCustomsgExample(t *testing.T) {
 	t.Parallel()
+	acquireResources(t)
 
 	options := testhelper.TestOptionsDefaultWithVars(&testhelper.TestOptions{
 		Testing:          t,
 		TerraformDir:     customsgExampleDir,
 		Prefix:           "base-ocp-customsg",
 		ResourceGroup:    resourceGroup,
+		Region:           leaseRegion(t, 1),
 		CloudInfoService: sharedInfoSvc,
 		ImplicitDestroy: []string{
 			"module.ocp_base.null_resource.confirm_network_healthy",
@@ -347,12 +1455,16 @@ func TestRunCustomsgExample(t *testing.T) {
 		ImplicitRequired: false,
 		TerraformVars: map[string]interface{}{
 			"ocp_version":                      ocpVersion2,
-			"access_tags":                      permanentResources["accessTags"],
+			"access_tags":                      permanentResources.AccessTags,
 			"ocp_entitlement":                  "cloud_pak",
 			"enable_openshift_version_upgrade": true,
 		},
 	})
-	options.PostApplyHook = getClusterIngress
+	// both worker pools of the example run RHEL 9, so the cluster keeps the default network plugin
+	expected := expectedNodeFleet(t, ocpVersion2, map[string]string{"default": verify.OperatingSystemRHEL9, "custom-sg": verify.OperatingSystemRHEL9}, verify.DefaultNetworkPlugin)
+	// the example keeps outbound traffic protection enabled
+	hook := withNetworkCheck(withNodeFleetCheck(getClusterIngress, expected), verify.NetworkExpectation{OutboundTraffic: false})
+	options.PostApplyHook = mustGatherOnFailure(hook, "cluster_name")
 
 	// Temp workaround for https://github.com/terraform-ibm-modules/terraform-ibm-base-ocp-vpc?tab=readme-ov-file#the-specified-api-key-could-not-be-found
 	createContainersApikey(t, options.Region, options.ResourceGroup)
@@ -368,9 +1480,10 @@ func TestRunCustomsgExample(t *testing.T) {
 ********************************************************************/
 func TestRunQuickstartSchematics(t *testing.T) {
 	t.Parallel()
+	acquireResources(t)
 
 	options := setupQuickstartOptions(t, "ocp-qs")
-	options.PostApplyHook = getClusterIngressSchematics
+	options.PostApplyHook = mustGatherOnFailureSchematics(getClusterIngressSchematics, "cluster_name")
 
 	// Temp workaround for https://github.com/terraform-ibm-modules/terraform-ibm-base-ocp-vpc?tab=readme-ov-file#the-specified-api-key-could-not-be-found
 	createContainersApikey(t, options.Region, options.ResourceGroup)
@@ -382,9 +1495,10 @@ func TestRunQuickstartSchematics(t *testing.T) {
 // Upgrade test for the Quickstart DA
 func TestRunQuickstartUpgradeSchematics(t *testing.T) {
 	t.Parallel()
+	acquireResources(t)
 
 	options := setupQuickstartOptions(t, "ocp-qs-upg")
-	options.PostApplyHook = getClusterIngressSchematics
+	options.PostApplyHook = mustGatherOnFailureSchematics(getClusterIngressSchematics, "cluster_name")
 
 	// Temp workaround for https://github.com/terraform-ibm-modules/terraform-ibm-base-ocp-vpc?tab=readme-ov-file#the-specified-api-key-could-not-be-found
 	createContainersApikey(t, options.Region, options.ResourceGroup)
@@ -397,6 +1511,7 @@ func TestRunQuickstartUpgradeSchematics(t *testing.T) {
 
 func TestRoksAddonDefaultConfiguration(t *testing.T) {
 	t.Parallel()
+	acquireResources(t)
 
 	options := testaddons.TestAddonsOptionsDefault(&testaddons.TestAddonOptions{
 		Testing:               t,
@@ -405,7 +1520,7 @@ func TestRoksAddonDefaultConfiguration(t *testing.T) {
 		QuietMode:             false, // Suppress logs except on failure
 		OverrideInputMappings: core.BoolPtr(true),
 	})
-	region := "eu-de"
+	region := leaseRegion(t, 1)
 
 	// Temp workaround for https://github.com/terraform-ibm-modules/terraform-ibm-base-ocp-vpc?tab=readme-ov-file#the-specified-api-key-could-not-be-found
 	createContainersApikey(t, region, options.ResourceGroup)
@@ -426,7 +1541,7 @@ func TestRoksAddonDefaultConfiguration(t *testing.T) {
 			OfferingName:   "deploy-arch-ibm-secrets-manager",
 			OfferingFlavor: "fully-configurable",
 			Inputs: map[string]interface{}{
-				"existing_secrets_manager_crn":         permanentResources["privateOnlySecMgrCRN"],
+				"existing_secrets_manager_crn":         permanentResources.PrivateOnlySecMgrCRN,
 				"service_plan":                         "__NULL__", // no plan value needed when using existing SM
 				"skip_secrets_manager_iam_auth_policy": true,       // since using an existing Secrets Manager instance, attempting to re-create auth policy can cause conflicts if the policy already exists
 				"secret_groups":                        []string{}, // passing empty array for secret groups as default value is creating general group and it will cause conflicts as we are using an existing SM
@@ -459,6 +1574,7 @@ func setupOptions(t *testing.T, prefix string, terraformDir string, ocpVersion s
 		TerraformDir:     terraformDir,
 		Prefix:           prefix,
 		ResourceGroup:    resourceGroup,
+		Region:           leaseRegion(t, 1),
 		CloudInfoService: sharedInfoSvc,
 		IgnoreUpdates: testhelper.Exemptions{ // Ignore for consistency check
 			List: []string{
@@ -467,7 +1583,7 @@ func setupOptions(t *testing.T, prefix string, terraformDir string, ocpVersion s
 		},
 		TerraformVars: map[string]interface{}{
 			"ocp_version":     ocpVersion,
-			"access_tags":     permanentResources["accessTags"],
+			"access_tags":     permanentResources.AccessTags,
 			"ocp_entitlement": "cloud_pak",
 		},
 		CheckApplyResultForUpgrade: true,
@@ -478,9 +1594,15 @@ func setupOptions(t *testing.T, prefix string, terraformDir string, ocpVersion s
 
 func TestRunBasicExample(t *testing.T) {
 	t.Parallel()
+	acquireResources(t)
 
 	options := setupOptions(t, "base-ocp", basicExampleDir, ocpVersion4)
-	options.PostApplyHook = getClusterIngress
+	expected := expectedNodeFleet(t, ocpVersion4, map[string]string{"default": verify.OperatingSystemRHCOS}, verify.DefaultNetworkPlugin)
+	// the example disables outbound traffic protection
+	hook := withNetworkCheck(withNodeFleetCheck(getClusterIngress, expected), verify.NetworkExpectation{OutboundTraffic: true})
+	// the example lets the module create the Object Storage instance of the registry
+	hook = withRegistryCheck(hook, "")
+	options.PostApplyHook = mustGatherOnFailure(hook, "cluster_name")
 
 	// Temp workaround for https://github.com/terraform-ibm-modules/terraform-ibm-base-ocp-vpc?tab=readme-ov-file#the-specified-api-key-could-not-be-found
 	createContainersApikey(t, options.Region, resourceGroup)
@@ -0,0 +1,103 @@
+package static
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/terraform-ibm-modules/terraform-ibm-base-ocp-vpc/internal/catalog"
+	"github.com/terraform-ibm-modules/terraform-ibm-base-ocp-vpc/internal/tfstatic"
+)
+
+const catalogPath = "../../ibm_catalog.json"
+
+// TestCatalogMatchesSolutions checks the configuration of every catalog flavor against the variables declared in its
+// working directory
+func TestCatalogMatchesSolutions(t *testing.T) {
+	ibmCatalog, err := catalog.Load(catalogPath)
+	require.NoError(t, err)
+
+	for _, product := range ibmCatalog.Products {
+		for _, flavor := range product.Flavors {
+			t.Run(flavor.Name, func(t *testing.T) {
+				module, err := tfstatic.LoadModule(filepath.Join("../..", flavor.WorkingDirectory))
+				require.NoError(t, err)
+				for _, mismatch := range ibmCatalog.CheckFlavor(flavor, module) {
+					t.Error(mismatch)
+				}
+			})
+		}
+	}
+}
+
+// TestTerraformVersionMatchesCatalog checks that the Terraform version used by the Schematics tests is the one that the
+// catalog flavors deploy with
+func TestTerraformVersionMatchesCatalog(t *testing.T) {
+	ibmCatalog, err := catalog.Load(catalogPath)
+	require.NoError(t, err)
+	version, position := stringConstant(t, "../pr_test.go", "terraformVersion")
+
+	for _, product := range ibmCatalog.Products {
+		for _, flavor := range product.Flavors {
+			if want := "terraform_v" + flavor.TerraformVersion; version != want {
+				t.Errorf("%s: terraformVersion is %q, but flavor %s at %s uses terraform_version %q, so it should be %q", position, version, flavor.Name, ibmCatalog.Location(flavor.Line), flavor.TerraformVersion, want)
+			}
+		}
+	}
+}
+
+// TestAddonPermutationsFromCatalog checks that TestAddonPermutations can generate its cases from the catalog, and that the
+// required addons are deployed in all of them
+func TestAddonPermutationsFromCatalog(t *testing.T) {
+	ibmCatalog, err := catalog.Load(catalogPath)
+	require.NoError(t, err)
+	flavor, err := ibmCatalog.Flavor("deploy-arch-ibm-slz-ocp", "fully-configurable")
+	require.NoError(t, err)
+
+	for _, coverage := range []catalog.Coverage{catalog.CoverageFull, catalog.CoveragePairwise, catalog.CoverageSmoke} {
+		cases, err := flavor.AddonPermutations(coverage)
+		require.NoError(t, err)
+		t.Logf("%s coverage: %d cases", coverage, len(cases))
+		for _, addonCase := range cases {
+			for _, addon := range addonCase.Addons {
+				if addon.Name == "deploy-arch-ibm-slz-vpc" || addon.Name == "deploy-arch-ibm-cos" {
+					require.True(t, addon.Required && addon.Enabled, "%s is not enabled in %s", addon.Name, addonCase.Name)
+				}
+			}
+		}
+	}
+}
+
+// stringConstant returns the value and position of a package level string constant in a Go file
+func stringConstant(t *testing.T, path string, name string) (string, token.Position) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, path, nil, 0)
+	require.NoError(t, err)
+
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.CONST {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			valueSpec := spec.(*ast.ValueSpec)
+			for i, ident := range valueSpec.Names {
+				if ident.Name != name || i >= len(valueSpec.Values) {
+					continue
+				}
+				literal, ok := valueSpec.Values[i].(*ast.BasicLit)
+				require.True(t, ok && literal.Kind == token.STRING, "%s is not a string literal", name)
+				value, err := strconv.Unquote(literal.Value)
+				require.NoError(t, err)
+				return value, fset.Position(literal.Pos())
+			}
+		}
+	}
+	require.FailNow(t, "constant not found", "%s does not declare the constant %s", path, name)
+	return "", token.Position{}
+}
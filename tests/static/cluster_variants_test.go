@@ -0,0 +1,171 @@
+package static
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zclconf/go-cty/cty"
+
+	"github.com/terraform-ibm-modules/terraform-ibm-base-ocp-vpc/internal/tfstatic"
+)
+
+const clusterResourceType = "ibm_container_vpc_cluster"
+
+// clusterVariant is one of the cluster resources that main.tf selects from enable_openshift_version_upgrade and
+// ignore_worker_pool_size_changes
+type clusterVariant struct {
+	enableUpgrade bool
+	autoscaling   bool
+	instance      string
+}
+
+var clusterVariants = []clusterVariant{
+	{enableUpgrade: false, autoscaling: false, instance: "ibm_container_vpc_cluster.cluster[0]"},
+	{enableUpgrade: true, autoscaling: false, instance: "ibm_container_vpc_cluster.cluster_with_upgrade[0]"},
+	{enableUpgrade: false, autoscaling: true, instance: "ibm_container_vpc_cluster.autoscaling_cluster[0]"},
+	{enableUpgrade: true, autoscaling: true, instance: "ibm_container_vpc_cluster.autoscaling_cluster_with_upgrade[0]"},
+}
+
+func (v clusterVariant) String() string {
+	return fmt.Sprintf("enable_openshift_version_upgrade=%t,ignore_worker_pool_size_changes=%t", v.enableUpgrade, v.autoscaling)
+}
+
+func (v clusterVariant) evaluate(t *testing.T, module *tfstatic.Module) *tfstatic.Evaluation {
+	evaluation, err := module.Evaluate(map[string]cty.Value{
+		"enable_openshift_version_upgrade": cty.BoolVal(v.enableUpgrade),
+		"ignore_worker_pool_size_changes":  cty.BoolVal(v.autoscaling),
+	})
+	require.NoError(t, err)
+	return evaluation
+}
+
+func loadRootModule(t *testing.T) *tfstatic.Module {
+	module, err := tfstatic.LoadModule("../..")
+	require.NoError(t, err, "Failed to load the root module")
+	return module
+}
+
+// clusterOutputs returns the outputs of the module that read attributes of a cluster resource
+func clusterOutputs(module *tfstatic.Module) []string {
+	var outputs []string
+	for name, expr := range module.Outputs {
+		for _, traversal := range expr.Variables() {
+			if root := traversal.RootName(); root == clusterResourceType || (root == "local" && strings.HasPrefix(name, "cluster_")) {
+				outputs = append(outputs, name)
+				break
+			}
+		}
+	}
+	slices.Sort(outputs)
+	return outputs
+}
+
+// TestClusterVariants checks that every combination of the variant toggles selects exactly one cluster resource, and
+// that all outputs reading the cluster resolve to that resource
+func TestClusterVariants(t *testing.T) {
+	module := loadRootModule(t)
+	outputs := clusterOutputs(module)
+	require.Contains(t, outputs, "cluster_id")
+	require.Contains(t, outputs, "cluster_crn")
+
+	for _, variant := range clusterVariants {
+		t.Run(variant.String(), func(t *testing.T) {
+			evaluation := variant.evaluate(t, module)
+
+			instances, err := evaluation.Instances(clusterResourceType)
+			require.NoError(t, err)
+			assert.Equal(t, []string{variant.instance}, instances, "expected exactly one cluster resource")
+
+			for _, name := range outputs {
+				value, err := evaluation.Output(name)
+				if !assert.NoError(t, err, "output %s", name) {
+					continue
+				}
+				if assert.True(t, value.IsKnown() && !value.IsNull() && value.Type() == cty.String, "output %s does not resolve to a cluster attribute: %#v", name, value) {
+					assert.True(t, strings.HasPrefix(value.AsString(), variant.instance+"."), "output %s resolves to %s, expected an attribute of %s", name, value.AsString(), variant.instance)
+				}
+			}
+		})
+	}
+}
+
+const upgradingDoc = "../../docs/upgrading-ocp-version.md"
+
+var (
+	hclFence     = regexp.MustCompile("(?s)```hcl\n(.*?)```")
+	modulePrefix = regexp.MustCompile(`^module\.[^.]+\.`)
+)
+
+// docsMovedBlocks returns the moved blocks that docs/upgrading-ocp-version.md asks consumers to add when they enable
+// enable_openshift_version_upgrade on an existing cluster, with the module prefix removed
+func docsMovedBlocks(t *testing.T) []tfstatic.Moved {
+	doc, err := os.ReadFile(upgradingDoc)
+	require.NoError(t, err)
+	var blocks []tfstatic.Moved
+	for _, fence := range hclFence.FindAllSubmatch(doc, -1) {
+		moves, err := tfstatic.ParseMoved(fence[1], upgradingDoc)
+		require.NoError(t, err)
+		for _, moved := range moves {
+			blocks = append(blocks, tfstatic.Moved{
+				From: modulePrefix.ReplaceAllString(moved.From, ""),
+				To:   modulePrefix.ReplaceAllString(moved.To, ""),
+			})
+		}
+	}
+	require.NotEmpty(t, blocks, "found no moved blocks in %s", upgradingDoc)
+	return blocks
+}
+
+// TestClusterVariantToggles evaluates every switch from one variant to another on existing state, and reports whether a
+// moved block of the module, or one of the moved blocks documented for consumers, keeps the cluster from being
+// replaced. Every documented moved block must match a switch, and enabling the upgrade must be covered.
+func TestClusterVariantToggles(t *testing.T) {
+	module := loadRootModule(t)
+	documented := docsMovedBlocks(t)
+	withDocs := *module
+	withDocs.Moved = append(slices.Clone(module.Moved), documented...)
+
+	var switches []tfstatic.Moved
+	var report strings.Builder
+	fmt.Fprintf(&report, "%-62s %-62s %-10s %s\n", "from", "to", "module", "with documented moved blocks")
+	for _, from := range clusterVariants {
+		for _, to := range clusterVariants {
+			if from == to {
+				continue
+			}
+			// the addresses come from the evaluation, so a change to the variant selection in main.tf is caught here
+			fromInstances, err := from.evaluate(t, module).Instances(clusterResourceType)
+			require.NoError(t, err)
+			toInstances, err := to.evaluate(t, module).Instances(clusterResourceType)
+			require.NoError(t, err)
+			require.Len(t, fromInstances, 1)
+			require.Len(t, toInstances, 1)
+			switches = append(switches, tfstatic.Moved{From: fromInstances[0], To: toInstances[0]})
+
+			moved := module.Moves(fromInstances[0], toInstances[0])
+			movedWithDocs := withDocs.Moves(fromInstances[0], toInstances[0])
+			fmt.Fprintf(&report, "%-62s %-62s %-10s %s\n", fromInstances[0], toInstances[0], replacement(moved), replacement(movedWithDocs))
+
+			if !from.enableUpgrade && to.enableUpgrade && from.autoscaling == to.autoscaling {
+				assert.True(t, movedWithDocs, "enabling the upgrade replaces %s with %s, add a moved block to %s", fromInstances[0], toInstances[0], upgradingDoc)
+			}
+		}
+	}
+	for _, moved := range documented {
+		assert.Contains(t, switches, moved, "documented moved block does not match the addresses of a switch between variants")
+	}
+	t.Logf("Cluster replacement when switching between variants:\n%s", report.String())
+}
+
+func replacement(moved bool) string {
+	if moved {
+		return "moved"
+	}
+	return "replaced"
+}
@@ -0,0 +1,3 @@
+// Package static holds offline tests of the Terraform configuration of the module. They evaluate the configuration
+// without providers, so they run without an API key and without provisioning anything.
+package static
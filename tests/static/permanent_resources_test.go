@@ -0,0 +1,38 @@
+package static
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zclconf/go-cty/cty"
+
+	"github.com/terraform-ibm-modules/terraform-ibm-base-ocp-vpc/internal/permanent"
+	"github.com/terraform-ibm-modules/terraform-ibm-base-ocp-vpc/internal/tfstatic"
+)
+
+const fullyConfigurableDir = "../../solutions/fully-configurable"
+
+// TestFullyConfigurableAcceptsPermanentResources evaluates the fully configurable solution with the permanent resources
+// that TestRunFullyConfigurableInSchematics passes, using fake values, so that the input validations run offline
+func TestFullyConfigurableAcceptsPermanentResources(t *testing.T) {
+	module, err := tfstatic.LoadModule(fullyConfigurableDir)
+	require.NoError(t, err)
+
+	resources := permanent.Fake()
+	_, err = module.Evaluate(map[string]cty.Value{
+		"prefix":                                cty.StringVal("fc"),
+		"kms_encryption_enabled_cluster":        cty.True,
+		"kms_encryption_enabled_boot_volume":    cty.True,
+		"existing_kms_instance_crn":             cty.StringVal(resources.HpcsSouthCRN),
+		"enable_secrets_manager_integration":    cty.True,
+		"existing_secrets_manager_instance_crn": cty.StringVal(resources.SecretsManagerCRN),
+	})
+	require.NoError(t, err)
+
+	_, err = module.Evaluate(map[string]cty.Value{
+		"prefix":                    cty.StringVal("fc"),
+		"existing_kms_instance_crn": cty.StringVal(resources.HpcsSouthRootKeyCRN),
+	})
+	assert.ErrorContains(t, err, `invalid value for variable "existing_kms_instance_crn"`, "a key CRN is not a KMS instance CRN")
+}
@@ -0,0 +1,147 @@
+package static
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zclconf/go-cty/cty"
+
+	"github.com/terraform-ibm-modules/terraform-ibm-base-ocp-vpc/internal/tfstatic"
+)
+
+const quickstartDir = "../../solutions/quickstart"
+
+// sizePreset is the default worker pool that a size of the quickstart solution deploys, as documented in DA_docs.md and
+// the catalog options of the size input
+type sizePreset struct {
+	flavor         string
+	workersPerZone int
+	zones          int
+}
+
+var sizePresets = map[string]sizePreset{
+	"mini":   {flavor: "bx2.4x16", workersPerZone: 1, zones: 2},
+	"small":  {flavor: "bx2.8x32", workersPerZone: 1, zones: 3},
+	"medium": {flavor: "bx2.8x32", workersPerZone: 2, zones: 3},
+	"large":  {flavor: "bx2.16x64", workersPerZone: 3, zones: 3},
+}
+
+// quickstartZones is the number of zones that the quickstart VPC declares subnets for
+const quickstartZones = 3
+
+func evaluateQuickstartSize(t *testing.T, module *tfstatic.Module, size string) *tfstatic.Evaluation {
+	evaluation, err := module.Evaluate(map[string]cty.Value{
+		"size":   cty.StringVal(size),
+		"prefix": cty.StringVal("qs"),
+	})
+	require.NoError(t, err)
+	return evaluation
+}
+
+func local(t *testing.T, evaluation *tfstatic.Evaluation, name string) cty.Value {
+	value, err := evaluation.Local(name)
+	require.NoError(t, err)
+	return value
+}
+
+func intValue(t *testing.T, value cty.Value) int {
+	require.True(t, value.IsKnown() && value.Type() == cty.Number, "%#v is not a known number", value)
+	i, _ := value.AsBigFloat().Int64()
+	return int(i)
+}
+
+// TestQuickstartSizes evaluates every size of the quickstart solution and checks the default worker pool and the subnet
+// layout against the preset table
+func TestQuickstartSizes(t *testing.T) {
+	module, err := tfstatic.LoadModule(quickstartDir)
+	require.NoError(t, err)
+
+	for size, preset := range sizePresets {
+		t.Run(size, func(t *testing.T) {
+			evaluation := evaluateQuickstartSize(t, module, size)
+
+			pools := local(t, evaluation, "worker_pools").AsValueSlice()
+			require.Len(t, pools, 1)
+			pool := pools[0]
+			assert.Equal(t, "default", pool.GetAttr("pool_name").AsString())
+			assert.Equal(t, preset.flavor, pool.GetAttr("machine_type").AsString())
+			assert.Equal(t, preset.workersPerZone, intValue(t, pool.GetAttr("workers_per_zone")))
+			assert.Equal(t, preset.zones, pool.GetAttr("vpc_subnets").LengthInt(), "zones of the default pool")
+
+			clusterSubnets := local(t, evaluation, "cluster_vpc_subnets").GetAttr("default")
+			assert.Equal(t, preset.zones, clusterSubnets.LengthInt(), "subnets in cluster_vpc_subnets")
+
+			subnets := local(t, evaluation, "subnets")
+			gateways := local(t, evaluation, "public_gateway")
+			for zone := 1; zone <= quickstartZones; zone++ {
+				key := fmt.Sprintf("zone-%d", zone)
+				inUse := zone <= preset.zones
+				zoneSubnets := subnets.GetAttr(key)
+				assert.Equal(t, gateways.GetAttr(key), cty.BoolVal(inUse), "public gateway of %s", key)
+				if !inUse {
+					assert.Equal(t, 0, zoneSubnets.LengthInt(), "subnets of unused %s", key)
+					continue
+				}
+				require.Equal(t, 1, zoneSubnets.LengthInt(), "subnets of %s", key)
+				subnet := zoneSubnets.Index(cty.NumberIntVal(0))
+				assert.Equal(t, fmt.Sprintf("qs-subnet-%d", zone), subnet.GetAttr("name").AsString())
+				assert.Equal(t, fmt.Sprintf("10.%d.10.0/24", 10+(zone-1)*10), subnet.GetAttr("cidr").AsString())
+			}
+		})
+	}
+}
+
+// TestQuickstartSizesMatchValidation fails if the sizes accepted by the validation of the size variable, the presets in
+// local.size_config and the preset table of this test drift apart
+func TestQuickstartSizesMatchValidation(t *testing.T) {
+	module, err := tfstatic.LoadModule(quickstartDir)
+	require.NoError(t, err)
+	variable := module.Variables["size"]
+	require.NotNil(t, variable, "quickstart solution has no size variable")
+	require.NotEmpty(t, variable.Validations, "size variable has no validation")
+
+	evaluation := evaluateQuickstartSize(t, module, variable.Default.AsString())
+	var configured []string
+	for key := range local(t, evaluation, "size_config").AsValueMap() {
+		configured = append(configured, key)
+	}
+	slices.Sort(configured)
+
+	var accepted []string
+	for _, validation := range variable.Validations {
+		accepted = append(accepted, stringLiterals(validation.Condition)...)
+	}
+	slices.Sort(accepted)
+
+	var tested []string
+	for size := range sizePresets {
+		tested = append(tested, size)
+	}
+	slices.Sort(tested)
+
+	assert.Equal(t, configured, accepted, "sizes accepted by the validation at %s do not match the keys of local.size_config", variable.Range)
+	assert.Equal(t, configured, tested, "keys of local.size_config do not match the preset table of this test")
+	for _, size := range configured {
+		_, err := module.Evaluate(map[string]cty.Value{"size": cty.StringVal(size)})
+		assert.NoError(t, err, "size %s of local.size_config is rejected", size)
+	}
+	_, err = module.Evaluate(map[string]cty.Value{"size": cty.StringVal("huge")})
+	assert.ErrorContains(t, err, `invalid value for variable "size"`)
+}
+
+// stringLiterals returns the string literals of an expression, e.g. the allowed values of a contains() condition
+func stringLiterals(expr hcl.Expression) []string {
+	var literals []string
+	hclsyntax.VisitAll(expr.(hclsyntax.Node), func(node hclsyntax.Node) hcl.Diagnostics {
+		if literal, ok := node.(*hclsyntax.LiteralValueExpr); ok && literal.Val.Type() == cty.String {
+			literals = append(literals, literal.Val.AsString())
+		}
+		return nil
+	})
+	return literals
+}
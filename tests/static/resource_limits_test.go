@@ -0,0 +1,41 @@
+package static
+
+import (
+	"maps"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/terraform-ibm-modules/terraform-ibm-base-ocp-vpc/internal/catalog"
+	"github.com/terraform-ibm-modules/terraform-ibm-base-ocp-vpc/internal/governor"
+	"github.com/terraform-ibm-modules/terraform-ibm-base-ocp-vpc/internal/shard"
+)
+
+// TestResourceLimitsMatchTests checks that resource-limits.yaml only weights tests of the tests package and addons of
+// the fully-configurable flavor, so that a renamed test or addon does not silently fall back to the default weight
+func TestResourceLimitsMatchTests(t *testing.T) {
+	config, err := governor.LoadConfig("../resource-limits.yaml")
+	require.NoError(t, err)
+
+	tests, err := shard.TestNames("..")
+	require.NoError(t, err)
+	for _, name := range slices.Sorted(maps.Keys(config.Weights)) {
+		if name != governor.DefaultWeight {
+			assert.Contains(t, tests, name, "weight of an unknown test")
+		}
+	}
+
+	cat, err := catalog.Load("../../ibm_catalog.json")
+	require.NoError(t, err)
+	flavor, err := cat.Flavor("deploy-arch-ibm-slz-ocp", "fully-configurable")
+	require.NoError(t, err)
+	var addons []string
+	for _, dependency := range flavor.Dependencies {
+		addons = append(addons, dependency.Name)
+	}
+	for _, name := range slices.Sorted(maps.Keys(config.Addons)) {
+		assert.Contains(t, addons, name, "weight of an unknown addon")
+	}
+}